@@ -0,0 +1,235 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxAliasDepth bounds alias-to-alias expansion even when no cycle is found.
+const maxAliasDepth = 16
+
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+var builtinCommands = map[string]bool{
+	"parse":         true,
+	"show":          true,
+	"applescript":   true,
+	"as":            true,
+	"validate":      true,
+	"doctor":        true,
+	"config":        true,
+	"cache":         true,
+	"eventkit":      true,
+	"mcp":           true,
+	"serve":         true,
+	"greta":         true,
+	"explain":       true,
+	"man":           true,
+	"completion":    true,
+	"help":          true,
+	"-h":            true,
+	"--help":        true,
+	"version":       true,
+	"--version":     true,
+	completeCommand: true,
+}
+
+func isBuiltinCommand(name string) bool {
+	return builtinCommands[strings.ToLower(name)]
+}
+
+type aliasInfo struct {
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+}
+
+func validateAliases(aliases map[string]string) error {
+	for name, expansion := range aliases {
+		if !aliasNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid alias name %q (use letters, digits, '.', '_' or '-')", name)
+		}
+		if isBuiltinCommand(name) {
+			return fmt.Errorf("alias %q shadows a built-in command", name)
+		}
+		if strings.TrimSpace(expansion) == "" {
+			return fmt.Errorf("alias %q is empty", name)
+		}
+		if _, err := splitCommandLine(expansion); err != nil {
+			return fmt.Errorf("alias %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// sortedAliases returns aliases ordered by name for help, greta and completions.
+func sortedAliases(aliases map[string]string) []aliasInfo {
+	out := make([]aliasInfo, 0, len(aliases))
+	for name, expansion := range aliases {
+		out = append(out, aliasInfo{Name: name, Expansion: expansion})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func aliasNames(aliases map[string]string) []string {
+	names := make([]string, 0, len(aliases))
+	for _, alias := range sortedAliases(aliases) {
+		names = append(names, alias.Name)
+	}
+	return names
+}
+
+// loadAliases returns the aliases from the default config locations. Help
+// output must not depend on a valid config, so a config that fails to load
+// is reported on errOut and treated as defining no aliases.
+func loadAliases(errOut io.Writer) map[string]string {
+	cfg, err := loadConfigWithPath("")
+	if err != nil {
+		fmt.Fprintf(errOut, "[fantastical] warning: ignoring aliases: %v\n", err)
+		return nil
+	}
+	return cfg.Aliases
+}
+
+// expandRunAlias loads aliases (honoring --config in args) and expands args.
+func expandRunAlias(args []string) ([]string, error) {
+	configPath, err := extractConfigPath(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfigWithPath(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return expandAlias(args, cfg.Aliases)
+}
+
+// expandAlias rewrites args (command first) while the command names an alias.
+// $@ in an alias is replaced by all remaining args and $1..$9 by single args;
+// if neither appears, the remaining args are appended. It returns args
+// unchanged when the command is not an alias.
+func expandAlias(args []string, aliases map[string]string) ([]string, error) {
+	if len(args) == 0 || len(aliases) == 0 {
+		return args, nil
+	}
+
+	chain := []string{}
+	seen := map[string]bool{}
+	for !isBuiltinCommand(args[0]) {
+		name := args[0]
+		expansion, ok := aliases[name]
+		if !ok {
+			return args, nil
+		}
+		chain = append(chain, name)
+		if seen[name] {
+			return nil, fmt.Errorf("%w: alias cycle: %s", errUsage, strings.Join(chain, " -> "))
+		}
+		if len(chain) > maxAliasDepth {
+			return nil, fmt.Errorf("%w: alias expansion too deep: %s", errUsage, strings.Join(chain, " -> "))
+		}
+		seen[name] = true
+
+		tokens, err := splitCommandLine(expansion)
+		if err != nil {
+			return nil, fmt.Errorf("alias %q: %w", name, err)
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("alias %q is empty", name)
+		}
+		args = substituteAliasArgs(tokens, args[1:])
+	}
+	return args, nil
+}
+
+func substituteAliasArgs(tokens, rest []string) []string {
+	out := make([]string, 0, len(tokens)+len(rest))
+	placeholder := false
+	for _, token := range tokens {
+		if token == "$@" {
+			out = append(out, rest...)
+			placeholder = true
+			continue
+		}
+		if len(token) == 2 && token[0] == '$' && token[1] >= '1' && token[1] <= '9' {
+			idx, _ := strconv.Atoi(token[1:])
+			if idx <= len(rest) {
+				out = append(out, rest[idx-1])
+			}
+			placeholder = true
+			continue
+		}
+		out = append(out, token)
+	}
+	if !placeholder {
+		out = append(out, rest...)
+	}
+	return out
+}
+
+// splitCommandLine splits an alias body into words using shell-like quoting:
+// single quotes are literal, double quotes allow backslash escapes.
+func splitCommandLine(s string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+	)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				if i+1 < len(runes) {
+					i++
+					current.WriteRune(runes[i])
+				}
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,155 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeAliasConfig(t *testing.T, config string) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("FANTASTICAL_CONFIG", configPath)
+	isolateHelperEnv(t)
+}
+
+func TestExpandAlias(t *testing.T) {
+	aliases := map[string]string{
+		"standup": "parse --add --calendar Team 'Daily standup at 9:15am'",
+		"wk":      "eventkit events --this-week --format table",
+		"mine":    "wk --calendar $1",
+		"quick":   "parse --open=false $@ -- Quick sync",
+	}
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"standup"}, []string{"parse", "--add", "--calendar", "Team", "Daily standup at 9:15am"}},
+		{[]string{"wk", "--json"}, []string{"eventkit", "events", "--this-week", "--format", "table", "--json"}},
+		{[]string{"mine", "Work"}, []string{"eventkit", "events", "--this-week", "--format", "table", "--calendar", "Work"}},
+		{[]string{"quick", "--print", "--json"}, []string{"parse", "--open=false", "--print", "--json", "--", "Quick", "sync"}},
+		{[]string{"parse", "x"}, []string{"parse", "x"}},
+		{[]string{"unknown"}, []string{"unknown"}},
+	}
+	for _, tc := range cases {
+		got, err := expandAlias(tc.args, aliases)
+		if err != nil {
+			t.Fatalf("expandAlias(%v): %v", tc.args, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("expandAlias(%v) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestExpandAliasCycle(t *testing.T) {
+	aliases := map[string]string{
+		"a": "b --x",
+		"b": "c",
+		"c": "a",
+	}
+	_, err := expandAlias([]string{"a"}, aliases)
+	if err == nil {
+		t.Fatalf("expected cycle error")
+	}
+	if !errors.Is(err, errUsage) || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateAliases(t *testing.T) {
+	bad := []map[string]string{
+		{"parse": "show mini"},
+		{"has space": "show mini"},
+		{"empty": "  "},
+		{"quote": "parse 'unterminated"},
+	}
+	for _, aliases := range bad {
+		if err := validateAliases(aliases); err == nil {
+			t.Fatalf("expected error for %v", aliases)
+		}
+	}
+}
+
+func TestRunAlias(t *testing.T) {
+	writeAliasConfig(t, `{"aliases":{"standup":"parse --open=false --print --calendar Team $@ -- Daily standup"}}`)
+
+	var out, errOut bytes.Buffer
+	code := run([]string{appName, "standup", "--add"}, strings.NewReader(""), &out, &errOut)
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d (%s)", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "calendarName=Team") || !strings.Contains(out.String(), "add=1") {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "s=Daily%20standup") {
+		t.Fatalf("unexpected sentence: %q", out.String())
+	}
+}
+
+func TestRunAliasCycleExitCode(t *testing.T) {
+	writeAliasConfig(t, `{"aliases":{"a":"b","b":"a"}}`)
+
+	var out, errOut bytes.Buffer
+	code := run([]string{appName, "a"}, strings.NewReader(""), &out, &errOut)
+	if code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "alias cycle") {
+		t.Fatalf("unexpected stderr: %q", errOut.String())
+	}
+}
+
+func TestAliasesInHelpGretaAndCompletion(t *testing.T) {
+	writeAliasConfig(t, `{"aliases":{"wk":"eventkit events --this-week --format table"}}`)
+
+	var out, errOut bytes.Buffer
+	if err := cmdHelp(nil, &out, &errOut); err != nil {
+		t.Fatalf("help: %v", err)
+	}
+	if !strings.Contains(out.String(), "ALIASES") || !strings.Contains(out.String(), "wk  eventkit events") {
+		t.Fatalf("expected aliases in help: %q", out.String())
+	}
+
+	out.Reset()
+	if err := cmdGreta(context.Background(), []string{"--format", "json"}, &out, &errOut); err != nil {
+		t.Fatalf("greta: %v", err)
+	}
+	if !strings.Contains(out.String(), `"aliases":[{"name":"wk","expansion":"eventkit events --this-week --format table"}]`) {
+		t.Fatalf("expected aliases in greta: %q", out.String())
+	}
+
+	out.Reset()
+	if err := cmdComplete([]string{"w"}, &out, &errOut); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if !strings.Contains(out.String(), "wk\tAlias (config)\n") {
+		t.Fatalf("expected alias in completion: %q", out.String())
+	}
+}
+
+func TestHelpAndGretaSurviveBrokenConfig(t *testing.T) {
+	writeAliasConfig(t, `{"aliases": `)
+
+	for _, args := range [][]string{{"--help"}, {"help"}, {"help", "--json"}, {"help", "parse"}, {"greta", "--format", "markdown"}} {
+		var out, errOut bytes.Buffer
+		if code := run(append([]string{"fantastical"}, args...), strings.NewReader(""), &out, &errOut); code != 0 {
+			t.Fatalf("%v: expected exit 0, got %d: %s", args, code, errOut.String())
+		}
+		if out.Len() == 0 || !strings.Contains(errOut.String(), "warning: ignoring aliases: ") {
+			t.Fatalf("%v: expected output and a warning, got %q / %q", args, out.String(), errOut.String())
+		}
+	}
+}
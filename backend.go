@@ -0,0 +1,205 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CalendarBackend is a calendar store the eventkit commands, MCP tools and
+// HTTP API read from. The EventKit helper is the default; the ics backend
+// reads exported .ics files or a vdir directory and the caldav backend a
+// CalDAV server, both without Calendar access.
+type CalendarBackend interface {
+	// Name identifies the backend, e.g. "eventkit" or "ics".
+	Name() string
+	ListCalendars(ctx context.Context) ([]calendarInfo, error)
+	ListEvents(ctx context.Context, req eventFetch) ([]eventInfo, error)
+	CreateEvent(ctx context.Context, ev newEvent) (eventInfo, error)
+	// DeleteEvent removes the event with the given UID, with all of its
+	// occurrences, from a calendar (title or ID).
+	DeleteEvent(ctx context.Context, calendar, id string) error
+}
+
+var (
+	// errBackendUnsupported reports an operation a backend cannot perform.
+	errBackendUnsupported = errors.New("not supported by this backend")
+	errEventNotFound      = errors.New("event not found")
+)
+
+// eventFetch selects the events a backend returns: those overlapping Range
+// in the given calendars (all when empty). Times are reported in Range's
+// location.
+type eventFetch struct {
+	Range           eventRange
+	Calendars       []calendarRef
+	IncludeAllDay   bool
+	IncludeDeclined bool
+	// Timezone is the --tz name passed to the EventKit helper.
+	Timezone string
+	Refresh  bool
+	NoInput  bool
+}
+
+// newEvent is an event to create. Calendar is a calendar title or ID.
+type newEvent struct {
+	Calendar string
+	Title    string
+	Start    time.Time
+	End      time.Time
+	AllDay   bool
+	Location string
+	Notes    string
+}
+
+// backendNames lists the --backend values.
+var backendNames = []string{"eventkit", "ics", "caldav", "fixture"}
+
+// backendSelection is a resolved --backend: the backend name and its
+// source (a path or URL; empty for eventkit and the built-in fixture).
+type backendSelection struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+}
+
+// selectBackend picks the backend from --backend, then config backend.name
+// (or FANTASTICAL_BACKEND), defaulting to eventkit. A spec may carry its
+// source inline, e.g. ics:~/calendars or caldav:https://dav.example.com/;
+// otherwise backend.<name>.path (or backend.caldav.url) is used.
+func selectBackend(spec string, cfg *Config) (backendSelection, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" && cfg != nil {
+		spec = strings.TrimSpace(cfg.Backend.Name)
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	name, source, _ := strings.Cut(spec, ":")
+	name = strings.ToLower(strings.TrimSpace(name))
+	source = strings.TrimSpace(source)
+	switch name {
+	case "", "eventkit":
+		if source != "" {
+			return backendSelection{}, fmt.Errorf("%w: the eventkit backend takes no path", errUsage)
+		}
+		return backendSelection{Name: "eventkit"}, nil
+	case "ics", "vdir":
+		if source == "" {
+			source = strings.TrimSpace(cfg.Backend.ICS.Path)
+		}
+		if source == "" {
+			return backendSelection{}, fmt.Errorf("%w: the ics backend needs a path (--backend ics:/path or backend.ics.path)", errUsage)
+		}
+		return backendSelection{Name: "ics", Source: expandHome(source)}, nil
+	case "caldav":
+		if source == "" {
+			source = strings.TrimSpace(cfg.Backend.CalDAV.URL)
+		}
+		if source == "" {
+			return backendSelection{}, fmt.Errorf("%w: the caldav backend needs a URL (--backend caldav:https://... or backend.caldav.url)", errUsage)
+		}
+		return backendSelection{Name: "caldav", Source: source}, nil
+	case "fixture":
+		if source == "" {
+			source = strings.TrimSpace(cfg.Backend.Fixture.Path)
+		}
+		if source != "" {
+			source = expandHome(source)
+		}
+		return backendSelection{Name: "fixture", Source: source}, nil
+	default:
+		return backendSelection{}, fmt.Errorf("%w: unknown backend %q (want %s)", errUsage, name, strings.Join(backendNames, ", "))
+	}
+}
+
+// resolveBackend opens the backend selectBackend picks.
+func resolveBackend(spec string, cfg *Config, errOut io.Writer, verbose bool) (CalendarBackend, error) {
+	sel, err := selectBackend(spec, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Source != "" {
+		logVerbose(errOut, verbose, "%s backend: %s", sel.Name, sel.Source)
+	}
+	switch sel.Name {
+	case "ics":
+		return newICSBackend(sel.Source, errOut, verbose)
+	case "caldav":
+		dav := CalDAVBackendConfig{}
+		if cfg != nil {
+			dav = cfg.Backend.CalDAV
+		}
+		return newCalDAVBackend(sel.Source, dav.Username, dav.Password, errOut, verbose)
+	case "fixture":
+		return newFixtureBackend(sel.Source)
+	default:
+		return &eventKitBackend{errOut: errOut, verbose: verbose}, nil
+	}
+}
+
+// expandHome replaces a leading ~/ with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// eventKitBackend reads through the Swift EventKit helper.
+type eventKitBackend struct {
+	errOut  io.Writer
+	verbose bool
+}
+
+func (b *eventKitBackend) Name() string { return "eventkit" }
+
+func (b *eventKitBackend) ListCalendars(ctx context.Context) ([]calendarInfo, error) {
+	return fetchEventKitCalendars(ctx, b.errOut, b.verbose)
+}
+
+// ListEvents runs the helper's events command, in chunks for long ranges.
+func (b *eventKitBackend) ListEvents(ctx context.Context, req eventFetch) ([]eventInfo, error) {
+	args := []string{"events", "--format", "json"}
+	if req.NoInput {
+		args = append(args, "--no-input")
+	}
+	for _, ref := range req.Calendars {
+		if ref.byID {
+			args = append(args, "--calendar-id", ref.ID)
+			continue
+		}
+		args = append(args, "--calendar", ref.Title)
+	}
+	if !req.IncludeAllDay {
+		args = append(args, "--no-all-day")
+	}
+	if req.IncludeDeclined {
+		args = append(args, "--include-declined")
+	}
+	if tz := strings.TrimSpace(req.Timezone); tz != "" {
+		args = append(args, "--tz", tz)
+	}
+	return fetchEventRange(ctx, args, req.Range, req.Refresh, b.errOut, b.verbose)
+}
+
+// CreateEvent is unsupported: events are created through Fantastical (parse).
+func (b *eventKitBackend) CreateEvent(context.Context, newEvent) (eventInfo, error) {
+	return eventInfo{}, fmt.Errorf("eventkit backend: creating events: %w; use fantastical parse", errBackendUnsupported)
+}
+
+// DeleteEvent is unsupported: the helper only reads.
+func (b *eventKitBackend) DeleteEvent(context.Context, string, string) error {
+	return fmt.Errorf("eventkit backend: deleting events: %w", errBackendUnsupported)
+}
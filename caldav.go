@@ -0,0 +1,464 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// caldavTimeout bounds each CalDAV request.
+const caldavTimeout = 30 * time.Second
+
+// caldavMaxResponse caps a response body; a year of a busy calendar is a
+// few megabytes.
+const caldavMaxResponse = 64 << 20
+
+// caldavBackend reads and writes events on a CalDAV server (Nextcloud,
+// Radicale, Fastmail, iCloud). Calendars are discovered from the configured
+// URL with PROPFIND; events are queried with a calendar-query REPORT over
+// the requested range and expanded like the ics backend's.
+type caldavBackend struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+	errOut   io.Writer
+	verbose  bool
+	// calendars is filled on first use; IDs are collection paths.
+	calendars []calendarInfo
+}
+
+func newCalDAVBackend(rawURL, username, password string, errOut io.Writer, verbose bool) (*caldavBackend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid CalDAV URL %q", errUsage, rawURL)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	client := &http.Client{
+		Timeout: caldavTimeout,
+		// Credentials never follow a redirect off the configured origin.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !sameOrigin(req.URL, u) {
+				req.Header.Del("Authorization")
+			}
+			return nil
+		},
+	}
+	return &caldavBackend{
+		base:     u,
+		username: username,
+		password: password,
+		client:   client,
+		errOut:   errOut,
+		verbose:  verbose,
+	}, nil
+}
+
+func (b *caldavBackend) Name() string { return "caldav" }
+
+func (b *caldavBackend) warn(format string, args ...any) {
+	fmt.Fprintf(b.errOut, "[fantastical] warning: "+format+"\n", args...)
+}
+
+// davMultistatus is a WebDAV 207 Multi-Status body. Only the properties
+// the backend asks for are decoded.
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	Propstats []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davProp struct {
+	ResourceType struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName string  `xml:"DAV: displayname"`
+	Color       string  `xml:"http://apple.com/ns/ical/ calendar-color"`
+	Principal   davHref `xml:"DAV: current-user-principal"`
+	Home        davHref `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	Components  struct {
+		Comps []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	ETag         string `xml:"DAV: getetag"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+// prop merges the response's successful propstats; servers report missing
+// properties in a separate 404 propstat.
+func (r davResponse) prop() (davProp, bool) {
+	var merged davProp
+	ok := false
+	for _, ps := range r.Propstats {
+		if !strings.Contains(ps.Status, " 200 ") {
+			continue
+		}
+		ok = true
+		p := ps.Prop
+		if p.ResourceType.Calendar != nil {
+			merged.ResourceType = p.ResourceType
+		}
+		merged.Components.Comps = append(merged.Components.Comps, p.Components.Comps...)
+		if p.DisplayName != "" {
+			merged.DisplayName = p.DisplayName
+		}
+		if p.Color != "" {
+			merged.Color = p.Color
+		}
+		if p.Principal.Href != "" {
+			merged.Principal = p.Principal
+		}
+		if p.Home.Href != "" {
+			merged.Home = p.Home
+		}
+		if p.ETag != "" {
+			merged.ETag = p.ETag
+		}
+		if p.CalendarData != "" {
+			merged.CalendarData = p.CalendarData
+		}
+	}
+	return merged, ok
+}
+
+// isEventCalendar reports whether p describes a calendar collection that
+// holds events; collections without a component set accept everything.
+func (p davProp) isEventCalendar() bool {
+	if p.ResourceType.Calendar == nil {
+		return false
+	}
+	if len(p.Components.Comps) == 0 {
+		return true
+	}
+	for _, c := range p.Components.Comps {
+		if strings.EqualFold(c.Name, "VEVENT") {
+			return true
+		}
+	}
+	return false
+}
+
+const caldavPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:a="http://apple.com/ns/ical/">
+  <d:prop>
+    <d:resourcetype/>
+    <d:displayname/>
+    <d:current-user-principal/>
+    <c:calendar-home-set/>
+    <c:supported-calendar-component-set/>
+    <a:calendar-color/>
+  </d:prop>
+</d:propfind>`
+
+// do sends one request to href, resolved against the configured URL, and
+// returns the body of a response with one of the wanted status codes.
+func (b *caldavBackend) do(ctx context.Context, method, href string, header http.Header, body string, want ...int) (*http.Response, []byte, error) {
+	target, err := b.base.Parse(href)
+	if err != nil {
+		return nil, nil, fmt.Errorf("caldav: invalid href %q: %w", href, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("caldav: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	}
+	if b.username != "" || b.password != "" {
+		// A server may name principals or calendars on another host; do
+		// not hand it the credentials for this one.
+		if !sameOrigin(target, b.base) {
+			return nil, nil, fmt.Errorf("caldav: refusing to send credentials to %s://%s (outside %s://%s)", target.Scheme, target.Host, b.base.Scheme, b.base.Host)
+		}
+		req.SetBasicAuth(b.username, b.password)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("caldav: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, caldavMaxResponse))
+	if err != nil {
+		return nil, nil, fmt.Errorf("caldav: %s %s: %w", method, target.Path, err)
+	}
+	logVerbose(b.errOut, b.verbose, "caldav: %s %s -> %d", method, target.Path, resp.StatusCode)
+	for _, code := range want {
+		if resp.StatusCode == code {
+			return resp, data, nil
+		}
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return resp, nil, fmt.Errorf("caldav: %s %s: %s (check backend.caldav.username and FANTASTICAL_CALDAV_PASSWORD)", method, target.Path, resp.Status)
+	}
+	return resp, nil, fmt.Errorf("caldav: %s %s: %s", method, target.Path, resp.Status)
+}
+
+// sameOrigin reports whether a and b share a scheme, host and port.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+// multistatus sends a PROPFIND or REPORT and decodes the 207 response.
+func (b *caldavBackend) multistatus(ctx context.Context, method, href, depth, body string) (davMultistatus, error) {
+	var ms davMultistatus
+	_, data, err := b.do(ctx, method, href, http.Header{"Depth": {depth}}, body, http.StatusMultiStatus)
+	if err != nil {
+		return ms, err
+	}
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return ms, fmt.Errorf("caldav: %s %s: invalid multistatus: %w", method, href, err)
+	}
+	return ms, nil
+}
+
+// ListCalendars discovers calendars: the configured URL may itself be a
+// calendar; otherwise its calendar-home-set (directly or through the
+// current-user-principal) is listed. Without either, the URL is treated as
+// the calendar home.
+func (b *caldavBackend) ListCalendars(ctx context.Context) ([]calendarInfo, error) {
+	if b.calendars != nil {
+		return b.calendars, nil
+	}
+	ms, err := b.multistatus(ctx, "PROPFIND", b.base.Path, "0", caldavPropfindBody)
+	if err != nil {
+		return nil, err
+	}
+	var self davProp
+	if len(ms.Responses) > 0 {
+		self, _ = ms.Responses[0].prop()
+	}
+	if self.isEventCalendar() {
+		b.calendars = []calendarInfo{b.calendarInfo(b.base.Path, self)}
+		return b.calendars, nil
+	}
+
+	home := self.Home.Href
+	if home == "" && self.Principal.Href != "" {
+		ms, err := b.multistatus(ctx, "PROPFIND", self.Principal.Href, "0", caldavPropfindBody)
+		if err != nil {
+			return nil, err
+		}
+		if len(ms.Responses) > 0 {
+			p, _ := ms.Responses[0].prop()
+			home = p.Home.Href
+		}
+	}
+	if home == "" {
+		home = b.base.Path
+	}
+	logVerbose(b.errOut, b.verbose, "caldav: calendar home %s", home)
+
+	ms, err = b.multistatus(ctx, "PROPFIND", home, "1", caldavPropfindBody)
+	if err != nil {
+		return nil, err
+	}
+	calendars := []calendarInfo{}
+	for _, r := range ms.Responses {
+		p, ok := r.prop()
+		if !ok || !p.isEventCalendar() {
+			continue
+		}
+		calendars = append(calendars, b.calendarInfo(r.Href, p))
+	}
+	if len(calendars) == 0 {
+		return nil, fmt.Errorf("caldav: no event calendars under %s", home)
+	}
+	b.calendars = calendars
+	return calendars, nil
+}
+
+// calendarInfo describes the collection at href. The ID is its path, which
+// stays stable across renames.
+func (b *caldavBackend) calendarInfo(href string, p davProp) calendarInfo {
+	id := href
+	if u, err := b.base.Parse(href); err == nil {
+		id = u.Path
+	}
+	title := strings.TrimSpace(p.DisplayName)
+	if title == "" {
+		title = path.Base(strings.TrimSuffix(id, "/"))
+	}
+	color := strings.TrimSpace(p.Color)
+	// Apple-style colors carry an alpha channel (#RRGGBBAA).
+	if len(color) == 9 && strings.HasPrefix(color, "#") {
+		color = color[:7]
+	}
+	return calendarInfo{
+		ID:                  id,
+		Title:               title,
+		Source:              b.base.Host,
+		Type:                "caldav",
+		Color:               color,
+		AllowsModifications: true,
+	}
+}
+
+// caldavTime formats t for a time-range filter.
+func caldavTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// ListEvents queries each selected calendar for objects overlapping
+// req.Range. Servers return whole recurring objects, which are expanded
+// locally.
+func (b *caldavBackend) ListEvents(ctx context.Context, req eventFetch) ([]eventInfo, error) {
+	calendars, err := b.ListCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The range end is inclusive here and exclusive in CalDAV.
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="` + caldavTime(req.Range.From) + `" end="` + caldavTime(req.Range.To.Add(time.Second)) + `"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+	loc := req.Range.From.Location()
+	var pages [][]eventInfo
+	for _, cal := range calendars {
+		if !calendarSelected(cal, req.Calendars) {
+			continue
+		}
+		ms, err := b.multistatus(ctx, "REPORT", cal.ID, "1", body)
+		if err != nil {
+			return nil, err
+		}
+		set := newICSSeriesSet()
+		for _, r := range ms.Responses {
+			if p, ok := r.prop(); ok && p.CalendarData != "" {
+				set.add(r.Href, p.CalendarData, loc, b.warn)
+			}
+		}
+		pages = append(pages, set.occurrences(req, cal, b.warn))
+	}
+	return mergeEventPages(pages), nil
+}
+
+// CreateEvent PUTs a new event resource into a calendar collection,
+// refusing to overwrite an existing one.
+func (b *caldavBackend) CreateEvent(ctx context.Context, ev newEvent) (eventInfo, error) {
+	cal, err := b.calendar(ctx, ev.Calendar)
+	if err != nil {
+		return eventInfo{}, err
+	}
+	ev, err = ev.normalize()
+	if err != nil {
+		return eventInfo{}, err
+	}
+	name, uid, text, err := ev.resource()
+	if err != nil {
+		return eventInfo{}, err
+	}
+	header := http.Header{
+		"Content-Type":  {"text/calendar; charset=utf-8"},
+		"If-None-Match": {"*"},
+	}
+	if _, _, err := b.do(ctx, "PUT", collectionPath(cal.ID)+name, header, text, http.StatusCreated, http.StatusNoContent); err != nil {
+		return eventInfo{}, err
+	}
+	return ev.info(uid, cal), nil
+}
+
+// DeleteEvent finds the resource holding UID id and DELETEs it, guarded by
+// its ETag so a concurrent edit is not lost.
+func (b *caldavBackend) DeleteEvent(ctx context.Context, calendar, id string) error {
+	cal, err := b.calendar(ctx, calendar)
+	if err != nil {
+		return err
+	}
+	var uid strings.Builder
+	if err := xml.EscapeText(&uid, []byte(id)); err != nil {
+		return err
+	}
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:prop-filter name="UID">
+          <c:text-match collation="i;octet">` + uid.String() + `</c:text-match>
+        </c:prop-filter>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+	ms, err := b.multistatus(ctx, "REPORT", cal.ID, "1", body)
+	if err != nil {
+		return err
+	}
+	for _, r := range ms.Responses {
+		p, ok := r.prop()
+		if !ok {
+			continue
+		}
+		header := http.Header{}
+		if p.ETag != "" {
+			header.Set("If-Match", p.ETag)
+		}
+		resp, _, err := b.do(ctx, "DELETE", r.Href, header, "", http.StatusOK, http.StatusNoContent)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %q in %s", errEventNotFound, id, cal.Title)
+		}
+		return err
+	}
+	return fmt.Errorf("%w: %q in %s", errEventNotFound, id, cal.Title)
+}
+
+// calendar returns the discovered calendar named by a title or ID.
+func (b *caldavBackend) calendar(ctx context.Context, name string) (calendarInfo, error) {
+	calendars, err := b.ListCalendars(ctx)
+	if err != nil {
+		return calendarInfo{}, err
+	}
+	i, err := findCalendar(calendars, name)
+	if err != nil {
+		return calendarInfo{}, err
+	}
+	return calendars[i], nil
+}
+
+// collectionPath returns p with a trailing slash, so resource names
+// resolve inside the collection.
+func collectionPath(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p
+	}
+	return p + "/"
+}
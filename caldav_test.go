@@ -0,0 +1,299 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCalDAV is an in-process CalDAV server in the shape of Nextcloud or
+// Radicale: the root names a principal, the principal a calendar home, and
+// the home holds a Work event calendar and a Tasks (VTODO) calendar.
+type fakeCalDAV struct {
+	mu      sync.Mutex
+	objects map[string]string
+	etags   map[string]string
+	next    int
+	reports []string
+}
+
+func newFakeCalDAV(t *testing.T) (*httptest.Server, *fakeCalDAV) {
+	t.Helper()
+	fake := &fakeCalDAV{objects: map[string]string{}, etags: map[string]string{}}
+	for _, name := range []string{"standup.ics", "review.ics"} {
+		data, err := os.ReadFile("testdata/ics/work/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fake.put("/calendars/ana/work/"+name, string(data))
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return srv, fake
+}
+
+func (f *fakeCalDAV) put(path, data string) {
+	f.next++
+	f.objects[path] = data
+	f.etags[path] = `"` + strconv.Itoa(f.next) + `"`
+}
+
+const fakeWorkProps = `<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>
+<d:displayname>Work</d:displayname><a:calendar-color>#FF2968FF</a:calendar-color>
+<c:supported-calendar-component-set><c:comp name="VEVENT"/></c:supported-calendar-component-set>`
+
+func davOK(href, props string) string {
+	return "<d:response><d:href>" + href + "</d:href><d:propstat><d:prop>" + props +
+		"</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>"
+}
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "ana" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var responses []string
+	switch r.Method + " " + r.URL.Path {
+	case "PROPFIND /":
+		responses = append(responses, davOK("/", "<d:current-user-principal><d:href>/principals/ana/</d:href></d:current-user-principal>"))
+	case "PROPFIND /principals/ana/":
+		responses = append(responses, davOK("/principals/ana/", "<c:calendar-home-set><d:href>/calendars/ana/</d:href></c:calendar-home-set>"))
+	case "PROPFIND /calendars/ana/":
+		if r.Header.Get("Depth") != "1" {
+			http.Error(w, "depth", http.StatusBadRequest)
+			return
+		}
+		responses = append(responses,
+			davOK("/calendars/ana/", "<d:resourcetype><d:collection/></d:resourcetype>"),
+			strings.TrimSuffix(davOK("/calendars/ana/work/", fakeWorkProps), "</d:response>")+
+				"<d:propstat><d:prop><d:current-user-principal/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat></d:response>",
+			davOK("/calendars/ana/tasks/", `<d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>Tasks</d:displayname>
+<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`),
+		)
+	case "PROPFIND /calendars/ana/work/":
+		responses = append(responses, davOK("/calendars/ana/work/", fakeWorkProps))
+	case "REPORT /calendars/ana/work/":
+		f.reports = append(f.reports, string(body))
+		uid := ""
+		if _, rest, ok := strings.Cut(string(body), `<c:text-match collation="i;octet">`); ok {
+			uid, _, _ = strings.Cut(rest, "<")
+		}
+		for path, data := range f.objects {
+			if uid != "" {
+				if strings.Contains(data, "UID:"+uid+"\n") || strings.Contains(data, "UID:"+uid+"\r\n") {
+					responses = append(responses, davOK(path, "<d:getetag>"+f.etags[path]+"</d:getetag>"))
+				}
+				continue
+			}
+			var escaped bytes.Buffer
+			xml.EscapeText(&escaped, []byte(data))
+			responses = append(responses, davOK(path, "<d:getetag>"+f.etags[path]+"</d:getetag><c:calendar-data>"+escaped.String()+"</c:calendar-data>"))
+		}
+	default:
+		if !strings.HasPrefix(r.URL.Path, "/calendars/ana/work/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, exists := f.objects[r.URL.Path]
+		switch r.Method {
+		case "PUT":
+			if exists && r.Header.Get("If-None-Match") == "*" {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			f.put(r.URL.Path, string(body))
+			w.WriteHeader(http.StatusCreated)
+		case "DELETE":
+			switch {
+			case !exists:
+				w.WriteHeader(http.StatusNotFound)
+			case r.Header.Get("If-Match") != f.etags[r.URL.Path]:
+				w.WriteHeader(http.StatusPreconditionFailed)
+			default:
+				delete(f.objects, r.URL.Path)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:a="http://apple.com/ns/ical/">`+
+		strings.Join(responses, "")+"</d:multistatus>")
+}
+
+func TestCalDAVBackend(t *testing.T) {
+	r := icsTestRange(t)
+	srv, fake := newFakeCalDAV(t)
+	host := strings.TrimPrefix(srv.URL, "http://")
+	var errOut bytes.Buffer
+	b, err := newCalDAVBackend(srv.URL, "ana", "secret", &errOut, false)
+	if err != nil {
+		t.Fatalf("newCalDAVBackend: %v", err)
+	}
+
+	calendars, err := b.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("ListCalendars: %v", err)
+	}
+	work := calendarInfo{ID: "/calendars/ana/work/", Title: "Work", Source: host, Type: "caldav", Color: "#FF2968", AllowsModifications: true}
+	if len(calendars) != 1 || calendars[0] != work {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+
+	events, err := b.ListEvents(context.Background(), eventFetch{Range: r, IncludeDeclined: true})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	want := strings.Join([]string{
+		"2026-10-19T09:00:00+02:00 09:15 /calendars/ana/work/ Daily standup",
+		"2026-10-22T14:00:00+02:00 15:30 /calendars/ana/work/ Quarterly review with a very long title that has to be folded across two lines",
+		"2026-10-23T10:00:00+02:00 10:15 /calendars/ana/work/ Daily standup (moved)",
+		"2026-10-26T09:00:00+01:00 09:15 /calendars/ana/work/ Daily standup",
+	}, "\n")
+	if got := formatICSEvents(events); got != want {
+		t.Fatalf("events:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.Contains(fake.reports[0], `<c:time-range start="20261018T220000Z" end="20261026T230000Z"/>`) {
+		t.Fatalf("unexpected calendar-query: %s", fake.reports[0])
+	}
+
+	start := time.Date(2026, 10, 22, 16, 0, 0, 0, r.From.Location())
+	created, err := b.CreateEvent(context.Background(), newEvent{Calendar: "work", Title: "Retro & beers", Start: start})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if created.CalendarID != work.ID || !created.End.Equal(start.Add(time.Hour)) {
+		t.Fatalf("unexpected created event: %+v", created)
+	}
+	events, err = b.ListEvents(context.Background(), eventFetch{Range: r})
+	if err != nil || len(events) != 4 || events[2].ID != created.ID || events[2].Title != "Retro & beers" {
+		t.Fatalf("created event not listed: %+v (%v)", events, err)
+	}
+
+	if err := b.DeleteEvent(context.Background(), "Work", created.ID); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if err := b.DeleteEvent(context.Background(), "Work", "review-42"); err != nil {
+		t.Fatalf("DeleteEvent review: %v", err)
+	}
+	if err := b.DeleteEvent(context.Background(), "Work", created.ID); !errors.Is(err, errEventNotFound) {
+		t.Fatalf("expected errEventNotFound, got %v", err)
+	}
+	events, err = b.ListEvents(context.Background(), eventFetch{Range: r})
+	if err != nil || len(events) != 2 || events[0].Title != "Daily standup" || events[1].Title != "Daily standup (moved)" {
+		t.Fatalf("deleted events still listed: %+v (%v)", events, err)
+	}
+	if _, err := b.CreateEvent(context.Background(), newEvent{Calendar: "Tasks", Title: "x", Start: start}); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for a VTODO calendar, got %v", err)
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("unexpected warnings: %q", errOut.String())
+	}
+
+	direct, err := newCalDAVBackend(srv.URL+"/calendars/ana/work/", "ana", "secret", &errOut, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calendars, err := direct.ListCalendars(context.Background()); err != nil || len(calendars) != 1 || calendars[0] != work {
+		t.Fatalf("calendar URL: %+v (%v)", calendars, err)
+	}
+
+	denied, err := newCalDAVBackend(srv.URL, "ana", "wrong", &errOut, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := denied.ListCalendars(context.Background()); err == nil || !strings.Contains(err.Error(), "FANTASTICAL_CALDAV_PASSWORD") {
+		t.Fatalf("expected an auth hint, got %v", err)
+	}
+	for _, raw := range []string{"not a url", "ftp://example.com/dav", "https://"} {
+		if _, err := newCalDAVBackend(raw, "", "", &errOut, false); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected usage error, got %v", raw, err)
+		}
+	}
+}
+
+func TestCalDAVCredentialsStayOnOrigin(t *testing.T) {
+	var leaked atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked.Store(true)
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, `<d:multistatus xmlns:d="DAV:"/>`)
+	}))
+	t.Cleanup(other.Close)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/moved/", http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(origin.Close)
+
+	b, err := newCalDAVBackend(origin.URL, "ana", "secret", io.Discard, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.multistatus(context.Background(), "PROPFIND", other.URL+"/principals/ana/", "0", ""); err == nil || !strings.Contains(err.Error(), "refusing to send credentials") {
+		t.Fatalf("expected a cross-origin href to be refused, got %v", err)
+	}
+	if _, err := b.multistatus(context.Background(), "PROPFIND", "/", "0", ""); err != nil {
+		t.Fatalf("redirect: %v", err)
+	}
+	if leaked.Load() {
+		t.Fatal("credentials were sent to another origin")
+	}
+}
+
+func TestCmdEventKitCalDAVBackend(t *testing.T) {
+	setupTestEnv(t)
+	icsTestRange(t)
+	srv, _ := newFakeCalDAV(t)
+	t.Setenv("FANTASTICAL_CALDAV_USERNAME", "ana")
+	t.Setenv("FANTASTICAL_CALDAV_PASSWORD", "secret")
+
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), []string{"calendars", "--backend", "caldav:" + srv.URL, "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("calendars: %v", err)
+	}
+	var calendars []calendarInfo
+	if err := json.Unmarshal(out.Bytes(), &calendars); err != nil || len(calendars) != 1 || calendars[0].Title != "Work" {
+		t.Fatalf("unexpected calendars: %s (%v)", out.String(), err)
+	}
+
+	t.Setenv("FANTASTICAL_BACKEND", "caldav")
+	t.Setenv("FANTASTICAL_CALDAV_URL", srv.URL)
+	out.Reset()
+	args := []string{"events", "--from", "2026-10-19", "--to", "2026-10-26", "--tz", "Europe/Zagreb", "--calendar", "work", "--json"}
+	if err := cmdEventKit(context.Background(), args, &out, &errOut); err != nil {
+		t.Fatalf("events: %v (%s)", err, errOut.String())
+	}
+	var events []eventInfo
+	if err := json.Unmarshal(out.Bytes(), &events); err != nil || len(events) != 3 || events[1].ID != "review-42" {
+		t.Fatalf("unexpected events: %s (%v)", out.String(), err)
+	}
+
+	t.Setenv("FANTASTICAL_CALDAV_URL", "")
+	if err := cmdEventKit(context.Background(), []string{"events", "--today"}, &out, &errOut); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error without a URL, got %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	calendarCacheFile       = "calendars.json"
+	defaultCalendarCacheTTL = time.Hour
+)
+
+// calendarCache is the on-disk shape of the cached `eventkit calendars` output.
+type calendarCache struct {
+	FetchedAt time.Time      `json:"fetched_at"`
+	Calendars []calendarInfo `json:"calendars"`
+}
+
+func calendarCachePath() (string, error) {
+	root, err := fantasticalCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("calendar cache dir: %w", err)
+	}
+	return filepath.Join(root, calendarCacheFile), nil
+}
+
+// calendarCacheTTL returns the configured cache lifetime. A zero TTL disables
+// the cache. FANTASTICAL_CALENDAR_CACHE_TTL overrides calendars.cache_ttl.
+func calendarCacheTTL(cfg *Config) (time.Duration, error) {
+	raw := ""
+	if cfg != nil {
+		raw = strings.TrimSpace(cfg.Calendars.CacheTTL)
+	}
+	if v, ok := envString("FANTASTICAL_CALENDAR_CACHE_TTL"); ok {
+		raw = v
+	}
+	if raw == "" {
+		return defaultCalendarCacheTTL, nil
+	}
+	if raw == "0" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("%w: invalid calendar cache ttl %q (want a duration like 30m)", errUsage, raw)
+	}
+	return ttl, nil
+}
+
+// readCalendarCache returns the cached calendars regardless of age. A missing
+// cache is not an error; it returns nil.
+func readCalendarCache() (*calendarCache, error) {
+	path, err := calendarCachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read calendar cache: %w", err)
+	}
+	var cache calendarCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("parse calendar cache %s: %w", path, err)
+	}
+	return &cache, nil
+}
+
+func writeCalendarCache(calendars []calendarInfo) error {
+	path, err := calendarCachePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(calendarCache{FetchedAt: time.Now().UTC(), Calendars: calendars})
+	if err != nil {
+		return err
+	}
+	if _, err := ensureCacheDir(); err != nil {
+		return fmt.Errorf("calendar %w", err)
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
+
+func clearCalendarCache() (string, bool, error) {
+	path, err := calendarCachePath()
+	if err != nil {
+		return "", false, err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return path, false, nil
+		}
+		return path, false, fmt.Errorf("remove %s: %w", path, err)
+	}
+	return path, true, nil
+}
+
+// calendarSource loads calendar metadata, preferring a fresh cache entry and
+// falling back to the EventKit helper (which refreshes the cache).
+type calendarSource struct {
+	ttl     time.Duration
+	noCache bool
+	errOut  io.Writer
+	verbose bool
+	// list, when set, replaces the cache and helper (non-EventKit backends).
+	list func(context.Context) ([]calendarInfo, error)
+	// cacheOnly uses the cache at any age and never runs the helper, which
+	// may have to be compiled or trigger a Calendar permission prompt.
+	cacheOnly bool
+}
+
+// load returns the calendars and whether they came from the cache, which
+// may be missing calendars created since it was written.
+func (s calendarSource) load(ctx context.Context) ([]calendarInfo, bool, error) {
+	if s.list != nil {
+		calendars, err := s.list(ctx)
+		return calendars, false, err
+	}
+	if s.cacheOnly {
+		cache, err := readCalendarCache()
+		switch {
+		case s.noCache:
+			return nil, false, errors.New("calendar cache bypassed (--no-cache)")
+		case err != nil:
+			return nil, false, err
+		case cache == nil:
+			return nil, false, errors.New("no cached calendar list")
+		}
+		return cache.Calendars, true, nil
+	}
+	if !s.noCache && s.ttl > 0 {
+		cache, err := readCalendarCache()
+		if err != nil {
+			logVerbose(s.errOut, s.verbose, "ignoring calendar cache: %v", err)
+		} else if cache != nil && time.Since(cache.FetchedAt) < s.ttl {
+			logVerbose(s.errOut, s.verbose, "calendar cache hit (%d calendars, age %s)", len(cache.Calendars), time.Since(cache.FetchedAt).Round(time.Second))
+			return cache.Calendars, true, nil
+		}
+	}
+
+	calendars, err := s.fetch(ctx)
+	return calendars, false, err
+}
+
+// fetch lists calendars through the EventKit helper and rewrites the cache.
+func (s calendarSource) fetch(ctx context.Context) ([]calendarInfo, error) {
+	calendars, err := fetchEventKitCalendars(ctx, s.errOut, s.verbose)
+	if err != nil {
+		return nil, err
+	}
+	if !s.noCache && s.ttl > 0 {
+		if err := writeCalendarCache(calendars); err != nil {
+			logVerbose(s.errOut, s.verbose, "calendar cache not written: %v", err)
+		}
+	}
+	return calendars, nil
+}
+
+// cachedCalendarTitles returns titles from the cache without spawning the
+// helper, for callers such as shell completion that must stay fast.
+func cachedCalendarTitles() []string {
+	cache, err := readCalendarCache()
+	if err != nil || cache == nil {
+		return nil
+	}
+	titles := make([]string, 0, len(cache.Calendars))
+	for _, cal := range cache.Calendars {
+		titles = append(titles, cal.Title)
+	}
+	return titles
+}
+
+// cacheShowResult is the output of cache show --json. The cache fields are
+// omitted when no cache exists.
+type cacheShowResult struct {
+	Path       string         `json:"path"`
+	Exists     bool           `json:"exists"`
+	FetchedAt  *time.Time     `json:"fetched_at,omitempty"`
+	AgeSeconds *int           `json:"age_seconds,omitempty"`
+	Calendars  []calendarInfo `json:"calendars,omitempty"`
+}
+
+// cacheClearResult is the output of cache clear --json.
+type cacheClearResult struct {
+	Path    string `json:"path"`
+	Removed bool   `json:"removed"`
+}
+
+type cacheOptions struct {
+	json bool
+}
+
+func cacheUsage(w io.Writer) {
+	fmt.Fprint(w, "USAGE:\n  fantastical cache show [--json]\n  fantastical cache clear [--json]\n")
+	printFlagHelp(w, "cache show")
+	fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical cache show --json\n  fantastical cache clear")
+}
+
+func cmdCache(args []string, out, errOut io.Writer) error {
+	if len(args) < 1 {
+		cacheUsage(errOut)
+		return fmt.Errorf("%w: missing cache subcommand", errUsage)
+	}
+
+	sub := strings.ToLower(strings.TrimSpace(args[0]))
+	if sub != "show" && sub != "clear" {
+		cacheUsage(errOut)
+		return fmt.Errorf("%w: unknown cache subcommand %q", errUsage, sub)
+	}
+
+	fs := flag.NewFlagSet("cache "+sub, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts := cacheOptions{}
+	bindFlags(fs, "cache "+sub, map[string]any{"json": &opts.json})
+	fs.Usage = func() {
+		cacheUsage(errOut)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	if sub == "clear" {
+		path, removed, err := clearCalendarCache()
+		if err != nil {
+			return err
+		}
+		if opts.json {
+			return writeJSON(out, cacheClearResult{Path: path, Removed: removed})
+		}
+		if removed {
+			fmt.Fprintf(out, "removed calendar cache %s\n", path)
+		} else {
+			fmt.Fprintf(out, "no calendar cache at %s\n", path)
+		}
+		return nil
+	}
+
+	path, err := calendarCachePath()
+	if err != nil {
+		return err
+	}
+	cache, err := readCalendarCache()
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		payload := cacheShowResult{Path: path, Exists: cache != nil}
+		if cache != nil {
+			payload.FetchedAt = &cache.FetchedAt
+			age := int(time.Since(cache.FetchedAt).Seconds())
+			payload.AgeSeconds = &age
+			payload.Calendars = cache.Calendars
+		}
+		return writeJSON(out, payload)
+	}
+	if cache == nil {
+		fmt.Fprintf(out, "no calendar cache at %s\n", path)
+		return nil
+	}
+	fmt.Fprintf(out, "path: %s\nfetched: %s (%s ago)\ncalendars: %d\n", path, cache.FetchedAt.Local().Format(time.RFC3339), time.Since(cache.FetchedAt).Round(time.Second), len(cache.Calendars))
+	return nil
+}
@@ -0,0 +1,151 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarCacheReusedUntilExpired(t *testing.T) {
+	setupTestEnv(t)
+	stubCalendarHelper(t)
+	stubOpener(t)
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--print", "--calendar", "home", "Dinner"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache, err := readCalendarCache()
+	if err != nil || cache == nil || len(cache.Calendars) != 4 {
+		t.Fatalf("expected cache to be written, got %+v (%v)", cache, err)
+	}
+
+	// With the helper gone the cached list must still resolve names.
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "false")
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--print", "--calendar", "bdays", "Party"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Birthdays") {
+		t.Fatalf("expected cached resolution: %q", out.String())
+	}
+
+	// --no-cache ignores the cache, so the name passes through unchanged.
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--print", "--no-cache", "--calendar", "bdays", "Party"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=bdays") {
+		t.Fatalf("expected --no-cache to bypass cache: %q", out.String())
+	}
+
+	// An expired cache is not used either.
+	cache.FetchedAt = time.Now().Add(-2 * time.Hour)
+	data, _ := json.Marshal(cache)
+	path, _ := calendarCachePath()
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--print", "--calendar", "bdays", "Party"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=bdays") {
+		t.Fatalf("expected expired cache to be ignored: %q", out.String())
+	}
+}
+
+func TestCalendarCacheTTL(t *testing.T) {
+	setupTestEnv(t)
+	cfg := &Config{Calendars: CalendarsConfig{CacheTTL: "15m"}}
+	if ttl, err := calendarCacheTTL(cfg); err != nil || ttl != 15*time.Minute {
+		t.Fatalf("calendarCacheTTL = %v, %v", ttl, err)
+	}
+	t.Setenv("FANTASTICAL_CALENDAR_CACHE_TTL", "0")
+	if ttl, err := calendarCacheTTL(cfg); err != nil || ttl != 0 {
+		t.Fatalf("expected env to disable cache, got %v, %v", ttl, err)
+	}
+	t.Setenv("FANTASTICAL_CALENDAR_CACHE_TTL", "soon")
+	if _, err := calendarCacheTTL(cfg); err == nil || !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestCmdEventKitCalendarsWritesCache(t *testing.T) {
+	setupTestEnv(t)
+	stubCalendarHelper(t)
+
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), []string{"calendars", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Work (Exchange)") {
+		t.Fatalf("expected calendars in output: %q", out.String())
+	}
+	titles := cachedCalendarTitles()
+	if len(titles) != 4 || titles[2] != "Home" {
+		t.Fatalf("unexpected cached titles: %v", titles)
+	}
+}
+
+func TestCmdCacheShowAndClear(t *testing.T) {
+	setupTestEnv(t)
+
+	var out, errOut bytes.Buffer
+	if err := cmdCache([]string{"show", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if payload["exists"] != false {
+		t.Fatalf("expected no cache: %v", payload)
+	}
+
+	if err := writeCalendarCache([]calendarInfo{{ID: "h", Title: "Home"}}); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+	out.Reset()
+	if err := cmdCache([]string{"show", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload = nil
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if payload["exists"] != true || len(payload["calendars"].([]any)) != 1 {
+		t.Fatalf("expected cached calendar: %v", payload)
+	}
+
+	// Stray arguments are rejected before anything is removed.
+	if err := cmdCache([]string{"clear", "now", "please"}, &out, &errOut); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+	if cache, _ := readCalendarCache(); cache == nil {
+		t.Fatalf("expected the cache kept")
+	}
+
+	out.Reset()
+	if err := cmdCache([]string{"clear"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "removed calendar cache") {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	if cache, _ := readCalendarCache(); cache != nil {
+		t.Fatalf("expected cache to be removed")
+	}
+
+	if err := cmdCache(nil, &out, &errOut); err == nil || !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
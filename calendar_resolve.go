@@ -0,0 +1,365 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// calendarInfo mirrors the helper's CalendarOutput JSON.
+type calendarInfo struct {
+	ID                  string `json:"id"`
+	Title               string `json:"title"`
+	Source              string `json:"source"`
+	Type                string `json:"type"`
+	Color               string `json:"color,omitempty"`
+	AllowsModifications bool   `json:"allowsModifications"`
+}
+
+// calendarResolver maps user-supplied calendar names to canonical calendars.
+// Config aliases are applied first; the remaining name is matched against the
+// calendar list (cached, or fetched from EventKit lazily; a name missing from
+// the cached list refetches it once). When the list cannot be loaded (no
+// access, no helper) names pass through unchanged.
+type calendarResolver struct {
+	aliases map[string]string
+	source  calendarSource
+	errOut  io.Writer
+	verbose bool
+
+	loaded    bool
+	cached    bool
+	calendars []calendarInfo
+	loadErr   error
+}
+
+func newCalendarResolver(cfg *Config, noCache bool, errOut io.Writer, verbose bool) (*calendarResolver, error) {
+	ttl, err := calendarCacheTTL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &calendarResolver{
+		source:  calendarSource{ttl: ttl, noCache: noCache, errOut: errOut, verbose: verbose},
+		errOut:  errOut,
+		verbose: verbose,
+	}
+	if cfg != nil {
+		r.aliases = cfg.Calendars.Aliases
+	}
+	return r, nil
+}
+
+// useBackend resolves names against b's calendars. Only EventKit calendars
+// are cached; other backends are listed directly.
+func (r *calendarResolver) useBackend(b CalendarBackend) {
+	if _, ok := b.(*eventKitBackend); !ok {
+		r.source.list = b.ListCalendars
+	}
+}
+
+// cacheOnly resolves names from aliases and the cached calendar list only,
+// for commands that do not otherwise touch EventKit. Names the cache does
+// not know pass through unchanged.
+func (r *calendarResolver) cacheOnly() {
+	r.source.cacheOnly = true
+}
+
+// calendarRef is a resolved calendar. byID is set when the input matched a
+// calendar identifier rather than a title.
+type calendarRef struct {
+	Title string
+	ID    string
+	byID  bool
+}
+
+func (r *calendarResolver) list(ctx context.Context) ([]calendarInfo, error) {
+	if !r.loaded {
+		r.loaded = true
+		r.calendars, r.cached, r.loadErr = r.source.load(ctx)
+	}
+	return r.calendars, r.loadErr
+}
+
+// refetch replaces a cached calendar list with a fresh one from EventKit,
+// at most once. It reports whether the list was replaced.
+func (r *calendarResolver) refetch(ctx context.Context) bool {
+	if !r.cached || r.source.cacheOnly {
+		return false
+	}
+	r.cached = false
+	calendars, err := r.source.fetch(ctx)
+	if err != nil {
+		logVerbose(r.errOut, r.verbose, "calendar list refresh failed (%v); using the cached list", err)
+		return false
+	}
+	r.calendars = calendars
+	return true
+}
+
+func (r *calendarResolver) resolve(ctx context.Context, name string) (calendarRef, error) {
+	name = applyNameAlias(name, r.aliases)
+
+	calendars, err := r.list(ctx)
+	if err != nil {
+		logVerbose(r.errOut, r.verbose, "calendar list unavailable (%v); using %q as given", err, name)
+		return calendarRef{Title: name}, nil
+	}
+
+	ref, ok, err := lookupCalendar(calendars, name)
+	if err != nil || ok {
+		return ref, err
+	}
+	titles := calendarTitles(calendars)
+	matches, tier := findNameMatches(name, titles)
+	if len(matches) == 0 && r.refetch(ctx) {
+		// The cache may predate the calendar.
+		logVerbose(r.errOut, r.verbose, "calendar %q not in the cached list; refetched it", name)
+		calendars = r.calendars
+		if ref, ok, err := lookupCalendar(calendars, name); err != nil || ok {
+			return ref, err
+		}
+		titles = calendarTitles(calendars)
+		matches, tier = findNameMatches(name, titles)
+	}
+	if len(matches) == 0 && r.source.cacheOnly {
+		logVerbose(r.errOut, r.verbose, "calendar %q not in the cached list; using it as given", name)
+		return calendarRef{Title: name}, nil
+	}
+
+	title, err := matchName("calendar", name, titles)
+	if err != nil {
+		return calendarRef{}, err
+	}
+	if tier >= fuzzyNameTier {
+		// A loose match may be a calendar the user did not mean, and events
+		// created in it are easy to miss, so say so even without --verbose.
+		fmt.Fprintf(r.errOut, "[fantastical] calendar %q resolved to %q\n", name, title)
+	} else {
+		logVerbose(r.errOut, r.verbose, "calendar %q resolved to %q", name, title)
+	}
+	ref, ok, err = lookupCalendar(calendars, title)
+	if err != nil || ok {
+		return ref, err
+	}
+	return calendarRef{Title: title}, nil
+}
+
+// lookupCalendar looks name up as an exact title, then as an identifier.
+// Titles are only unique within an account, so several calendars with the
+// title are an error that lists their identifiers.
+func lookupCalendar(calendars []calendarInfo, name string) (calendarRef, bool, error) {
+	var hits []calendarInfo
+	for _, cal := range calendars {
+		if cal.Title == name {
+			hits = append(hits, cal)
+		}
+	}
+	if len(hits) == 1 {
+		return calendarRef{Title: hits[0].Title, ID: hits[0].ID}, true, nil
+	}
+	if len(hits) > 1 {
+		choices := make([]string, len(hits))
+		for i, cal := range hits {
+			choices[i] = fmt.Sprintf("%q (%s, id %s)", cal.Title, cal.Source, cal.ID)
+		}
+		return calendarRef{}, false, fmt.Errorf("%w: calendar %q is ambiguous; matches: %s; pass the id instead", errUsage, name, strings.Join(choices, ", "))
+	}
+	for _, cal := range calendars {
+		if cal.ID == name {
+			return calendarRef{Title: cal.Title, ID: cal.ID, byID: true}, true, nil
+		}
+	}
+	return calendarRef{}, false, nil
+}
+
+func calendarTitles(calendars []calendarInfo) []string {
+	titles := make([]string, 0, len(calendars))
+	for _, cal := range calendars {
+		titles = append(titles, cal.Title)
+	}
+	return titles
+}
+
+// resolveCalendarSet applies calendar aliases and, when the config lists known
+// calendar sets, matches the name against them.
+func resolveCalendarSet(name string, cfg *Config) (string, error) {
+	if cfg == nil {
+		return strings.TrimSpace(name), nil
+	}
+	name = applyNameAlias(name, cfg.Calendars.Aliases)
+	if len(cfg.Show.CalendarSets) == 0 {
+		return name, nil
+	}
+	return matchName("calendar set", name, cfg.Show.CalendarSets)
+}
+
+// applyNameAlias returns the alias target for name (case-insensitive), or name.
+func applyNameAlias(name string, aliases map[string]string) string {
+	name = strings.TrimSpace(name)
+	if target, ok := aliases[name]; ok {
+		return target
+	}
+	for alias, target := range aliases {
+		if strings.EqualFold(alias, name) {
+			return target
+		}
+	}
+	return name
+}
+
+func fetchEventKitCalendars(ctx context.Context, errOut io.Writer, verbose bool) ([]calendarInfo, error) {
+	var stdout, stderr bytes.Buffer
+	if err := runEventKitHelper(ctx, []string{"calendars", "--format", "json", "--no-input"}, &stdout, &stderr, verbose); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	var calendars []calendarInfo
+	if err := json.Unmarshal(stdout.Bytes(), &calendars); err != nil {
+		return nil, fmt.Errorf("decode calendars: %w", err)
+	}
+	return calendars, nil
+}
+
+// fuzzyNameTier is the first matchName tier (prefix) whose match may not be
+// the name the user meant.
+const fuzzyNameTier = 2
+
+// findNameMatches returns the distinct candidates of the first tier with
+// matches and that tier's index. Tiers are tried in order: exact,
+// case-insensitive, prefix, substring and subsequence.
+func findNameMatches(input string, candidates []string) ([]string, int) {
+	needle := normalizeName(input)
+	if needle == "" {
+		return nil, 0
+	}
+	tiers := []func(string) bool{
+		func(c string) bool { return c == input },
+		func(c string) bool { return normalizeName(c) == needle },
+		func(c string) bool { return strings.HasPrefix(normalizeName(c), needle) },
+		func(c string) bool { return strings.Contains(normalizeName(c), needle) },
+		func(c string) bool { return isSubsequence(needle, normalizeName(c)) },
+	}
+	for i, match := range tiers {
+		found := map[string]bool{}
+		var matches []string
+		for _, c := range candidates {
+			if match(c) && !found[c] {
+				found[c] = true
+				matches = append(matches, c)
+			}
+		}
+		if len(matches) > 0 {
+			return matches, i
+		}
+	}
+	return nil, 0
+}
+
+// matchName picks the single candidate that best matches input (see
+// findNameMatches). More than one distinct match in the winning tier is an
+// error listing the choices, and no match at all suggests the closest names.
+func matchName(kind, input string, candidates []string) (string, error) {
+	matches, _ := findNameMatches(input, candidates)
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		sort.Strings(matches)
+		return "", fmt.Errorf("%w: %s %q is ambiguous; matches: %s", errUsage, kind, input, quoteList(matches))
+	}
+
+	if suggestions := suggestNames(normalizeName(input), candidates); len(suggestions) > 0 {
+		return "", fmt.Errorf("%w: unknown %s %q; did you mean: %s", errUsage, kind, input, quoteList(suggestions))
+	}
+	return "", fmt.Errorf("%w: unknown %s %q", errUsage, kind, input)
+}
+
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func isSubsequence(needle, haystack string) bool {
+	if needle == "" {
+		return false
+	}
+	n := []rune(needle)
+	i := 0
+	for _, r := range haystack {
+		if r == n[i] {
+			i++
+			if i == len(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suggestNames returns up to three candidates within a small edit distance.
+func suggestNames(needle string, candidates []string) []string {
+	type scored struct {
+		name string
+		dist int
+	}
+	limit := len([]rune(needle))/3 + 2
+	seen := map[string]bool{}
+	var scoredNames []scored
+	for _, c := range candidates {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := levenshtein(needle, normalizeName(c)); d <= limit {
+			scoredNames = append(scoredNames, scored{name: c, dist: d})
+		}
+	}
+	sort.SliceStable(scoredNames, func(i, j int) bool {
+		if scoredNames[i].dist == scoredNames[j].dist {
+			return scoredNames[i].name < scoredNames[j].name
+		}
+		return scoredNames[i].dist < scoredNames[j].dist
+	})
+	var out []string
+	for i := 0; i < len(scoredNames) && i < 3; i++ {
+		out = append(out, scoredNames[i].name)
+	}
+	return out
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	return strings.Join(quoted, ", ")
+}
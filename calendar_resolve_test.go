@@ -0,0 +1,280 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCalendarsJSON = `[{"id":"work-ex","title":"Work (Exchange)","source":"Exchange","type":"exchange","allowsModifications":true},` +
+	`{"id":"work-ic","title":"Work (iCloud)","source":"iCloud","type":"caldav","allowsModifications":true},` +
+	`{"id":"home-1","title":"Home","source":"iCloud","type":"caldav","allowsModifications":true},` +
+	`{"id":"bday","title":"Birthdays","source":"Other","type":"birthday","allowsModifications":false}]`
+
+// stubCalendarHelper installs a helper that answers "calendars" with fixture
+// JSON and echoes its arguments for every other subcommand; "events" echoes
+// them to stderr and returns no events.
+func stubCalendarHelper(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "calendars.json"), []byte(testCalendarsJSON), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	helper := filepath.Join(dir, "helper.sh")
+	script := "#!/bin/sh\nif [ \"$1\" = calendars ]; then cat '" + filepath.Join(dir, "calendars.json") + "'; exit 0; fi\n" +
+		"if [ \"$1\" = events ]; then printf '%s\\n' \"$@\" >&2; echo '[]'; exit 0; fi\nprintf '%s\\n' \"$@\"\n"
+	if err := os.WriteFile(helper, withHandshake(t, script), 0o755); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
+}
+
+// seedCalendarCache writes the stub helper's calendars to a fresh cache.
+func seedCalendarCache(t *testing.T) {
+	t.Helper()
+	var calendars []calendarInfo
+	if err := json.Unmarshal([]byte(testCalendarsJSON), &calendars); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCalendarCache(calendars); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	candidates := []string{"Work (Exchange)", "Work (iCloud)", "Home", "Birthdays"}
+	cases := map[string]string{
+		"Home":      "Home",
+		"home":      "Home",
+		"work (ex":  "Work (Exchange)",
+		"icloud":    "Work (iCloud)",
+		"bdays":     "Birthdays",
+		"  HOME  ":  "Home",
+		"Birthdays": "Birthdays",
+	}
+	for input, want := range cases {
+		got, err := matchName("calendar", input, candidates)
+		if err != nil {
+			t.Fatalf("matchName(%q): %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("matchName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestMatchNameAmbiguous(t *testing.T) {
+	_, err := matchName("calendar", "work", []string{"Work (Exchange)", "Work (iCloud)", "Home"})
+	if err == nil || !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"Work (Exchange)", "Work (iCloud)"`) {
+		t.Fatalf("expected choices in error: %v", err)
+	}
+}
+
+func TestMatchNameSuggestions(t *testing.T) {
+	_, err := matchName("calendar", "Hmoe", []string{"Home", "Work"})
+	if err == nil || !strings.Contains(err.Error(), `did you mean: "Home"`) {
+		t.Fatalf("expected suggestion, got %v", err)
+	}
+}
+
+func TestCmdParseResolvesCalendarAlias(t *testing.T) {
+	writeAliasConfig(t, `{"calendars":{"aliases":{"w":"Work (Exchange)"}}}`)
+	stubCalendarHelper(t)
+	stubOpener(t)
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--print", "--calendar", "w", "Standup"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Work%20%28Exchange%29") {
+		t.Fatalf("expected canonical calendar: %q", out.String())
+	}
+
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--print", "--calendar", "home", "Dinner"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Home") {
+		t.Fatalf("expected case-insensitive match: %q", out.String())
+	}
+}
+
+func TestCmdParseAmbiguousCalendar(t *testing.T) {
+	setupTestEnv(t)
+	stubCalendarHelper(t)
+	stubOpener(t)
+
+	var out, errOut bytes.Buffer
+	err := cmdParse(context.Background(), []string{"--print", "--calendar", "work", "Standup"}, strings.NewReader(""), &out, &errOut)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+}
+
+func TestCmdParseCalendarWithoutHelper(t *testing.T) {
+	setupTestEnv(t)
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--open=false", "--print", "--calendar", "Anything", "Standup"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Anything") {
+		t.Fatalf("expected name to pass through: %q", out.String())
+	}
+}
+
+func TestCmdParsePreviewUsesCacheOnly(t *testing.T) {
+	setupTestEnv(t)
+	stubCalendarHelper(t)
+	marker := filepath.Join(t.TempDir(), "ran")
+	helper := os.Getenv("FANTASTICAL_EVENTKIT_HELPER")
+	wrapper := filepath.Join(t.TempDir(), "helper.sh")
+	if err := os.WriteFile(wrapper, []byte("#!/bin/sh\ntouch '"+marker+"'\nexec '"+helper+"' \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", wrapper)
+
+	for _, args := range [][]string{{"--dry-run"}, {"--explain"}, {"--open=false", "--print"}} {
+		var out, errOut bytes.Buffer
+		if err := cmdParse(context.Background(), append(args, "--calendar", "home", "Dinner"), strings.NewReader(""), &out, &errOut); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if !strings.Contains(out.String(), "calendarName=home") {
+			t.Fatalf("%v: expected the name as given without a cache: %q", args, out.String())
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("a preview must not run the helper")
+	}
+
+	seedCalendarCache(t)
+	var out, errOut bytes.Buffer
+	if err := cmdValidate(context.Background(), []string{"parse", "--calendar", "home", "Dinner"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Home") {
+		t.Fatalf("expected the cached calendar used: %q", out.String())
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("a preview must not run the helper")
+	}
+}
+
+func TestCmdParseCalendarMissingFromCache(t *testing.T) {
+	setupTestEnv(t)
+	stubCalendarHelper(t)
+	stubOpener(t)
+	if err := writeCalendarCache([]calendarInfo{{ID: "home-1", Title: "Home", Source: "iCloud"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A preview never runs the helper, so a name the cache lacks is kept.
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--dry-run", "--calendar", "Team", "x"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("dry-run: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Team") {
+		t.Fatalf("expected the name as given: %q", out.String())
+	}
+
+	// Otherwise a miss refetches the list and rewrites the cache.
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--print", "--calendar", "Birthdays", "Party"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Birthdays") {
+		t.Fatalf("expected the refetched calendar: %q", out.String())
+	}
+	cache, err := readCalendarCache()
+	if err != nil || cache == nil || len(cache.Calendars) != 4 {
+		t.Fatalf("expected the cache rewritten, got %+v (%v)", cache, err)
+	}
+}
+
+func TestCalendarResolverDuplicateTitles(t *testing.T) {
+	r := &calendarResolver{errOut: io.Discard}
+	r.source.list = func(context.Context) ([]calendarInfo, error) {
+		return []calendarInfo{
+			{ID: "ic-1", Title: "Work", Source: "iCloud"},
+			{ID: "ex-1", Title: "Work", Source: "Exchange"},
+		}, nil
+	}
+	_, err := r.resolve(context.Background(), "Work")
+	if !errors.Is(err, errUsage) || !strings.Contains(err.Error(), `"Work" (iCloud, id ic-1), "Work" (Exchange, id ex-1)`) {
+		t.Fatalf("expected an ambiguity error listing both calendars, got %v", err)
+	}
+	ref, err := r.resolve(context.Background(), "ex-1")
+	if err != nil || ref.ID != "ex-1" || !ref.byID {
+		t.Fatalf("expected the id to pick one, got %+v (%v)", ref, err)
+	}
+}
+
+func TestCalendarResolverReportsLooseMatches(t *testing.T) {
+	var errOut bytes.Buffer
+	r := &calendarResolver{errOut: &errOut}
+	r.source.list = func(context.Context) ([]calendarInfo, error) {
+		return []calendarInfo{{ID: "home-1", Title: "Home"}, {ID: "work-1", Title: "Work"}}, nil
+	}
+	if ref, err := r.resolve(context.Background(), "work"); err != nil || ref.Title != "Work" || errOut.Len() != 0 {
+		t.Fatalf("case-insensitive match: %+v, %v, %q", ref, err, errOut.String())
+	}
+	if ref, err := r.resolve(context.Background(), "hm"); err != nil || ref.Title != "Home" {
+		t.Fatalf("subsequence match: %+v, %v", ref, err)
+	}
+	if errOut.String() != "[fantastical] calendar \"hm\" resolved to \"Home\"\n" {
+		t.Fatalf("expected the loose match reported without --verbose, got %q", errOut.String())
+	}
+}
+
+func TestCmdEventKitEventsResolvesCalendars(t *testing.T) {
+	setupTestEnv(t)
+	stubCalendarHelper(t)
+
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), []string{"events", "--calendar", "icloud", "--calendar", "home-1"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := errOut.String()
+	if !strings.Contains(output, "--calendar\nWork (iCloud)\n") {
+		t.Fatalf("expected resolved title: %q", output)
+	}
+	if !strings.Contains(output, "--calendar-id\nhome-1\n") {
+		t.Fatalf("expected id passed as --calendar-id: %q", output)
+	}
+}
+
+func TestCmdShowResolvesCalendarSet(t *testing.T) {
+	writeAliasConfig(t, `{"show":{"calendar_sets":["Work Week","Family"]},"calendars":{"aliases":{"fam":"Family"}}}`)
+
+	var out, errOut bytes.Buffer
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "--calendar-set", "work"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "name=Work%20Week") {
+		t.Fatalf("expected resolved set: %q", out.String())
+	}
+
+	out.Reset()
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "--calendar-set", "fam"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "name=Family") {
+		t.Fatalf("expected aliased set: %q", out.String())
+	}
+
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "--calendar-set", "zzz"}, &out, &errOut); err == nil {
+		t.Fatalf("expected unknown set error")
+	}
+}
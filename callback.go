@@ -0,0 +1,166 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultAwaitTimeout = 30 * time.Second
+
+// callbackOutcome reports how Fantastical answered an x-callback-url request.
+// Params holds the query parameters of the callback (x-error sends
+// errorCode and errorMessage). Status "cancelled" means the command itself
+// was interrupted (signal or --timeout) before Fantastical called back.
+type callbackOutcome struct {
+	Status    string            `json:"status" enum:"success,error,cancel,timeout,cancelled"`
+	Params    map[string]string `json:"params,omitempty"`
+	ElapsedMs int64             `json:"elapsed_ms"`
+
+	cause error
+}
+
+func (o callbackOutcome) err() error {
+	switch o.Status {
+	case "success":
+		return nil
+	case "timeout":
+		return fmt.Errorf("no callback from Fantastical within %s", time.Duration(o.ElapsedMs)*time.Millisecond)
+	case "cancelled":
+		return o.cause
+	case "error":
+		if msg := o.Params["errorMessage"]; msg != "" {
+			return fmt.Errorf("Fantastical reported an error: %s", msg)
+		}
+		return errors.New("Fantastical reported an error")
+	default:
+		return fmt.Errorf("Fantastical reported %s", o.Status)
+	}
+}
+
+// callbackListener is a one-shot loopback HTTP listener that receives the
+// x-success, x-error and x-cancel callbacks of a single request. A random
+// path prefix keeps other local processes from answering for Fantastical.
+type callbackListener struct {
+	ln      net.Listener
+	srv     *http.Server
+	prefix  string
+	results chan callbackOutcome
+}
+
+func newCallbackListener() (*callbackListener, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("callback listener: %w", err)
+	}
+	l := &callbackListener{
+		ln:      ln,
+		prefix:  "/" + hex.EncodeToString(nonce) + "/",
+		results: make(chan callbackOutcome, 1),
+	}
+	l.srv = &http.Server{Handler: http.HandlerFunc(l.serveHTTP), ReadHeaderTimeout: 5 * time.Second}
+	go l.srv.Serve(ln)
+	return l, nil
+}
+
+// callbackURL returns the URL Fantastical should open for status.
+func (l *callbackListener) callbackURL(status string) string {
+	return "http://" + l.ln.Addr().String() + l.prefix + status
+}
+
+func (l *callbackListener) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	status := strings.TrimPrefix(r.URL.Path, l.prefix)
+	if !strings.HasPrefix(r.URL.Path, l.prefix) || (status != "success" && status != "error" && status != "cancel") {
+		http.NotFound(w, r)
+		return
+	}
+	params := map[string]string{}
+	for key, values := range r.URL.Query() {
+		params[key] = values[len(values)-1]
+	}
+	select {
+	case l.results <- callbackOutcome{Status: status, Params: params}:
+	default:
+		// Only the first callback counts.
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "fantastical: received", status, "- you can close this window.")
+}
+
+// wait blocks until a callback arrives, timeout elapses or ctx ends.
+func (l *callbackListener) wait(ctx context.Context, timeout time.Duration) callbackOutcome {
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var outcome callbackOutcome
+	select {
+	case outcome = <-l.results:
+	case <-timer.C:
+		outcome = callbackOutcome{Status: "timeout"}
+	case <-ctx.Done():
+		outcome = callbackOutcome{Status: "cancelled", cause: context.Cause(ctx)}
+	}
+	outcome.ElapsedMs = time.Since(start).Milliseconds()
+	return outcome
+}
+
+func (l *callbackListener) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	l.srv.Shutdown(ctx)
+}
+
+// withCallbacks rewrites a Fantastical URL into its x-callback-url form
+// (x-fantastical3://x-callback-url/parse?...) with callbacks pointing at l.
+func withCallbacks(u string, l *callbackListener) (string, error) {
+	action, query, _ := strings.Cut(strings.TrimPrefix(u, fantasticalScheme), "?")
+	if action == u || action == "" {
+		return "", fmt.Errorf("not a Fantastical URL: %s", u)
+	}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+	q.Set("x-source", appName)
+	q.Set("x-success", l.callbackURL("success"))
+	q.Set("x-error", l.callbackURL("error"))
+	q.Set("x-cancel", l.callbackURL("cancel"))
+	return fantasticalScheme + "x-callback-url/" + action + "?" + encodeQuery(q), nil
+}
+
+// openAndAwait opens u with callbacks attached and waits for Fantastical to
+// call back. It returns the URL that was actually opened.
+func openAndAwait(ctx context.Context, u string, timeout time.Duration, open func(string) error, errOut io.Writer, verbose bool) (string, callbackOutcome, error) {
+	l, err := newCallbackListener()
+	if err != nil {
+		return u, callbackOutcome{}, err
+	}
+	defer l.close()
+
+	opened, err := withCallbacks(u, l)
+	if err != nil {
+		return u, callbackOutcome{}, err
+	}
+	logVerbose(errOut, verbose, "await: listening on %s (timeout %s)", l.ln.Addr(), timeout)
+	if err := open(opened); err != nil {
+		return opened, callbackOutcome{}, err
+	}
+	outcome := l.wait(ctx, timeout)
+	logVerbose(errOut, verbose, "await: %s after %dms", outcome.Status, outcome.ElapsedMs)
+	return opened, outcome, nil
+}
@@ -0,0 +1,165 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// answerCallback plays Fantastical: it reads the x-<status> parameter from
+// the opened URL and requests it with extra query parameters.
+func answerCallback(opened, status, extra string) error {
+	u, err := url.Parse(opened)
+	if err != nil {
+		return err
+	}
+	target := u.Query().Get("x-" + status)
+	if target == "" {
+		return errors.New("no x-" + status + " in " + opened)
+	}
+	if extra != "" {
+		target += "?" + extra
+	}
+	resp, err := http.Get(target)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestOpenAndAwait(t *testing.T) {
+	base := buildParseURL("Lunch", "", "", true, nil)
+
+	var opened string
+	got, outcome, err := openAndAwait(context.Background(), base, 5*time.Second, func(u string) error {
+		opened = u
+		// A request outside the random prefix must not count as a callback.
+		q, _ := url.Parse(u)
+		success, _ := url.Parse(q.Query().Get("x-success"))
+		resp, err := http.Get("http://" + success.Host + "/success")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404 without the callback prefix, got %d", resp.StatusCode)
+		}
+		return answerCallback(u, "success", "")
+	}, io.Discard, false)
+	if err != nil || outcome.Status != "success" || outcome.err() != nil {
+		t.Fatalf("success: %+v %v", outcome, err)
+	}
+	if got != opened || !strings.HasPrefix(got, "x-fantastical3://x-callback-url/parse?") || !strings.Contains(got, "s=Lunch") || !strings.Contains(got, "add=1") {
+		t.Fatalf("unexpected callback URL: %s", got)
+	}
+	q, _ := url.Parse(got)
+	for _, key := range []string{"x-success", "x-error", "x-cancel"} {
+		if !strings.HasPrefix(q.Query().Get(key), "http://127.0.0.1:") {
+			t.Fatalf("%s not a loopback URL: %s", key, got)
+		}
+	}
+
+	_, outcome, err = openAndAwait(context.Background(), base, 5*time.Second, func(u string) error {
+		return answerCallback(u, "error", "errorCode=1&errorMessage=Calendar%20is%20read-only")
+	}, io.Discard, false)
+	if err != nil || outcome.Status != "error" || outcome.Params["errorCode"] != "1" {
+		t.Fatalf("error: %+v %v", outcome, err)
+	}
+	if err := outcome.err(); err == nil || !strings.Contains(err.Error(), "Calendar is read-only") {
+		t.Fatalf("expected error message from callback, got %v", err)
+	}
+
+	_, outcome, err = openAndAwait(context.Background(), base, 50*time.Millisecond, func(string) error { return nil }, io.Discard, false)
+	if err != nil || outcome.Status != "timeout" || outcome.ElapsedMs < 50 || outcome.err() == nil {
+		t.Fatalf("timeout: %+v %v", outcome, err)
+	}
+
+	// Interrupting the command is not Fantastical failing to call back.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	errInterrupted := errors.New("interrupted")
+	_, outcome, err = openAndAwait(ctx, base, 5*time.Second, func(string) error {
+		cancel(errInterrupted)
+		return nil
+	}, io.Discard, false)
+	if err != nil || outcome.Status != "cancelled" || !errors.Is(outcome.err(), errInterrupted) {
+		t.Fatalf("cancelled: %+v %v (%v)", outcome, err, outcome.err())
+	}
+
+	if _, _, err := openAndAwait(context.Background(), base, time.Second, func(string) error { return errors.New("open failed") }, io.Discard, false); err == nil {
+		t.Fatalf("expected opener error")
+	}
+}
+
+// TestCallbackOpenerProcess is not a real test: it is the stub opener that
+// cmdParse runs via FANTASTICAL_OPEN_COMMAND in TestCmdParseAwait.
+func TestCallbackOpenerProcess(t *testing.T) {
+	status := os.Getenv("FANTASTICAL_TEST_CALLBACK")
+	if status == "" {
+		return
+	}
+	extra := ""
+	if status == "error" {
+		extra = "errorMessage=nope"
+	}
+	if status != "none" {
+		if err := answerCallback(os.Args[len(os.Args)-1], status, extra); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Exit before the test framework prints to the stdout cmdParse captures.
+	os.Exit(0)
+}
+
+func TestCmdParseAwait(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("FANTASTICAL_OPEN_COMMAND", os.Args[0]+" -test.run=^TestCallbackOpenerProcess$ --")
+
+	t.Setenv("FANTASTICAL_TEST_CALLBACK", "success")
+	var out bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--await", "--json", "Lunch"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("await success: %v", err)
+	}
+	var result parseResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if result.Callback == nil || result.Callback.Status != "success" || !strings.Contains(result.URL, "x-callback-url/parse") {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+
+	t.Setenv("FANTASTICAL_TEST_CALLBACK", "error")
+	out.Reset()
+	err := cmdParse(context.Background(), []string{"--await", "--json", "Lunch"}, strings.NewReader(""), &out, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "nope") || !strings.Contains(out.String(), `"status":"error"`) {
+		t.Fatalf("expected error outcome, got %v %s", err, out.String())
+	}
+
+	t.Setenv("FANTASTICAL_TEST_CALLBACK", "none")
+	out.Reset()
+	err = cmdParse(context.Background(), []string{"--await", "--await-timeout", "100ms", "--json", "Lunch"}, strings.NewReader(""), &out, io.Discard)
+	if err == nil || !strings.Contains(out.String(), `"status":"timeout"`) {
+		t.Fatalf("expected timeout outcome, got %v %s", err, out.String())
+	}
+
+	for _, args := range [][]string{
+		{"--await", "--dry-run", "Lunch"},
+		{"--await", "--open=false", "Lunch"},
+		{"--await", "--await-timeout", "0s", "Lunch"},
+	} {
+		if err := cmdParse(context.Background(), args, strings.NewReader(""), io.Discard, io.Discard); !errors.Is(err, errUsage) {
+			t.Fatalf("%v: expected usage error, got %v", args, err)
+		}
+	}
+}
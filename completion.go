@@ -0,0 +1,484 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completeCommand is the hidden subcommand the shell scripts call:
+//
+//	fantastical __complete <words after "fantastical"...> <current word>
+//
+// It prints one candidate per line as "value<TAB>description". No output
+// tells the shell to fall back to file completion.
+const completeCommand = "__complete"
+
+type completion struct {
+	Value string
+	Desc  string
+}
+
+// showViews are the targets accepted by `show <view>`.
+var showViews = []completion{
+	{"mini", "Mini window"},
+	{"calendar", "Main calendar window"},
+	{"day", "Day view"},
+	{"week", "Week view"},
+	{"month", "Month view"},
+	{"agenda", "Agenda view"},
+	{"set", "Calendar set"},
+}
+
+var completionShells = []completion{{"bash", ""}, {"zsh", ""}, {"fish", ""}}
+
+// registryCompletions returns the visible commands in specs.
+func registryCompletions(specs []commandSpec) []completion {
+	var candidates []completion
+	for _, spec := range specs {
+		if !spec.Hidden {
+			candidates = append(candidates, completion{Value: spec.Name, Desc: spec.Summary})
+		}
+	}
+	return candidates
+}
+
+// completionFlags returns the flags of a command path plus the implicit
+// --help every flag set accepts.
+func completionFlags(path string) []flagSpec {
+	spec, ok := lookupCommand(path)
+	if !ok {
+		return nil
+	}
+	return append(append([]flagSpec(nil), spec.Flags...), flagSpec{Name: "help", Usage: "Show help"})
+}
+
+func findCompletionFlag(flags []flagSpec, token string) (flagSpec, bool) {
+	name := strings.TrimLeft(token, "-")
+	for _, f := range flags {
+		for _, n := range f.names() {
+			if n == name {
+				return f, true
+			}
+		}
+	}
+	return flagSpec{}, false
+}
+
+func cmdComplete(args []string, out, errOut io.Writer) error {
+	for _, c := range completeArgs(args) {
+		if c.Desc == "" {
+			fmt.Fprintln(out, c.Value)
+			continue
+		}
+		fmt.Fprintf(out, "%s\t%s\n", c.Value, strings.ReplaceAll(c.Desc, "\n", " "))
+	}
+	return nil
+}
+
+// completeArgs returns the candidates for the last word of args given the
+// words before it. Config and cache errors are ignored: completion must never
+// fail loudly.
+func completeArgs(args []string) []completion {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	cur := args[len(args)-1]
+	prior := args[:len(args)-1]
+
+	// Global flags come before the command name.
+	for len(prior) > 0 && strings.HasPrefix(prior[0], "-") {
+		if _, ok := findCompletionFlag(globalFlags, strings.SplitN(prior[0], "=", 2)[0]); !ok {
+			break
+		}
+		if strings.Contains(prior[0], "=") {
+			prior = prior[1:]
+			continue
+		}
+		if len(prior) == 1 {
+			return nil
+		}
+		prior = prior[2:]
+	}
+
+	cfg := completionConfig(prior)
+	if len(prior) == 0 {
+		if strings.HasPrefix(cur, "-") {
+			return filterCompletions(flagCompletions(globalFlags), cur)
+		}
+		return filterCompletions(commandCompletions(cfg), cur)
+	}
+
+	if !isBuiltinCommand(prior[0]) {
+		if expanded, err := expandAlias(prior, cfg.Aliases); err == nil {
+			prior = expanded
+		}
+	}
+	cmd := strings.ToLower(prior[0])
+	if cmd == "as" {
+		cmd = "applescript"
+	}
+	rest := prior[1:]
+
+	// validate [--json] <parse|show|events> ... completes like the wrapped
+	// command; events wraps eventkit events.
+	if cmd == "validate" {
+		for i, word := range rest {
+			target := strings.ToLower(word)
+			if target == "parse" || target == "show" {
+				cmd, rest = target, rest[i+1:]
+				break
+			}
+			if target == "events" {
+				cmd, rest = "eventkit", append([]string{"events"}, rest[i+1:]...)
+				break
+			}
+		}
+	}
+
+	path := cmd
+	for {
+		spec, ok := lookupCommand(path)
+		if !ok || len(spec.Subcommands) == 0 {
+			break
+		}
+		if len(rest) == 0 {
+			if strings.HasPrefix(cur, "-") {
+				return nil
+			}
+			subs := append(registryCompletions(spec.Subcommands), positionalCompletions(path, nil, cfg)...)
+			return filterCompletions(subs, cur)
+		}
+		path += " " + strings.ToLower(rest[0])
+		rest = rest[1:]
+	}
+	flags := completionFlags(path)
+
+	// Walk the words after the command to find positionals and whether the
+	// previous word is a flag still waiting for its value.
+	var positionals []string
+	var pending *flagSpec
+	onlyArgs := false
+	for i := 0; i < len(rest); i++ {
+		word := rest[i]
+		if onlyArgs || !strings.HasPrefix(word, "-") || word == "-" {
+			positionals = append(positionals, word)
+			continue
+		}
+		if word == "--" {
+			onlyArgs = true
+			continue
+		}
+		f, ok := findCompletionFlag(flags, word)
+		if strings.Contains(word, "=") || !ok || !f.takesValue() {
+			continue
+		}
+		// bash splits --flag=value into "--flag", "=", "value".
+		if i+1 < len(rest) && rest[i+1] == "=" {
+			i++
+		}
+		if i+1 >= len(rest) {
+			pending = &f
+			break
+		}
+		i++
+	}
+
+	if pending != nil {
+		return filterCompletions(flagValueCompletions(*pending, cfg), cur)
+	}
+	if !onlyArgs && strings.HasPrefix(cur, "-") {
+		if name, value, ok := strings.Cut(cur, "="); ok {
+			f, found := findCompletionFlag(flags, name)
+			if !found || !f.takesValue() {
+				return nil
+			}
+			var candidates []completion
+			for _, c := range filterCompletions(flagValueCompletions(f, cfg), value) {
+				candidates = append(candidates, completion{Value: name + "=" + c.Value, Desc: c.Desc})
+			}
+			return candidates
+		}
+		return filterCompletions(flagCompletions(flags), cur)
+	}
+	return filterCompletions(positionalCompletions(path, positionals, cfg), cur)
+}
+
+// completionConfig loads the config named by --config in words, or the
+// default config. Errors yield an empty config.
+func completionConfig(words []string) *Config {
+	path, err := extractConfigPath(words)
+	if err != nil {
+		path = ""
+	}
+	cfg, err := loadConfigWithPath(path)
+	if err != nil || cfg == nil {
+		return &Config{}
+	}
+	return cfg
+}
+
+func flagCompletions(flags []flagSpec) []completion {
+	var candidates []completion
+	for _, f := range flags {
+		for _, name := range f.names() {
+			candidates = append(candidates, completion{Value: flagToken(name), Desc: f.Usage})
+		}
+	}
+	return candidates
+}
+
+func commandCompletions(cfg *Config) []completion {
+	candidates := registryCompletions(commandRegistry)
+	for _, name := range aliasNames(cfg.Aliases) {
+		candidates = append(candidates, completion{Value: name, Desc: "Alias (config)"})
+	}
+	return candidates
+}
+
+func positionalCompletions(path string, positionals []string, cfg *Config) []completion {
+	switch path {
+	case "validate":
+		if len(positionals) == 0 {
+			return []completion{{"parse", "Validate a parse sentence"}, {"show", "Validate a show target"}, {"events", "Resolve an eventkit events range"}}
+		}
+	case "show":
+		if len(positionals) == 0 {
+			return showViews
+		}
+		if strings.EqualFold(positionals[0], "set") {
+			return calendarSetCompletions(cfg)
+		}
+		if len(positionals) == 1 {
+			return []completion{{"today", ""}, {"tomorrow", ""}, {"yesterday", ""}}
+		}
+	case "help", "explain":
+		if len(positionals) == 0 {
+			if path == "help" {
+				return commandCompletions(cfg)
+			}
+			return registryCompletions(commandRegistry)
+		}
+	case "completion", "completion install", "completion uninstall":
+		if len(positionals) == 0 {
+			return completionShells
+		}
+	}
+	return nil
+}
+
+func flagValueCompletions(f flagSpec, cfg *Config) []completion {
+	switch f.Complete {
+	case completeCalendar:
+		return calendarNameCompletions(cfg)
+	case completeCalendarID:
+		return calendarIDCompletions()
+	case completeCalendarSet:
+		return calendarSetCompletions(cfg)
+	case completeTimezone:
+		return timezoneCompletions()
+	}
+	candidates := make([]completion, len(f.Values))
+	for i, v := range f.Values {
+		candidates[i] = completion{Value: v}
+	}
+	return candidates
+}
+
+// calendarNameCompletions offers cached calendar titles (stale entries are
+// fine here; completion never runs the helper) and calendar aliases.
+func calendarNameCompletions(cfg *Config) []completion {
+	var candidates []completion
+	if cache, err := readCalendarCache(); err == nil && cache != nil {
+		for _, cal := range cache.Calendars {
+			candidates = append(candidates, completion{Value: cal.Title, Desc: cal.Source})
+		}
+	}
+	for _, alias := range sortedAliases(cfg.Calendars.Aliases) {
+		candidates = append(candidates, completion{Value: alias.Name, Desc: "Alias for " + alias.Expansion})
+	}
+	return candidates
+}
+
+func calendarIDCompletions() []completion {
+	cache, err := readCalendarCache()
+	if err != nil || cache == nil {
+		return nil
+	}
+	candidates := make([]completion, 0, len(cache.Calendars))
+	for _, cal := range cache.Calendars {
+		candidates = append(candidates, completion{Value: cal.ID, Desc: cal.Title})
+	}
+	return candidates
+}
+
+func calendarSetCompletions(cfg *Config) []completion {
+	var candidates []completion
+	sets := map[string]bool{}
+	for _, set := range cfg.Show.CalendarSets {
+		sets[set] = true
+		candidates = append(candidates, completion{Value: set})
+	}
+	for _, alias := range sortedAliases(cfg.Calendars.Aliases) {
+		if sets[alias.Expansion] || len(sets) == 0 {
+			candidates = append(candidates, completion{Value: alias.Name, Desc: "Alias for " + alias.Expansion})
+		}
+	}
+	return candidates
+}
+
+// timezoneCompletions lists IANA zone names from the system zoneinfo
+// database ($ZONEINFO when it is a directory).
+func timezoneCompletions() []completion {
+	roots := []string{"/var/db/timezone/zoneinfo", "/usr/share/zoneinfo"}
+	if dir := os.Getenv("ZONEINFO"); dir != "" {
+		roots = append([]string{dir}, roots...)
+	}
+	for _, root := range roots {
+		info, err := os.Stat(root)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		names := map[string]bool{"UTC": true, "Local": true}
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || path == root {
+				return nil
+			}
+			first := d.Name()[0]
+			if first < 'A' || first > 'Z' {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || strings.Contains(d.Name(), ".") {
+				return nil
+			}
+			if rel, err := filepath.Rel(root, path); err == nil {
+				names[filepath.ToSlash(rel)] = true
+			}
+			return nil
+		})
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		candidates := make([]completion, len(sorted))
+		for i, name := range sorted {
+			candidates[i] = completion{Value: name}
+		}
+		return candidates
+	}
+	return []completion{{Value: "Local"}, {Value: "UTC"}}
+}
+
+func filterCompletions(candidates []completion, prefix string) []completion {
+	var out []completion
+	seen := map[string]bool{}
+	lower := strings.ToLower(prefix)
+	for _, c := range candidates {
+		if seen[c.Value] || !strings.HasPrefix(strings.ToLower(c.Value), lower) {
+			continue
+		}
+		seen[c.Value] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+// completionHeader marks a script written by fantastical; doctor --fix
+// only replaces completion files that carry it.
+const completionHeader = "# fantastical shell completion, generated by fantastical completion"
+
+// completionScript renders the script for shell. The scripts are thin
+// wrappers around `fantastical __complete`, so candidates such as calendar
+// names and config aliases stay current without reinstalling.
+func completionScript(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(), nil
+	case "zsh":
+		return zshCompletion(), nil
+	case "fish":
+		return fishCompletion(), nil
+	default:
+		return "", fmt.Errorf("%w: unknown shell %q (want: bash, zsh, fish)", errUsage, shell)
+	}
+}
+
+func bashCompletion() string {
+	return completionHeader + `
+_fantastical_completions() {
+  local IFS=$'\n'
+  local line
+  local -a candidates
+  candidates=( $(fantastical ` + completeCommand + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null) )
+  COMPREPLY=()
+  for line in "${candidates[@]}"; do
+    line="${line%%$'\t'*}"
+    if [[ "$line" == *" "* ]]; then
+      COMPREPLY+=( "$(printf '%q' "$line")" )
+    else
+      COMPREPLY+=( "$line" )
+    fi
+  done
+  return 0
+}
+
+complete -F _fantastical_completions -o default fantastical`
+}
+
+func zshCompletion() string {
+	return `#compdef fantastical
+` + completionHeader + `
+
+_fantastical() {
+  local -a lines candidates
+  local line value desc
+  lines=("${(@f)$(fantastical ` + completeCommand + ` "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+  for line in $lines; do
+    [[ -z "$line" ]] && continue
+    value="${line%%$'\t'*}"
+    desc=""
+    [[ "$line" == *$'\t'* ]] && desc="${line#*$'\t'}"
+    value="${value//:/\\:}"
+    if [[ -n "$desc" ]]; then
+      candidates+=("$value:$desc")
+    else
+      candidates+=("$value")
+    fi
+  done
+  if (( ${#candidates} )); then
+    _describe -V 'fantastical' candidates
+  else
+    _files
+  fi
+}
+
+_fantastical "$@"`
+}
+
+func fishCompletion() string {
+	return completionHeader + `
+function __fantastical_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    set -l candidates (fantastical ` + completeCommand + ` $tokens $current 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path $current
+        return
+    end
+    printf '%s\n' $candidates
+end
+
+complete -c fantastical -f -a '(__fantastical_complete)'`
+}
@@ -0,0 +1,166 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupCompletionEnv writes a config and seeds the calendar cache from the
+// shared calendar fixture.
+func setupCompletionEnv(t *testing.T) {
+	t.Helper()
+	writeAliasConfig(t, `{
+  "aliases": {"wk": "eventkit events --this-week"},
+  "calendars": {"aliases": {"w": "Work (Exchange)", "fam": "Family"}},
+  "show": {"calendar_sets": ["Work Week", "Family"]}
+}`)
+	stubCalendarHelper(t)
+	if _, err := fetchAndCacheTestCalendars(); err != nil {
+		t.Fatalf("seed cache: %v", err)
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "false")
+}
+
+func fetchAndCacheTestCalendars() ([]calendarInfo, error) {
+	calendars, _, err := calendarSource{ttl: defaultCalendarCacheTTL, errOut: &bytes.Buffer{}}.load(context.Background())
+	return calendars, err
+}
+
+func completionValues(args ...string) []string {
+	var values []string
+	for _, c := range completeArgs(args) {
+		values = append(values, c.Value)
+	}
+	return values
+}
+
+func TestCompleteCommandsAndAliases(t *testing.T) {
+	setupCompletionEnv(t)
+
+	values := completionValues("")
+	for _, want := range []string{"parse", "eventkit", "wk"} {
+		if !containsString(values, want) {
+			t.Fatalf("expected %q in %v", want, values)
+		}
+	}
+	if containsString(values, completeCommand) {
+		t.Fatalf("hidden command should not be offered: %v", values)
+	}
+	if got := completionValues("pa"); !reflect.DeepEqual(got, []string{"parse"}) {
+		t.Fatalf("unexpected prefix completion: %v", got)
+	}
+}
+
+func TestCompleteCalendarNames(t *testing.T) {
+	setupCompletionEnv(t)
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"parse", "--calendar", "Wo"}, []string{"Work (Exchange)", "Work (iCloud)"}},
+		{[]string{"parse", "--calendar", "w"}, []string{"Work (Exchange)", "Work (iCloud)", "w"}},
+		{[]string{"parse", "--calendar=Ho"}, []string{"--calendar=Home"}},
+		{[]string{"parse", "--calendar", "=", "Ho"}, []string{"Home"}},
+		{[]string{"eventkit", "events", "--calendar-id", "work"}, []string{"work-ex", "work-ic"}},
+		{[]string{"wk", "--calendar", "Bi"}, []string{"Birthdays"}},
+		{[]string{"show", "--calendar-set", ""}, []string{"Work Week", "Family", "fam"}},
+		{[]string{"show", "set", "W"}, []string{"Work Week"}},
+	}
+	for _, tc := range cases {
+		if got := completionValues(tc.args...); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("complete %q = %v, want %v", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCompleteSubcommandsFlagsAndViews(t *testing.T) {
+	setupCompletionEnv(t)
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"eventkit", ""}, []string{"status", "calendars", "events", "search", "create", "delete", "helper"}},
+		{[]string{"eventkit", "events", "--no-"}, []string{"--no-input", "--no-cache"}},
+		{[]string{"eventkit", "events", "--format", ""}, []string{"plain", "json", "table"}},
+		{[]string{"show", "m"}, []string{"mini", "month"}},
+		{[]string{"show", "--view", "a"}, []string{"agenda"}},
+		{[]string{"show", "month", "to"}, []string{"today", "tomorrow"}},
+		{[]string{"validate", "--json", ""}, []string{"parse", "show", "events"}},
+		{[]string{"validate", "events", "--week-start", ""}, []string{"monday", "sunday"}},
+		{[]string{"validate", "--json", "parse", "--calendarN"}, []string{"--calendarName"}},
+		{[]string{"as", "--ru"}, []string{"--run"}},
+		{[]string{"help", "ev"}, []string{"eventkit"}},
+		{[]string{"completion", "install", "z"}, []string{"zsh"}},
+		{[]string{"parse", "--config", ""}, nil},
+	}
+	for _, tc := range cases {
+		if got := completionValues(tc.args...); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("complete %q = %v, want %v", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCompleteTimezones(t *testing.T) {
+	setupCompletionEnv(t)
+	zoneinfo := t.TempDir()
+	for _, name := range []string{"Europe/Zagreb", "Europe/Zurich", "America/New_York", "zone.tab", "posix/Europe/Zagreb"} {
+		path := filepath.Join(zoneinfo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("write zone: %v", err)
+		}
+	}
+	t.Setenv("ZONEINFO", zoneinfo)
+
+	if got := completionValues("show", "--timezone", "Europe/Z"); !reflect.DeepEqual(got, []string{"Europe/Zagreb", "Europe/Zurich"}) {
+		t.Fatalf("unexpected timezones: %v", got)
+	}
+	if got := completionValues("eventkit", "events", "--tz", "u"); !reflect.DeepEqual(got, []string{"UTC"}) {
+		t.Fatalf("unexpected timezones: %v", got)
+	}
+}
+
+func TestRunComplete(t *testing.T) {
+	setupCompletionEnv(t)
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"fantastical", completeCommand, "parse", "--calendar", "Ho"}, strings.NewReader(""), &out, &errOut); code != 0 {
+		t.Fatalf("expected exit 0, got %d (%s)", code, errOut.String())
+	}
+	if out.String() != "Home\tiCloud\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestCompletionScriptsCallComplete(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		script, err := completionScript(shell)
+		if err != nil {
+			t.Fatalf("completion %s: %v", shell, err)
+		}
+		if !strings.Contains(script, "fantastical "+completeCommand) {
+			t.Fatalf("expected %s script to call %s: %q", shell, completeCommand, script)
+		}
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// eventSpec holds the structured parse flags (--title, --start, ...).
+type eventSpec struct {
+	title    string
+	start    string
+	end      string
+	duration time.Duration
+	location string
+	invitees stringSlice
+	repeat   string
+	alert    time.Duration
+}
+
+func (s eventSpec) isSet() bool {
+	return s.title != "" || s.start != "" || s.end != "" || s.duration != 0 || s.location != "" ||
+		len(s.invitees) > 0 || s.repeat != "" || s.alert != 0
+}
+
+// sentencePart is one phrase of a composed sentence. Note explains a
+// non-obvious composition choice, such as quoting.
+type sentencePart struct {
+	Kind string `json:"kind" enum:"title,location,when,duration,repeat,invitees,alert,calendar"`
+	Text string `json:"text"`
+	Note string `json:"note,omitempty"`
+}
+
+// repeatPhrases maps --repeat values to Fantastical's wording.
+var repeatPhrases = map[string]string{
+	"daily":    "every day",
+	"weekdays": "every weekday",
+	"weekends": "every weekend",
+	"weekly":   "every week",
+	"biweekly": "every other week",
+	"monthly":  "every month",
+	"yearly":   "every year",
+}
+
+var repeatValues = []string{"daily", "weekdays", "weekends", "weekly", "biweekly", "monthly", "yearly"}
+
+const (
+	sentenceDateLayout = "Jan 2 2006"
+	sentenceTimeLayout = "3:04pm"
+)
+
+// composeReference is the clock used when checking whether a title or
+// location would be read as something else; the answer never depends on it.
+var composeReference = time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// composeSentence builds a Fantastical sentence from structured flags. The
+// output depends only on its inputs: parts always appear in the order
+// title, location, when, duration, repeat, invitees, alert, calendar.
+func composeSentence(spec eventSpec, calendar string, loc *time.Location) ([]sentencePart, error) {
+	title := strings.Join(strings.Fields(spec.title), " ")
+	if title == "" {
+		return nil, fmt.Errorf("%w: --title is required with structured parse flags", errUsage)
+	}
+	if strings.Contains(title, `"`) {
+		return nil, fmt.Errorf("%w: --title cannot contain double quotes (Fantastical has no way to escape them)", errUsage)
+	}
+	// The title is always quoted: whether Fantastical would read part of it as
+	// a date, place or calendar cannot be predicted reliably.
+	parts := []sentencePart{{Kind: "title", Text: `"` + title + `"`}}
+
+	if location := strings.Join(strings.Fields(spec.location), " "); location != "" {
+		if strings.Contains(location, `"`) {
+			return nil, fmt.Errorf("%w: --location cannot contain double quotes (Fantastical has no way to escape them)", errUsage)
+		}
+		p := predictEvent("x at "+location, composeReference)
+		part := quotedPart("location", location, p.Location != location)
+		part.Text = "at " + part.Text
+		parts = append(parts, part)
+	}
+
+	when, err := composeWhen(spec, loc)
+	if err != nil {
+		return nil, err
+	}
+	if when != "" {
+		parts = append(parts, sentencePart{Kind: "when", Text: when})
+	}
+
+	if spec.duration != 0 {
+		text, err := spokenDuration("--duration", spec.duration)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, sentencePart{Kind: "duration", Text: "for " + text})
+	}
+
+	if spec.repeat != "" {
+		phrase, ok := repeatPhrases[strings.ToLower(spec.repeat)]
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid --repeat %q (want %s)", errUsage, spec.repeat, strings.Join(repeatValues, ", "))
+		}
+		parts = append(parts, sentencePart{Kind: "repeat", Text: phrase})
+	}
+
+	var invitees []string
+	for _, value := range spec.invitees {
+		for _, email := range strings.Split(value, ",") {
+			email = strings.TrimSpace(email)
+			if email == "" {
+				continue
+			}
+			if !strings.Contains(email, "@") || strings.ContainsAny(email, " \t\"") {
+				return nil, fmt.Errorf("%w: invalid --invitees address %q", errUsage, email)
+			}
+			invitees = append(invitees, email)
+		}
+	}
+	if len(invitees) > 0 {
+		parts = append(parts, sentencePart{Kind: "invitees", Text: "with " + strings.Join(invitees, ", ")})
+	}
+
+	if spec.alert != 0 {
+		text, err := spokenDuration("--alert", spec.alert)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, sentencePart{Kind: "alert", Text: "alert " + text + " before"})
+	}
+
+	if calendar = strings.TrimSpace(calendar); calendar != "" {
+		if strings.ContainsAny(calendar, " \t") {
+			parts = append(parts, sentencePart{Kind: "calendar", Note: "name has spaces; passed only as calendarName"})
+		} else {
+			parts = append(parts, sentencePart{Kind: "calendar", Text: "/" + calendar, Note: "also passed as calendarName"})
+		}
+	}
+	return parts, nil
+}
+
+func quotedPart(kind, text string, quote bool) sentencePart {
+	if !quote {
+		return sentencePart{Kind: kind, Text: text}
+	}
+	return sentencePart{
+		Kind: kind,
+		Text: `"` + text + `"`,
+		Note: "quoted: would otherwise be read as a date, time, place or calendar",
+	}
+}
+
+// sentenceFromParts joins the non-empty parts with single spaces.
+func sentenceFromParts(parts []sentencePart) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+func composeWhen(spec eventSpec, loc *time.Location) (string, error) {
+	if spec.start == "" {
+		if spec.end != "" {
+			return "", fmt.Errorf("%w: --end needs --start", errUsage)
+		}
+		return "", nil
+	}
+	start, startDateOnly, err := parseEventBound(spec.start, loc)
+	if err != nil {
+		return "", err
+	}
+	if spec.duration != 0 && startDateOnly {
+		return "", fmt.Errorf("%w: --duration needs a --start with a time", errUsage)
+	}
+	if spec.end == "" {
+		if startDateOnly {
+			return "on " + start.Format(sentenceDateLayout), nil
+		}
+		return "on " + start.Format(sentenceDateLayout) + " at " + start.Format(sentenceTimeLayout), nil
+	}
+	if spec.duration != 0 {
+		return "", fmt.Errorf("%w: --end and --duration are mutually exclusive", errUsage)
+	}
+	end, endDateOnly, err := parseEventBound(spec.end, loc)
+	if err != nil {
+		return "", err
+	}
+	if startDateOnly != endDateOnly {
+		return "", fmt.Errorf("%w: --start and --end must both be dates or both have a time", errUsage)
+	}
+	if end.Before(start) || (!endDateOnly && end.Equal(start)) {
+		return "", fmt.Errorf("%w: --end must be after --start", errUsage)
+	}
+
+	sameDay := start.Format("2006-01-02") == end.Format("2006-01-02")
+	switch {
+	case startDateOnly && sameDay:
+		return "on " + start.Format(sentenceDateLayout), nil
+	case startDateOnly:
+		return "from " + start.Format(sentenceDateLayout) + " to " + end.Format(sentenceDateLayout), nil
+	case sameDay:
+		return "on " + start.Format(sentenceDateLayout) + " from " + start.Format(sentenceTimeLayout) + " to " + end.Format(sentenceTimeLayout), nil
+	default:
+		return "from " + start.Format(sentenceDateLayout) + " at " + start.Format(sentenceTimeLayout) +
+			" to " + end.Format(sentenceDateLayout) + " at " + end.Format(sentenceTimeLayout), nil
+	}
+}
+
+// parseEventBound accepts the --from/--to layouts plus RFC 3339 timestamps,
+// which are converted to loc.
+func parseEventBound(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+		return t.In(loc), false, nil
+	}
+	return parseRangeBound(value, loc)
+}
+
+// spokenDuration renders d as "1 hour 30 minutes"; d must be whole minutes.
+func spokenDuration(flagName string, d time.Duration) (string, error) {
+	if d <= 0 || d%time.Minute != 0 {
+		return "", fmt.Errorf("%w: %s must be a positive number of whole minutes", errUsage, flagName)
+	}
+	units := []struct {
+		name string
+		size time.Duration
+	}{{"day", 24 * time.Hour}, {"hour", time.Hour}, {"minute", time.Minute}}
+	var words []string
+	for _, u := range units {
+		n := int(d / u.size)
+		d -= time.Duration(n) * u.size
+		switch {
+		case n == 1:
+			words = append(words, "1 "+u.name)
+		case n > 1:
+			words = append(words, fmt.Sprintf("%d %ss", n, u.name))
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+// printSentenceExplain writes the --explain view of a parse run.
+func printSentenceExplain(w io.Writer, sentence string, parts []sentencePart, u string) {
+	fmt.Fprintf(w, "sentence: %s\n", sentence)
+	for _, part := range parts {
+		line := strings.TrimRight(fmt.Sprintf("  %-9s %s", part.Kind, part.Text), " ")
+		if part.Note != "" {
+			line += "  (" + part.Note + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "url: %s\n", u)
+}
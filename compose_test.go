@@ -0,0 +1,140 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata golden files")
+
+// composeGoldenCases are parse --explain runs whose output is pinned in
+// testdata/compose.golden. Run `go test -run TestComposeGolden -update` after
+// an intended change to the composition rules.
+var composeGoldenCases = []struct {
+	name string
+	args []string
+}{
+	{"title only", []string{"--title", "Pay rent"}},
+	{"timed with duration", []string{"--title", "Design review", "--start", "2026-10-20T10:00", "--duration", "45m"}},
+	{"same-day range", []string{"--title", "Workshop", "--start", "2026-10-20T09:30", "--end", "2026-10-20T12:00"}},
+	{"multi-day timed range", []string{"--title", "Offsite", "--start", "2026-10-20T18:00", "--end", "2026-10-22T13:15"}},
+	{"all-day", []string{"--title", "Vacation", "--start", "2026-12-24"}},
+	{"multi-day all-day", []string{"--title", "Vacation", "--start", "2026-12-24", "--end", "2026-12-31"}},
+	{"rfc3339 start converted to timezone", []string{"--title", "Call", "--start", "2026-10-20T16:00:00Z", "--timezone", "Europe/Zagreb"}},
+	{"title that reads as a time", []string{"--title", "Meeting at 5", "--start", "2026-10-20T15:00"}},
+	{"title with a calendar token", []string{"--title", "Fix /etc/hosts"}},
+	{"title with single quotes", []string{"--title", "Review 'Q4' tomorrow"}},
+	{"plain location", []string{"--title", "Lunch", "--location", "Cafe Nero", "--start", "2026-10-20T12:00"}},
+	{"location that reads as a date is quoted", []string{"--title", "Lunch", "--location", "Monday Club"}},
+	{"invitees repeat and alert", []string{"--title", "Standup", "--start", "2026-10-19T09:00", "--duration", "15m", "--repeat", "weekdays", "--invitees", "ana@example.com, ben@example.com", "--invitees", "cy@example.com", "--alert", "10m"}},
+	{"long durations", []string{"--title", "Retreat", "--start", "2026-10-19T09:00", "--duration", "26h30m", "--alert", "24h"}},
+	{"calendar token", []string{"--title", "Dentist", "--calendar", "home"}},
+	{"calendar with spaces", []string{"--title", "Budget", "--calendar", "work (ex", "--add"}},
+}
+
+func TestComposeGolden(t *testing.T) {
+	setupTestEnv(t)
+	seedCalendarCache(t)
+
+	var got bytes.Buffer
+	for _, tc := range composeGoldenCases {
+		var out bytes.Buffer
+		args := append([]string{"--explain"}, tc.args...)
+		if err := cmdParse(context.Background(), args, strings.NewReader(""), &out, io.Discard); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		fmt.Fprintf(&got, "# %s\n$ fantastical parse %s\n%s\n", tc.name, strings.Join(quoteArgs(args), " "), out.String())
+	}
+
+	golden := filepath.Join("testdata", "compose.golden")
+	if *updateGolden {
+		if err := writeFileWithDirs(golden, got.Bytes()); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create it): %v", err)
+	}
+	if got.String() != string(want) {
+		t.Fatalf("composed sentences differ from %s (run with -update if intended):\n%s", golden, got.String())
+	}
+}
+
+func quoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if strings.ContainsAny(arg, " \"'()/") {
+			quoted[i] = "'" + arg + "'"
+		}
+	}
+	return quoted
+}
+
+func TestComposeSentenceErrors(t *testing.T) {
+	loc := time.UTC
+	cases := []eventSpec{
+		{start: "2026-10-20"},
+		{title: "x", end: "2026-10-20T10:00"},
+		{title: "x", start: "2026-10-20T10:00", end: "2026-10-20T09:00"},
+		{title: "x", start: "2026-10-20", end: "2026-10-20T09:00"},
+		{title: "x", start: "2026-10-20T10:00", end: "2026-10-20T11:00", duration: time.Hour},
+		{title: "x", start: "2026-10-20", duration: time.Hour},
+		{title: "x", start: "next week"},
+		{title: "x", duration: 90 * time.Second},
+		{title: "x", alert: -time.Minute},
+		{title: "x", repeat: "hourly"},
+		{title: "x", invitees: stringSlice{"not-an-email"}},
+		{title: `Review "Q4"`},
+		{title: "x", location: `The "Hub"`},
+	}
+	for _, spec := range cases {
+		if _, err := composeSentence(spec, "", loc); !errors.Is(err, errUsage) {
+			t.Fatalf("%+v: expected usage error, got %v", spec, err)
+		}
+	}
+}
+
+func TestCmdParseStructured(t *testing.T) {
+	setupTestEnv(t)
+
+	var out bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--json", "--dry-run", "--title", "Lunch", "--start", "2026-10-20T12:00", "--duration", "1h"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var result parseResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if result.Sentence != `"Lunch" on Oct 20 2026 at 12:00pm for 1 hour` || len(result.Parts) != 3 || result.Parts[1].Kind != "when" {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+	if !strings.Contains(result.URL, "s=%22Lunch%22%20on%20Oct%2020%202026%20at%2012%3A00pm%20for%201%20hour") {
+		t.Fatalf("sentence not passed through buildParseURL: %s", result.URL)
+	}
+
+	if err := cmdParse(context.Background(), []string{"--title", "Lunch", "Dinner at 7"}, strings.NewReader(""), io.Discard, io.Discard); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error mixing --title and a sentence, got %v", err)
+	}
+	log := stubOpener(t)
+	if err := cmdParse(context.Background(), []string{"--explain", "Dinner at 7"}, strings.NewReader(""), io.Discard, io.Discard); err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	if opened := openedURLs(t, log); len(opened) != 0 {
+		t.Fatalf("--explain must not open, got %v", opened)
+	}
+}
@@ -5,7 +5,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -17,6 +20,28 @@ type Config struct {
 	Parse       ParseConfig       `json:"parse"`
 	Show        ShowConfig        `json:"show"`
 	AppleScript AppleScriptConfig `json:"applescript"`
+	Calendars   CalendarsConfig   `json:"calendars"`
+	Serve       ServeConfig       `json:"serve"`
+	Dates       DatesConfig       `json:"dates"`
+	Backend     BackendConfig     `json:"backend"`
+	Aliases     map[string]string `json:"aliases,omitempty"`
+
+	sources []configSource
+}
+
+// configSource records a config file that contributed to the merged config.
+type configSource struct {
+	Scope  string `json:"scope" enum:"user,project"`
+	Path   string `json:"path"`
+	Format string `json:"format" enum:"json,toml,yaml"`
+}
+
+// projectConfigNames lists the accepted project config file names in lookup order.
+var projectConfigNames = []string{
+	".fantastical.json",
+	".fantastical.toml",
+	".fantastical.yaml",
+	".fantastical.yml",
 }
 
 type OutputConfig struct {
@@ -36,7 +61,59 @@ type ParseConfig struct {
 }
 
 type ShowConfig struct {
-	// Reserved for future defaults.
+	// CalendarSets lists known Fantastical calendar set names so --calendar-set
+	// can be matched case-insensitively. EventKit does not expose sets.
+	CalendarSets []string `json:"calendar_sets,omitempty"`
+}
+
+type CalendarsConfig struct {
+	// Aliases maps short names to calendar titles, IDs or calendar set names.
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// CacheTTL is how long cached calendar metadata is trusted (e.g. "30m").
+	CacheTTL string `json:"cache_ttl,omitempty"`
+}
+
+type ServeConfig struct {
+	// Addr is the loopback address fantastical serve listens on.
+	Addr string `json:"addr,omitempty"`
+	// Token, when set, must be sent as "Authorization: Bearer <token>".
+	Token string `json:"token,omitempty"`
+}
+
+type DatesConfig struct {
+	// WeekStart is "monday" or "sunday"; empty follows the locale.
+	WeekStart string `json:"week_start,omitempty"`
+}
+
+type BackendConfig struct {
+	// Name selects the calendar backend for eventkit commands and tools:
+	// eventkit (default), ics, caldav or fixture; "ics:/path" also sets
+	// the path.
+	Name    string               `json:"name,omitempty"`
+	ICS     ICSBackendConfig     `json:"ics"`
+	CalDAV  CalDAVBackendConfig  `json:"caldav"`
+	Fixture FixtureBackendConfig `json:"fixture"`
+}
+
+type ICSBackendConfig struct {
+	// Path is an .ics file, a vdir collection or a directory of either.
+	Path string `json:"path,omitempty"`
+}
+
+type FixtureBackendConfig struct {
+	// Path is a JSON file of calendars and events; without it the fixture
+	// backend serves built-in demo data.
+	Path string `json:"path,omitempty"`
+}
+
+type CalDAVBackendConfig struct {
+	// URL is the server's DAV root, a principal, a calendar home or a
+	// single calendar collection.
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	// Password is sent with HTTP basic auth; prefer FANTASTICAL_CALDAV_PASSWORD
+	// or an app password over storing an account password here.
+	Password string `json:"password,omitempty"`
 }
 
 type AppleScriptConfig struct {
@@ -55,6 +132,9 @@ func loadConfigWithPath(path string) (*Config, error) {
 			return nil, err
 		}
 		mergeConfig(cfg, readCfg)
+		if readCfg != nil {
+			cfg.sources = append(cfg.sources, configSource{Scope: "user", Path: userPath, Format: configFormat(userPath)})
+		}
 	}
 	if projectPath != "" {
 		readCfg, err := readConfigFile(projectPath)
@@ -62,10 +142,17 @@ func loadConfigWithPath(path string) (*Config, error) {
 			return nil, err
 		}
 		mergeConfig(cfg, readCfg)
+		if readCfg != nil {
+			cfg.sources = append(cfg.sources, configSource{Scope: "project", Path: projectPath, Format: configFormat(projectPath)})
+		}
 	}
 
 	applyEnvOverrides(cfg)
 
+	if err := validateAliases(cfg.Aliases); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
 	return cfg, nil
 }
 
@@ -79,7 +166,7 @@ func configPaths(override string) (string, string) {
 		userPath = strings.TrimSpace(override)
 	}
 
-	projectPath := ".fantastical.json"
+	projectPath := findProjectConfig()
 	return userPath, projectPath
 }
 
@@ -88,10 +175,86 @@ func defaultUserConfigPath() string {
 	if err != nil || strings.TrimSpace(dir) == "" {
 		return ""
 	}
-	return filepath.Join(dir, "fantastical", "config.json")
+	base := filepath.Join(dir, "fantastical")
+	for _, name := range []string{"config.json", "config.toml", "config.yaml", "config.yml"} {
+		path := filepath.Join(base, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(base, "config.json")
+}
+
+// findProjectConfig walks up from the working directory looking for a project
+// config file. The search stops at the first directory containing .git, at the
+// user's home directory, or at the filesystem root.
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+
+	for {
+		for _, name := range projectConfigNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		if home != "" && dir == filepath.Clean(home) {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configSpec describes config lookup for greta and man output.
+func configSpec() map[string]any {
+	return map[string]any{
+		"user":    "~/.config/fantastical/config.json (or config.toml/config.yaml)",
+		"project": ".fantastical.json|.fantastical.toml|.fantastical.yaml, searched upward to the repo root or home",
+		"formats": []string{"json", "toml", "yaml"},
+		"env":     "FANTASTICAL_CONFIG overrides user config path",
+		"order":   "flags > env > project config > user config",
+	}
+}
+
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return "toml"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "json"
+	}
 }
 
 func readConfigFile(path string) (*Config, error) {
+	data, err := readConfigJSON(path)
+	if data == nil || err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// readConfigJSON reads a config file of any supported format as JSON. A
+// missing or empty file yields nil.
+func readConfigJSON(path string) ([]byte, error) {
 	if strings.TrimSpace(path) == "" {
 		return nil, nil
 	}
@@ -108,12 +271,16 @@ func readConfigFile(path string) (*Config, error) {
 		return nil, nil
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	switch configFormat(path) {
+	case "toml":
+		data, err = tomlToJSON(data)
+	case "yaml":
+		data, err = yamlToJSON(data)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("parse config %s: %w", path, err)
 	}
-
-	return &cfg, nil
+	return data, nil
 }
 
 func mergeConfig(dst, src *Config) {
@@ -162,6 +329,58 @@ func mergeConfig(dst, src *Config) {
 	if src.AppleScript.Print != nil {
 		dst.AppleScript.Print = src.AppleScript.Print
 	}
+
+	if len(src.Show.CalendarSets) > 0 {
+		dst.Show.CalendarSets = src.Show.CalendarSets
+	}
+
+	if strings.TrimSpace(src.Calendars.CacheTTL) != "" {
+		dst.Calendars.CacheTTL = src.Calendars.CacheTTL
+	}
+
+	if strings.TrimSpace(src.Serve.Addr) != "" {
+		dst.Serve.Addr = src.Serve.Addr
+	}
+	if strings.TrimSpace(src.Serve.Token) != "" {
+		dst.Serve.Token = src.Serve.Token
+	}
+
+	if strings.TrimSpace(src.Dates.WeekStart) != "" {
+		dst.Dates.WeekStart = src.Dates.WeekStart
+	}
+
+	if strings.TrimSpace(src.Backend.Name) != "" {
+		dst.Backend.Name = src.Backend.Name
+	}
+	if strings.TrimSpace(src.Backend.ICS.Path) != "" {
+		dst.Backend.ICS.Path = src.Backend.ICS.Path
+	}
+	if strings.TrimSpace(src.Backend.Fixture.Path) != "" {
+		dst.Backend.Fixture.Path = src.Backend.Fixture.Path
+	}
+	if strings.TrimSpace(src.Backend.CalDAV.URL) != "" {
+		dst.Backend.CalDAV.URL = src.Backend.CalDAV.URL
+	}
+	if strings.TrimSpace(src.Backend.CalDAV.Username) != "" {
+		dst.Backend.CalDAV.Username = src.Backend.CalDAV.Username
+	}
+	if src.Backend.CalDAV.Password != "" {
+		dst.Backend.CalDAV.Password = src.Backend.CalDAV.Password
+	}
+
+	for alias, target := range src.Calendars.Aliases {
+		if dst.Calendars.Aliases == nil {
+			dst.Calendars.Aliases = map[string]string{}
+		}
+		dst.Calendars.Aliases[alias] = target
+	}
+
+	for name, expansion := range src.Aliases {
+		if dst.Aliases == nil {
+			dst.Aliases = map[string]string{}
+		}
+		dst.Aliases[name] = expansion
+	}
 }
 
 func applyEnvOverrides(cfg *Config) {
@@ -210,6 +429,27 @@ func applyEnvOverrides(cfg *Config) {
 	if v, ok := envBool("FANTASTICAL_APPLESCRIPT_PRINT"); ok {
 		cfg.AppleScript.Print = boolPtr(v)
 	}
+
+	if v, ok := envString("FANTASTICAL_SERVE_TOKEN"); ok {
+		cfg.Serve.Token = v
+	}
+
+	if v, ok := envString("FANTASTICAL_WEEK_START"); ok {
+		cfg.Dates.WeekStart = v
+	}
+
+	if v, ok := envString("FANTASTICAL_BACKEND"); ok {
+		cfg.Backend.Name = v
+	}
+	if v, ok := envString("FANTASTICAL_CALDAV_URL"); ok {
+		cfg.Backend.CalDAV.URL = v
+	}
+	if v, ok := envString("FANTASTICAL_CALDAV_USERNAME"); ok {
+		cfg.Backend.CalDAV.Username = v
+	}
+	if v := os.Getenv("FANTASTICAL_CALDAV_PASSWORD"); v != "" {
+		cfg.Backend.CalDAV.Password = v
+	}
 }
 
 func envString(key string) (string, bool) {
@@ -235,3 +475,137 @@ func envBool(key string) (bool, bool) {
 func boolPtr(v bool) *bool {
 	return &v
 }
+
+// configSources returns the config files that were loaded, in merge order.
+func (c *Config) configSources() []configSource {
+	if c == nil {
+		return nil
+	}
+	return c.sources
+}
+
+func logConfigSources(w io.Writer, verbose bool, cfg *Config) {
+	sources := cfg.configSources()
+	if len(sources) == 0 {
+		logVerbose(w, verbose, "config: none")
+		return
+	}
+	for _, src := range sources {
+		logVerbose(w, verbose, "config (%s): %s", src.Scope, src.Path)
+	}
+}
+
+// configShowResult is the output of config show --json.
+type configShowResult struct {
+	UserPath    string         `json:"user_path"`
+	ProjectPath string         `json:"project_path"`
+	Sources     []configSource `json:"sources"`
+	Config      Config         `json:"config"`
+}
+
+type configShowOptions struct {
+	json   bool
+	config string
+}
+
+func configUsage(w io.Writer) {
+	fmt.Fprint(w, "USAGE:\n  fantastical config show [--json] [--config <path>]\n")
+	printFlagHelp(w, "config show")
+	fmt.Fprintln(w, "\nEXAMPLE:\n  fantastical config show --json")
+}
+
+func cmdConfig(args []string, out, errOut io.Writer) error {
+	if len(args) < 1 {
+		configUsage(errOut)
+		return fmt.Errorf("%w: missing config subcommand", errUsage)
+	}
+
+	sub := strings.ToLower(strings.TrimSpace(args[0]))
+	switch sub {
+	case "show":
+		return cmdConfigShow(args[1:], out, errOut)
+	case "-h", "--help", "help":
+		configUsage(out)
+		return nil
+	default:
+		configUsage(errOut)
+		return fmt.Errorf("%w: unknown config subcommand %q", errUsage, sub)
+	}
+}
+
+func cmdConfigShow(args []string, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	opts := configShowOptions{}
+	bindFlags(fs, "config show", map[string]any{
+		"json":   &opts.json,
+		"config": &opts.config,
+	})
+
+	fs.Usage = func() {
+		configUsage(errOut)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return err
+	}
+	userPath, projectPath := configPaths(opts.config)
+
+	sources := cfg.configSources()
+	if sources == nil {
+		sources = []configSource{}
+	}
+
+	if opts.json {
+		payload := configShowResult{
+			UserPath:    userPath,
+			ProjectPath: projectPath,
+			Sources:     sources,
+			Config:      cfg.redacted(),
+		}
+		return writeJSON(out, payload)
+	}
+
+	if len(sources) == 0 {
+		fmt.Fprintln(out, "sources: none")
+	}
+	for _, src := range sources {
+		fmt.Fprintf(out, "source (%s, %s): %s\n", src.Scope, src.Format, src.Path)
+	}
+	data, err := json.MarshalIndent(cfg.redacted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(data))
+	return nil
+}
+
+// redactedSecret replaces secrets in printed config.
+const redactedSecret = "(redacted)"
+
+// redacted returns a copy of c safe to print: secrets that are set, from a
+// file or the environment, are replaced by redactedSecret.
+func (c Config) redacted() Config {
+	if c.Serve.Token != "" {
+		c.Serve.Token = redactedSecret
+	}
+	if c.Backend.CalDAV.Password != "" {
+		c.Backend.CalDAV.Password = redactedSecret
+	}
+	return c
+}
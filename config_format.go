@@ -0,0 +1,638 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The config file only uses tables of scalars, string lists and string maps, so
+// TOML and YAML support is limited to that subset. Both decoders produce a
+// generic map that is re-encoded as JSON and decoded into Config, which keeps a
+// single set of field names and validation rules for every format.
+
+func tomlToJSON(data []byte) ([]byte, error) {
+	root := map[string]any{}
+	current := root
+
+	for idx, raw := range strings.Split(string(data), "\n") {
+		lineNo := idx + 1
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: arrays of tables are not supported", lineNo)
+			}
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", lineNo)
+			}
+			keys, err := splitTOMLKey(strings.TrimSpace(line[1 : len(line)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			table, err := tomlTable(root, keys)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			current = table
+			continue
+		}
+
+		eq := indexOutsideQuotes(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		keys, err := splitTOMLKey(strings.TrimSpace(line[:eq]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		table, err := tomlTable(current, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		last := keys[len(keys)-1]
+		if _, exists := table[last]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, last)
+		}
+		table[last] = value
+	}
+
+	return json.Marshal(root)
+}
+
+func tomlTable(root map[string]any, keys []string) (map[string]any, error) {
+	table := root
+	for _, key := range keys {
+		next, ok := table[key]
+		if !ok {
+			created := map[string]any{}
+			table[key] = created
+			table = created
+			continue
+		}
+		nested, ok := next.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("key %q is not a table", key)
+		}
+		table = nested
+	}
+	return table, nil
+}
+
+func splitTOMLKey(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	var keys []string
+	for _, part := range splitOutsideQuotes(raw, '.') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid key %q", raw)
+		}
+		if part[0] == '"' || part[0] == '\'' {
+			unquoted, err := parseTOMLString(part)
+			if err != nil {
+				return nil, err
+			}
+			part = unquoted
+		}
+		keys = append(keys, part)
+	}
+	return keys, nil
+}
+
+func parseTOMLValue(raw string) (any, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+	switch raw[0] {
+	case '"', '\'':
+		return parseTOMLString(raw)
+	case '[':
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("unterminated array (arrays must be on one line)")
+		}
+		items := []any{}
+		for _, part := range splitOutsideQuotes(raw[1:len(raw)-1], ',') {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			item, err := parseTOMLValue(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case '{':
+		if !strings.HasSuffix(raw, "}") {
+			return nil, fmt.Errorf("unterminated inline table")
+		}
+		table := map[string]any{}
+		for _, part := range splitOutsideQuotes(raw[1:len(raw)-1], ',') {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			eq := indexOutsideQuotes(part, '=')
+			if eq < 0 {
+				return nil, fmt.Errorf("expected key = value in inline table")
+			}
+			keys, err := splitTOMLKey(strings.TrimSpace(part[:eq]))
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseTOMLValue(strings.TrimSpace(part[eq+1:]))
+			if err != nil {
+				return nil, err
+			}
+			nested, err := tomlTable(table, keys[:len(keys)-1])
+			if err != nil {
+				return nil, err
+			}
+			nested[keys[len(keys)-1]] = value
+		}
+		return table, nil
+	}
+
+	switch raw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", raw)
+}
+
+func yamlToJSON(data []byte) ([]byte, error) {
+	var lines []yamlLine
+	for idx, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.Contains(text[:len(text)-len(strings.TrimLeft(text, " \t"))], "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", idx+1)
+		}
+		lines = append(lines, yamlLine{
+			no:     idx + 1,
+			indent: len(text) - len(strings.TrimLeft(text, " ")),
+			text:   trimmed,
+		})
+	}
+
+	if len(lines) == 0 {
+		return []byte("{}"), nil
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].no)
+	}
+	return json.Marshal(value)
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-" {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (any, error) {
+	out := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.no)
+		}
+		colon := yamlKeyColon(line.text)
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected key: value", line.no)
+		}
+		key := strings.TrimSpace(line.text[:colon])
+		if key != "" && (key[0] == '"' || key[0] == '\'') {
+			unquoted, err := parseYAMLString(key)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.no, err)
+			}
+			key = unquoted
+		}
+		if _, exists := out[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.no, key)
+		}
+		rest := strings.TrimSpace(line.text[colon+1:])
+		p.pos++
+
+		if rest != "" {
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.no, err)
+			}
+			out[key] = value
+			continue
+		}
+
+		if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+			value, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+			continue
+		}
+		// A sequence may sit at the same indentation as its parent key.
+		if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "- ") {
+			value, err := p.parseSequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+			continue
+		}
+		out[key] = nil
+	}
+	return out, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (any, error) {
+	out := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !(strings.HasPrefix(line.text, "- ") || line.text == "-") {
+			if line.indent > indent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", line.no)
+			}
+			break
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		p.pos++
+		if rest == "" {
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.parseBlock(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, value)
+				continue
+			}
+			out = append(out, nil)
+			continue
+		}
+		if yamlKeyColon(rest) >= 0 {
+			return nil, fmt.Errorf("line %d: mappings inside sequences are not supported", line.no)
+		}
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line.no, err)
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+// yamlKeyColon returns the index of the colon separating a mapping key from its
+// value, or -1 when the text is not a mapping entry.
+func yamlKeyColon(text string) int {
+	idx := indexOutsideQuotes(text, ':')
+	for idx >= 0 {
+		if idx == len(text)-1 || text[idx+1] == ' ' {
+			return idx
+		}
+		next := indexOutsideQuotes(text[idx+1:], ':')
+		if next < 0 {
+			return -1
+		}
+		idx += next + 1
+	}
+	return -1
+}
+
+func parseYAMLScalar(raw string) (any, error) {
+	switch raw[0] {
+	case '"', '\'':
+		return parseYAMLString(raw)
+	case '[':
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		items := []any{}
+		for _, part := range splitOutsideQuotes(raw[1:len(raw)-1], ',') {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			item, err := parseYAMLScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case '{':
+		if !strings.HasSuffix(raw, "}") {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		table := map[string]any{}
+		for _, part := range splitOutsideQuotes(raw[1:len(raw)-1], ',') {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			colon := yamlKeyColon(part)
+			if colon < 0 {
+				return nil, fmt.Errorf("expected key: value in flow mapping")
+			}
+			key := strings.TrimSpace(part[:colon])
+			if key != "" && (key[0] == '"' || key[0] == '\'') {
+				unquoted, err := parseYAMLString(key)
+				if err != nil {
+					return nil, err
+				}
+				key = unquoted
+			}
+			value, err := parseYAMLScalar(strings.TrimSpace(part[colon+1:]))
+			if err != nil {
+				return nil, err
+			}
+			table[key] = value
+		}
+		return table, nil
+	case '|', '>':
+		return nil, fmt.Errorf("block scalars are not supported")
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+
+	switch raw {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	return raw, nil
+}
+
+// parseTOMLString decodes a TOML literal ('...') or basic ("...") string.
+// Literal strings have no escapes at all; basic strings take TOML's escape
+// set, which differs from Go's (no \x, \a or octal; \UXXXXXXXX is a code
+// point).
+func parseTOMLString(raw string) (string, error) {
+	if len(raw) < 2 || raw[len(raw)-1] != raw[0] {
+		return "", fmt.Errorf("unterminated string %s", raw)
+	}
+	body := raw[1 : len(raw)-1]
+	if raw[0] == '\'' {
+		if strings.ContainsRune(body, '\'') {
+			return "", fmt.Errorf("invalid string %s (literal strings cannot contain ')", raw)
+		}
+		return body, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '"' {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(body) {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		switch body[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(body[i])
+		case 'u', 'U':
+			n := 4
+			if body[i] == 'U' {
+				n = 8
+			}
+			if i+n >= len(body) {
+				return "", fmt.Errorf("invalid escape in string %s", raw)
+			}
+			code, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid escape in string %s", raw)
+			}
+			b.WriteRune(rune(code))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape \\%c in string %s", body[i], raw)
+		}
+	}
+	return b.String(), nil
+}
+
+// parseYAMLString decodes a YAML single-quoted string, where a quote is
+// escaped by doubling it, or a double-quoted one. Double-quoted strings take
+// YAML's escape set, which differs from Go's (\e, \/, \N, \_, \L, \P and an
+// escaped space or tab; no octal).
+func parseYAMLString(raw string) (string, error) {
+	if len(raw) < 2 || raw[len(raw)-1] != raw[0] {
+		return "", fmt.Errorf("unterminated string %s", raw)
+	}
+	body := raw[1 : len(raw)-1]
+	if raw[0] == '\'' {
+		return strings.ReplaceAll(body, "''", "'"), nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '"' {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(body) {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		switch body[i] {
+		case '0':
+			b.WriteByte(0)
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 't', '\t':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'e':
+			b.WriteByte(0x1b)
+		case 'N':
+			b.WriteString("\u0085")
+		case '_':
+			b.WriteString("\u00a0")
+		case 'L':
+			b.WriteString("\u2028")
+		case 'P':
+			b.WriteString("\u2029")
+		case ' ', '"', '/', '\\':
+			b.WriteByte(body[i])
+		case 'x', 'u', 'U':
+			n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[body[i]]
+			if i+n >= len(body) {
+				return "", fmt.Errorf("invalid escape in string %s", raw)
+			}
+			code, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid escape in string %s", raw)
+			}
+			b.WriteRune(rune(code))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape \\%c in string %s", body[i], raw)
+		}
+	}
+	return b.String(), nil
+}
+
+// stripComment removes a trailing # comment that is not inside a quoted string.
+func stripComment(line string) string {
+	if idx := indexOutsideQuotes(line, '#'); idx >= 0 {
+		return line[:idx]
+	}
+	return line
+}
+
+// stripYAMLComment removes a # comment that starts a line or follows whitespace.
+// Quotes only open a string at the start of a scalar, so apostrophes inside
+// plain values (e.g. "Don't") do not hide a trailing comment.
+func stripYAMLComment(line string) string {
+	var quote byte
+	lastToken := byte(' ')
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+			continue
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && strings.IndexByte(" :-[{,", lastToken) >= 0:
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+		if c != ' ' && c != '\t' {
+			lastToken = c
+		}
+	}
+	return line
+}
+
+func indexOutsideQuotes(s string, target byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == target:
+			return i
+		}
+	}
+	return -1
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	start := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
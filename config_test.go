@@ -0,0 +1,262 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func chdirTest(t *testing.T, dir string) {
+	t.Helper()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(old)
+	})
+}
+
+// setupProjectTree creates root/.git and root/sub/deeper, changes into the
+// deepest directory and returns the project root.
+func setupProjectTree(t *testing.T) string {
+	t.Helper()
+	setupTestEnv(t)
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	deeper := filepath.Join(root, "sub", "deeper")
+	if err := os.MkdirAll(deeper, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	chdirTest(t, deeper)
+	return root
+}
+
+func TestProjectConfigWalksUp(t *testing.T) {
+	root := setupProjectTree(t)
+	config := `{"output":{"open":false,"print":true},"parse":{"calendar":"Team"}}`
+	if err := os.WriteFile(filepath.Join(root, ".fantastical.json"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--verbose", "Standup"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Team") {
+		t.Fatalf("expected project calendar in output: %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "config (project): ") || !strings.Contains(errOut.String(), ".fantastical.json") {
+		t.Fatalf("expected config source in verbose output: %q", errOut.String())
+	}
+}
+
+func TestProjectConfigStopsAtRepoRoot(t *testing.T) {
+	root := setupProjectTree(t)
+	parentConfig := filepath.Join(filepath.Dir(root), ".fantastical.json")
+	if _, err := os.Stat(parentConfig); err == nil {
+		t.Skip("unexpected config above temp dir")
+	}
+
+	if got := findProjectConfig(); got != "" {
+		t.Fatalf("expected no project config, got %q", got)
+	}
+}
+
+func TestProjectConfigTOML(t *testing.T) {
+	root := setupProjectTree(t)
+	config := `# project defaults
+[output]
+open = false   # never open in CI
+print = true
+
+[parse]
+calendar = "Work (Exchange)"
+note = "Don't forget"
+add = true
+`
+	if err := os.WriteFile(filepath.Join(root, "sub", ".fantastical.toml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"Standup"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Work%20%28Exchange%29") {
+		t.Fatalf("expected calendar in output: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "add=1") {
+		t.Fatalf("expected add=1 in output: %q", out.String())
+	}
+}
+
+func TestProjectConfigYAML(t *testing.T) {
+	root := setupProjectTree(t)
+	config := `output:
+  open: false
+  print: true # comment
+parse:
+  calendar: Home
+  note: "Bring #2 pencils"
+`
+	if err := os.WriteFile(filepath.Join(root, ".fantastical.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"Standup"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "calendarName=Home") {
+		t.Fatalf("expected calendar in output: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "n=Bring%20%232%20pencils") {
+		t.Fatalf("expected note in output: %q", out.String())
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	input := `# comment
+a:
+  b: 1
+  c: [x, "y, z"]
+  d:
+    - one
+    - 'two'
+e: Don't panic # trailing
+f: ~
+`
+	data, err := yamlToJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"a":{"b":1,"c":["x","y, z"],"d":["one","two"]},"e":"Don't panic","f":null}`
+	if string(data) != want {
+		t.Fatalf("unexpected json: %s", data)
+	}
+}
+
+func TestTOMLStrings(t *testing.T) {
+	input := `a = 'C:\Users\ana'
+b = "tab\there \"quoted\" \u00e9 \U0001F600"
+"c.d" = 'x'
+`
+	data, err := tomlToJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": `C:\Users\ana`, "b": "tab\there \"quoted\" \u00e9 \U0001F600", "c.d": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// YAML keeps its own rule of doubling a quote in single-quoted strings.
+	data, err = yamlToJSON([]byte("a: 'it''s'\n"))
+	if err != nil || string(data) != `{"a":"it's"}` {
+		t.Fatalf("unexpected yaml result %s (%v)", data, err)
+	}
+
+	// Double-quoted strings take YAML's escapes, not Go's.
+	data, err = yamlToJSON([]byte(`a: "a\/b\ c\e\N\x41\_"` + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = nil
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["a"] != "a/b c\x1b\u0085A\u00a0" {
+		t.Fatalf("unexpected yaml escapes %q", got["a"])
+	}
+	for _, bad := range []string{`a: "\q"`, `a: "\101"`, `a: "\x4"`, `a: "say "hi""`} {
+		if _, err := yamlToJSON([]byte(bad + "\n")); err == nil {
+			t.Fatalf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestTOMLToJSONErrors(t *testing.T) {
+	cases := []string{
+		"[output\nopen = true",
+		"open true",
+		"a = 1\na = 2",
+		"a = \"unterminated",
+		"a = 'it''s'",
+		`a = "\x41"`,
+		`a = "\u00"`,
+		`a = "\UD800DC00"`,
+	}
+	for _, input := range cases {
+		if _, err := tomlToJSON([]byte(input)); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestCmdConfigShowJSON(t *testing.T) {
+	root := setupProjectTree(t)
+	if err := os.WriteFile(filepath.Join(root, ".fantastical.yml"), []byte("parse:\n  calendar: Team\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdConfig([]string{"show", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var payload struct {
+		Sources []configSource `json:"sources"`
+		Config  Config         `json:"config"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v (%s)", err, out.String())
+	}
+	if len(payload.Sources) != 1 || payload.Sources[0].Scope != "project" || payload.Sources[0].Format != "yaml" {
+		t.Fatalf("unexpected sources: %+v", payload.Sources)
+	}
+	if payload.Config.Parse.Calendar != "Team" {
+		t.Fatalf("unexpected config: %+v", payload.Config)
+	}
+}
+
+func TestCmdConfigShowRedactsSecrets(t *testing.T) {
+	writeAliasConfig(t, `{"serve": {"addr": "127.0.0.1:9000", "token": "from-file"}, "backend": {"caldav": {"password": "from-file"}}}`)
+	for _, env := range []string{"", "from-env"} {
+		t.Setenv("FANTASTICAL_SERVE_TOKEN", env)
+		t.Setenv("FANTASTICAL_CALDAV_PASSWORD", env)
+		for _, args := range [][]string{{"show"}, {"show", "--json"}} {
+			var out, errOut bytes.Buffer
+			if err := cmdConfig(args, &out, &errOut); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(out.String(), "from-") || strings.Count(out.String(), redactedSecret) != 2 || !strings.Contains(out.String(), "127.0.0.1:9000") {
+				t.Fatalf("%v: expected the token and password redacted:\n%s", args, out.String())
+			}
+		}
+	}
+}
+
+func TestCmdConfigMissingSubcommand(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if err := cmdConfig([]string{}, &out, &errOut); err == nil {
+		t.Fatalf("expected error")
+	}
+}
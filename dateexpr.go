@@ -0,0 +1,328 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateExprHint lists the accepted forms for error messages.
+const dateExprHint = "want YYYY-MM-DD[THH:MM], RFC 3339, 2026-W42, today, tomorrow, yesterday, now, +3d, -1w, +2h, monday, next monday, start/end of week|month|year, optionally followed by a time like 9am or 14:30+02:00"
+
+// dateContext resolves date expressions against a clock, time zone and
+// week start.
+type dateContext struct {
+	now       time.Time
+	loc       *time.Location
+	weekStart time.Weekday
+}
+
+func newDateContext(now time.Time, loc *time.Location) dateContext {
+	if loc == nil {
+		loc = time.Local
+	}
+	return dateContext{now: now.In(loc), loc: loc, weekStart: time.Monday}
+}
+
+// parseDateExpr is shorthand for newDateContext(now, loc).parse(expr).
+func parseDateExpr(expr string, now time.Time, loc *time.Location) (time.Time, bool, error) {
+	return newDateContext(now, loc).parse(expr)
+}
+
+var (
+	isoWeekPattern   = regexp.MustCompile(`^(\d{4})-w(\d{1,2})(?:-([1-7]))?$`)
+	offsetPattern    = regexp.MustCompile(`^([+-])(\d+)(min|mo|h|d|w|y)$`)
+	dateTimePattern  = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})t(.+)$`)
+	clockExprPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?(am|pm)?(z|[+-]\d{2}(?::?\d{2})?)?$`)
+	zoneOffsetRegexp = regexp.MustCompile(`^(?:z|([+-])(\d{2})(?::?(\d{2}))?)$`)
+)
+
+// parse resolves expr to an instant in the context's zone. dateOnly reports
+// that expr named a whole day rather than a time of day; the instant is then
+// that day's midnight.
+func (c dateContext) parse(expr string) (time.Time, bool, error) {
+	raw := strings.TrimSpace(expr)
+	if raw == "" {
+		return time.Time{}, false, fmt.Errorf("%w: empty date (%s)", errUsage, dateExprHint)
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(c.loc), false, nil
+	}
+
+	s := strings.ToLower(raw)
+	if m := dateTimePattern.FindStringSubmatch(s); m != nil {
+		s = m[1] + " " + m[2]
+	}
+	tokens := strings.Fields(s)
+
+	day, timed, n, ok := c.parseBase(tokens)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: invalid date %q (%s)", errUsage, raw, dateExprHint)
+	}
+	rest := tokens[n:]
+	if len(rest) > 0 && rest[0] == "at" {
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		if timed.IsZero() {
+			return day, true, nil
+		}
+		return timed, false, nil
+	}
+	if !timed.IsZero() {
+		return time.Time{}, false, fmt.Errorf("%w: invalid date %q: %s already names a time (%s)", errUsage, raw, strings.Join(tokens[:n], " "), dateExprHint)
+	}
+	t, err := c.applyClock(day, rest)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: invalid date %q: %v", errUsage, raw, err)
+	}
+	return t, false, nil
+}
+
+func (c dateContext) today() time.Time {
+	return time.Date(c.now.Year(), c.now.Month(), c.now.Day(), 0, 0, 0, 0, c.loc)
+}
+
+func (c dateContext) startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) - int(c.weekStart) + 7) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, c.loc)
+}
+
+// parseBase reads the leading date phrase. It returns the day (midnight),
+// or a non-zero timed instant for "now" and hour/minute offsets, plus the
+// number of tokens used.
+func (c dateContext) parseBase(tokens []string) (day, timed time.Time, n int, ok bool) {
+	if len(tokens) == 0 {
+		return
+	}
+	today := c.today()
+	w := tokens[0]
+	second := ""
+	if len(tokens) > 1 {
+		second = tokens[1]
+	}
+
+	switch w {
+	case "now":
+		return today, c.now, 1, true
+	case "today":
+		return today, time.Time{}, 1, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), time.Time{}, 1, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), time.Time{}, 1, true
+	case "next", "last", "this":
+		if d, found := weekdayNames[second]; found {
+			return c.relativeWeekday(d, w), time.Time{}, 2, true
+		}
+		if start, found := c.periodStart(second, w); found {
+			return start, time.Time{}, 2, true
+		}
+		return
+	case "start", "end":
+		// "start of month", "end of next week"
+		if second != "of" || len(tokens) < 3 {
+			return
+		}
+		which, unit, used := "this", tokens[2], 3
+		if (unit == "next" || unit == "last" || unit == "this") && len(tokens) > 3 {
+			which, unit, used = unit, tokens[3], 4
+		}
+		start, found := c.periodStart(unit, which)
+		if !found {
+			return
+		}
+		if w == "end" {
+			start = c.periodEnd(start, unit)
+		}
+		return start, time.Time{}, used, true
+	}
+
+	if d, found := weekdayNames[w]; found {
+		return c.relativeWeekday(d, "this"), time.Time{}, 1, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", w, c.loc); err == nil {
+		return t, time.Time{}, 1, true
+	}
+	if m := isoWeekPattern.FindStringSubmatch(w); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+		weekday := 1
+		if m[3] != "" {
+			weekday, _ = strconv.Atoi(m[3])
+		}
+		t, found := isoWeekDate(year, week, weekday, c.loc)
+		return t, time.Time{}, 1, found
+	}
+	if m := offsetPattern.FindStringSubmatch(w); m != nil {
+		amount, err := strconv.Atoi(m[2])
+		if err != nil {
+			return
+		}
+		if m[1] == "-" {
+			amount = -amount
+		}
+		switch m[3] {
+		case "min":
+			return today, c.now.Add(time.Duration(amount) * time.Minute), 1, true
+		case "h":
+			return today, c.now.Add(time.Duration(amount) * time.Hour), 1, true
+		case "d":
+			return today.AddDate(0, 0, amount), time.Time{}, 1, true
+		case "w":
+			return today.AddDate(0, 0, 7*amount), time.Time{}, 1, true
+		case "mo":
+			return today.AddDate(0, amount, 0), time.Time{}, 1, true
+		case "y":
+			return today.AddDate(amount, 0, 0), time.Time{}, 1, true
+		}
+	}
+	return
+}
+
+// relativeWeekday resolves "monday" and "this monday" to the next Monday
+// (today included), "next monday" to the first Monday after today and
+// "last monday" to the most recent Monday before today.
+func (c dateContext) relativeWeekday(d time.Weekday, which string) time.Time {
+	today := c.today()
+	switch which {
+	case "last":
+		offset := (int(today.Weekday()) - int(d) + 7) % 7
+		if offset == 0 {
+			offset = 7
+		}
+		return today.AddDate(0, 0, -offset)
+	default:
+		offset := (int(d) - int(today.Weekday()) + 7) % 7
+		if offset == 0 && which == "next" {
+			offset = 7
+		}
+		return today.AddDate(0, 0, offset)
+	}
+}
+
+// periodStart returns the first day of this, next or last day/week/month/year.
+func (c dateContext) periodStart(unit, which string) (time.Time, bool) {
+	shift := map[string]int{"this": 0, "next": 1, "last": -1}[which]
+	today := c.today()
+	switch unit {
+	case "day":
+		return today.AddDate(0, 0, shift), true
+	case "week":
+		return c.startOfWeek(today).AddDate(0, 0, 7*shift), true
+	case "month":
+		return time.Date(today.Year(), today.Month()+time.Month(shift), 1, 0, 0, 0, 0, c.loc), true
+	case "year":
+		return time.Date(today.Year()+shift, time.January, 1, 0, 0, 0, 0, c.loc), true
+	}
+	return time.Time{}, false
+}
+
+// periodEnd returns the last day of the period that starts at start.
+func (c dateContext) periodEnd(start time.Time, unit string) time.Time {
+	switch unit {
+	case "week":
+		return start.AddDate(0, 0, 6)
+	case "month":
+		return start.AddDate(0, 1, -1)
+	case "year":
+		return start.AddDate(1, 0, -1)
+	}
+	return start
+}
+
+// isoWeekDate returns the given ISO 8601 weekday (1 = Monday) of week in year.
+func isoWeekDate(year, week, weekday int, loc *time.Location) (time.Time, bool) {
+	if week < 1 || week > 53 {
+		return time.Time{}, false
+	}
+	// January 4th is always in week 1.
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	t := monday.AddDate(0, 0, 7*(week-1)+weekday-1)
+	if y, w := t.ISOWeek(); y != year || w != week {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// applyClock sets the time of day on day from tokens like "9am", "14:30",
+// "noon" or "10:00+02:00", optionally followed by a zone offset or
+// abbreviation ("-05:00", "PST"). The result is expressed in c.loc.
+func (c dateContext) applyClock(day time.Time, tokens []string) (time.Time, error) {
+	if len(tokens) > 1 && (tokens[1] == "am" || tokens[1] == "pm") {
+		tokens = append([]string{tokens[0] + tokens[1]}, tokens[2:]...)
+	}
+	if len(tokens) == 0 || len(tokens) > 2 {
+		return time.Time{}, fmt.Errorf("want a time like 9am, 14:30 or 14:30+02:00")
+	}
+	clock := tokens[0]
+	switch clock {
+	case "noon":
+		clock = "12:00"
+	case "midnight":
+		clock = "00:00"
+	}
+	m := clockExprPattern.FindStringSubmatch(clock)
+	if m == nil || (m[2] == "" && m[4] == "") {
+		return time.Time{}, fmt.Errorf("invalid time %q", tokens[0])
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+	if m[4] != "" {
+		if hour < 1 || hour > 12 {
+			return time.Time{}, fmt.Errorf("invalid time %q", tokens[0])
+		}
+		hour %= 12
+		if m[4] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("invalid time %q", tokens[0])
+	}
+
+	zone := c.loc
+	zoneText := m[5]
+	if len(tokens) == 2 {
+		if zoneText != "" {
+			return time.Time{}, fmt.Errorf("unexpected %q after %q", tokens[1], tokens[0])
+		}
+		zoneText = tokens[1]
+	}
+	if zoneText != "" {
+		z, err := parseZoneOffset(zoneText)
+		if err != nil {
+			return time.Time{}, err
+		}
+		zone = z
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, zone).In(c.loc), nil
+}
+
+// parseZoneOffset accepts Z, +02, +0200, +02:00 and the abbreviations the
+// sentence predictor knows (PST, CET, ...).
+func parseZoneOffset(s string) (*time.Location, error) {
+	if m := zoneOffsetRegexp.FindStringSubmatch(s); m != nil {
+		if s == "z" {
+			return time.UTC, nil
+		}
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone("", offset), nil
+	}
+	if loc := zoneForAbbreviation(s); loc != nil {
+		return loc, nil
+	}
+	return nil, fmt.Errorf("invalid time zone %q", s)
+}
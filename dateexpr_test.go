@@ -0,0 +1,79 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDateExpr(t *testing.T) {
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// Sunday afternoon.
+	now := time.Date(2026, time.October, 18, 14, 0, 0, 0, zagreb)
+
+	tests := []struct {
+		expr     string
+		want     string
+		dateOnly bool
+	}{
+		{"2026-10-20", "2026-10-20T00:00:00+02:00", true},
+		{"2026-10-20T09:30", "2026-10-20T09:30:00+02:00", false},
+		{"2026-10-20T09:30:15", "2026-10-20T09:30:15+02:00", false},
+		{"2026-10-20T16:00:00Z", "2026-10-20T18:00:00+02:00", false},
+		{"2026-10-20 10:00-05:00", "2026-10-20T17:00:00+02:00", false},
+		{"today", "2026-10-18T00:00:00+02:00", true},
+		{"Tomorrow", "2026-10-19T00:00:00+02:00", true},
+		{"yesterday 18:00", "2026-10-17T18:00:00+02:00", false},
+		{"now", "2026-10-18T14:00:00+02:00", false},
+		{"+3d", "2026-10-21T00:00:00+02:00", true},
+		{"-1w", "2026-10-11T00:00:00+02:00", true},
+		{"+2h", "2026-10-18T16:00:00+02:00", false},
+		{"-30min", "2026-10-18T13:30:00+02:00", false},
+		{"+1mo", "2026-11-18T00:00:00+01:00", true},
+		{"+1y", "2027-10-18T00:00:00+02:00", true},
+		{"sunday", "2026-10-18T00:00:00+02:00", true},
+		{"monday", "2026-10-19T00:00:00+02:00", true},
+		{"next monday", "2026-10-19T00:00:00+02:00", true},
+		{"next sun", "2026-10-25T00:00:00+02:00", true},
+		{"last friday 9am", "2026-10-16T09:00:00+02:00", false},
+		{"fri at 3 pm", "2026-10-23T15:00:00+02:00", false},
+		{"next week", "2026-10-19T00:00:00+02:00", true},
+		{"last month", "2026-09-01T00:00:00+02:00", true},
+		{"start of week", "2026-10-12T00:00:00+02:00", true},
+		{"end of week", "2026-10-18T00:00:00+02:00", true},
+		{"end of month", "2026-10-31T00:00:00+01:00", true},
+		{"end of next month", "2026-11-30T00:00:00+01:00", true},
+		{"start of year", "2026-01-01T00:00:00+01:00", true},
+		{"2026-W42", "2026-10-12T00:00:00+02:00", true},
+		{"2026-w42-7", "2026-10-18T00:00:00+02:00", true},
+		{"2027-W01", "2027-01-04T00:00:00+01:00", true},
+		{"tomorrow noon PST", "2026-10-19T22:00:00+02:00", false},
+		{"tomorrow 9:15+0100", "2026-10-19T10:15:00+02:00", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			got, dateOnly, err := parseDateExpr(tc.expr, now, zagreb)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Format(time.RFC3339) != tc.want || dateOnly != tc.dateOnly {
+				t.Fatalf("got %s (dateOnly=%v), want %s (dateOnly=%v)", got.Format(time.RFC3339), dateOnly, tc.want, tc.dateOnly)
+			}
+		})
+	}
+}
+
+func TestParseDateExprInvalid(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 14, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"", "soon", "+3m", "2026-W54", "2027-W53", "next fortnight", "tomorrow 25:00", "tomorrow 13pm", "+2h 9am", "monday 9am XYZ", "2026-13-01"} {
+		if _, _, err := parseDateExpr(expr, now, time.UTC); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected usage error, got %v", expr, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// nowFunc is the clock for CLI date resolution; tests replace it.
+var nowFunc = time.Now
+
+// dateRangeFlags are the eventkit events options that choose the range.
+type dateRangeFlags struct {
+	from     string
+	to       string
+	days     int
+	daysSet  bool
+	today    bool
+	tomorrow bool
+	thisWeek bool
+	nextWeek bool
+}
+
+// eventRange is the absolute window passed to the EventKit helper. Both
+// bounds are inclusive, as in EventKit's predicate.
+type eventRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// resolveDateRange applies the shortcut, --days and --from/--to rules in c.
+// Without any of them the range is today.
+func resolveDateRange(f dateRangeFlags, c dateContext) (eventRange, error) {
+	presets := 0
+	for _, set := range []bool{f.today, f.tomorrow, f.thisWeek, f.nextWeek} {
+		if set {
+			presets++
+		}
+	}
+	hasBounds := strings.TrimSpace(f.from) != "" || strings.TrimSpace(f.to) != ""
+	switch {
+	case presets > 1:
+		return eventRange{}, fmt.Errorf("%w: only one of --today/--tomorrow/--this-week/--next-week can be used", errUsage)
+	case presets > 0 && (hasBounds || f.daysSet):
+		return eventRange{}, fmt.Errorf("%w: --from/--to/--days cannot be combined with date shortcuts", errUsage)
+	case f.daysSet && hasBounds:
+		return eventRange{}, fmt.Errorf("%w: --days cannot be combined with --from/--to", errUsage)
+	case f.daysSet && f.days <= 0:
+		return eventRange{}, fmt.Errorf("%w: --days must be greater than 0", errUsage)
+	}
+
+	today := c.today()
+	switch {
+	case f.daysSet:
+		return eventRange{From: c.now, To: c.now.AddDate(0, 0, f.days)}, nil
+	case f.tomorrow:
+		day := today.AddDate(0, 0, 1)
+		return eventRange{From: day, To: endOfDay(day)}, nil
+	case f.thisWeek, f.nextWeek:
+		start := c.startOfWeek(today)
+		if f.nextWeek {
+			start = start.AddDate(0, 0, 7)
+		}
+		return eventRange{From: start, To: endOfDay(start.AddDate(0, 0, 6))}, nil
+	case hasBounds:
+		return resolveEventBounds(f.from, f.to, c)
+	default:
+		return eventRange{From: today, To: endOfDay(today)}, nil
+	}
+}
+
+// resolveEventBounds turns --from/--to date expressions into an absolute
+// range: a date-only --from starts at midnight, a date-only --to ends at
+// 23:59:59, and a missing bound is the other bound's day (date-only) or one
+// day away (timed).
+func resolveEventBounds(fromValue, toValue string, c dateContext) (eventRange, error) {
+	fromValue, toValue = strings.TrimSpace(fromValue), strings.TrimSpace(toValue)
+	var r eventRange
+	var fromDateOnly, toDateOnly bool
+	var err error
+	if fromValue != "" {
+		if r.From, fromDateOnly, err = c.parse(fromValue); err != nil {
+			return eventRange{}, fmt.Errorf("--from: %w", err)
+		}
+	}
+	if toValue != "" {
+		if r.To, toDateOnly, err = c.parse(toValue); err != nil {
+			return eventRange{}, fmt.Errorf("--to: %w", err)
+		}
+		if toDateOnly {
+			r.To = endOfDay(r.To)
+		}
+	}
+	switch {
+	case toValue == "" && fromDateOnly:
+		r.To = endOfDay(r.From)
+	case toValue == "":
+		r.To = r.From.AddDate(0, 0, 1)
+	case fromValue == "" && toDateOnly:
+		r.From = time.Date(r.To.Year(), r.To.Month(), r.To.Day(), 0, 0, 0, 0, c.loc)
+	case fromValue == "":
+		r.From = r.To.AddDate(0, 0, -1)
+	}
+	if r.To.Before(r.From) {
+		return eventRange{}, fmt.Errorf("%w: --to must be after --from", errUsage)
+	}
+	return r, nil
+}
+
+// endOfDay returns the last second of t's day.
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(-time.Second)
+}
+
+// parseWeekStart accepts monday/mon and sunday/sun.
+func parseWeekStart(value string) (time.Weekday, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "monday", "mon":
+		return time.Monday, nil
+	case "sunday", "sun":
+		return time.Sunday, nil
+	}
+	return time.Monday, fmt.Errorf("%w: invalid week start %q (want monday or sunday)", errUsage, value)
+}
+
+// sundayFirstRegions are the locale regions whose calendars start the week
+// on Sunday (CLDR firstDay); everywhere else gets Monday.
+var sundayFirstRegions = map[string]bool{
+	"AG": true, "AS": true, "BR": true, "BS": true, "BT": true, "BW": true, "BZ": true, "CA": true,
+	"CN": true, "CO": true, "DM": true, "DO": true, "ET": true, "GT": true, "GU": true, "HK": true,
+	"HN": true, "ID": true, "IL": true, "IN": true, "JM": true, "JP": true, "KE": true, "KH": true,
+	"KR": true, "LA": true, "MH": true, "MM": true, "MO": true, "MT": true, "MX": true, "MZ": true,
+	"NI": true, "NP": true, "PA": true, "PE": true, "PH": true, "PK": true, "PR": true, "PT": true,
+	"PY": true, "SA": true, "SG": true, "SV": true, "TH": true, "TT": true, "TW": true, "UM": true,
+	"US": true, "VE": true, "VI": true, "WS": true, "YE": true, "ZA": true, "ZW": true,
+}
+
+// localeWeekStart derives the week start from LC_ALL, LC_TIME or LANG
+// (e.g. en_US.UTF-8 -> Sunday, hr_HR.UTF-8 -> Monday).
+func localeWeekStart() time.Weekday {
+	for _, key := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		value := strings.TrimSpace(os.Getenv(key))
+		if value == "" {
+			continue
+		}
+		if i := strings.IndexAny(value, ".@"); i >= 0 {
+			value = value[:i]
+		}
+		_, region, ok := strings.Cut(strings.ReplaceAll(value, "-", "_"), "_")
+		if !ok {
+			return time.Monday
+		}
+		if sundayFirstRegions[strings.ToUpper(region)] {
+			return time.Sunday
+		}
+		return time.Monday
+	}
+	return time.Monday
+}
+
+// resolveWeekStart picks the week start from the flag, then config
+// (dates.week_start or FANTASTICAL_WEEK_START), then the locale.
+func resolveWeekStart(flagValue string, cfg *Config) (time.Weekday, error) {
+	if strings.TrimSpace(flagValue) != "" {
+		return parseWeekStart(flagValue)
+	}
+	if cfg != nil && strings.TrimSpace(cfg.Dates.WeekStart) != "" {
+		d, err := parseWeekStart(cfg.Dates.WeekStart)
+		if err != nil {
+			return d, fmt.Errorf("config dates.week_start: %w", err)
+		}
+		return d, nil
+	}
+	return localeWeekStart(), nil
+}
@@ -0,0 +1,226 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveDateRange(t *testing.T) {
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		now              time.Time
+		loc              *time.Location
+		weekStart        time.Weekday
+		flags            dateRangeFlags
+		wantFrom, wantTo string
+	}{
+		{
+			name: "default is today", now: time.Date(2026, 10, 18, 14, 0, 0, 0, zagreb), loc: zagreb,
+			wantFrom: "2026-10-18T00:00:00+02:00", wantTo: "2026-10-18T23:59:59+02:00",
+		},
+		{
+			name: "today on the fall-back day lasts 25 hours", now: time.Date(2026, 10, 25, 9, 0, 0, 0, zagreb), loc: zagreb,
+			flags:    dateRangeFlags{today: true},
+			wantFrom: "2026-10-25T00:00:00+02:00", wantTo: "2026-10-25T23:59:59+01:00",
+		},
+		{
+			name: "tomorrow across spring-forward", now: time.Date(2026, 3, 28, 22, 30, 0, 0, zagreb), loc: zagreb,
+			flags:    dateRangeFlags{tomorrow: true},
+			wantFrom: "2026-03-29T00:00:00+01:00", wantTo: "2026-03-29T23:59:59+02:00",
+		},
+		{
+			name: "days keeps the wall clock across DST", now: time.Date(2026, 10, 24, 12, 0, 0, 0, zagreb), loc: zagreb,
+			flags:    dateRangeFlags{days: 2, daysSet: true},
+			wantFrom: "2026-10-24T12:00:00+02:00", wantTo: "2026-10-26T12:00:00+01:00",
+		},
+		{
+			name: "this week starting monday on a sunday", now: time.Date(2026, 10, 18, 14, 0, 0, 0, zagreb), loc: zagreb, weekStart: time.Monday,
+			flags:    dateRangeFlags{thisWeek: true},
+			wantFrom: "2026-10-12T00:00:00+02:00", wantTo: "2026-10-18T23:59:59+02:00",
+		},
+		{
+			name: "this week starting sunday on a sunday", now: time.Date(2026, 10, 18, 14, 0, 0, 0, zagreb), loc: zagreb, weekStart: time.Sunday,
+			flags:    dateRangeFlags{thisWeek: true},
+			wantFrom: "2026-10-18T00:00:00+02:00", wantTo: "2026-10-24T23:59:59+02:00",
+		},
+		{
+			name: "next week spans the fall-back weekend", now: time.Date(2026, 10, 20, 8, 0, 0, 0, zagreb), loc: zagreb, weekStart: time.Monday,
+			flags:    dateRangeFlags{nextWeek: true},
+			wantFrom: "2026-10-26T00:00:00+01:00", wantTo: "2026-11-01T23:59:59+01:00",
+		},
+		{
+			name: "this week with sunday start over US spring-forward", now: time.Date(2026, 3, 9, 10, 0, 0, 0, newYork), loc: newYork, weekStart: time.Sunday,
+			flags:    dateRangeFlags{thisWeek: true},
+			wantFrom: "2026-03-08T00:00:00-05:00", wantTo: "2026-03-14T23:59:59-04:00",
+		},
+		{
+			name: "next week across the year boundary", now: time.Date(2026, 12, 30, 10, 0, 0, 0, newYork), loc: newYork, weekStart: time.Sunday,
+			flags:    dateRangeFlags{nextWeek: true},
+			wantFrom: "2027-01-03T00:00:00-05:00", wantTo: "2027-01-09T23:59:59-05:00",
+		},
+		{
+			name: "the clock's zone is converted to loc", now: time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC), loc: zagreb,
+			wantFrom: "2026-10-19T00:00:00+02:00", wantTo: "2026-10-19T23:59:59+02:00",
+		},
+		{
+			name: "date-only from", now: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), loc: time.UTC,
+			flags:    dateRangeFlags{from: "2026-10-20"},
+			wantFrom: "2026-10-20T00:00:00Z", wantTo: "2026-10-20T23:59:59Z",
+		},
+		{
+			name: "timed from", now: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), loc: time.UTC,
+			flags:    dateRangeFlags{from: "tomorrow 9am"},
+			wantFrom: "2026-10-19T09:00:00Z", wantTo: "2026-10-20T09:00:00Z",
+		},
+		{
+			name: "date-only to", now: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), loc: time.UTC,
+			flags:    dateRangeFlags{to: "end of month"},
+			wantFrom: "2026-10-31T00:00:00Z", wantTo: "2026-10-31T23:59:59Z",
+		},
+		{
+			name: "timed to", now: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), loc: time.UTC,
+			flags:    dateRangeFlags{to: "+2h"},
+			wantFrom: "2026-10-17T16:00:00Z", wantTo: "2026-10-18T16:00:00Z",
+		},
+		{
+			name: "end of week follows the week start", now: time.Date(2026, 10, 14, 14, 0, 0, 0, time.UTC), loc: time.UTC, weekStart: time.Sunday,
+			flags:    dateRangeFlags{from: "today", to: "end of week"},
+			wantFrom: "2026-10-14T00:00:00Z", wantTo: "2026-10-17T23:59:59Z",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newDateContext(tc.now, tc.loc)
+			c.weekStart = tc.weekStart
+			r, err := resolveDateRange(tc.flags, c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := r.From.Format(time.RFC3339); got != tc.wantFrom {
+				t.Fatalf("from = %s, want %s", got, tc.wantFrom)
+			}
+			if got := r.To.Format(time.RFC3339); got != tc.wantTo {
+				t.Fatalf("to = %s, want %s", got, tc.wantTo)
+			}
+		})
+	}
+}
+
+func TestResolveDateRangeConflicts(t *testing.T) {
+	c := newDateContext(time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), time.UTC)
+	for _, flags := range []dateRangeFlags{
+		{today: true, tomorrow: true},
+		{thisWeek: true, nextWeek: true},
+		{today: true, from: "2026-10-20"},
+		{nextWeek: true, days: 3, daysSet: true},
+		{days: 3, daysSet: true, to: "+1w"},
+		{days: -1, daysSet: true},
+		{days: 0, daysSet: true},
+		{from: "tomorrow", to: "yesterday"},
+		{from: "someday"},
+	} {
+		if _, err := resolveDateRange(flags, c); !errors.Is(err, errUsage) {
+			t.Fatalf("%+v: expected usage error, got %v", flags, err)
+		}
+	}
+}
+
+func TestResolveWeekStart(t *testing.T) {
+	for _, key := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		t.Setenv(key, "")
+	}
+	tests := []struct {
+		flag, config, lcTime, lang string
+		want                       time.Weekday
+	}{
+		{want: time.Monday},
+		{lang: "en_US.UTF-8", want: time.Sunday},
+		{lang: "hr_HR.UTF-8", want: time.Monday},
+		{lcTime: "en_GB", lang: "en_US.UTF-8", want: time.Monday},
+		{lang: "C", want: time.Monday},
+		{config: "sunday", lang: "hr_HR.UTF-8", want: time.Sunday},
+		{flag: "mon", config: "sunday", lang: "en_US.UTF-8", want: time.Monday},
+	}
+	for _, tc := range tests {
+		t.Setenv("LC_TIME", tc.lcTime)
+		t.Setenv("LANG", tc.lang)
+		got, err := resolveWeekStart(tc.flag, &Config{Dates: DatesConfig{WeekStart: tc.config}})
+		if err != nil || got != tc.want {
+			t.Fatalf("%+v: got %v, %v", tc, got, err)
+		}
+	}
+	if _, err := resolveWeekStart("friday", nil); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestCmdEventKitEventsAbsoluteRange(t *testing.T) {
+	isolateHelperEnv(t)
+	restore := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = restore })
+
+	helper := filepath.Join(t.TempDir(), "helper.sh")
+	if err := os.WriteFile(helper, withHandshake(t, "#!/bin/sh\necho \"$@\" >&2\necho '[]'\n"), 0o755); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
+	t.Setenv("FANTASTICAL_WEEK_START", "sunday")
+
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), []string{"events", "--next-week", "--tz", "UTC"}, &out, &errOut); err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "--from 2026-10-25T00:00:00Z --to 2026-10-31T23:59:59Z") || strings.Contains(errOut.String(), "--next-week") {
+		t.Fatalf("expected absolute bounds, got %q", errOut.String())
+	}
+
+	out.Reset()
+	if err := cmdValidate(context.Background(), []string{"--json", "events", "--this-week", "--week-start", "monday", "--tz", "UTC"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var result validateResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || !result.OK || result.Range == nil ||
+		result.Range.From.Format(time.RFC3339) != "2026-10-12T00:00:00Z" || result.Range.To.Format(time.RFC3339) != "2026-10-18T23:59:59Z" {
+		t.Fatalf("unexpected validate result: %s", out.String())
+	}
+
+	out.Reset()
+	if err := cmdValidate(context.Background(), []string{"--json", "events", "--today", "--days", "2"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	result = validateResult{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || result.OK || !strings.Contains(result.Error, "date shortcuts") {
+		t.Fatalf("expected conflict error, got %s", out.String())
+	}
+
+	// --days 0 is an error, not a fallback to today.
+	out.Reset()
+	if err := cmdValidate(context.Background(), []string{"--json", "events", "--days", "0"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	result = validateResult{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || result.OK || !strings.Contains(result.Error, "--days must be greater than 0") {
+		t.Fatalf("expected --days error, got %s", out.String())
+	}
+}
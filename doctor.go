@@ -0,0 +1,460 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Doctor check statuses. A failed check makes doctor exit non-zero; a
+// warning only affects optional features (EventKit, completion).
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorCheck is one finding of doctor.
+type doctorCheck struct {
+	ID      string `json:"id"`
+	Status  string `json:"status" enum:"ok,warn,fail,skip"`
+	Summary string `json:"summary"`
+	Hint    string `json:"hint,omitempty"`
+	// Fixed is set when --fix repaired the check.
+	Fixed bool `json:"fixed,omitempty"`
+}
+
+// doctorResult is the output of doctor --json. Checks holds every check
+// that ran; the fields after it predate the checks and are kept for
+// existing consumers.
+type doctorResult struct {
+	OK             bool                `json:"ok"`
+	Checks         []doctorCheck       `json:"checks"`
+	Osascript      doctorTool          `json:"osascript"`
+	Pbcopy         doctorTool          `json:"pbcopy"`
+	FantasticalApp doctorAppCheck      `json:"fantastical_app"`
+	EventKitHelper *eventKitHelperInfo `json:"eventkit_helper,omitempty"`
+	Permissions    string              `json:"permissions"`
+}
+
+type doctorTool struct {
+	OK   bool   `json:"ok"`
+	Path string `json:"path"`
+}
+
+type doctorAppCheck struct {
+	OK    bool `json:"ok"`
+	Check bool `json:"check"`
+}
+
+type doctorOptions struct {
+	json    bool
+	verbose bool
+	skipApp bool
+	fix     bool
+	checks  stringSlice
+}
+
+// doctorRun is the state checks share within one doctor run.
+type doctorRun struct {
+	opts   doctorOptions
+	errOut io.Writer
+	// appMissing is set by fantastical-app so the checks after it skip.
+	appMissing bool
+	helper     *eventKitHelperInfo
+}
+
+// doctorCheckSpec is one entry of doctorChecks. fix, if set, performs a
+// safe remediation for doctor --fix; the check is then run again.
+type doctorCheckSpec struct {
+	ID      string
+	Summary string
+	run     func(ctx context.Context, r *doctorRun) doctorCheck
+	fix     func(ctx context.Context, r *doctorRun) error
+}
+
+// doctorChecks lists the checks in the order they run; later checks may
+// use what earlier ones found.
+var doctorChecks = []doctorCheckSpec{
+	{ID: "osascript", Summary: "osascript is available for applescript", run: checkOsascript},
+	{ID: "pbcopy", Summary: "pbcopy is available for --copy", run: checkPbcopy},
+	{ID: "fantastical-app", Summary: "Fantastical is installed", run: checkFantasticalApp},
+	{ID: "fantastical-version", Summary: "Fantastical is version 3 or later", run: checkFantasticalVersion},
+	{ID: "url-handler", Summary: "x-fantastical3:// URLs open Fantastical", run: checkURLHandler},
+	{ID: "config", Summary: "Config files parse and have no unknown keys", run: checkConfig},
+	{ID: "swift-toolchain", Summary: "swiftc is available to build the EventKit helper", run: checkSwiftToolchain},
+	{ID: "eventkit-helper", Summary: "The EventKit helper is built and current", run: checkEventKitHelperBuild, fix: fixEventKitHelperBuild},
+	{ID: "eventkit-access", Summary: "Calendar access is granted", run: checkEventKitAccess},
+	{ID: "completion", Summary: "Shell completion is installed for $SHELL", run: checkCompletion, fix: fixCompletion},
+}
+
+func doctorCheckIDs() []string {
+	ids := make([]string, len(doctorChecks))
+	for i, c := range doctorChecks {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func doctorUsage(w io.Writer) {
+	fmt.Fprint(w, "USAGE:\n  fantastical doctor [--json] [--skip-app] [--fix] [--check id]...\n")
+	printFlagHelp(w, "doctor")
+	fmt.Fprintf(w, "\nCHECKS:\n")
+	for _, c := range doctorChecks {
+		fmt.Fprintf(w, "  %-20s %s\n", c.ID, c.Summary)
+	}
+	fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical doctor --json\n  fantastical doctor --fix\n  fantastical doctor --check eventkit-helper,eventkit-access")
+}
+
+func cmdDoctor(ctx context.Context, args []string, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	opts := doctorOptions{}
+	bindFlags(fs, "doctor", map[string]any{
+		"json":     &opts.json,
+		"verbose":  &opts.verbose,
+		"skip-app": &opts.skipApp,
+		"fix":      &opts.fix,
+		"check":    &opts.checks,
+	})
+
+	fs.Usage = func() {
+		doctorUsage(errOut)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	selected := map[string]bool{}
+	for _, value := range opts.checks {
+		for _, id := range strings.Split(value, ",") {
+			id = strings.ToLower(strings.TrimSpace(id))
+			if !slices.Contains(doctorCheckIDs(), id) {
+				return fmt.Errorf("%w: unknown check %q (want: %s)", errUsage, id, strings.Join(doctorCheckIDs(), ", "))
+			}
+			selected[id] = true
+		}
+	}
+
+	r := &doctorRun{opts: opts, errOut: errOut}
+	osascriptPath, osascriptErr := exec.LookPath("osascript")
+	pbcopyPath, pbcopyErr := exec.LookPath("pbcopy")
+	result := doctorResult{
+		OK:          true,
+		Checks:      []doctorCheck{},
+		Osascript:   doctorTool{OK: osascriptErr == nil, Path: osascriptPath},
+		Pbcopy:      doctorTool{OK: pbcopyErr == nil, Path: pbcopyPath},
+		Permissions: "Grant Terminal Automation permission if AppleScript prompts or fails.",
+	}
+	var failed []string
+	for _, spec := range doctorChecks {
+		if len(selected) > 0 && !selected[spec.ID] {
+			continue
+		}
+		c := r.check(ctx, spec)
+		switch spec.ID {
+		case "fantastical-app":
+			result.FantasticalApp = doctorAppCheck{OK: c.Status == checkOK, Check: c.Status != checkSkip}
+		case "eventkit-helper":
+			result.EventKitHelper = r.helper
+		}
+		if c.Status == checkFail {
+			failed = append(failed, c.ID)
+		}
+		result.Checks = append(result.Checks, c)
+	}
+	result.OK = len(failed) == 0
+
+	if opts.json {
+		if err := writeJSON(out, result); err != nil {
+			return err
+		}
+	} else {
+		writeDoctorChecks(out, result.Checks)
+		fmt.Fprintln(out, "Automation permissions: grant Terminal access if AppleScript fails.")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("doctor: %s failed", strings.Join(failed, ", "))
+	}
+	logVerbose(errOut, opts.verbose, "doctor completed")
+	return nil
+}
+
+// check runs spec and, with --fix, its remediation.
+func (r *doctorRun) check(ctx context.Context, spec doctorCheckSpec) doctorCheck {
+	c := spec.run(ctx, r)
+	c.ID = spec.ID
+	logVerbose(r.errOut, r.opts.verbose, "doctor: %s: %s", c.ID, c.Status)
+	if !r.opts.fix || spec.fix == nil || c.Status == checkOK || c.Status == checkSkip {
+		return c
+	}
+	logVerbose(r.errOut, r.opts.verbose, "doctor: fixing %s", c.ID)
+	if err := spec.fix(ctx, r); err != nil {
+		c.Hint = "fix failed: " + err.Error()
+		return c
+	}
+	c = spec.run(ctx, r)
+	c.ID = spec.ID
+	c.Fixed = c.Status == checkOK
+	return c
+}
+
+func writeDoctorChecks(w io.Writer, checks []doctorCheck) {
+	for _, c := range checks {
+		status := c.Status
+		if c.Fixed {
+			status = "fixed"
+		}
+		fmt.Fprintf(w, "%-5s  %-19s  %s\n", status, c.ID, c.Summary)
+		if c.Hint != "" {
+			fmt.Fprintf(w, "%-5s  %-19s  hint: %s\n", "", "", c.Hint)
+		}
+	}
+}
+
+func checkOsascript(ctx context.Context, r *doctorRun) doctorCheck {
+	path, err := exec.LookPath("osascript")
+	if err != nil {
+		return doctorCheck{Status: checkFail, Summary: "osascript not found", Hint: "applescript and the url-handler check need macOS scripting"}
+	}
+	return doctorCheck{Status: checkOK, Summary: path}
+}
+
+func checkPbcopy(ctx context.Context, r *doctorRun) doctorCheck {
+	path, err := exec.LookPath("pbcopy")
+	if err != nil {
+		return doctorCheck{Status: checkWarn, Summary: "pbcopy not found", Hint: "install Xcode Command Line Tools (xcode-select --install) or use --print instead of --copy"}
+	}
+	return doctorCheck{Status: checkOK, Summary: path}
+}
+
+func checkFantasticalApp(ctx context.Context, r *doctorRun) doctorCheck {
+	if r.opts.skipApp {
+		return doctorCheck{Status: checkSkip, Summary: "skipped (--skip-app)"}
+	}
+	if err := runSubprocess(ctx, subprocess(ctx, "open", "-Ra", "Fantastical")); err != nil {
+		r.appMissing = true
+		return doctorCheck{Status: checkFail, Summary: "Fantastical not found", Hint: "install Fantastical from the App Store or flexibits.com"}
+	}
+	return doctorCheck{Status: checkOK, Summary: "Fantastical is installed"}
+}
+
+// fantasticalAppPaths are searched for Fantastical.app to read its version.
+var fantasticalAppPaths = []string{"/Applications/Fantastical.app", "~/Applications/Fantastical.app"}
+
+// fantasticalVersion reads CFBundleShortVersionString of the first
+// Fantastical.app in fantasticalAppPaths.
+func fantasticalVersion(ctx context.Context) (string, string, error) {
+	for _, app := range fantasticalAppPaths {
+		app = expandHome(app)
+		plist := filepath.Join(app, "Contents", "Info.plist")
+		if _, err := os.Stat(plist); err != nil {
+			continue
+		}
+		var stdout bytes.Buffer
+		cmd := subprocess(ctx, "plutil", "-extract", "CFBundleShortVersionString", "raw", "-o", "-", plist)
+		cmd.Stdout = &stdout
+		if err := runSubprocess(ctx, cmd); err != nil {
+			return "", app, fmt.Errorf("read %s: %w", plist, err)
+		}
+		return strings.TrimSpace(stdout.String()), app, nil
+	}
+	return "", "", fs.ErrNotExist
+}
+
+func checkFantasticalVersion(ctx context.Context, r *doctorRun) doctorCheck {
+	if r.opts.skipApp || r.appMissing {
+		return doctorCheck{Status: checkSkip, Summary: "skipped (no Fantastical app)"}
+	}
+	version, app, err := fantasticalVersion(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		return doctorCheck{Status: checkWarn, Summary: "Fantastical.app not found in /Applications or ~/Applications", Hint: "the version is not checked for apps installed elsewhere"}
+	}
+	if err != nil {
+		return doctorCheck{Status: checkWarn, Summary: err.Error()}
+	}
+	major, _, _ := strings.Cut(version, ".")
+	if n, err := strconv.Atoi(major); err != nil || n < 3 {
+		return doctorCheck{Status: checkFail, Summary: fmt.Sprintf("Fantastical %s (%s)", version, app), Hint: "x-fantastical3:// URLs need Fantastical 3 or later; update Fantastical"}
+	}
+	return doctorCheck{Status: checkOK, Summary: fmt.Sprintf("Fantastical %s (%s)", version, app)}
+}
+
+// urlHandlerScript prints the path of the app registered for
+// x-fantastical3:// URLs, or nothing.
+const urlHandlerScript = `ObjC.import("AppKit");
+var app = $.NSWorkspace.sharedWorkspace.URLForApplicationToOpenURL($.NSURL.URLWithString("x-fantastical3://"));
+app.isNil() ? "" : app.path.js`
+
+func checkURLHandler(ctx context.Context, r *doctorRun) doctorCheck {
+	if r.opts.skipApp || r.appMissing {
+		return doctorCheck{Status: checkSkip, Summary: "skipped (no Fantastical app)"}
+	}
+	var stdout bytes.Buffer
+	cmd := subprocess(ctx, osascriptCommand(), "-l", "JavaScript", "-e", urlHandlerScript)
+	cmd.Stdout = &stdout
+	if err := runSubprocess(ctx, cmd); err != nil {
+		return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("could not look up the x-fantastical3 handler: %v", err)}
+	}
+	handler := strings.TrimSpace(stdout.String())
+	switch {
+	case handler == "":
+		return doctorCheck{Status: checkFail, Summary: "no application handles x-fantastical3:// URLs", Hint: "open Fantastical once so macOS registers its URL scheme"}
+	case !strings.Contains(filepath.Base(handler), "Fantastical"):
+		return doctorCheck{Status: checkWarn, Summary: "x-fantastical3:// URLs open " + handler, Hint: "open Fantastical once so macOS registers it for its URL scheme"}
+	}
+	return doctorCheck{Status: checkOK, Summary: "handled by " + handler}
+}
+
+func checkConfig(ctx context.Context, r *doctorRun) doctorCheck {
+	cfg, err := loadConfigWithPath("")
+	if err != nil {
+		return doctorCheck{Status: checkFail, Summary: err.Error(), Hint: "fix the file, then check it with fantastical config show"}
+	}
+	sources := cfg.configSources()
+	if len(sources) == 0 {
+		return doctorCheck{Status: checkOK, Summary: "no config files; using defaults"}
+	}
+	var paths []string
+	for _, src := range sources {
+		// Unknown keys decode silently; a strict decode catches typos.
+		data, _ := readConfigJSON(src.Path)
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&Config{}); err != nil {
+			return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("%s: %v", src.Path, strings.TrimPrefix(err.Error(), "json: ")), Hint: "see fantastical greta --schemas for the config keys"}
+		}
+		paths = append(paths, src.Scope+" "+src.Path)
+	}
+	return doctorCheck{Status: checkOK, Summary: "loaded " + strings.Join(paths, ", ")}
+}
+
+func checkSwiftToolchain(ctx context.Context, r *doctorRun) doctorCheck {
+	if xcrun, err := exec.LookPath("xcrun"); err == nil {
+		var stdout bytes.Buffer
+		cmd := subprocess(ctx, xcrun, "--find", "swiftc")
+		cmd.Stdout = &stdout
+		if err := runSubprocess(ctx, cmd); err == nil {
+			return doctorCheck{Status: checkOK, Summary: strings.TrimSpace(stdout.String()) + " (xcrun)"}
+		}
+	}
+	if path, err := exec.LookPath("swiftc"); err == nil {
+		return doctorCheck{Status: checkOK, Summary: path}
+	}
+	return doctorCheck{Status: checkWarn, Summary: "swiftc not found", Hint: "install Xcode Command Line Tools (xcode-select --install) to build the EventKit helper"}
+}
+
+func checkEventKitHelperBuild(ctx context.Context, r *doctorRun) doctorCheck {
+	info := inspectEventKitHelper(ctx)
+	r.helper = &info
+	switch {
+	case info.OK:
+		return doctorCheck{Status: checkOK, Summary: info.summary()}
+	case info.Override:
+		return doctorCheck{Status: checkFail, Summary: info.summary(), Hint: "rebuild it with fantastical eventkit helper build --output " + info.Path + " or unset FANTASTICAL_EVENTKIT_HELPER"}
+	}
+	return doctorCheck{Status: checkWarn, Summary: info.summary(), Hint: "run fantastical doctor --fix or fantastical eventkit helper build"}
+}
+
+func fixEventKitHelperBuild(ctx context.Context, r *doctorRun) error {
+	if r.helper != nil && r.helper.Override {
+		return errors.New("FANTASTICAL_EVENTKIT_HELPER is set; rebuild or unset it")
+	}
+	_, err := ensureEventKitHelper(ctx, r.errOut, r.opts.verbose, helperBuildOptions{force: true})
+	return err
+}
+
+func checkEventKitAccess(ctx context.Context, r *doctorRun) doctorCheck {
+	if r.helper == nil {
+		info := inspectEventKitHelper(ctx)
+		r.helper = &info
+	}
+	if !r.helper.OK {
+		return doctorCheck{Status: checkSkip, Summary: "EventKit helper unavailable", Hint: "fix the eventkit-helper check first (fantastical doctor --fix)"}
+	}
+	var stdout bytes.Buffer
+	cmd := subprocess(ctx, r.helper.Path, "status", "--format", "json")
+	cmd.Stdout = &stdout
+	cmd.Stderr = r.errOut
+	if err := runSubprocess(ctx, cmd); err != nil {
+		return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("eventkit helper status failed: %v", err)}
+	}
+	var status eventKitStatus
+	if err := json.Unmarshal(stdout.Bytes(), &status); err != nil {
+		return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("invalid eventkit helper status: %v", err)}
+	}
+	switch status.Status {
+	case "full_access", "authorized":
+		return doctorCheck{Status: checkOK, Summary: status.Status}
+	case "not_determined":
+		return doctorCheck{Status: checkWarn, Summary: status.Status, Hint: "run fantastical eventkit calendars to get the Calendar access prompt"}
+	}
+	return doctorCheck{Status: checkWarn, Summary: status.Status, Hint: "allow full Calendar access in System Settings > Privacy & Security > Calendars"}
+}
+
+// completionTarget returns the login shell and its default completion path.
+func completionTarget() (string, string, error) {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	path, err := defaultCompletionPath(shell)
+	return shell, path, err
+}
+
+func checkCompletion(ctx context.Context, r *doctorRun) doctorCheck {
+	shell, path, err := completionTarget()
+	if err != nil {
+		return doctorCheck{Status: checkSkip, Summary: fmt.Sprintf("no completion for shell %q", shell), Hint: "install one with fantastical completion install bash|zsh|fish"}
+	}
+	script, _ := completionScript(shell)
+	data, err := os.ReadFile(path)
+	switch {
+	case err != nil:
+		return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("not installed for %s (%s)", shell, path), Hint: "run fantastical doctor --fix or fantastical completion install " + shell}
+	case !strings.Contains(string(data), completionHeader):
+		return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("%s was not written by fantastical; doctor --fix leaves it alone", path), Hint: "review it, then run fantastical completion install " + shell + " to replace it"}
+	case string(data) != script:
+		return doctorCheck{Status: checkWarn, Summary: fmt.Sprintf("out of date for %s (%s)", shell, path), Hint: "run fantastical doctor --fix or fantastical completion install " + shell}
+	}
+	return doctorCheck{Status: checkOK, Summary: fmt.Sprintf("installed for %s (%s)", shell, path)}
+}
+
+func fixCompletion(ctx context.Context, r *doctorRun) error {
+	shell, path, err := completionTarget()
+	if err != nil {
+		return err
+	}
+	script, err := completionScript(shell)
+	if err != nil {
+		return err
+	}
+	if data, err := os.ReadFile(path); err == nil && !strings.Contains(string(data), completionHeader) {
+		return fmt.Errorf("%s was not written by fantastical; review it, then run fantastical completion install %s", path, shell)
+	}
+	return writeFileWithDirs(path, []byte(script))
+}
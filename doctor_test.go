@@ -0,0 +1,223 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runDoctor(t *testing.T, args ...string) (doctorResult, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	err := cmdDoctor(context.Background(), append([]string{"--json"}, args...), &out, &errOut)
+	var result doctorResult
+	if jsonErr := json.Unmarshal(out.Bytes(), &result); jsonErr != nil {
+		t.Fatalf("decode: %v (%v)\n%s", jsonErr, err, out.String())
+	}
+	return result, err
+}
+
+func doctorCheckByID(t *testing.T, result doctorResult, id string) doctorCheck {
+	t.Helper()
+	for _, c := range result.Checks {
+		if c.ID == id {
+			return c
+		}
+	}
+	t.Fatalf("no %s check in %+v", id, result.Checks)
+	return doctorCheck{}
+}
+
+func TestDoctorCheckSelection(t *testing.T) {
+	setupTestEnv(t)
+
+	result, err := runDoctor(t, "--check", "completion", "--check", "config")
+	if err != nil || !result.OK || len(result.Checks) != 2 || result.Checks[0].ID != "config" || result.Checks[1].ID != "completion" {
+		t.Fatalf("expected config and completion in registry order, got %+v (%v)", result.Checks, err)
+	}
+	if result.EventKitHelper != nil || result.FantasticalApp.Check {
+		t.Fatalf("unselected checks must not run: %+v", result)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdDoctor(context.Background(), []string{"--check", "config,nope"}, &out, &errOut); !errors.Is(err, errUsage) || !strings.Contains(err.Error(), "eventkit-access") {
+		t.Fatalf("expected a usage error listing the checks, got %v", err)
+	}
+}
+
+func TestDoctorFixCompletion(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("SHELL", "/bin/zsh")
+	path := filepath.Join(os.Getenv("HOME"), ".zsh", "completions", "_fantastical")
+
+	result, _ := runDoctor(t, "--check", "completion")
+	if c := doctorCheckByID(t, result, "completion"); c.Status != checkWarn || !strings.Contains(c.Hint, "completion install zsh") {
+		t.Fatalf("expected a missing completion, got %+v", c)
+	}
+
+	result, _ = runDoctor(t, "--check", "completion", "--fix")
+	if c := doctorCheckByID(t, result, "completion"); c.Status != checkOK || !c.Fixed {
+		t.Fatalf("expected the completion installed, got %+v", c)
+	}
+	script, _ := completionScript("zsh")
+	if data, err := os.ReadFile(path); err != nil || string(data) != script {
+		t.Fatalf("expected the zsh script at %s: %v", path, err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdDoctor(context.Background(), []string{"--check", "completion"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "ok     completion           installed for zsh ("+path+")\n") {
+		t.Fatalf("unexpected plain output:\n%s", out.String())
+	}
+
+	// A file fantastical did not write is reported but never overwritten.
+	custom := "#compdef fantastical\n_fantastical() { _files }\n"
+	if err := os.WriteFile(path, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = runDoctor(t, "--check", "completion", "--fix")
+	if c := doctorCheckByID(t, result, "completion"); c.Status != checkWarn || c.Fixed || !strings.Contains(c.Hint, "completion install zsh") {
+		t.Fatalf("expected a foreign completion left alone, got %+v", c)
+	}
+	if data, _ := os.ReadFile(path); string(data) != custom {
+		t.Fatalf("doctor --fix overwrote %s:\n%s", path, data)
+	}
+	// An older generated script is refreshed.
+	if err := os.WriteFile(path, []byte(strings.Replace(script, "_fantastical", "_fantastical_old", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = runDoctor(t, "--check", "completion", "--fix")
+	if c := doctorCheckByID(t, result, "completion"); c.Status != checkOK || !c.Fixed {
+		t.Fatalf("expected the generated completion refreshed, got %+v", c)
+	}
+
+	t.Setenv("SHELL", "/bin/tcsh")
+	result, _ = runDoctor(t, "--check", "completion", "--fix")
+	if c := doctorCheckByID(t, result, "completion"); c.Status != checkSkip {
+		t.Fatalf("expected an unsupported shell skipped, got %+v", c)
+	}
+}
+
+func TestDoctorConfigCheck(t *testing.T) {
+	writeAliasConfig(t, `{"parse": {"calender": "Work"}}`)
+	result, err := runDoctor(t, "--check", "config")
+	if c := doctorCheckByID(t, result, "config"); err != nil || c.Status != checkWarn || !strings.Contains(c.Summary, `unknown field "calender"`) {
+		t.Fatalf("expected an unknown key warning, got %+v (%v)", c, err)
+	}
+
+	writeAliasConfig(t, `{"parse": `)
+	result, err = runDoctor(t, "--check", "config")
+	if c := doctorCheckByID(t, result, "config"); err == nil || result.OK || c.Status != checkFail || !strings.Contains(c.Summary, "parse config") {
+		t.Fatalf("expected a parse failure, got %+v (%v)", c, err)
+	}
+
+	writeAliasConfig(t, `{"parse": {"calendar": "Work"}}`)
+	result, _ = runDoctor(t, "--check", "config")
+	if c := doctorCheckByID(t, result, "config"); c.Status != checkOK || !strings.HasPrefix(c.Summary, "loaded user ") {
+		t.Fatalf("expected the config loaded, got %+v", c)
+	}
+}
+
+func TestDoctorEventKitChecks(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "")
+	runs := stubCompiler(t, 0)
+
+	result, _ := runDoctor(t, "--check", "eventkit-helper,eventkit-access")
+	if c := doctorCheckByID(t, result, "eventkit-helper"); c.Status != checkWarn || !strings.Contains(c.Summary, "not built yet") {
+		t.Fatalf("expected an unbuilt helper, got %+v", c)
+	}
+	if c := doctorCheckByID(t, result, "eventkit-access"); c.Status != checkSkip {
+		t.Fatalf("expected access skipped without a helper, got %+v", c)
+	}
+	if runs.Load() != 0 {
+		t.Fatalf("doctor must not compile without --fix")
+	}
+
+	result, _ = runDoctor(t, "--check", "eventkit-helper", "--fix")
+	if c := doctorCheckByID(t, result, "eventkit-helper"); c.Status != checkOK || !c.Fixed || runs.Load() != 1 {
+		t.Fatalf("expected the helper built, got %+v (%d builds)", c, runs.Load())
+	}
+
+	for status, want := range map[string]string{"full_access": checkOK, "not_determined": checkWarn, "denied": checkWarn} {
+		t.Setenv("FANTASTICAL_EVENTKIT_HELPER", writeHelper(t, withHandshake(t, "#!/bin/sh\necho '{\"status\":\""+status+"\",\"canPrompt\":false}'\n")))
+		result, err := runDoctor(t, "--check", "eventkit-helper,eventkit-access", "--fix")
+		c := doctorCheckByID(t, result, "eventkit-access")
+		if err != nil || c.Status != want || c.Summary != status {
+			t.Fatalf("%s: expected %s, got %+v (%v)", status, want, c, err)
+		}
+	}
+
+	// A broken override fails and is not rebuilt.
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", writeHelper(t, []byte("#!/bin/sh\nexit 2\n")))
+	result, err := runDoctor(t, "--check", "eventkit-helper", "--fix")
+	if c := doctorCheckByID(t, result, "eventkit-helper"); err == nil || c.Status != checkFail || !strings.HasPrefix(c.Hint, "fix failed: FANTASTICAL_EVENTKIT_HELPER is set") {
+		t.Fatalf("expected a failed override, got %+v (%v)", c, err)
+	}
+}
+
+func TestDoctorFantasticalChecks(t *testing.T) {
+	setupTestEnv(t)
+	bin := t.TempDir()
+	writeScript := func(name, body string) string {
+		path := filepath.Join(bin, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	versionFile := filepath.Join(bin, "version")
+	writeScript("plutil", "cat "+versionFile+"\n")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+	app := filepath.Join(t.TempDir(), "Fantastical.app")
+	if err := os.MkdirAll(filepath.Join(app, "Contents"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app, "Contents", "Info.plist"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	restore := fantasticalAppPaths
+	fantasticalAppPaths = []string{filepath.Join(t.TempDir(), "Missing.app"), app}
+	t.Cleanup(func() { fantasticalAppPaths = restore })
+
+	cases := []struct {
+		version, handler string
+		want             map[string]string
+	}{
+		{"3.9.1", "/Applications/Fantastical.app", map[string]string{"fantastical-version": checkOK, "url-handler": checkOK}},
+		{"2.5.13", "/Applications/Calendar.app", map[string]string{"fantastical-version": checkFail, "url-handler": checkWarn}},
+		{"4.0", "", map[string]string{"fantastical-version": checkOK, "url-handler": checkFail}},
+	}
+	for _, tc := range cases {
+		if err := os.WriteFile(versionFile, []byte(tc.version+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("FANTASTICAL_OSASCRIPT_COMMAND", writeScript("osascript", "echo '"+tc.handler+"'\n"))
+		result, err := runDoctor(t, "--check", "fantastical-version,url-handler")
+		for id, want := range tc.want {
+			if c := doctorCheckByID(t, result, id); c.Status != want {
+				t.Fatalf("%s/%q: expected %s %s, got %+v", tc.version, tc.handler, id, want, c)
+			}
+		}
+		if (err == nil) != result.OK {
+			t.Fatalf("expected the exit status to follow ok=%t, got %v", result.OK, err)
+		}
+	}
+
+	result, _ := runDoctor(t, "--skip-app", "--check", "fantastical-app,fantastical-version,url-handler")
+	for _, c := range result.Checks {
+		if c.Status != checkSkip {
+			t.Fatalf("expected --skip-app to skip %s, got %+v", c.ID, c)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// eventChunkDays is the widest window sent to the helper in one call.
+// EventKit's predicateForEvents silently truncates ranges longer than about
+// four years, and a year per call keeps each fetch quick.
+const eventChunkDays = 365
+
+// eventChunkWorkers caps how many helper processes fetch chunks at once.
+const eventChunkWorkers = 4
+
+// planEventChunks splits r into consecutive windows of at most days days.
+// Adjacent windows share their boundary instant so events that straddle it
+// are fetched by both; mergeEventPages drops the duplicates.
+func planEventChunks(r eventRange, days int) []eventRange {
+	if days <= 0 {
+		return []eventRange{r}
+	}
+	var chunks []eventRange
+	from := r.From
+	for {
+		to := from.AddDate(0, 0, days)
+		if !to.Before(r.To) {
+			return append(chunks, eventRange{From: from, To: r.To})
+		}
+		chunks = append(chunks, eventRange{From: from, To: to})
+		from = to
+	}
+}
+
+// fetchEventChunks fetches every chunk and merges the pages. The first chunk
+// runs on its own so a Calendar permission prompt (or denial) is settled by
+// a single helper; the rest run up to workers at a time. The first failure
+// cancels the chunks in flight, no further chunks are started, and that
+// failure is returned.
+func fetchEventChunks(ctx context.Context, chunks []eventRange, workers int, fetch func(ctx context.Context, i int, r eventRange) ([]eventInfo, error)) ([]eventInfo, error) {
+	pages := make([][]eventInfo, len(chunks))
+	var err error
+	if pages[0], err = fetch(ctx, 0, chunks[0]); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, max(workers, 1))
+	for i := 1; i < len(chunks); i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			page, err := fetch(ctx, i, chunks[i])
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			pages[i] = page
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	return mergeEventPages(pages), nil
+}
+
+// eventOccurrence identifies one occurrence: recurring events share an ID,
+// so the start is part of the key.
+type eventOccurrence struct {
+	id         string
+	calendarID string
+	title      string
+	start      int64
+}
+
+// mergeEventPages concatenates chunk pages, keeps the first copy of each
+// occurrence and orders the result by start, then end.
+func mergeEventPages(pages [][]eventInfo) []eventInfo {
+	seen := map[eventOccurrence]bool{}
+	merged := []eventInfo{}
+	for _, page := range pages {
+		for _, e := range page {
+			key := eventOccurrence{id: e.ID, calendarID: e.CalendarID, title: e.Title, start: e.Start.UnixNano()}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, e)
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b eventInfo) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return a.End.Compare(b.End)
+	})
+	return merged
+}
+
+// lockedWriter serializes writes from concurrent helper processes.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// fetchEventRange runs the helper over r in chunks. args are the helper's
+// events arguments without --from/--to; --refresh, when set, is only sent
+// with the first chunk.
+func fetchEventRange(ctx context.Context, args []string, r eventRange, refresh bool, errOut io.Writer, verbose bool) ([]eventInfo, error) {
+	chunks := planEventChunks(r, eventChunkDays)
+	if len(chunks) > 1 {
+		logVerbose(errOut, verbose, "fetching %s .. %s in %d chunks", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339), len(chunks))
+		errOut = &lockedWriter{w: errOut}
+	}
+	return fetchEventChunks(ctx, chunks, eventChunkWorkers, func(ctx context.Context, i int, chunk eventRange) ([]eventInfo, error) {
+		chunkArgs := append(slices.Clip(args), "--from", chunk.From.Format(time.RFC3339), "--to", chunk.To.Format(time.RFC3339))
+		if refresh && i == 0 {
+			chunkArgs = append(chunkArgs, "--refresh")
+		}
+		return fetchHelperEvents(ctx, chunkArgs, errOut, verbose)
+	})
+}
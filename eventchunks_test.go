@@ -0,0 +1,256 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func formatChunks(chunks []eventRange) string {
+	parts := make([]string, len(chunks))
+	for i, c := range chunks {
+		parts[i] = c.From.Format(time.RFC3339) + ".." + c.To.Format(time.RFC3339)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestPlanEventChunks(t *testing.T) {
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	utc := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		r    eventRange
+		days int
+		want string
+	}{
+		{
+			name: "short range is one chunk",
+			r:    eventRange{From: utc(2026, 10, 18), To: endOfDay(utc(2026, 10, 24))},
+			days: 365,
+			want: "2026-10-18T00:00:00Z..2026-10-24T23:59:59Z",
+		},
+		{
+			name: "exactly one window",
+			r:    eventRange{From: utc(2025, 1, 1), To: utc(2026, 1, 1)},
+			days: 365,
+			want: "2025-01-01T00:00:00Z..2026-01-01T00:00:00Z",
+		},
+		{
+			name: "windows share boundaries across a leap year",
+			r:    eventRange{From: utc(2024, 1, 1), To: endOfDay(utc(2026, 6, 30))},
+			days: 365,
+			want: "2024-01-01T00:00:00Z..2024-12-31T00:00:00Z 2024-12-31T00:00:00Z..2025-12-31T00:00:00Z 2025-12-31T00:00:00Z..2026-06-30T23:59:59Z",
+		},
+		{
+			name: "boundaries stay at local midnight across DST",
+			r:    eventRange{From: time.Date(2026, 10, 24, 0, 0, 0, 0, zagreb), To: endOfDay(time.Date(2026, 10, 26, 0, 0, 0, 0, zagreb))},
+			days: 1,
+			want: "2026-10-24T00:00:00+02:00..2026-10-25T00:00:00+02:00 2026-10-25T00:00:00+02:00..2026-10-26T00:00:00+01:00 2026-10-26T00:00:00+01:00..2026-10-26T23:59:59+01:00",
+		},
+		{
+			name: "no chunking without a window",
+			r:    eventRange{From: utc(2016, 1, 1), To: utc(2026, 1, 1)},
+			days: 0,
+			want: "2016-01-01T00:00:00Z..2026-01-01T00:00:00Z",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatChunks(planEventChunks(tc.r, tc.days)); got != tc.want {
+				t.Fatalf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+
+	// A ten-year search never asks EventKit for more than a year at once.
+	r := eventRange{From: utc(2016, 10, 18), To: endOfDay(utc(2027, 10, 18))}
+	chunks := planEventChunks(r, eventChunkDays)
+	if len(chunks) != 12 || !chunks[0].From.Equal(r.From) || !chunks[len(chunks)-1].To.Equal(r.To) {
+		t.Fatalf("unexpected plan: %s", formatChunks(chunks))
+	}
+	for i, c := range chunks {
+		if c.To.Sub(c.From) > eventChunkDays*24*time.Hour {
+			t.Fatalf("chunk %d is too long: %s", i, formatChunks([]eventRange{c}))
+		}
+		if i > 0 && !c.From.Equal(chunks[i-1].To) {
+			t.Fatalf("chunk %d does not start where %d ends", i, i-1)
+		}
+	}
+}
+
+func loadChunkPages(t *testing.T) [][]eventInfo {
+	t.Helper()
+	var pages [][]eventInfo
+	for i := 1; i <= 3; i++ {
+		data, err := os.ReadFile(filepath.Join("testdata", "chunks", fmt.Sprintf("page-%d.json", i)))
+		if err != nil {
+			t.Fatalf("read page: %v", err)
+		}
+		var page []eventInfo
+		if err := json.Unmarshal(data, &page); err != nil {
+			t.Fatalf("decode page %d: %v", i, err)
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func occurrences(events []eventInfo) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = e.ID + "/" + e.CalendarID + "@" + e.Start.Format("2006-01-02T15")
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestMergeEventPages(t *testing.T) {
+	pages := loadChunkPages(t)
+	want := "rec-standup/work@2024-12-23T09 rec-standup/work@2024-12-30T09 nye/home@2024-12-31T20 " +
+		"holiday/holidays@2025-01-01T00 rec-standup/work@2025-01-06T09 dentist/home@2025-12-31T08 " +
+		"review/work@2026-01-05T08 rec-standup/team@2026-01-05T09 rec-standup/work@2026-01-05T09"
+	if got := occurrences(mergeEventPages(pages)); got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+
+	// Page order does not change the result.
+	reversed := [][]eventInfo{pages[2], pages[1], pages[0]}
+	if got := occurrences(mergeEventPages(reversed)); got != want {
+		t.Fatalf("reversed pages: got %s", got)
+	}
+	if got := mergeEventPages(nil); got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty slice, got %#v", got)
+	}
+}
+
+func TestFetchEventChunks(t *testing.T) {
+	pages := loadChunkPages(t)
+	chunks := planEventChunks(eventRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+	}, 365)
+
+	var running, peak, firstDone atomic.Int32
+	events, err := fetchEventChunks(context.Background(), chunks, 2, func(ctx context.Context, i int, r eventRange) ([]eventInfo, error) {
+		if i > 0 && firstDone.Load() == 0 {
+			t.Errorf("chunk %d started before the first chunk finished", i)
+		}
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		if i == 0 {
+			firstDone.Store(1)
+		}
+		return pages[i], nil
+	})
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("expected at most 2 concurrent fetches, saw %d", peak.Load())
+	}
+	if len(events) != 9 {
+		t.Fatalf("expected 9 merged occurrences, got %s", occurrences(events))
+	}
+
+	// A failing middle chunk stops the chunks after it: with one worker they
+	// never start, and with several the one in flight is cancelled.
+	errSecond := errors.New("second failed")
+	five := planEventChunks(eventRange{From: chunks[0].From, To: chunks[0].From.AddDate(5, 0, 0)}, 365)
+	for _, workers := range []int{1, 4} {
+		var started atomic.Int32
+		_, err = fetchEventChunks(context.Background(), five, workers, func(ctx context.Context, i int, r eventRange) ([]eventInfo, error) {
+			started.Add(1)
+			switch {
+			case i == 1:
+				return nil, fmt.Errorf("chunk %d: %w", i, errSecond)
+			case i > 1 && workers == 1:
+				t.Errorf("chunk %d started after chunk 1 failed", i)
+			case i > 1:
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return nil, nil
+		})
+		if err == nil || err.Error() != "chunk 1: second failed" {
+			t.Fatalf("workers %d: expected the failing chunk's error, got %v", workers, err)
+		}
+		if workers == 1 && started.Load() != 2 {
+			t.Fatalf("expected 2 chunks to start, got %d", started.Load())
+		}
+	}
+
+	// A cancelled context stops the fetch before the remaining chunks.
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = fetchEventChunks(ctx, five, 1, func(ctx context.Context, i int, r eventRange) ([]eventInfo, error) {
+		if i > 0 {
+			t.Errorf("chunk %d started after cancellation", i)
+		}
+		cancel()
+		return nil, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCmdEventKitSearch(t *testing.T) {
+	isolateHelperEnv(t)
+	restore := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = restore })
+
+	helper := filepath.Join(t.TempDir(), "helper.sh")
+	script := "#!/bin/sh\n[ \"$1\" = events ] || exit 1\necho \"$@\" >&2\necho '" + testEventsJSON + "'\n"
+	if err := os.WriteFile(helper, withHandshake(t, script), 0o755); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
+
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), []string{"search", "stand", "--years", "2", "--tz", "UTC", "--refresh", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	calls := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	if len(calls) != 4 {
+		t.Fatalf("expected 4 chunked helper calls, got %q", errOut.String())
+	}
+	if !strings.Contains(calls[0], "--from 2024-10-18T00:00:00Z --to 2025-10-18T00:00:00Z --refresh") {
+		t.Fatalf("unexpected first chunk: %q", calls[0])
+	}
+	refreshes := strings.Count(errOut.String(), "--refresh")
+	if refreshes != 1 || !strings.Contains(errOut.String(), "--to 2027-10-18T23:59:59Z") {
+		t.Fatalf("expected one refresh and a range ending next year: %q", errOut.String())
+	}
+	// Every chunk returned the same occurrences; the search keeps one copy.
+	var events []eventInfo
+	if err := json.Unmarshal(out.Bytes(), &events); err != nil || len(events) != 1 || events[0].ID != "ev-1" {
+		t.Fatalf("unexpected search result: %s (%v)", out.String(), err)
+	}
+
+	for _, args := range [][]string{
+		{"search"},
+		{"search", "x", "--years", "0"},
+		{"search", "x", "--years", "3", "--from", "2020-01-01"},
+		{"search", "x", "--from", "tomorrow", "--to", "yesterday"},
+	} {
+		if err := cmdEventKit(context.Background(), args, &out, &errOut); !errors.Is(err, errUsage) {
+			t.Fatalf("%v: expected usage error, got %v", args, err)
+		}
+	}
+}
@@ -4,8 +4,9 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -14,14 +15,40 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// eventKitStatus and eventInfo mirror the JSON the Swift helper prints for
+// status and events (calendars use calendarInfo). eventkit status and
+// calendars relay that output unchanged; events are decoded, filtered and
+// re-encoded with the same shape.
+type eventKitStatus struct {
+	Status    string `json:"status" enum:"authorized,full_access,write_only,not_determined,denied,restricted,unknown"`
+	CanPrompt bool   `json:"canPrompt"`
+}
+
+type eventInfo struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Calendar   string    `json:"calendar"`
+	CalendarID string    `json:"calendarId"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	AllDay     bool      `json:"allDay"`
+	Location   string    `json:"location,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+}
+
 type eventKitCalendarsOptions struct {
 	format  string
 	json    bool
 	plain   bool
 	verbose bool
 	noInput bool
+	noCache bool
+	backend string
+	config  string
 }
 
 type eventKitEventsOptions struct {
@@ -39,7 +66,10 @@ type eventKitEventsOptions struct {
 	tomorrow        bool
 	thisWeek        bool
 	nextWeek        bool
+	weekStart       string
 	limit           int
+	offset          int
+	where           string
 	includeAllDay   bool
 	includeDeclined bool
 	sort            string
@@ -48,6 +78,16 @@ type eventKitEventsOptions struct {
 	refresh         bool
 	waitSeconds     int
 	intervalSeconds int
+	config          string
+	noCache         bool
+	backend         string
+}
+
+// eventKitSearchOptions are the eventkit events options that apply to a
+// search, plus how far back it reaches.
+type eventKitSearchOptions struct {
+	eventKitEventsOptions
+	years int
 }
 
 type eventKitStatusOptions struct {
@@ -58,8 +98,8 @@ type eventKitStatusOptions struct {
 }
 
 func eventKitUsage(w io.Writer) {
-	fmt.Fprint(w, "USAGE:\n  fantastical eventkit status [flags]\n  fantastical eventkit calendars [flags]\n  fantastical eventkit events [flags]\n")
-	fmt.Fprint(w, "\nEXAMPLES:\n  fantastical eventkit status --json\n  fantastical eventkit calendars --json\n  fantastical eventkit events --next-week --calendar \"Work\"\n")
+	fmt.Fprint(w, "USAGE:\n  fantastical eventkit status [flags]\n  fantastical eventkit calendars [flags]\n  fantastical eventkit events [flags]\n  fantastical eventkit search [flags] <term...>\n  fantastical eventkit create --backend <backend> --calendar <name> --title <text> --start <datetime> [flags]\n  fantastical eventkit delete --backend <backend> --calendar <name> <event-id>\n  fantastical eventkit helper build|path|info|clean [flags]\n")
+	fmt.Fprint(w, "\nEXAMPLES:\n  fantastical eventkit status --json\n  fantastical eventkit calendars --json\n  fantastical eventkit events --next-week --calendar \"Work\"\n  fantastical eventkit search \"dentist\" --years 5\n  fantastical eventkit create --backend ics:~/calendars --calendar Home --title Dentist --start 2026-10-23T10:00 --duration 45m\n")
 }
 
 func newEventKitCalendarsFlagSet(w io.Writer) (*flag.FlagSet, *eventKitCalendarsOptions) {
@@ -67,15 +107,20 @@ func newEventKitCalendarsFlagSet(w io.Writer) (*flag.FlagSet, *eventKitCalendars
 	fs := flag.NewFlagSet("eventkit calendars", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	fs.StringVar(&opts.format, "format", "", "Output format (plain|json|table)")
-	fs.BoolVar(&opts.json, "json", false, "Print machine-readable JSON output")
-	fs.BoolVar(&opts.plain, "plain", false, "Print stable plain-text output")
-	fs.BoolVar(&opts.noInput, "no-input", false, "Do not prompt for Calendar access")
-	fs.BoolVar(&opts.verbose, "verbose", false, "Verbose output to stderr")
+	bindFlags(fs, "eventkit calendars", map[string]any{
+		"format":   &opts.format,
+		"json":     &opts.json,
+		"plain":    &opts.plain,
+		"no-input": &opts.noInput,
+		"verbose":  &opts.verbose,
+		"no-cache": &opts.noCache,
+		"backend":  &opts.backend,
+		"config":   &opts.config,
+	})
 
 	fs.Usage = func() {
 		fmt.Fprint(w, "USAGE:\n  fantastical eventkit calendars [flags]\n")
-		fs.PrintDefaults()
+		printFlagHelp(w, "eventkit calendars")
 		fmt.Fprintln(w, "\nNOTE:\n  Requires Calendar access; macOS will prompt on first use.")
 	}
 
@@ -83,38 +128,85 @@ func newEventKitCalendarsFlagSet(w io.Writer) (*flag.FlagSet, *eventKitCalendars
 }
 
 func newEventKitEventsFlagSet(w io.Writer) (*flag.FlagSet, *eventKitEventsOptions) {
-	opts := &eventKitEventsOptions{includeAllDay: true}
+	opts := &eventKitEventsOptions{includeAllDay: true, sort: "start", intervalSeconds: 2}
 	fs := flag.NewFlagSet("eventkit events", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	fs.StringVar(&opts.format, "format", "", "Output format (plain|json|table)")
-	fs.BoolVar(&opts.json, "json", false, "Print machine-readable JSON output")
-	fs.BoolVar(&opts.plain, "plain", false, "Print stable plain-text output")
-	fs.BoolVar(&opts.noInput, "no-input", false, "Do not prompt for Calendar access")
-	fs.BoolVar(&opts.verbose, "verbose", false, "Verbose output to stderr")
-	fs.Var(&opts.calendars, "calendar", "Calendar name (repeatable)")
-	fs.Var(&opts.calendarIDs, "calendar-id", "Calendar identifier (repeatable)")
-	fs.StringVar(&opts.from, "from", "", "Start date/time (YYYY-MM-DD or YYYY-MM-DDTHH:MM)")
-	fs.StringVar(&opts.to, "to", "", "End date/time (YYYY-MM-DD or YYYY-MM-DDTHH:MM)")
-	fs.IntVar(&opts.days, "days", 0, "Days from now (shortcut for --from now --to now+days)")
-	fs.BoolVar(&opts.today, "today", false, "Use today's date range")
-	fs.BoolVar(&opts.tomorrow, "tomorrow", false, "Use tomorrow's date range")
-	fs.BoolVar(&opts.thisWeek, "this-week", false, "Use this week's date range")
-	fs.BoolVar(&opts.nextWeek, "next-week", false, "Use next week's date range")
-	fs.IntVar(&opts.limit, "limit", 0, "Limit number of events returned")
-	fs.BoolVar(&opts.includeAllDay, "include-all-day", true, "Include all-day events")
-	fs.BoolVar(&opts.includeDeclined, "include-declined", false, "Include declined events")
-	fs.StringVar(&opts.sort, "sort", "start", "Sort by start|end|title|calendar")
-	fs.StringVar(&opts.timezone, "tz", "", "Timezone for output (IANA name)")
-	fs.StringVar(&opts.query, "query", "", "Filter by title/location/notes (case-insensitive)")
-	fs.BoolVar(&opts.refresh, "refresh", false, "Refresh calendar sources before querying")
-	fs.IntVar(&opts.waitSeconds, "wait", 0, "Wait up to N seconds for events to appear")
-	fs.IntVar(&opts.intervalSeconds, "interval", 2, "Polling interval in seconds when using --wait")
+	bindFlags(fs, "eventkit events", map[string]any{
+		"format":           &opts.format,
+		"json":             &opts.json,
+		"plain":            &opts.plain,
+		"no-input":         &opts.noInput,
+		"verbose":          &opts.verbose,
+		"calendar":         &opts.calendars,
+		"calendar-id":      &opts.calendarIDs,
+		"from":             &opts.from,
+		"to":               &opts.to,
+		"days":             &opts.days,
+		"today":            &opts.today,
+		"tomorrow":         &opts.tomorrow,
+		"this-week":        &opts.thisWeek,
+		"next-week":        &opts.nextWeek,
+		"week-start":       &opts.weekStart,
+		"limit":            &opts.limit,
+		"offset":           &opts.offset,
+		"where":            &opts.where,
+		"include-all-day":  &opts.includeAllDay,
+		"include-declined": &opts.includeDeclined,
+		"sort":             &opts.sort,
+		"tz":               &opts.timezone,
+		"query":            &opts.query,
+		"refresh":          &opts.refresh,
+		"wait":             &opts.waitSeconds,
+		"interval":         &opts.intervalSeconds,
+		"config":           &opts.config,
+		"no-cache":         &opts.noCache,
+		"backend":          &opts.backend,
+	})
 
 	fs.Usage = func() {
 		fmt.Fprint(w, "USAGE:\n  fantastical eventkit events [flags]\n")
-		fs.PrintDefaults()
-		fmt.Fprintln(w, "\nNOTES:\n  Requires Calendar access; macOS will prompt on first use.\n  Date shortcuts (--today/--tomorrow/--this-week/--next-week/--days) are mutually exclusive with --from/--to.\n  --wait polls until a matching event appears or the timeout expires.")
+		printFlagHelp(w, "eventkit events")
+		fmt.Fprintln(w, "\nNOTES:\n  Requires Calendar access; macOS will prompt on first use.\n  Date shortcuts (--today/--tomorrow/--this-week/--next-week/--days) are mutually exclusive with --from/--to.\n  The range is resolved by the CLI and sent to the helper as absolute RFC 3339 bounds.\n  --query, --where, --sort, --offset and --limit are applied by the CLI to the helper's events.\n  --wait polls until an event passes them or the timeout expires.")
+	}
+
+	return fs, opts
+}
+
+func newEventKitSearchFlagSet(w io.Writer) (*flag.FlagSet, *eventKitSearchOptions) {
+	opts := &eventKitSearchOptions{eventKitEventsOptions: eventKitEventsOptions{includeAllDay: true, sort: "-start"}, years: 5}
+	fs := flag.NewFlagSet("eventkit search", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	bindFlags(fs, "eventkit search", map[string]any{
+		"format":           &opts.format,
+		"json":             &opts.json,
+		"plain":            &opts.plain,
+		"no-input":         &opts.noInput,
+		"verbose":          &opts.verbose,
+		"calendar":         &opts.calendars,
+		"calendar-id":      &opts.calendarIDs,
+		"years":            &opts.years,
+		"from":             &opts.from,
+		"to":               &opts.to,
+		"week-start":       &opts.weekStart,
+		"where":            &opts.where,
+		"offset":           &opts.offset,
+		"limit":            &opts.limit,
+		"include-all-day":  &opts.includeAllDay,
+		"include-declined": &opts.includeDeclined,
+		"sort":             &opts.sort,
+		"tz":               &opts.timezone,
+		"refresh":          &opts.refresh,
+		"config":           &opts.config,
+		"no-cache":         &opts.noCache,
+		"backend":          &opts.backend,
+	})
+
+	fs.Usage = func() {
+		fmt.Fprint(w, "USAGE:\n  fantastical eventkit search [flags] <term...>\n")
+		printFlagHelp(w, "eventkit search")
+		fmt.Fprintln(w, "\nNOTES:\n  Matches the term in title, location and notes (case-insensitive), newest first.\n  Searches the last --years years (default 5) through one year ahead; --from/--to replace either end.\n  Long ranges are fetched from the helper in one-year chunks.")
 	}
 
 	return fs, opts
@@ -125,20 +217,22 @@ func newEventKitStatusFlagSet(w io.Writer) (*flag.FlagSet, *eventKitStatusOption
 	fs := flag.NewFlagSet("eventkit status", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	fs.StringVar(&opts.format, "format", "", "Output format (plain|json)")
-	fs.BoolVar(&opts.json, "json", false, "Print machine-readable JSON output")
-	fs.BoolVar(&opts.plain, "plain", false, "Print stable plain-text output")
-	fs.BoolVar(&opts.verbose, "verbose", false, "Verbose output to stderr")
+	bindFlags(fs, "eventkit status", map[string]any{
+		"format":  &opts.format,
+		"json":    &opts.json,
+		"plain":   &opts.plain,
+		"verbose": &opts.verbose,
+	})
 
 	fs.Usage = func() {
 		fmt.Fprint(w, "USAGE:\n  fantastical eventkit status [flags]\n")
-		fs.PrintDefaults()
+		printFlagHelp(w, "eventkit status")
 	}
 
 	return fs, opts
 }
 
-func cmdEventKit(args []string, out, errOut io.Writer) error {
+func cmdEventKit(ctx context.Context, args []string, out, errOut io.Writer) error {
 	if len(args) < 1 {
 		eventKitUsage(errOut)
 		return fmt.Errorf("%w: missing eventkit subcommand", errUsage)
@@ -147,11 +241,19 @@ func cmdEventKit(args []string, out, errOut io.Writer) error {
 	sub := strings.ToLower(strings.TrimSpace(args[0]))
 	switch sub {
 	case "status":
-		return cmdEventKitStatus(args[1:], out, errOut)
+		return cmdEventKitStatus(ctx, args[1:], out, errOut)
 	case "calendars":
-		return cmdEventKitCalendars(args[1:], out, errOut)
+		return cmdEventKitCalendars(ctx, args[1:], out, errOut)
 	case "events":
-		return cmdEventKitEvents(args[1:], out, errOut)
+		return cmdEventKitEvents(ctx, args[1:], out, errOut)
+	case "search":
+		return cmdEventKitSearch(ctx, args[1:], out, errOut)
+	case "create":
+		return cmdEventKitCreate(ctx, args[1:], out, errOut)
+	case "delete":
+		return cmdEventKitDelete(ctx, args[1:], out, errOut)
+	case "helper":
+		return cmdEventKitHelper(ctx, args[1:], out, errOut)
 	default:
 		eventKitUsage(errOut)
 		return fmt.Errorf("%w: unknown eventkit subcommand %q", errUsage, sub)
@@ -184,7 +286,7 @@ func resolveEventKitFormat(format string, json, plain bool, allowed map[string]b
 	return "json", nil
 }
 
-func cmdEventKitStatus(args []string, out, errOut io.Writer) error {
+func cmdEventKitStatus(ctx context.Context, args []string, out, errOut io.Writer) error {
 	fs, opts := newEventKitStatusFlagSet(errOut)
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -204,10 +306,10 @@ func cmdEventKitStatus(args []string, out, errOut io.Writer) error {
 	}
 
 	helperArgs := []string{"status", "--format", format}
-	return runEventKitHelper(helperArgs, out, errOut, opts.verbose)
+	return runEventKitHelper(ctx, helperArgs, out, errOut, opts.verbose)
 }
 
-func cmdEventKitCalendars(args []string, out, errOut io.Writer) error {
+func cmdEventKitCalendars(ctx context.Context, args []string, out, errOut io.Writer) error {
 	fs, opts := newEventKitCalendarsFlagSet(errOut)
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -227,16 +329,48 @@ func cmdEventKitCalendars(args []string, out, errOut io.Writer) error {
 		return err
 	}
 
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return err
+	}
+	backend, err := resolveBackend(opts.backend, cfg, errOut, opts.verbose)
+	if err != nil {
+		return err
+	}
+	if _, ok := backend.(*eventKitBackend); !ok {
+		calendars, err := backend.ListCalendars(ctx)
+		if err != nil {
+			return err
+		}
+		return writeCalendars(out, calendars, format)
+	}
+
 	helperArgs := []string{"calendars"}
 	helperArgs = append(helperArgs, "--format", format)
 	if opts.noInput {
 		helperArgs = append(helperArgs, "--no-input")
 	}
 
-	return runEventKitHelper(helperArgs, out, errOut, opts.verbose)
+	if format != "json" || opts.noCache {
+		return runEventKitHelper(ctx, helperArgs, out, errOut, opts.verbose)
+	}
+
+	// JSON output is exactly what the calendar cache stores, so refresh it.
+	var buf bytes.Buffer
+	if err := runEventKitHelper(ctx, helperArgs, &buf, errOut, opts.verbose); err != nil {
+		return err
+	}
+	var calendars []calendarInfo
+	if err := json.Unmarshal(buf.Bytes(), &calendars); err == nil {
+		if err := writeCalendarCache(calendars); err != nil {
+			logVerbose(errOut, opts.verbose, "calendar cache not written: %v", err)
+		}
+	}
+	_, err = out.Write(buf.Bytes())
+	return err
 }
 
-func cmdEventKitEvents(args []string, out, errOut io.Writer) error {
+func cmdEventKitEvents(ctx context.Context, args []string, out, errOut io.Writer) error {
 	fs, opts := newEventKitEventsFlagSet(errOut)
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -256,156 +390,407 @@ func cmdEventKitEvents(args []string, out, errOut io.Writer) error {
 		return err
 	}
 
-	helperArgs := []string{"events"}
-	helperArgs = append(helperArgs, "--format", format)
-	if opts.noInput {
-		helperArgs = append(helperArgs, "--no-input")
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return err
 	}
-	for _, name := range opts.calendars {
-		helperArgs = append(helperArgs, "--calendar", name)
+
+	c, err := opts.dateContext(cfg)
+	if err != nil {
+		return err
 	}
-	for _, id := range opts.calendarIDs {
-		helperArgs = append(helperArgs, "--calendar-id", id)
+	r, err := opts.resolveRange(fs, c)
+	if err != nil {
+		return err
 	}
-	if strings.TrimSpace(opts.from) != "" {
-		helperArgs = append(helperArgs, "--from", strings.TrimSpace(opts.from))
+	q, err := opts.eventQuery(c)
+	if err != nil {
+		return err
 	}
-	if strings.TrimSpace(opts.to) != "" {
-		helperArgs = append(helperArgs, "--to", strings.TrimSpace(opts.to))
+	if opts.waitSeconds < 0 {
+		return fmt.Errorf("%w: --wait must be >= 0", errUsage)
 	}
-	if opts.days > 0 {
-		helperArgs = append(helperArgs, "--days", fmt.Sprintf("%d", opts.days))
+	if opts.waitSeconds > 0 && opts.intervalSeconds <= 0 {
+		return fmt.Errorf("%w: --interval must be > 0", errUsage)
 	}
-	if opts.today {
-		helperArgs = append(helperArgs, "--today")
+
+	// The backend only fetches; filtering, sorting, paging and output
+	// formatting happen here.
+	backend, err := resolveBackend(opts.backend, cfg, errOut, opts.verbose)
+	if err != nil {
+		return err
 	}
-	if opts.tomorrow {
-		helperArgs = append(helperArgs, "--tomorrow")
+	req, err := opts.eventFetch(ctx, cfg, backend, r, errOut)
+	if err != nil {
+		return err
 	}
-	if opts.thisWeek {
-		helperArgs = append(helperArgs, "--this-week")
+	events, err := backend.ListEvents(ctx, req)
+	if err != nil {
+		return err
 	}
-	if opts.nextWeek {
-		helperArgs = append(helperArgs, "--next-week")
+	matched := q.apply(events)
+	if opts.waitSeconds > 0 {
+		interval := time.Duration(opts.intervalSeconds) * time.Second
+		deadline := time.Now().Add(time.Duration(opts.waitSeconds) * time.Second)
+		for len(matched) == 0 && time.Now().Add(interval).Before(deadline) {
+			logVerbose(errOut, opts.verbose, "no matching events yet; retrying in %s", interval)
+			if !sleepCommand(ctx, interval) {
+				return context.Cause(ctx)
+			}
+			if events, err = backend.ListEvents(ctx, req); err != nil {
+				return err
+			}
+			matched = q.apply(events)
+		}
 	}
-	if opts.limit > 0 {
-		helperArgs = append(helperArgs, "--limit", fmt.Sprintf("%d", opts.limit))
+	return writeEvents(out, matched, format, c.loc)
+}
+
+func cmdEventKitSearch(ctx context.Context, args []string, out, errOut io.Writer) error {
+	fs, opts := newEventKitSearchFlagSet(errOut)
+	terms, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
 	}
-	if !opts.includeAllDay {
-		helperArgs = append(helperArgs, "--no-all-day")
+	opts.query = strings.TrimSpace(strings.Join(terms, " "))
+	if opts.query == "" && strings.TrimSpace(opts.where) == "" {
+		fs.Usage()
+		return fmt.Errorf("%w: missing search term", errUsage)
 	}
-	if opts.includeDeclined {
-		helperArgs = append(helperArgs, "--include-declined")
+	if opts.years <= 0 {
+		return fmt.Errorf("%w: --years must be > 0", errUsage)
 	}
-	if strings.TrimSpace(opts.sort) != "" {
-		helperArgs = append(helperArgs, "--sort", strings.TrimSpace(opts.sort))
+	yearsSet := false
+	fs.Visit(func(f *flag.Flag) { yearsSet = yearsSet || f.Name == "years" })
+	if yearsSet && strings.TrimSpace(opts.from) != "" {
+		return fmt.Errorf("%w: --years cannot be combined with --from", errUsage)
 	}
-	if strings.TrimSpace(opts.timezone) != "" {
-		helperArgs = append(helperArgs, "--tz", strings.TrimSpace(opts.timezone))
+
+	format, err := resolveEventKitFormat(opts.format, opts.json, opts.plain, map[string]bool{
+		"plain": true,
+		"json":  true,
+		"table": true,
+	})
+	if err != nil {
+		return err
 	}
-	if strings.TrimSpace(opts.query) != "" {
-		helperArgs = append(helperArgs, "--query", strings.TrimSpace(opts.query))
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return err
 	}
-	if opts.refresh {
-		helperArgs = append(helperArgs, "--refresh")
+	c, err := opts.dateContext(cfg)
+	if err != nil {
+		return err
 	}
-	if opts.waitSeconds > 0 {
-		helperArgs = append(helperArgs, "--wait", fmt.Sprintf("%d", opts.waitSeconds))
+	r, err := searchRange(c, opts.years, opts.from, opts.to)
+	if err != nil {
+		return err
 	}
-	if opts.intervalSeconds > 0 {
-		helperArgs = append(helperArgs, "--interval", fmt.Sprintf("%d", opts.intervalSeconds))
+	q, err := opts.eventQuery(c)
+	if err != nil {
+		return err
 	}
 
-	return runEventKitHelper(helperArgs, out, errOut, opts.verbose)
-}
-
-func runEventKitHelper(args []string, out, errOut io.Writer, verbose bool) error {
-	cmd, err := eventKitHelperCommand(args, errOut, verbose)
+	backend, err := resolveBackend(opts.backend, cfg, errOut, opts.verbose)
 	if err != nil {
 		return err
 	}
-	cmd.Stdout = out
-	cmd.Stderr = errOut
-	return cmd.Run()
+	req, err := opts.eventFetch(ctx, cfg, backend, r, errOut)
+	if err != nil {
+		return err
+	}
+	events, err := backend.ListEvents(ctx, req)
+	if err != nil {
+		return err
+	}
+	return writeEvents(out, q.apply(events), format, c.loc)
 }
 
-func eventKitHelperCommand(args []string, errOut io.Writer, verbose bool) (*exec.Cmd, error) {
-	if override := strings.TrimSpace(os.Getenv("FANTASTICAL_EVENTKIT_HELPER")); override != "" {
-		logVerbose(errOut, verbose, "eventkit helper override: %s", override)
-		return exec.Command(override, args...), nil
+// searchRange is the window eventkit search covers: years back from today
+// through the end of the same day next year, with --from/--to replacing
+// either end.
+func searchRange(c dateContext, years int, fromValue, toValue string) (eventRange, error) {
+	today := c.today()
+	r := eventRange{From: today.AddDate(-years, 0, 0), To: endOfDay(today.AddDate(1, 0, 0))}
+	if v := strings.TrimSpace(fromValue); v != "" {
+		from, _, err := c.parse(v)
+		if err != nil {
+			return eventRange{}, fmt.Errorf("--from: %w", err)
+		}
+		r.From = from
 	}
-
-	path, err := ensureEventKitHelper(errOut, verbose)
-	if err != nil {
-		return nil, err
+	if v := strings.TrimSpace(toValue); v != "" {
+		to, dateOnly, err := c.parse(v)
+		if err != nil {
+			return eventRange{}, fmt.Errorf("--to: %w", err)
+		}
+		if dateOnly {
+			to = endOfDay(to)
+		}
+		r.To = to
 	}
-	logVerbose(errOut, verbose, "eventkit helper: %s", path)
-	return exec.Command(path, args...), nil
+	if r.To.Before(r.From) {
+		return eventRange{}, fmt.Errorf("%w: --to must be after --from", errUsage)
+	}
+	return r, nil
 }
 
-func ensureEventKitHelper(errOut io.Writer, verbose bool) (string, error) {
-	cacheDir, err := os.UserCacheDir()
-	if err != nil {
-		return "", fmt.Errorf("eventkit cache dir: %w", err)
+// eventFetch builds the backend request for r, resolving --calendar names
+// and aliases against the backend's calendars.
+func (opts *eventKitEventsOptions) eventFetch(ctx context.Context, cfg *Config, backend CalendarBackend, r eventRange, errOut io.Writer) (eventFetch, error) {
+	req := eventFetch{
+		Range:           r,
+		IncludeAllDay:   opts.includeAllDay,
+		IncludeDeclined: opts.includeDeclined,
+		Timezone:        strings.TrimSpace(opts.timezone),
+		Refresh:         opts.refresh,
+		NoInput:         opts.noInput,
+	}
+	if len(opts.calendars) > 0 {
+		resolver, err := newCalendarResolver(cfg, opts.noCache, errOut, opts.verbose)
+		if err != nil {
+			return req, err
+		}
+		resolver.useBackend(backend)
+		for _, name := range opts.calendars {
+			ref, err := resolver.resolve(ctx, name)
+			if err != nil {
+				return req, err
+			}
+			req.Calendars = append(req.Calendars, ref)
+		}
+	}
+	for _, id := range opts.calendarIDs {
+		req.Calendars = append(req.Calendars, calendarRef{ID: id, byID: true})
 	}
+	return req, nil
+}
 
-	cacheRoot := filepath.Join(cacheDir, "fantastical")
-	helperPath := filepath.Join(cacheRoot, "eventkit-helper")
-	sourcePath := filepath.Join(cacheRoot, "eventkit-helper.swift")
-	hashPath := filepath.Join(cacheRoot, "eventkit-helper.hash")
+// fetchHelperEvents runs the helper with --format json and decodes events.
+func fetchHelperEvents(ctx context.Context, args []string, errOut io.Writer, verbose bool) ([]eventInfo, error) {
+	var buf bytes.Buffer
+	if err := runEventKitHelper(ctx, args, &buf, errOut, verbose); err != nil {
+		return nil, err
+	}
+	var events []eventInfo
+	if err := json.Unmarshal(buf.Bytes(), &events); err != nil {
+		return nil, fmt.Errorf("eventkit helper returned invalid events JSON: %w", err)
+	}
+	return events, nil
+}
 
-	hash := sha256.Sum256([]byte(eventKitHelperSource))
-	hashStr := hex.EncodeToString(hash[:8])
+// eventsTimeLayout is how plain and table output print event times.
+const eventsTimeLayout = "2006-01-02 15:04"
+
+// writeEvents prints events as json, a table or tab-separated lines with
+// times in loc, matching the helper's own formats.
+func writeEvents(w io.Writer, events []eventInfo, format string, loc *time.Location) error {
+	if events == nil {
+		events = []eventInfo{}
+	}
+	switch format {
+	case "json":
+		return writeJSON(w, events)
+	case "table":
+		rows := make([][]string, len(events))
+		for i, e := range events {
+			rows[i] = []string{e.Start.In(loc).Format(eventsTimeLayout), e.End.In(loc).Format(eventsTimeLayout), e.Calendar, e.Title}
+		}
+		_, err := fmt.Fprintln(w, renderTable([]string{"Start", "End", "Calendar", "Title"}, rows))
+		return err
+	default:
+		for _, e := range events {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Start.In(loc).Format(eventsTimeLayout), e.End.In(loc).Format(eventsTimeLayout), e.Calendar, e.Title); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
 
-	if _, err := os.Stat(helperPath); err == nil {
-		if current, err := os.ReadFile(hashPath); err == nil && strings.TrimSpace(string(current)) == hashStr {
-			return helperPath, nil
+// writeCalendars prints calendars from a non-EventKit backend in the
+// helper's formats.
+func writeCalendars(w io.Writer, calendars []calendarInfo, format string) error {
+	if calendars == nil {
+		calendars = []calendarInfo{}
+	}
+	switch format {
+	case "json":
+		return writeJSON(w, calendars)
+	case "table":
+		rows := make([][]string, len(calendars))
+		for i, cal := range calendars {
+			rows[i] = []string{cal.Title, cal.Source, cal.Type, cal.ID}
+		}
+		_, err := fmt.Fprintln(w, renderTable([]string{"Title", "Source", "Type", "ID"}, rows))
+		return err
+	default:
+		for _, cal := range calendars {
+			if _, err := fmt.Fprintf(w, "%s\t(%s)\n", cal.Title, cal.Source); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
+}
 
-	if _, err := os.Stat(helperPath); err == nil {
-		logVerbose(errOut, verbose, "eventkit helper hash mismatch; recompiling")
+// renderTable pads columns to their widest cell, with a dashed rule under
+// the header.
+func renderTable(headers []string, rows [][]string) string {
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
 	}
+	line := func(cells []string) string {
+		padded := make([]string, len(cells))
+		for i, cell := range cells {
+			padded[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		return strings.Join(padded, "  ")
+	}
+	rules := make([]string, len(widths))
+	for i, width := range widths {
+		rules[i] = strings.Repeat("-", width)
+	}
+	lines := []string{line(headers), strings.Join(rules, "  ")}
+	for _, row := range rows {
+		lines = append(lines, line(row))
+	}
+	return strings.Join(lines, "\n")
+}
 
-	if err := os.MkdirAll(cacheRoot, 0o755); err != nil {
-		return "", fmt.Errorf("eventkit cache dir: %w", err)
+// eventKitEventsRange parses eventkit events flags and returns the range they
+// select without running the helper.
+func eventKitEventsRange(args []string, errOut io.Writer) (eventRange, error) {
+	fs, opts := newEventKitEventsFlagSet(errOut)
+	if err := fs.Parse(args); err != nil {
+		return eventRange{}, fmt.Errorf("%w: %v", errUsage, err)
 	}
-	if err := os.WriteFile(sourcePath, []byte(eventKitHelperSource), 0o644); err != nil {
-		return "", fmt.Errorf("eventkit helper source: %w", err)
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return eventRange{}, err
 	}
+	c, err := opts.dateContext(cfg)
+	if err != nil {
+		return eventRange{}, err
+	}
+	return opts.resolveRange(fs, c)
+}
 
-	compileErr := compileSwiftHelper(sourcePath, helperPath, errOut, verbose)
-	if compileErr != nil {
-		return "", compileErr
+// dateContext resolves dates in --tz (or the system zone) with the
+// configured week start.
+func (opts *eventKitEventsOptions) dateContext(cfg *Config) (dateContext, error) {
+	loc := time.Local
+	if tz := strings.TrimSpace(opts.timezone); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return dateContext{}, fmt.Errorf("%w: invalid --tz %q: %v", errUsage, tz, err)
+		}
+	}
+	weekStart, err := resolveWeekStart(opts.weekStart, cfg)
+	if err != nil {
+		return dateContext{}, err
 	}
+	c := newDateContext(nowFunc(), loc)
+	c.weekStart = weekStart
+	return c, nil
+}
 
-	_ = os.WriteFile(hashPath, []byte(hashStr+"\n"), 0o644)
+// resolveRange resolves the range flags parsed by fs. --days counts as set
+// whenever it was passed, so --days 0 is rejected rather than ignored.
+func (opts *eventKitEventsOptions) resolveRange(fs *flag.FlagSet, c dateContext) (eventRange, error) {
+	daysSet := false
+	fs.Visit(func(f *flag.Flag) { daysSet = daysSet || f.Name == "days" })
+	return resolveDateRange(dateRangeFlags{
+		from:     opts.from,
+		to:       opts.to,
+		days:     opts.days,
+		daysSet:  daysSet,
+		today:    opts.today,
+		tomorrow: opts.tomorrow,
+		thisWeek: opts.thisWeek,
+		nextWeek: opts.nextWeek,
+	}, c)
+}
 
-	return helperPath, nil
+// eventQuery compiles --query, --where, --sort, --offset and --limit.
+func (opts *eventKitEventsOptions) eventQuery(c dateContext) (eventQuery, error) {
+	q := eventQuery{query: opts.query, offset: opts.offset, limit: opts.limit}
+	if opts.offset < 0 || opts.limit < 0 {
+		return q, fmt.Errorf("%w: --offset and --limit must be >= 0", errUsage)
+	}
+	var err error
+	if q.sort, err = parseEventSort(opts.sort); err != nil {
+		return q, err
+	}
+	if strings.TrimSpace(opts.where) != "" {
+		if q.where, err = parseWhere(opts.where, c); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
 }
 
-func compileSwiftHelper(sourcePath, outputPath string, errOut io.Writer, verbose bool) error {
-	xcrunPath, err := exec.LookPath("xcrun")
-	if err == nil {
-		cmd := exec.Command(xcrunPath, "swiftc", "-O", "-framework", "EventKit", "-o", outputPath, sourcePath)
-		cmd.Stdout = errOut
-		cmd.Stderr = errOut
-		logVerbose(errOut, verbose, "compiling eventkit helper with xcrun swiftc")
-		if err := cmd.Run(); err == nil {
-			return nil
+func runEventKitHelper(ctx context.Context, args []string, out, errOut io.Writer, verbose bool) error {
+	cmd, err := eventKitHelperCommand(ctx, args, errOut, verbose)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return runSubprocess(ctx, cmd)
+}
+
+// eventKitHelperCommand prepares the helper for args after checking its
+// version handshake, which for the cached helper is read from its manifest.
+// A stale or incompatible cached helper is recompiled; an incompatible
+// FANTASTICAL_EVENTKIT_HELPER is asked every time and is an error.
+func eventKitHelperCommand(ctx context.Context, args []string, errOut io.Writer, verbose bool) (*exec.Cmd, error) {
+	if override := strings.TrimSpace(os.Getenv("FANTASTICAL_EVENTKIT_HELPER")); override != "" {
+		logVerbose(errOut, verbose, "eventkit helper override: %s", override)
+		v, err := checkEventKitHelper(ctx, override, args, false)
+		if err != nil {
+			return nil, fmt.Errorf("eventkit helper %s %v; rebuild it from this version or unset FANTASTICAL_EVENTKIT_HELPER", override, err)
+		}
+		if v.SourceHash != eventKitHelperHash() {
+			logVerbose(errOut, verbose, "eventkit helper override built from source %s (this CLI: %s)", v.SourceHash, eventKitHelperHash())
 		}
+		return subprocess(ctx, override, args...), nil
 	}
 
-	swiftcPath, err := exec.LookPath("swiftc")
-	if err == nil {
-		cmd := exec.Command(swiftcPath, "-O", "-framework", "EventKit", "-o", outputPath, sourcePath)
-		cmd.Stdout = errOut
-		cmd.Stderr = errOut
-		logVerbose(errOut, verbose, "compiling eventkit helper with swiftc")
-		if err := cmd.Run(); err == nil {
-			return nil
+	path, err := ensureEventKitHelper(ctx, errOut, verbose, helperBuildOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCachedHelper(ctx, path, args); err != nil {
+		logVerbose(errOut, verbose, "eventkit helper %v; recompiling", err)
+		if path, err = ensureEventKitHelper(ctx, errOut, verbose, helperBuildOptions{force: true}); err != nil {
+			return nil, err
+		}
+		if err := checkCachedHelper(ctx, path, args); err != nil {
+			return nil, fmt.Errorf("eventkit helper %s %v after recompiling", path, err)
 		}
 	}
+	logVerbose(errOut, verbose, "eventkit helper: %s", path)
+	return subprocess(ctx, path, args...), nil
+}
 
-	return errors.New("eventkit helper build failed; install Xcode Command Line Tools (xcode-select --install)")
+// fantasticalCacheDir returns the per-user cache directory shared by the
+// compiled helper and cached calendar metadata.
+func fantasticalCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "fantastical"), nil
 }
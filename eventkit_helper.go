@@ -3,8 +3,23 @@
 
 package main
 
+// eventKitHelperSource is the Swift helper. The CLI fills in the source hash
+// placeholder when it writes the file to compile; see eventKitHelperProgram.
 const eventKitHelperSource = `import Foundation
 import EventKit
+import CoreGraphics
+
+// Reported by "version --json" so the CLI can check a helper before use.
+let helperProtocol = 1
+let helperSourceHash = "` + helperSourceHashPlaceholder + `"
+let helperCommands = ["status", "calendars", "events", "version"]
+// helperFlags maps every flag to the number of values it takes.
+let helperFlags: [String: Int] = [
+    "--format": 1, "--json": 0, "--plain": 0, "--table": 0, "--no-input": 0,
+    "--calendar": 1, "--calendar-id": 1, "--from": 1, "--to": 1, "--limit": 1,
+    "--include-all-day": 0, "--no-all-day": 0, "--include-declined": 0,
+    "--sort": 1, "--tz": 1, "--query": 1, "--refresh": 0, "--wait": 1, "--interval": 1,
+]
 
 struct Options {
     var format: String = "plain"
@@ -13,11 +28,6 @@ struct Options {
     var calendarIds: [String] = []
     var from: String? = nil
     var to: String? = nil
-    var days: Int? = nil
-    var today: Bool = false
-    var tomorrow: Bool = false
-    var thisWeek: Bool = false
-    var nextWeek: Bool = false
     var limit: Int? = nil
     var includeAllDay: Bool = true
     var includeDeclined: Bool = false
@@ -38,9 +48,10 @@ func eprintln(_ message: String) {
 func usage() {
     let text = """
 USAGE:
+  eventkit version [--format plain|json]
   eventkit status [--format plain|json]
   eventkit calendars [--format plain|json|table] [--no-input]
-  eventkit events [--from <date>] [--to <date>] [--days N] [--today|--tomorrow|--this-week|--next-week]
+  eventkit events [--from <date>] [--to <date>]
                  [--calendar <name>] [--calendar-id <id>]
                  [--query <text>] [--sort start|end|title|calendar]
                  [--tz <iana>] [--limit N]
@@ -49,6 +60,7 @@ USAGE:
                  [--format plain|json|table] [--no-input]
 
 DATE FORMATS:
+  RFC 3339 (the CLI always sends these)
   YYYY-MM-DD
   YYYY-MM-DDTHH:MM
   YYYY-MM-DDTHH:MM:SS
@@ -116,27 +128,6 @@ func parseArgs(_ args: [String]) -> (String, Options)? {
                 return nil
             }
             opts.to = args[i]
-        case "--days":
-            i += 1
-            if i >= args.count {
-                eprintln("missing value for --days")
-                usage()
-                return nil
-            }
-            if let value = Int(args[i]) {
-                opts.days = value
-            } else {
-                eprintln("invalid --days value: \(args[i])")
-                return nil
-            }
-        case "--today":
-            opts.today = true
-        case "--tomorrow":
-            opts.tomorrow = true
-        case "--this-week":
-            opts.thisWeek = true
-        case "--next-week":
-            opts.nextWeek = true
         case "--limit":
             i += 1
             if i >= args.count {
@@ -229,6 +220,12 @@ func parseArgs(_ args: [String]) -> (String, Options)? {
 }
 
 func parseDate(_ value: String) -> (Date, Bool)? {
+    let iso = ISO8601DateFormatter()
+    iso.formatOptions = [.withInternetDateTime]
+    if let date = iso.date(from: value) {
+        return (date, false)
+    }
+
     let formats = ["yyyy-MM-dd'T'HH:mm:ss", "yyyy-MM-dd'T'HH:mm", "yyyy-MM-dd"]
     let formatter = DateFormatter()
     formatter.locale = Locale(identifier: "en_US_POSIX")
@@ -262,24 +259,12 @@ func resolveTimeZone(_ value: String?) -> TimeZone? {
     return TimeZone.current
 }
 
+// The CLI resolves date expressions, shortcuts and week boundaries and sends
+// absolute --from/--to bounds; the fallbacks below only serve direct calls.
 func resolveDateRange(_ opts: Options) -> (Date, Date)? {
     let now = Date()
     let calendar = Calendar.current
 
-    let presetCount = [opts.today, opts.tomorrow, opts.thisWeek, opts.nextWeek].filter { $0 }.count
-    if presetCount > 1 {
-        eprintln("only one of --today/--tomorrow/--this-week/--next-week can be used")
-        return nil
-    }
-    if presetCount > 0 && (opts.from != nil || opts.to != nil || opts.days != nil) {
-        eprintln("--from/--to/--days cannot be combined with date shortcuts")
-        return nil
-    }
-    if opts.days != nil && (opts.from != nil || opts.to != nil) {
-        eprintln("--days cannot be combined with --from/--to")
-        return nil
-    }
-
     if let waitSeconds = opts.waitSeconds, waitSeconds < 0 {
         eprintln("--wait must be >= 0")
         return nil
@@ -289,38 +274,6 @@ func resolveDateRange(_ opts: Options) -> (Date, Date)? {
         return nil
     }
 
-    if let days = opts.days {
-        if days <= 0 {
-            eprintln("--days must be greater than 0")
-            return nil
-        }
-        guard let toDate = calendar.date(byAdding: .day, value: days, to: now) else {
-            return nil
-        }
-        return (now, toDate)
-    }
-
-    if opts.today {
-        return (startOfDay(now), endOfDay(now))
-    }
-    if opts.tomorrow {
-        let tomorrow = calendar.date(byAdding: .day, value: 1, to: now) ?? now
-        return (startOfDay(tomorrow), endOfDay(tomorrow))
-    }
-    if opts.thisWeek {
-        if let interval = calendar.dateInterval(of: .weekOfYear, for: now) {
-            return (interval.start, interval.end.addingTimeInterval(-1))
-        }
-        return nil
-    }
-    if opts.nextWeek {
-        let next = calendar.date(byAdding: .day, value: 7, to: now) ?? now
-        if let interval = calendar.dateInterval(of: .weekOfYear, for: next) {
-            return (interval.start, interval.end.addingTimeInterval(-1))
-        }
-        return nil
-    }
-
     var fromDate = startOfDay(now)
     var toDate = endOfDay(now)
     var fromDateOnly = false
@@ -442,6 +395,7 @@ struct CalendarOutput: Codable {
     let title: String
     let source: String
     let type: String
+    let color: String?
     let allowsModifications: Bool
 }
 
@@ -468,6 +422,18 @@ func calendarTypeName(_ type: EKCalendarType) -> String {
     }
 }
 
+func hexColor(_ color: CGColor?) -> String? {
+    guard let color = color,
+          let srgb = CGColorSpace(name: CGColorSpace.sRGB),
+          let converted = color.converted(to: srgb, intent: .defaultIntent, options: nil),
+          let components = converted.components,
+          components.count >= 3 else {
+        return nil
+    }
+    let channels = components.prefix(3).map { Int(($0 * 255).rounded()) }
+    return String(format: "#%02X%02X%02X", channels[0], channels[1], channels[2])
+}
+
 func isDeclinedByCurrentUser(_ event: EKEvent) -> Bool {
     guard let attendees = event.attendees else {
         return false
@@ -526,10 +492,27 @@ func outputStatus(_ status: EKAuthorizationStatus, format: String) {
     print(statusString)
 }
 
+func outputVersion(format: String) {
+    if format == "json" {
+        let payload: [String: Any] = [
+            "protocol": helperProtocol,
+            "sourceHash": helperSourceHash,
+            "commands": helperCommands,
+            "flags": helperFlags
+        ]
+        if let data = try? JSONSerialization.data(withJSONObject: payload, options: [.sortedKeys]),
+           let text = String(data: data, encoding: .utf8) {
+            print(text)
+        }
+        return
+    }
+    print("protocol \(helperProtocol), source \(helperSourceHash)")
+}
+
 func outputCalendars(_ calendars: [EKCalendar], format: String) {
     if format == "json" {
         let items = calendars.map {
-            CalendarOutput(id: $0.calendarIdentifier, title: $0.title, source: $0.source.title, type: calendarTypeName($0.type), allowsModifications: $0.allowsContentModifications)
+            CalendarOutput(id: $0.calendarIdentifier, title: $0.title, source: $0.source.title, type: calendarTypeName($0.type), color: hexColor($0.cgColor), allowsModifications: $0.allowsContentModifications)
         }
         let encoder = JSONEncoder()
         if let data = try? encoder.encode(items), let text = String(data: data, encoding: .utf8) {
@@ -603,10 +586,14 @@ if let (command, opts) = parseArgs(args) {
         eprintln("invalid --format value: \(format)")
         exit(2)
     }
-    if command == "status" && format == "table" {
-        eprintln("status does not support table output")
+    if (command == "status" || command == "version") && format == "table" {
+        eprintln("\(command) does not support table output")
         exit(2)
     }
+    if command == "version" {
+        outputVersion(format: format)
+        exit(0)
+    }
     if command == "status" {
         outputStatus(EKEventStore.authorizationStatus(for: .event), format: format)
         exit(0)
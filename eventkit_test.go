@@ -5,22 +5,60 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 )
 
+// sourceHelperVersion is the version --json answer of a helper compiled
+// from eventKitHelperSource, read from the Swift declarations.
+func sourceHelperVersion(t *testing.T) helperVersion {
+	t.Helper()
+	v := helperVersion{Protocol: eventKitHelperProtocol, SourceHash: eventKitHelperHash(), Flags: map[string]int{}}
+	if !strings.Contains(eventKitHelperSource, "let helperProtocol = "+strconv.Itoa(eventKitHelperProtocol)+"\n") {
+		t.Fatalf("helperProtocol in the Swift source differs from eventKitHelperProtocol")
+	}
+	commands := regexp.MustCompile(`let helperCommands = \[(.*)\]`).FindStringSubmatch(eventKitHelperSource)
+	if commands == nil {
+		t.Fatalf("helperCommands not found in the Swift source")
+	}
+	for _, m := range regexp.MustCompile(`"(\w+)"`).FindAllStringSubmatch(commands[1], -1) {
+		v.Commands = append(v.Commands, m[1])
+	}
+	for _, m := range regexp.MustCompile(`"(--[a-z-]+)": (\d)`).FindAllStringSubmatch(eventKitHelperSource, -1) {
+		v.Flags[m[1]], _ = strconv.Atoi(m[2])
+	}
+	return v
+}
+
+// withHandshake makes a stub helper script answer version --json like the
+// real helper before running the rest of the script.
+func withHandshake(t *testing.T, script string) []byte {
+	t.Helper()
+	answer, err := json.Marshal(sourceHelperVersion(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shebang, body, _ := strings.Cut(script, "\n")
+	return []byte(shebang + "\nif [ \"$1\" = version ]; then echo '" + string(answer) + "'; exit 0; fi\n" + body)
+}
+
 func TestCmdEventKitCalendarsHelperOverride(t *testing.T) {
+	isolateHelperEnv(t)
 	helper := filepath.Join(t.TempDir(), "helper.sh")
 	script := "#!/bin/sh\nprintf '%s\n' \"$@\"\n"
-	if err := os.WriteFile(helper, []byte(script), 0o755); err != nil {
+	if err := os.WriteFile(helper, withHandshake(t, script), 0o755); err != nil {
 		t.Fatalf("write helper: %v", err)
 	}
 	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
 
 	var out, errOut bytes.Buffer
-	if err := cmdEventKit([]string{"calendars", "--plain"}, &out, &errOut); err != nil {
+	if err := cmdEventKit(context.Background(), []string{"calendars", "--plain"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	output := out.String()
@@ -33,51 +71,49 @@ func TestCmdEventKitCalendarsHelperOverride(t *testing.T) {
 }
 
 func TestCmdEventKitEventsArgs(t *testing.T) {
+	isolateHelperEnv(t)
 	helper := filepath.Join(t.TempDir(), "helper.sh")
-	script := "#!/bin/sh\nprintf '%s\n' \"$@\"\n"
-	if err := os.WriteFile(helper, []byte(script), 0o755); err != nil {
+	script := "#!/bin/sh\n[ \"$1\" = events ] || exit 1\nprintf '%s\\n' \"$@\" >&2\necho '" + testEventsJSON + "'\n"
+	if err := os.WriteFile(helper, withHandshake(t, script), 0o755); err != nil {
 		t.Fatalf("write helper: %v", err)
 	}
 	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
 
 	var out, errOut bytes.Buffer
-	args := []string{"events", "--format", "json", "--calendar", "Work", "--calendar-id", "abc123", "--from", "2026-01-03", "--sort", "title", "--query", "standup", "--refresh", "--wait", "10", "--interval", "2"}
-	if err := cmdEventKit(args, &out, &errOut); err != nil {
+	args := []string{"events", "--format", "plain", "--calendar", "Work", "--calendar-id", "abc123", "--from", "2026-01-03", "--sort", "title", "--query", "standup", "--refresh", "--wait", "10", "--interval", "2", "--tz", "Europe/Zagreb"}
+	if err := cmdEventKit(context.Background(), args, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	output := out.String()
-	if !strings.Contains(output, "events") {
-		t.Fatalf("expected subcommand in output: %q", output)
-	}
-	if !strings.Contains(output, "--calendar") || !strings.Contains(output, "Work") {
-		t.Fatalf("expected calendar args in output: %q", output)
-	}
-	if !strings.Contains(output, "--calendar-id") || !strings.Contains(output, "abc123") {
-		t.Fatalf("expected calendar-id args in output: %q", output)
+	helperArgs := errOut.String()
+	if !strings.HasPrefix(helperArgs, "events\n--format\njson\n") {
+		t.Fatalf("expected events --format json: %q", helperArgs)
 	}
-	if !strings.Contains(output, "--from") || !strings.Contains(output, "2026-01-03") {
-		t.Fatalf("expected from args in output: %q", output)
+	if !strings.Contains(helperArgs, "--calendar\nWork\n") {
+		t.Fatalf("expected calendar args: %q", helperArgs)
 	}
-	if !strings.Contains(output, "--sort") || !strings.Contains(output, "title") {
-		t.Fatalf("expected sort args in output: %q", output)
+	if !strings.Contains(helperArgs, "--calendar-id\nabc123\n") {
+		t.Fatalf("expected calendar-id args: %q", helperArgs)
 	}
-	if !strings.Contains(output, "--query") || !strings.Contains(output, "standup") {
-		t.Fatalf("expected query args in output: %q", output)
+	if !strings.Contains(helperArgs, "--from\n2026-01-03T00:00:00+01:00\n--to\n2026-01-03T23:59:59+01:00\n") {
+		t.Fatalf("expected absolute from/to args: %q", helperArgs)
 	}
-	if !strings.Contains(output, "--refresh") {
-		t.Fatalf("expected refresh arg in output: %q", output)
+	if !strings.Contains(helperArgs, "--refresh") {
+		t.Fatalf("expected refresh arg: %q", helperArgs)
 	}
-	if !strings.Contains(output, "--wait") || !strings.Contains(output, "10") {
-		t.Fatalf("expected wait args in output: %q", output)
+	// Sorting, filtering, paging and polling happen in Go.
+	for _, flag := range []string{"--sort", "--query", "--limit", "--wait", "--interval"} {
+		if strings.Contains(helperArgs, flag+"\n") {
+			t.Fatalf("%s must not reach the helper: %q", flag, helperArgs)
+		}
 	}
-	if !strings.Contains(output, "--interval") || !strings.Contains(output, "2") {
-		t.Fatalf("expected interval args in output: %q", output)
+	if got := out.String(); got != "2026-10-19 09:15\t2026-10-19 09:30\tWork (Exchange)\tStandup\n" {
+		t.Fatalf("unexpected output: %q", got)
 	}
 }
 
 func TestCmdEventKitMissingSubcommand(t *testing.T) {
 	var out, errOut bytes.Buffer
-	if err := cmdEventKit([]string{}, &out, &errOut); err == nil {
+	if err := cmdEventKit(context.Background(), []string{}, &out, &errOut); err == nil {
 		t.Fatalf("expected error")
 	}
 	if !strings.Contains(errOut.String(), "USAGE") {
@@ -86,15 +122,16 @@ func TestCmdEventKitMissingSubcommand(t *testing.T) {
 }
 
 func TestCmdEventKitStatusArgs(t *testing.T) {
+	isolateHelperEnv(t)
 	helper := filepath.Join(t.TempDir(), "helper.sh")
 	script := "#!/bin/sh\nprintf '%s\n' \"$@\"\n"
-	if err := os.WriteFile(helper, []byte(script), 0o755); err != nil {
+	if err := os.WriteFile(helper, withHandshake(t, script), 0o755); err != nil {
 		t.Fatalf("write helper: %v", err)
 	}
 	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
 
 	var out, errOut bytes.Buffer
-	if err := cmdEventKit([]string{"status", "--format", "json"}, &out, &errOut); err != nil {
+	if err := cmdEventKit(context.Background(), []string{"status", "--format", "json"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	output := out.String()
@@ -0,0 +1,214 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type eventKitCreateOptions struct {
+	format   string
+	json     bool
+	plain    bool
+	verbose  bool
+	calendar string
+	title    string
+	start    string
+	end      string
+	duration time.Duration
+	location string
+	notes    string
+	timezone string
+	backend  string
+	config   string
+}
+
+type eventKitDeleteOptions struct {
+	json     bool
+	verbose  bool
+	calendar string
+	backend  string
+	config   string
+}
+
+// eventDeleteResult is the output of eventkit delete --json.
+type eventDeleteResult struct {
+	ID       string `json:"id"`
+	Calendar string `json:"calendar"`
+	Backend  string `json:"backend"`
+}
+
+func newEventKitCreateFlagSet(w io.Writer) (*flag.FlagSet, *eventKitCreateOptions) {
+	opts := &eventKitCreateOptions{}
+	fs := flag.NewFlagSet("eventkit create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	bindFlags(fs, "eventkit create", map[string]any{
+		"format":   &opts.format,
+		"json":     &opts.json,
+		"plain":    &opts.plain,
+		"verbose":  &opts.verbose,
+		"calendar": &opts.calendar,
+		"title":    &opts.title,
+		"start":    &opts.start,
+		"end":      &opts.end,
+		"duration": &opts.duration,
+		"location": &opts.location,
+		"notes":    &opts.notes,
+		"tz":       &opts.timezone,
+		"backend":  &opts.backend,
+		"config":   &opts.config,
+	})
+
+	fs.Usage = func() {
+		fmt.Fprint(w, "USAGE:\n  fantastical eventkit create --backend <backend> --calendar <name> --title <text> --start <datetime> [flags]\n")
+		printFlagHelp(w, "eventkit create")
+		fmt.Fprintln(w, "\nNOTES:\n  Writes to the ics (vdir), caldav or fixture backend. EventKit is read-only here; use fantastical parse.\n  A date-only --start creates an all-day event; its --end is the last day.")
+	}
+
+	return fs, opts
+}
+
+func newEventKitDeleteFlagSet(w io.Writer) (*flag.FlagSet, *eventKitDeleteOptions) {
+	opts := &eventKitDeleteOptions{}
+	fs := flag.NewFlagSet("eventkit delete", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	bindFlags(fs, "eventkit delete", map[string]any{
+		"json":     &opts.json,
+		"verbose":  &opts.verbose,
+		"calendar": &opts.calendar,
+		"backend":  &opts.backend,
+		"config":   &opts.config,
+	})
+
+	fs.Usage = func() {
+		fmt.Fprint(w, "USAGE:\n  fantastical eventkit delete --backend <backend> --calendar <name> <event-id>\n")
+		printFlagHelp(w, "eventkit delete")
+		fmt.Fprintln(w, "\nNOTES:\n  Removes the event with this id (an ics/CalDAV UID), with all of its occurrences.")
+	}
+
+	return fs, opts
+}
+
+func cmdEventKitCreate(ctx context.Context, args []string, out, errOut io.Writer) error {
+	fs, opts := newEventKitCreateFlagSet(errOut)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	format, err := resolveEventKitFormat(opts.format, opts.json, opts.plain, map[string]bool{
+		"plain": true,
+		"json":  true,
+		"table": true,
+	})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(opts.calendar) == "" || strings.TrimSpace(opts.start) == "" {
+		fs.Usage()
+		return fmt.Errorf("%w: --calendar and --start are required", errUsage)
+	}
+	if opts.end != "" && opts.duration != 0 {
+		return fmt.Errorf("%w: --end and --duration are mutually exclusive", errUsage)
+	}
+	if opts.duration < 0 {
+		return fmt.Errorf("%w: --duration must be positive", errUsage)
+	}
+
+	loc := time.Local
+	if tz := strings.TrimSpace(opts.timezone); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", errUsage, tz)
+		}
+	}
+	ev := newEvent{Title: opts.title, Location: opts.location, Notes: opts.notes}
+	if ev.Start, ev.AllDay, err = parseEventBound(opts.start, loc); err != nil {
+		return err
+	}
+	if opts.end != "" {
+		end, dateOnly, err := parseEventBound(opts.end, loc)
+		if err != nil {
+			return err
+		}
+		if dateOnly != ev.AllDay {
+			return fmt.Errorf("%w: --start and --end must both be dates or both include a time", errUsage)
+		}
+		if ev.AllDay {
+			end = end.AddDate(0, 0, 1)
+		}
+		ev.End = end
+	} else if opts.duration > 0 {
+		ev.End = ev.Start.Add(opts.duration)
+	}
+
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return err
+	}
+	ev.Calendar = applyNameAlias(opts.calendar, cfg.Calendars.Aliases)
+	backend, err := resolveBackend(opts.backend, cfg, errOut, opts.verbose)
+	if err != nil {
+		return err
+	}
+	created, err := backend.CreateEvent(ctx, ev)
+	if err != nil {
+		return err
+	}
+	logVerbose(errOut, opts.verbose, "created %s in %s (%s backend)", created.ID, created.Calendar, backend.Name())
+	if format == "json" {
+		return writeJSON(out, created)
+	}
+	return writeEvents(out, []eventInfo{created}, format, loc)
+}
+
+func cmdEventKitDelete(ctx context.Context, args []string, out, errOut io.Writer) error {
+	fs, opts := newEventKitDeleteFlagSet(errOut)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 1 || strings.TrimSpace(opts.calendar) == "" {
+		fs.Usage()
+		return fmt.Errorf("%w: expected --calendar and one event id", errUsage)
+	}
+	id := fs.Arg(0)
+
+	cfg, err := loadConfigWithPath(opts.config)
+	if err != nil {
+		return err
+	}
+	calendar := applyNameAlias(opts.calendar, cfg.Calendars.Aliases)
+	backend, err := resolveBackend(opts.backend, cfg, errOut, opts.verbose)
+	if err != nil {
+		return err
+	}
+	if err := backend.DeleteEvent(ctx, calendar, id); err != nil {
+		return err
+	}
+	if opts.json {
+		return writeJSON(out, eventDeleteResult{ID: id, Calendar: calendar, Backend: backend.Name()})
+	}
+	_, err = fmt.Fprintf(out, "deleted %s from %s\n", id, calendar)
+	return err
+}
@@ -0,0 +1,122 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeVdirCollection creates an empty vdir collection shown as title.
+func writeVdirCollection(t *testing.T, title string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), strings.ToLower(title))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "displayname"), []byte(title+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCmdEventKitCreateAndDelete(t *testing.T) {
+	writeAliasConfig(t, `{"calendars": {"aliases": {"h": "Home"}}}`)
+	schemas := publishedSchemas(t)
+	backend := "ics:" + writeVdirCollection(t, "Home")
+
+	var out, errOut bytes.Buffer
+	err := cmdEventKit(context.Background(), []string{"create", "--backend", backend, "--json", "--calendar", "h", "--title", "Dentist",
+		"--start", "2026-10-23T10:00", "--duration", "45m", "--location", "Ilica 1", "--tz", "Europe/Zagreb"}, &out, &errOut)
+	if err != nil {
+		t.Fatalf("create: %v (%s)", err, errOut.String())
+	}
+	var created eventInfo
+	var doc any
+	if err := json.Unmarshal(out.Bytes(), &created); err != nil || json.Unmarshal(out.Bytes(), &doc) != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if errs := validateAgainstSchema("$", schemas["eventkit create"], doc); len(errs) > 0 {
+		t.Fatalf("create output does not match its schema: %v", errs)
+	}
+	zagreb, _ := time.LoadLocation("Europe/Zagreb")
+	if created.Calendar != "Home" || !created.Start.Equal(time.Date(2026, 10, 23, 10, 0, 0, 0, zagreb)) || created.End.Sub(created.Start) != 45*time.Minute || created.Location != "Ilica 1" {
+		t.Fatalf("unexpected event: %+v", created)
+	}
+
+	out.Reset()
+	if err := cmdEventKit(context.Background(), []string{"events", "--backend", backend, "--json", "--from", "2026-10-23", "--to", "2026-10-24", "--calendar", "Home"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), created.ID) {
+		t.Fatalf("expected the new event listed: %s", out.String())
+	}
+
+	out.Reset()
+	if err := cmdEventKit(context.Background(), []string{"delete", "--backend", backend, "--json", "--calendar", "Home", created.ID}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if errs := validateAgainstSchema("$", schemas["eventkit delete"], doc); len(errs) > 0 || !strings.Contains(out.String(), `"backend":"ics"`) {
+		t.Fatalf("unexpected delete output %s: %v", out.String(), errs)
+	}
+	err = cmdEventKit(context.Background(), []string{"delete", "--backend", backend, "--calendar", "Home", created.ID}, &out, &errOut)
+	if !errors.Is(err, errEventNotFound) {
+		t.Fatalf("expected the event gone, got %v", err)
+	}
+}
+
+func TestCmdEventKitCreateAllDayInVdir(t *testing.T) {
+	setupTestEnv(t)
+	dir := writeVdirCollection(t, "Personal")
+
+	var out, errOut bytes.Buffer
+	err := cmdEventKit(context.Background(), []string{"create", "--backend", "ics:" + dir, "--calendar", "Personal", "--title", "Trip", "--start", "2026-10-20", "--end", "2026-10-22"}, &out, &errOut)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if out.String() != "2026-10-20 00:00\t2026-10-23 00:00\tPersonal\tTrip\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.ics"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .ics file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "DTSTART;VALUE=DATE:20261020") || !strings.Contains(string(data), "DTEND;VALUE=DATE:20261023") {
+		t.Fatalf("unexpected event file:\n%s", data)
+	}
+}
+
+func TestCmdEventKitCreateErrors(t *testing.T) {
+	setupTestEnv(t)
+	backend := "ics:" + writeVdirCollection(t, "Home")
+	cases := [][]string{
+		{"create", "--backend", backend, "--title", "No calendar", "--start", "2026-10-23T10:00"},
+		{"create", "--backend", backend, "--calendar", "Home", "--start", "2026-10-23T10:00"},
+		{"create", "--backend", backend, "--calendar", "Home", "--title", "x", "--start", "2026-10-23T10:00", "--end", "2026-10-23T11:00", "--duration", "1h"},
+		{"create", "--backend", backend, "--calendar", "Home", "--title", "x", "--start", "2026-10-23", "--end", "2026-10-24T11:00"},
+		{"create", "--backend", backend, "--calendar", "Nope", "--title", "x", "--start", "2026-10-23"},
+		{"delete", "--backend", backend, "--calendar", "Home"},
+	}
+	for _, args := range cases {
+		if err := cmdEventKit(context.Background(), args, &bytes.Buffer{}, &bytes.Buffer{}); !errors.Is(err, errUsage) {
+			t.Fatalf("%v: expected a usage error, got %v", args, err)
+		}
+	}
+
+	err := cmdEventKit(context.Background(), []string{"create", "--calendar", "Home", "--title", "x", "--start", "2026-10-23"}, &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.Is(err, errBackendUnsupported) || !strings.Contains(err.Error(), "use fantastical parse") {
+		t.Fatalf("expected EventKit to refuse writes, got %v", err)
+	}
+}
@@ -0,0 +1,594 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// eventQuery is the Go-side filter, sort and paging applied to the helper's
+// raw events.
+type eventQuery struct {
+	query  string
+	where  eventMatcher
+	sort   []eventSortKey
+	offset int
+	limit  int
+}
+
+// apply filters, sorts and pages events. The input slice is not modified.
+func (q eventQuery) apply(events []eventInfo) []eventInfo {
+	needle := strings.ToLower(strings.TrimSpace(q.query))
+	result := make([]eventInfo, 0, len(events))
+	for _, e := range events {
+		if needle != "" && !strings.Contains(strings.ToLower(e.Title), needle) &&
+			!strings.Contains(strings.ToLower(e.Location), needle) && !strings.Contains(strings.ToLower(e.Notes), needle) {
+			continue
+		}
+		if q.where != nil && !q.where(e) {
+			continue
+		}
+		result = append(result, e)
+	}
+	sortEvents(result, q.sort)
+	if q.offset > 0 {
+		if q.offset >= len(result) {
+			return []eventInfo{}
+		}
+		result = result[q.offset:]
+	}
+	if q.limit > 0 && len(result) > q.limit {
+		result = result[:q.limit]
+	}
+	return result
+}
+
+// eventSortKey is one --sort key; desc comes from a leading "-".
+type eventSortKey struct {
+	field string
+	desc  bool
+}
+
+var eventSortFields = []string{"start", "end", "title", "calendar", "location", "duration"}
+
+// parseEventSort parses "calendar,-start". Ties fall back to start, then
+// title, so output is deterministic.
+func parseEventSort(spec string) ([]eventSortKey, error) {
+	var keys []eventSortKey
+	for _, raw := range strings.Split(spec, ",") {
+		name := strings.ToLower(strings.TrimSpace(raw))
+		if name == "" {
+			continue
+		}
+		key := eventSortKey{}
+		switch name[0] {
+		case '-':
+			key.desc, name = true, name[1:]
+		case '+':
+			name = name[1:]
+		}
+		if !containsFold(eventSortFields, name) {
+			return nil, fmt.Errorf("%w: invalid --sort key %q (want %s, optionally prefixed with -)", errUsage, raw, strings.Join(eventSortFields, ", "))
+		}
+		key.field = name
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func sortEvents(events []eventInfo, keys []eventSortKey) {
+	keys = append(append([]eventSortKey(nil), keys...), eventSortKey{field: "start"}, eventSortKey{field: "title"})
+	sort.SliceStable(events, func(i, j int) bool {
+		for _, key := range keys {
+			c := compareEventField(events[i], events[j], key.field)
+			if c == 0 {
+				continue
+			}
+			if key.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compareEventField(a, b eventInfo, field string) int {
+	switch field {
+	case "start":
+		return a.Start.Compare(b.Start)
+	case "end":
+		return a.End.Compare(b.End)
+	case "duration":
+		return cmp.Compare(a.End.Sub(a.Start), b.End.Sub(b.Start))
+	case "title":
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case "calendar":
+		return strings.Compare(strings.ToLower(a.Calendar), strings.ToLower(b.Calendar))
+	case "location":
+		return strings.Compare(strings.ToLower(a.Location), strings.ToLower(b.Location))
+	}
+	return 0
+}
+
+// eventMatcher reports whether an event passes a --where expression.
+type eventMatcher func(eventInfo) bool
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	timeField
+	durationField
+	boolField
+)
+
+// eventField describes a field usable in --where.
+type eventField struct {
+	kind fieldKind
+	text func(eventInfo) string
+	time func(eventInfo) time.Time
+	flag func(eventInfo) bool
+}
+
+var eventFields = map[string]eventField{
+	"id":         {kind: textField, text: func(e eventInfo) string { return e.ID }},
+	"title":      {kind: textField, text: func(e eventInfo) string { return e.Title }},
+	"calendar":   {kind: textField, text: func(e eventInfo) string { return e.Calendar }},
+	"calendarid": {kind: textField, text: func(e eventInfo) string { return e.CalendarID }},
+	"location":   {kind: textField, text: func(e eventInfo) string { return e.Location }},
+	"notes":      {kind: textField, text: func(e eventInfo) string { return e.Notes }},
+	"start":      {kind: timeField, time: func(e eventInfo) time.Time { return e.Start }},
+	"end":        {kind: timeField, time: func(e eventInfo) time.Time { return e.End }},
+	"duration":   {kind: durationField},
+	"allday":     {kind: boolField, flag: func(e eventInfo) bool { return e.AllDay }},
+}
+
+// whereFieldNames lists the --where fields for help and errors.
+const whereFieldNames = "title, calendar, calendarId, location, notes, id, start, end, duration, allDay"
+
+type whereTokenKind int
+
+const (
+	tokWord whereTokenKind = iota
+	tokString
+	tokRegex
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokEOF
+)
+
+type whereToken struct {
+	kind whereTokenKind
+	text string
+	pos  int
+}
+
+// lexWhere splits a --where expression into words, quoted strings, /regex/
+// literals, comparison operators, parentheses and commas.
+func lexWhere(expr string) ([]whereToken, error) {
+	var tokens []whereToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, whereToken{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, whereToken{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, whereToken{tokComma, ",", i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			tokens = append(tokens, whereToken{tokString, b.String(), start})
+		case r == '/':
+			start := i
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != '/'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '/' {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated regex at offset %d", start)
+			}
+			i++
+			flags := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, whereToken{tokRegex, b.String() + "/" + string(runes[flags:i]), start})
+		case strings.ContainsRune("=!~<>", r):
+			start := i
+			i++
+			if i < len(runes) && (runes[i] == '=' || (r == '!' && runes[i] == '~')) {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", op, start)
+			}
+			tokens = append(tokens, whereToken{tokOp, op, start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("(),=!~<>\"'", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, whereToken{tokWord, string(runes[start:i]), start})
+		}
+	}
+	return append(tokens, whereToken{tokEOF, "", len(runes)}), nil
+}
+
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+	dates  dateContext
+}
+
+// parseWhere compiles a --where expression such as
+//
+//	calendar in (Work, Team) and duration >= 30m and title ~ /standup/i and not allDay
+//
+// Date values are date expressions resolved in c; a date-only value stands
+// for the whole day, so "start = today" matches any time today.
+func parseWhere(expr string, c dateContext) (eventMatcher, error) {
+	tokens, err := lexWhere(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid --where: %v", errUsage, err)
+	}
+	p := &whereParser{tokens: tokens, dates: c}
+	m, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid --where: %v", errUsage, err)
+	}
+	return m, nil
+}
+
+func (p *whereParser) peek() whereToken { return p.tokens[p.pos] }
+
+func (p *whereParser) next() whereToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *whereParser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *whereParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s at offset %d", fmt.Sprintf(format, args...), p.peek().pos)
+}
+
+func (p *whereParser) parseOr() (eventMatcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e eventInfo) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseAnd() (eventMatcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e eventInfo) bool { return l(e) && right(e) }
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseUnary() (eventMatcher, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e eventInfo) bool { return !inner(e) }, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.errorf("missing )")
+		}
+		p.next()
+		return inner, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *whereParser) parseCondition() (eventMatcher, error) {
+	t := p.peek()
+	if t.kind != tokWord {
+		return nil, p.errorf("expected a field (%s)", whereFieldNames)
+	}
+	name := strings.ReplaceAll(strings.ToLower(t.text), "_", "")
+	field, ok := eventFields[name]
+	if !ok {
+		return nil, p.errorf("unknown field %q (want %s)", t.text, whereFieldNames)
+	}
+	p.next()
+
+	op := ""
+	switch next := p.peek(); {
+	case next.kind == tokOp:
+		op = p.next().text
+	case next.kind == tokWord && strings.EqualFold(next.text, "in"):
+		p.next()
+		op = "in"
+	case next.kind == tokWord && strings.EqualFold(next.text, "not") && p.tokens[p.pos+1].kind == tokWord && strings.EqualFold(p.tokens[p.pos+1].text, "in"):
+		p.pos += 2
+		op = "not in"
+	}
+	if op == "" {
+		if field.kind != boolField {
+			return nil, p.errorf("expected an operator after %q", t.text)
+		}
+		return field.flag, nil
+	}
+
+	switch field.kind {
+	case textField:
+		return p.textCondition(field.text, op)
+	case timeField:
+		return p.timeCondition(field.time, op)
+	case durationField:
+		return p.durationCondition(op)
+	default:
+		return p.boolCondition(field.flag, op)
+	}
+}
+
+// value reads a quoted string, or unquoted words up to the next and/or, so
+// values may contain spaces: calendar = Work Team, start >= next monday 9am.
+func (p *whereParser) value() (string, error) {
+	t := p.peek()
+	if t.kind != tokWord && t.kind != tokString {
+		return "", p.errorf("expected a value")
+	}
+	p.next()
+	if t.kind == tokString {
+		return t.text, nil
+	}
+	v := t.text
+	for next := p.peek(); next.kind == tokWord && !strings.EqualFold(next.text, "and") && !strings.EqualFold(next.text, "or"); next = p.peek() {
+		v += " " + p.next().text
+	}
+	return v, nil
+}
+
+func (p *whereParser) list() ([]string, error) {
+	if p.peek().kind != tokLParen {
+		return nil, p.errorf("expected ( after in")
+	}
+	p.next()
+	var values []string
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		// Unquoted list items may contain spaces: (Work Team, Home).
+		for p.peek().kind == tokWord {
+			v += " " + p.next().text
+		}
+		values = append(values, v)
+		if p.peek().kind == tokComma {
+			p.next()
+			continue
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.errorf("expected , or )")
+		}
+		p.next()
+		return values, nil
+	}
+}
+
+func (p *whereParser) textCondition(get func(eventInfo) string, op string) (eventMatcher, error) {
+	switch op {
+	case "in", "not in":
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		negate := op == "not in"
+		return func(e eventInfo) bool { return containsFold(values, get(e)) != negate }, nil
+	case "=", "!=":
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		negate := op == "!="
+		return func(e eventInfo) bool { return strings.EqualFold(get(e), v) != negate }, nil
+	case "~", "!~":
+		negate := op == "!~"
+		if p.peek().kind == tokRegex {
+			re, err := compileWhereRegex(p.peek().text)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			p.next()
+			return func(e eventInfo) bool { return re.MatchString(get(e)) != negate }, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		needle := strings.ToLower(v)
+		return func(e eventInfo) bool { return strings.Contains(strings.ToLower(get(e)), needle) != negate }, nil
+	}
+	return nil, p.errorf("operator %q does not apply to text fields (use =, !=, ~, !~, in)", op)
+}
+
+// compileWhereRegex compiles "pattern/flags" as produced by lexWhere.
+func compileWhereRegex(literal string) (*regexp.Regexp, error) {
+	i := strings.LastIndex(literal, "/")
+	pattern, flags := literal[:i], literal[i+1:]
+	for _, f := range flags {
+		if !strings.ContainsRune("imsU", f) {
+			return nil, fmt.Errorf("unknown regex flag %q", f)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %v", err)
+	}
+	return re, nil
+}
+
+func (p *whereParser) timeCondition(get func(eventInfo) time.Time, op string) (eventMatcher, error) {
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	t, dateOnly, err := p.dates.parse(v)
+	if err != nil {
+		return nil, p.errorf("%v", strings.TrimPrefix(err.Error(), errUsage.Error()+": "))
+	}
+	// The value covers [lo, hi): a whole day for date-only values.
+	lo, hi := t, t.Add(time.Nanosecond)
+	if dateOnly {
+		hi = t.AddDate(0, 0, 1)
+	}
+	var test func(time.Time) bool
+	switch op {
+	case "=":
+		test = func(x time.Time) bool { return !x.Before(lo) && x.Before(hi) }
+	case "!=":
+		test = func(x time.Time) bool { return x.Before(lo) || !x.Before(hi) }
+	case "<":
+		test = func(x time.Time) bool { return x.Before(lo) }
+	case "<=":
+		test = func(x time.Time) bool { return x.Before(hi) }
+	case ">":
+		test = func(x time.Time) bool { return !x.Before(hi) }
+	case ">=":
+		test = func(x time.Time) bool { return !x.Before(lo) }
+	default:
+		return nil, p.errorf("operator %q does not apply to dates (use =, !=, <, <=, >, >=)", op)
+	}
+	return func(e eventInfo) bool { return test(get(e)) }, nil
+}
+
+func (p *whereParser) durationCondition(op string) (eventMatcher, error) {
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseWhereDuration(v)
+	if err != nil {
+		return nil, p.errorf("invalid duration %q (want e.g. 30m, 1h30m, 2d)", v)
+	}
+	var test func(time.Duration) bool
+	switch op {
+	case "=":
+		test = func(x time.Duration) bool { return x == d }
+	case "!=":
+		test = func(x time.Duration) bool { return x != d }
+	case "<":
+		test = func(x time.Duration) bool { return x < d }
+	case "<=":
+		test = func(x time.Duration) bool { return x <= d }
+	case ">":
+		test = func(x time.Duration) bool { return x > d }
+	case ">=":
+		test = func(x time.Duration) bool { return x >= d }
+	default:
+		return nil, p.errorf("operator %q does not apply to durations (use =, !=, <, <=, >, >=)", op)
+	}
+	return func(e eventInfo) bool { return test(e.End.Sub(e.Start)) }, nil
+}
+
+// parseWhereDuration accepts Go durations plus whole days ("2d").
+func parseWhereDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration")
+	}
+	return d, nil
+}
+
+func (p *whereParser) boolCondition(get func(eventInfo) bool, op string) (eventMatcher, error) {
+	if op != "=" && op != "!=" {
+		return nil, p.errorf("operator %q does not apply to allDay (use =, !=)", op)
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	want, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, p.errorf("expected true or false, got %q", v)
+	}
+	if op == "!=" {
+		want = !want
+	}
+	return func(e eventInfo) bool { return get(e) == want }, nil
+}
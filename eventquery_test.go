@@ -0,0 +1,168 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// queryTestEvents is a week of events on Monday 2026-10-19 .. Wednesday.
+func queryTestEvents() []eventInfo {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+	return []eventInfo{
+		{ID: "standup-mon", Title: "Daily Standup", Calendar: "Work", Start: at(19, 9, 0), End: at(19, 9, 15)},
+		{ID: "design", Title: "Design review", Calendar: "Team", Location: "Room 4", Start: at(19, 10, 0), End: at(19, 11, 0)},
+		{ID: "lunch", Title: "Lunch", Calendar: "Home", Location: "Cafe Nero", Start: at(19, 12, 0), End: at(19, 13, 0)},
+		{ID: "standup-tue", Title: "daily standup", Calendar: "Work", Start: at(20, 9, 0), End: at(20, 9, 15)},
+		{ID: "planning", Title: "Sprint planning", Calendar: "Work", Notes: "bring standup notes", Start: at(20, 14, 0), End: at(20, 16, 0)},
+		{ID: "holiday", Title: "Holiday", Calendar: "Team", AllDay: true, Start: at(21, 0, 0), End: at(22, 0, 0)},
+	}
+}
+
+func queryIDs(events []eventInfo) string {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestParseWhere(t *testing.T) {
+	c := newDateContext(time.Date(2026, time.October, 19, 8, 0, 0, 0, time.UTC), time.UTC)
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{"calendar = work", "standup-mon,standup-tue,planning"},
+		{"calendar != Work", "design,lunch,holiday"},
+		{"calendar in (Work, Team) and duration >= 30m and not allDay", "design,planning"},
+		{"calendar not in (Work)", "design,lunch,holiday"},
+		{"title ~ /standup/i", "standup-mon,standup-tue"},
+		{"title ~ /^Daily/", "standup-mon"},
+		{"title ~ review", "design"},
+		{"title !~ /standup/i and notes ~ standup", "planning"},
+		{"location = 'Cafe Nero'", "lunch"},
+		{`location ~ "room"`, "design"},
+		{"allDay", "holiday"},
+		{"allDay = false and calendar = Team", "design"},
+		{"start = today", "standup-mon,design,lunch"},
+		{"start = tomorrow and end <= '2026-10-20 12:00'", "standup-tue"},
+		{"start >= tomorrow", "standup-tue,planning,holiday"},
+		{"start > today and not allDay", "standup-tue,planning"},
+		{"start < 2026-10-19T10:30", "standup-mon,design"},
+		{"end <= today", "standup-mon,design,lunch"},
+		{"duration > 1h or allDay", "planning,holiday"},
+		{"duration = 1d", "holiday"},
+		{"(calendar = Home or calendar = Team) and location ~ o", "design,lunch"},
+		{"not (calendar = Work or allDay)", "design,lunch"},
+		{"calendarId = '' and id ~ standup", "standup-mon,standup-tue"},
+		{"location = Cafe Nero", "lunch"},
+		{"calendar = Work Team or calendar = Home", "lunch"},
+		{"start >= next monday or allDay", "holiday"},
+		{"start >= tomorrow 9am and not allDay", "standup-tue,planning"},
+		{"end < end of month and start >= tomorrow", "standup-tue,planning,holiday"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.where, func(t *testing.T) {
+			m, err := parseWhere(tc.where, c)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := queryIDs(eventQuery{where: m}.apply(queryTestEvents())); got != tc.want {
+				t.Fatalf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	c := newDateContext(time.Date(2026, time.October, 19, 8, 0, 0, 0, time.UTC), time.UTC)
+	for _, where := range []string{
+		"",
+		"colour = red",
+		"title",
+		"title <",
+		"title < b",
+		"duration ~ 1h",
+		"duration > soon",
+		"start = someday",
+		"start in (today)",
+		"allDay = maybe",
+		"title ~ /[/",
+		"title ~ /x/g",
+		"(calendar = Work",
+		"calendar in Work",
+		"calendar = Work and",
+		"calendar = Work title = x",
+		"start >= next week day",
+		"title = 'unterminated",
+		"title ! x",
+	} {
+		if _, err := parseWhere(where, c); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected usage error, got %v", where, err)
+		}
+	}
+}
+
+func TestEventQuerySortAndPage(t *testing.T) {
+	tests := []struct {
+		sort          string
+		offset, limit int
+		want          string
+	}{
+		{"", 0, 0, "standup-mon,design,lunch,standup-tue,planning,holiday"},
+		{"calendar,-start", 0, 0, "lunch,holiday,design,planning,standup-tue,standup-mon"},
+		{"-duration", 0, 2, "holiday,planning"},
+		{"title", 0, 0, "standup-mon,standup-tue,design,holiday,lunch,planning"},
+		{"-location,start", 0, 0, "design,lunch,standup-mon,standup-tue,planning,holiday"},
+		{"start", 2, 2, "lunch,standup-tue"},
+		{"start", 5, 10, "holiday"},
+		{"start", 6, 0, ""},
+	}
+	for _, tc := range tests {
+		keys, err := parseEventSort(tc.sort)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.sort, err)
+		}
+		q := eventQuery{sort: keys, offset: tc.offset, limit: tc.limit}
+		if got := queryIDs(q.apply(queryTestEvents())); got != tc.want {
+			t.Fatalf("sort %q offset %d limit %d: got %s, want %s", tc.sort, tc.offset, tc.limit, got, tc.want)
+		}
+	}
+
+	if got := queryIDs(eventQuery{query: "STANDUP"}.apply(queryTestEvents())); got != "standup-mon,standup-tue,planning" {
+		t.Fatalf("query: got %s", got)
+	}
+	for _, spec := range []string{"priority", "start,--end", "-"} {
+		if _, err := parseEventSort(spec); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected usage error, got %v", spec, err)
+		}
+	}
+}
+
+func TestWriteEventsTable(t *testing.T) {
+	var out bytes.Buffer
+	events := queryTestEvents()[:2]
+	if err := writeEvents(&out, events, "table", time.UTC); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := "Start             End               Calendar  Title        \n" +
+		"----------------  ----------------  --------  -------------\n" +
+		"2026-10-19 09:00  2026-10-19 09:15  Work      Daily Standup\n" +
+		"2026-10-19 10:00  2026-10-19 11:00  Team      Design review\n"
+	if out.String() != want {
+		t.Fatalf("unexpected table:\n%q\nwant:\n%q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeEvents(&out, nil, "json", time.UTC); err != nil || out.String() != "[]\n" {
+		t.Fatalf("empty json: %q, %v", out.String(), err)
+	}
+}
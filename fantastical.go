@@ -22,6 +22,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -53,29 +54,63 @@ func main() {
 }
 
 func run(args []string, in io.Reader, out, errOut io.Writer) int {
+	ctx := context.Background()
+	if len(args) > 1 {
+		global, rest, err := splitGlobalFlags(args[1:])
+		if err != nil {
+			fmt.Fprintln(errOut, "Error:", err)
+			return 2
+		}
+		args = append([]string{args[0]}, rest...)
+		var stop func()
+		ctx, stop = commandContext(ctx, global.timeout)
+		defer stop()
+	}
 	if len(args) < 2 {
 		usage(errOut)
 		return 2
 	}
 
+	if !isBuiltinCommand(args[1]) {
+		expanded, err := expandRunAlias(args[1:])
+		if err != nil {
+			fmt.Fprintln(errOut, "Error:", err)
+			if errors.Is(err, errUsage) {
+				return 2
+			}
+			return 1
+		}
+		args = append([]string{args[0]}, expanded...)
+	}
+
 	cmd := strings.ToLower(args[1])
 	var err error
 
 	switch cmd {
 	case "parse":
-		err = cmdParse(args[2:], in, out, errOut)
+		err = cmdParse(ctx, args[2:], in, out, errOut)
 	case "show":
-		err = cmdShow(args[2:], out, errOut)
+		err = cmdShow(ctx, args[2:], out, errOut)
 	case "applescript", "as":
-		err = cmdAppleScript(args[2:], in, out, errOut)
+		err = cmdAppleScript(ctx, args[2:], in, out, errOut)
 	case "validate":
-		err = cmdValidate(args[2:], in, out, errOut)
+		err = cmdValidate(ctx, args[2:], in, out, errOut)
 	case "doctor":
-		err = cmdDoctor(args[2:], out, errOut)
+		err = cmdDoctor(ctx, args[2:], out, errOut)
+	case "config":
+		err = cmdConfig(args[2:], out, errOut)
+	case "cache":
+		err = cmdCache(args[2:], out, errOut)
+	case completeCommand:
+		err = cmdComplete(args[2:], out, errOut)
 	case "eventkit":
-		err = cmdEventKit(args[2:], out, errOut)
+		err = cmdEventKit(ctx, args[2:], out, errOut)
+	case "mcp":
+		err = cmdMCP(ctx, args[2:], in, out, errOut)
+	case "serve":
+		err = cmdServe(ctx, args[2:], out, errOut)
 	case "greta":
-		err = cmdGreta(args[2:], out, errOut)
+		err = cmdGreta(ctx, args[2:], out, errOut)
 	case "explain":
 		err = cmdExplain(args[2:], out, errOut)
 	case "man":
@@ -94,6 +129,9 @@ func run(args []string, in io.Reader, out, errOut io.Writer) int {
 
 	if err != nil {
 		fmt.Fprintln(errOut, "Error:", err)
+		if code, ok := exitCodeFor(ctx); ok {
+			return code
+		}
 		if errors.Is(err, errUsage) {
 			return 2
 		}
@@ -107,28 +145,17 @@ func usage(w io.Writer) {
 	fmt.Fprint(w, `fantastical - CLI for Fantastical URL handler + AppleScript integration
 
 USAGE
-  fantastical [--version] <command> [flags] [args]
+  fantastical [--version] [--timeout duration] <command> [flags] [args]
 
 COMMANDS
-  parse        Build (and optionally open) x-fantastical3://parse?... URLs
-  show         Build (and optionally open) x-fantastical3://show/... URLs
-  applescript  Send "parse sentence" to Fantastical via osascript (macOS)
-  validate     Validate parse/show input and print the URL
-  doctor       Check Fantastical + macOS integration status
-  eventkit     List calendars or events via EventKit (system Calendar access)
-  greta        Machine-readable CLI spec for agents
-  explain      Human-readable command walkthrough
-  man          Manual page output (markdown or json)
-  completion   Print or install shell completion (bash|zsh|fish)
-  help         Show help for a command
-  version      Print version information
-
+`+commandListing()+`
 NOTES
   - macOS only (Fantastical is a macOS app).
   - --open defaults to true (uses "open <url>").
   - Use --json for machine-readable output; use --plain for stable text output.
   - For parse/applescript, put flags before the sentence or use -- to separate.
   - eventkit commands require Calendar access; macOS will prompt on first use.
+  - --timeout 30s (before the command) stops it and its subprocesses; exit 124.
 
 EXAMPLES
   fantastical parse --add --calendar "Work" --note "Alarm" "Wake up at 8am"
@@ -141,6 +168,8 @@ EXAMPLES
   fantastical eventkit status --json
   fantastical eventkit calendars --json
   fantastical eventkit events --next-week --calendar "Work"
+  fantastical --timeout 1m eventkit events --today --json
+  fantastical config show --json
   fantastical greta --format json
   fantastical help --json parse
   fantastical explain parse
@@ -172,9 +201,21 @@ func printSubcommandHelp(cmd string, w io.Writer) error {
 	case "doctor":
 		doctorUsage(w)
 		return nil
+	case "config":
+		configUsage(w)
+		return nil
+	case "cache":
+		cacheUsage(w)
+		return nil
 	case "eventkit":
 		eventKitUsage(w)
 		return nil
+	case "mcp":
+		mcpUsage(w)
+		return nil
+	case "serve":
+		serveUsage(w)
+		return nil
 	case "greta":
 		gretaUsage(w)
 		return nil
@@ -198,7 +239,7 @@ func cmdHelp(args []string, out, errOut io.Writer) error {
 	fs.SetOutput(io.Discard)
 
 	opts := helpOptions{}
-	fs.BoolVar(&opts.json, "json", false, "Print machine-readable JSON help")
+	bindFlags(fs, "help", map[string]any{"json": &opts.json})
 
 	fs.Usage = func() {
 		fmt.Fprint(errOut, "USAGE:\n  fantastical help [--json] [command]\n")
@@ -219,8 +260,10 @@ func cmdHelp(args []string, out, errOut io.Writer) error {
 		cmd = fs.Arg(0)
 	}
 
+	aliases := loadAliases(errOut)
+
 	if opts.json {
-		spec, err := helpSpec(cmd)
+		spec, err := helpSpec(cmd, aliases)
 		if err != nil {
 			return err
 		}
@@ -229,18 +272,48 @@ func cmdHelp(args []string, out, errOut io.Writer) error {
 
 	if cmd == "" {
 		usage(out)
+		printAliases(out, aliases)
+		return nil
+	}
+
+	if expansion, ok := aliases[cmd]; ok {
+		fmt.Fprintf(out, "%s is an alias for: %s\n", cmd, expansion)
 		return nil
 	}
 
 	return printSubcommandHelp(cmd, out)
 }
 
-func helpSpec(command string) (any, error) {
+func printAliases(w io.Writer, aliases map[string]string) {
+	if len(aliases) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nALIASES (from config)")
+	width := 0
+	for name := range aliases {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	for _, alias := range sortedAliases(aliases) {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, alias.Name, alias.Expansion)
+	}
+}
+
+func helpSpec(command string, aliases map[string]string) (any, error) {
 	spec := gretaSpec("v1")
+	spec["aliases"] = sortedAliases(aliases)
 	if strings.TrimSpace(command) == "" {
 		return spec, nil
 	}
 
+	if expansion, ok := aliases[strings.TrimSpace(command)]; ok {
+		return map[string]any{
+			"schemaVersion": spec["schemaVersion"],
+			"alias":         aliasInfo{Name: strings.TrimSpace(command), Expansion: expansion},
+		}, nil
+	}
+
 	cmd := strings.ToLower(strings.TrimSpace(command))
 	commands, ok := spec["commands"].([]map[string]any)
 	if !ok {
@@ -287,15 +360,41 @@ type outputOptions struct {
 	verbose bool
 }
 
+// parseResult is the output of parse --json.
+type parseResult struct {
+	Command  string `json:"command" enum:"parse"`
+	Sentence string `json:"sentence"`
+	URL      string `json:"url"`
+	Open     bool   `json:"open"`
+	Copy     bool   `json:"copy"`
+	DryRun   bool   `json:"dry_run"`
+	// Callback is set with --await, Verify with --verify.
+	Callback *callbackOutcome `json:"callback,omitempty"`
+	Verify   *verifyOutcome   `json:"verify,omitempty"`
+	// Parts is set when the sentence was composed from --title, --start, ...
+	Parts []sentencePart `json:"parts,omitempty"`
+	// Recorded is the event a fixture backend stored instead of opening
+	// Fantastical.
+	Recorded *eventInfo `json:"recorded,omitempty"`
+}
+
 type parseOptions struct {
 	outputOptions
-	note     string
-	calendar string
-	add      bool
-	stdin    bool
-	params   stringSlice
-	timezone string
-	config   string
+	note          string
+	calendar      string
+	add           bool
+	stdin         bool
+	params        stringSlice
+	timezone      string
+	config        string
+	noCache       bool
+	await         bool
+	timeout       time.Duration
+	verify        bool
+	verifyTimeout time.Duration
+	event         eventSpec
+	explain       bool
+	backend       string
 }
 
 func defaultOutputOptions(cfg *Config) outputOptions {
@@ -332,6 +431,8 @@ func defaultOutputOptions(cfg *Config) outputOptions {
 func defaultParseOptions(cfg *Config) parseOptions {
 	opts := parseOptions{
 		outputOptions: defaultOutputOptions(cfg),
+		timeout:       defaultAwaitTimeout,
+		verifyTimeout: defaultVerifyTimeout,
 	}
 	if cfg == nil {
 		return opts
@@ -354,28 +455,41 @@ func newParseFlagSet(w io.Writer, defaults parseOptions) (*flag.FlagSet, *parseO
 	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
 	fs.SetOutput(io.Discard) // we'll print our own usage on error/help
 
-	fs.StringVar(&opts.note, "note", opts.note, "Optional note (maps to n=...)")
-	fs.StringVar(&opts.note, "n", opts.note, "Alias for --note")
-
-	fs.StringVar(&opts.calendar, "calendar", opts.calendar, "Optional calendar name (maps to calendarName=...)")
-	fs.StringVar(&opts.calendar, "calendarName", opts.calendar, "Alias for --calendar")
-
-	fs.BoolVar(&opts.add, "add", opts.add, "Add immediately without interaction (maps to add=1)")
-	fs.BoolVar(&opts.open, "open", opts.open, "Open the generated URL via system opener")
-	fs.BoolVar(&opts.print, "print", opts.print, "Print the generated URL to stdout")
-	fs.BoolVar(&opts.copy, "copy", opts.copy, "Copy the generated URL to clipboard (pbcopy)")
-	fs.BoolVar(&opts.json, "json", opts.json, "Print machine-readable JSON output")
-	fs.BoolVar(&opts.plain, "plain", opts.plain, "Print stable plain-text output")
-	fs.BoolVar(&opts.dryRun, "dry-run", opts.dryRun, "Preview only; do not open or copy")
-	fs.BoolVar(&opts.verbose, "verbose", opts.verbose, "Verbose output to stderr")
-	fs.BoolVar(&opts.stdin, "stdin", false, "Read sentence from stdin instead of args")
-	fs.Var(&opts.params, "param", "Extra Fantastical query param (key=value), repeatable")
-	fs.StringVar(&opts.timezone, "timezone", "", "Timezone to pass as tz=... (IANA name)")
-	fs.StringVar(&opts.config, "config", "", "Config file path (overrides default user config)")
+	bindFlags(fs, "parse", map[string]any{
+		"note":           &opts.note,
+		"calendar":       &opts.calendar,
+		"add":            &opts.add,
+		"open":           &opts.open,
+		"print":          &opts.print,
+		"copy":           &opts.copy,
+		"json":           &opts.json,
+		"plain":          &opts.plain,
+		"dry-run":        &opts.dryRun,
+		"verbose":        &opts.verbose,
+		"stdin":          &opts.stdin,
+		"param":          &opts.params,
+		"timezone":       &opts.timezone,
+		"config":         &opts.config,
+		"no-cache":       &opts.noCache,
+		"await":          &opts.await,
+		"await-timeout":  &opts.timeout,
+		"verify":         &opts.verify,
+		"verify-timeout": &opts.verifyTimeout,
+		"title":          &opts.event.title,
+		"start":          &opts.event.start,
+		"end":            &opts.event.end,
+		"duration":       &opts.event.duration,
+		"location":       &opts.event.location,
+		"invitees":       &opts.event.invitees,
+		"repeat":         &opts.event.repeat,
+		"alert":          &opts.event.alert,
+		"explain":        &opts.explain,
+		"backend":        &opts.backend,
+	})
 
 	fs.Usage = func() {
 		fmt.Fprint(w, "USAGE:\n  fantastical parse [flags] <sentence...>\n")
-		fs.PrintDefaults()
+		printFlagHelp(w, "parse")
 		fmt.Fprintln(w, "\nEXAMPLE:\n  fantastical parse --add --calendar Work --note \"Alarm\" \"Wake up at 8am\"")
 		fmt.Fprintln(w, "NOTE:\n  Put flags before the sentence, or use -- to separate flags from the sentence.")
 	}
@@ -383,7 +497,7 @@ func newParseFlagSet(w io.Writer, defaults parseOptions) (*flag.FlagSet, *parseO
 	return fs, &opts
 }
 
-func cmdParse(args []string, in io.Reader, out, errOut io.Writer) error {
+func cmdParse(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
 	configPath, err := extractConfigPath(args)
 	if err != nil {
 		return err
@@ -408,12 +522,60 @@ func cmdParse(args []string, in io.Reader, out, errOut io.Writer) error {
 	if opts.json && opts.plain {
 		return fmt.Errorf("%w: --json and --plain are mutually exclusive", errUsage)
 	}
-
-	sentence, err := readSentence(fs.Args(), opts.stdin, in)
+	if opts.explain {
+		opts.dryRun = true
+	}
+	if opts.await && (opts.dryRun || !opts.open) {
+		return fmt.Errorf("%w: --await needs the URL to be opened (not with --dry-run or --open=false)", errUsage)
+	}
+	if opts.verify && (opts.dryRun || !opts.open) {
+		return fmt.Errorf("%w: --verify needs the URL to be opened (not with --dry-run or --open=false)", errUsage)
+	}
+	if opts.timeout <= 0 || opts.verifyTimeout <= 0 {
+		return fmt.Errorf("%w: --await-timeout and --verify-timeout must be positive", errUsage)
+	}
+	fixture, err := parseFixtureBackend(opts.backend, cfg)
 	if err != nil {
-		fs.Usage()
 		return err
 	}
+	if fixture != nil && (opts.await || opts.verify) {
+		return fmt.Errorf("%w: --await and --verify need Fantastical, not the fixture backend", errUsage)
+	}
+
+	structured := opts.event.isSet()
+	var sentence string
+	if structured {
+		if fs.NArg() > 0 || opts.stdin {
+			fs.Usage()
+			return fmt.Errorf("%w: structured flags (--title, --start, ...) cannot be combined with a sentence", errUsage)
+		}
+	} else {
+		sentence, err = readSentence(ctx, fs.Args(), opts.stdin, in)
+		if err != nil {
+			fs.Usage()
+			return err
+		}
+	}
+
+	if strings.TrimSpace(opts.calendar) != "" {
+		resolver, err := newCalendarResolver(cfg, opts.noCache, errOut, opts.verbose)
+		if err != nil {
+			return err
+		}
+		if fixture != nil {
+			resolver.useBackend(fixture)
+		}
+		// Previews (--dry-run, --explain, validate) and --open=false must not
+		// build or run the helper just to canonicalize a name.
+		if opts.dryRun || !opts.open {
+			resolver.cacheOnly()
+		}
+		ref, err := resolver.resolve(ctx, opts.calendar)
+		if err != nil {
+			return err
+		}
+		opts.calendar = ref.Title
+	}
 
 	extraParams, err := parseParams(opts.params)
 	if err != nil {
@@ -424,6 +586,20 @@ func cmdParse(args []string, in io.Reader, out, errOut io.Writer) error {
 		extraParams.Set("tz", strings.TrimSpace(opts.timezone))
 	}
 
+	var parts []sentencePart
+	if structured {
+		loc := time.Local
+		if tz := strings.TrimSpace(opts.timezone); tz != "" {
+			if loc, err = time.LoadLocation(tz); err != nil {
+				return fmt.Errorf("%w: unknown timezone %q", errUsage, tz)
+			}
+		}
+		if parts, err = composeSentence(opts.event, opts.calendar, loc); err != nil {
+			return err
+		}
+		sentence = sentenceFromParts(parts)
+	}
+
 	u := buildParseURL(sentence, opts.note, opts.calendar, opts.add, extraParams)
 
 	if opts.dryRun {
@@ -431,19 +607,72 @@ func cmdParse(args []string, in io.Reader, out, errOut io.Writer) error {
 		opts.copy = false
 	}
 
+	logConfigSources(errOut, opts.verbose, cfg)
 	logVerbose(errOut, opts.verbose, "url: %s", u)
 	logVerbose(errOut, opts.verbose, "open=%t copy=%t dry-run=%t", opts.open, opts.copy, opts.dryRun)
 
+	if opts.explain && !opts.json {
+		printSentenceExplain(out, sentence, parts, u)
+		return nil
+	}
+
+	// A fixture backend records the predicted event in place of Fantastical.
+	var recorded *eventInfo
+	if fixture != nil && !opts.dryRun {
+		ev, err := recordParsedEvent(ctx, fixture, u, opts.calendar, opts.note, errOut)
+		if err != nil {
+			return err
+		}
+		recorded = &ev
+		opts.open = false
+		fmt.Fprintf(errOut, "[fantastical] recorded %q at %s in %s (%s)\n", ev.Title, ev.Start.Format(time.RFC3339), ev.Calendar, fixture.path)
+	}
+
+	// With --await or --verify the URL is opened first so the output can
+	// carry the outcome.
+	var callback *callbackOutcome
+	var verify *verifyOutcome
+	var snapshot *eventSnapshot
+	if opts.verify {
+		from, to := verifyWindow(u, time.Now())
+		snapshot, err = takeEventSnapshot(ctx, from, to, opts.calendar, errOut, opts.verbose)
+		if err != nil {
+			return err
+		}
+	}
+	openedEarly := opts.await || opts.verify
+	if opts.await {
+		opened, outcome, err := openAndAwait(ctx, u, opts.timeout, func(target string) error {
+			return openURL(ctx, target, out, errOut)
+		}, errOut, opts.verbose)
+		if err != nil {
+			return err
+		}
+		u, callback = opened, &outcome
+	} else if opts.verify {
+		if err := openURL(ctx, u, out, errOut); err != nil {
+			return err
+		}
+	}
+	if snapshot != nil && (callback == nil || callback.Status == "success") {
+		outcome := snapshot.poll(ctx, opts.verifyTimeout, verifyPollInterval)
+		verify = &outcome
+	}
+
 	didOutput := false
 
 	if opts.json {
-		payload := map[string]any{
-			"command":  "parse",
-			"sentence": sentence,
-			"url":      u,
-			"open":     opts.open,
-			"copy":     opts.copy,
-			"dry_run":  opts.dryRun,
+		payload := parseResult{
+			Command:  "parse",
+			Sentence: sentence,
+			URL:      u,
+			Open:     opts.open,
+			Copy:     opts.copy,
+			DryRun:   opts.dryRun,
+			Callback: callback,
+			Verify:   verify,
+			Parts:    parts,
+			Recorded: recorded,
 		}
 		if err := writeJSON(out, payload); err != nil {
 			return err
@@ -455,12 +684,12 @@ func cmdParse(args []string, in io.Reader, out, errOut io.Writer) error {
 	}
 
 	if opts.copy {
-		if err := copyToClipboard(u); err != nil {
+		if err := copyToClipboard(ctx, u); err != nil {
 			return err
 		}
 	}
-	if opts.open {
-		if err := openURL(u, out, errOut); err != nil {
+	if opts.open && !openedEarly {
+		if err := openURL(ctx, u, out, errOut); err != nil {
 			return err
 		}
 	}
@@ -469,9 +698,27 @@ func cmdParse(args []string, in io.Reader, out, errOut io.Writer) error {
 		fmt.Fprintln(out, u)
 	}
 
+	if callback != nil {
+		if err := callback.err(); err != nil {
+			return err
+		}
+	}
+	if verify != nil {
+		return verify.err()
+	}
 	return nil
 }
 
+// showResult is the output of show --json.
+type showResult struct {
+	Command string `json:"command" enum:"show"`
+	View    string `json:"view"`
+	URL     string `json:"url"`
+	Open    bool   `json:"open"`
+	Copy    bool   `json:"copy"`
+	DryRun  bool   `json:"dry_run"`
+}
+
 type showOptions struct {
 	outputOptions
 	params stringSlice
@@ -491,29 +738,31 @@ func newShowFlagSet(w io.Writer, defaults showOptions) (*flag.FlagSet, *showOpti
 	fs := flag.NewFlagSet("show", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	fs.BoolVar(&opts.open, "open", opts.open, "Open the generated URL via system opener")
-	fs.BoolVar(&opts.print, "print", opts.print, "Print the generated URL to stdout")
-	fs.BoolVar(&opts.copy, "copy", opts.copy, "Copy the generated URL to clipboard (pbcopy)")
-	fs.BoolVar(&opts.json, "json", opts.json, "Print machine-readable JSON output")
-	fs.BoolVar(&opts.plain, "plain", opts.plain, "Print stable plain-text output")
-	fs.BoolVar(&opts.dryRun, "dry-run", opts.dryRun, "Preview only; do not open or copy")
-	fs.BoolVar(&opts.verbose, "verbose", opts.verbose, "Verbose output to stderr")
-	fs.Var(&opts.params, "param", "Extra Fantastical query param (key=value), repeatable")
-	fs.StringVar(&opts.view, "view", "", "View name (e.g., mini, calendar, day, week, month, agenda)")
-	fs.StringVar(&opts.set, "calendar-set", "", "Calendar set name (equivalent to: show set <name>)")
-	fs.StringVar(&opts.tz, "timezone", "", "Timezone to pass as tz=... (IANA name)")
-	fs.StringVar(&opts.config, "config", "", "Config file path (overrides default user config)")
+	bindFlags(fs, "show", map[string]any{
+		"open":         &opts.open,
+		"print":        &opts.print,
+		"copy":         &opts.copy,
+		"json":         &opts.json,
+		"plain":        &opts.plain,
+		"dry-run":      &opts.dryRun,
+		"verbose":      &opts.verbose,
+		"param":        &opts.params,
+		"view":         &opts.view,
+		"calendar-set": &opts.set,
+		"timezone":     &opts.tz,
+		"config":       &opts.config,
+	})
 
 	fs.Usage = func() {
-		fmt.Fprint(w, "USAGE:\n  fantastical show [flags] <view> [yyyy-mm-dd|today|tomorrow|yesterday]\n  fantastical show [flags] --view <view> [date]\n  fantastical show [flags] set <calendar-set-name...>\n  fantastical show [flags] --calendar-set <name>\n")
-		fs.PrintDefaults()
+		fmt.Fprint(w, "USAGE:\n  fantastical show [flags] <view> [date]\n  fantastical show [flags] --view <view> [date]\n  fantastical show [flags] set <calendar-set-name...>\n  fantastical show [flags] --calendar-set <name>\n")
+		printFlagHelp(w, "show")
 		fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical show mini today\n  fantastical show --view month 2026-01-03\n  fantastical show --calendar-set \"My Calendar Set\"")
 	}
 
 	return fs, &opts
 }
 
-func cmdShow(args []string, out, errOut io.Writer) error {
+func cmdShow(ctx context.Context, args []string, out, errOut io.Writer) error {
 	configPath, err := extractConfigPath(args)
 	if err != nil {
 		return err
@@ -556,7 +805,10 @@ func cmdShow(args []string, out, errOut io.Writer) error {
 			fs.Usage()
 			return fmt.Errorf("%w: cannot combine --calendar-set with positional view arguments", errUsage)
 		}
-		name := strings.TrimSpace(opts.set)
+		name, err := resolveCalendarSet(opts.set, cfg)
+		if err != nil {
+			return err
+		}
 		q := url.Values{}
 		q.Set("name", name)
 		for key, vals := range extraParams {
@@ -580,7 +832,11 @@ func cmdShow(args []string, out, errOut io.Writer) error {
 		}
 		u = fantasticalScheme + "show/" + sub
 		if len(rest) == 1 {
-			d, err := parseDateArg(rest[0])
+			weekStart, err := resolveWeekStart("", cfg)
+			if err != nil {
+				return err
+			}
+			d, err := parseDateArg(rest[0], weekStart)
 			if err != nil {
 				return err
 			}
@@ -596,19 +852,20 @@ func cmdShow(args []string, out, errOut io.Writer) error {
 		opts.copy = false
 	}
 
+	logConfigSources(errOut, opts.verbose, cfg)
 	logVerbose(errOut, opts.verbose, "url: %s", u)
 	logVerbose(errOut, opts.verbose, "open=%t copy=%t dry-run=%t", opts.open, opts.copy, opts.dryRun)
 
 	didOutput := false
 
 	if opts.json {
-		payload := map[string]any{
-			"command": "show",
-			"view":    sub,
-			"url":     u,
-			"open":    opts.open,
-			"copy":    opts.copy,
-			"dry_run": opts.dryRun,
+		payload := showResult{
+			Command: "show",
+			View:    sub,
+			URL:     u,
+			Open:    opts.open,
+			Copy:    opts.copy,
+			DryRun:  opts.dryRun,
 		}
 		if err := writeJSON(out, payload); err != nil {
 			return err
@@ -620,12 +877,12 @@ func cmdShow(args []string, out, errOut io.Writer) error {
 	}
 
 	if opts.copy {
-		if err := copyToClipboard(u); err != nil {
+		if err := copyToClipboard(ctx, u); err != nil {
 			return err
 		}
 	}
 	if opts.open {
-		if err := openURL(u, out, errOut); err != nil {
+		if err := openURL(ctx, u, out, errOut); err != nil {
 			return err
 		}
 	}
@@ -678,17 +935,19 @@ func newAppleScriptFlagSet(w io.Writer, defaults appleScriptOptions) (*flag.Flag
 	fs := flag.NewFlagSet("applescript", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	fs.BoolVar(&opts.add, "add", opts.add, "Use Fantastical AppleScript 'with add immediately'")
-	fs.BoolVar(&opts.run, "run", opts.run, "Run osascript (macOS only)")
-	fs.BoolVar(&opts.print, "print", opts.print, "Print the AppleScript instead of (or in addition to) running it")
-	fs.BoolVar(&opts.dryRun, "dry-run", opts.dryRun, "Preview only; do not run osascript")
-	fs.BoolVar(&opts.verbose, "verbose", opts.verbose, "Verbose output to stderr")
-	fs.BoolVar(&opts.stdin, "stdin", false, "Read sentence from stdin instead of args")
-	fs.StringVar(&opts.config, "config", "", "Config file path (overrides default user config)")
+	bindFlags(fs, "applescript", map[string]any{
+		"add":     &opts.add,
+		"run":     &opts.run,
+		"print":   &opts.print,
+		"dry-run": &opts.dryRun,
+		"verbose": &opts.verbose,
+		"stdin":   &opts.stdin,
+		"config":  &opts.config,
+	})
 
 	fs.Usage = func() {
 		fmt.Fprint(w, "USAGE:\n  fantastical applescript|as [flags] <sentence...>\n")
-		fs.PrintDefaults()
+		printFlagHelp(w, "applescript")
 		fmt.Fprintln(w, "\nEXAMPLE:\n  fantastical applescript --add \"Wake up at 8am\"")
 		fmt.Fprintln(w, "NOTE:\n  Put flags before the sentence, or use -- to separate flags from the sentence.")
 	}
@@ -696,7 +955,7 @@ func newAppleScriptFlagSet(w io.Writer, defaults appleScriptOptions) (*flag.Flag
 	return fs, &opts
 }
 
-func cmdAppleScript(args []string, in io.Reader, out, errOut io.Writer) error {
+func cmdAppleScript(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
 	configPath, err := extractConfigPath(args)
 	if err != nil {
 		return err
@@ -724,7 +983,7 @@ func cmdAppleScript(args []string, in io.Reader, out, errOut io.Writer) error {
 		}
 	}
 
-	sentence, err := readSentence(fs.Args(), opts.stdin, in)
+	sentence, err := readSentence(ctx, fs.Args(), opts.stdin, in)
 	if err != nil {
 		fs.Usage()
 		return err
@@ -755,6 +1014,7 @@ func cmdAppleScript(args []string, in io.Reader, out, errOut io.Writer) error {
 		return nil
 	}
 
+	logConfigSources(errOut, opts.verbose, cfg)
 	logVerbose(errOut, opts.verbose, "running osascript (add=%t)", opts.add)
 
 	osascriptArgs := make([]string, 0, len(scriptLines)*2+3)
@@ -768,23 +1028,37 @@ func cmdAppleScript(args []string, in io.Reader, out, errOut io.Writer) error {
 	osascriptArgs = append(osascriptArgs, "--", sentence, addArg)
 
 	cmdName := osascriptCommand()
-	cmd := exec.Command(cmdName, osascriptArgs...)
+	cmd := subprocess(ctx, cmdName, osascriptArgs...)
 	cmd.Stdout = out
 	cmd.Stderr = errOut
-	return cmd.Run()
+	return runSubprocess(ctx, cmd)
+}
+
+// validateResult is the output of validate --json. Output holds what the
+// target would have printed; Error is set instead when validation failed.
+// Predicted is the offline guess at the parsed event (parse only).
+type validateResult struct {
+	OK        bool             `json:"ok"`
+	Target    string           `json:"target" enum:"parse,show,events"`
+	Output    string           `json:"output,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Predicted *eventPrediction `json:"predicted,omitempty"`
+	Range     *eventRange      `json:"range,omitempty"`
 }
 
 func validateUsage(w io.Writer) {
-	fmt.Fprint(w, "USAGE:\n  fantastical validate [--json] parse [flags] <sentence...>\n  fantastical validate [--json] show [flags] <view> [date]\n")
-	fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical validate --json parse \"Dinner at 7\"\n  fantastical validate show month 2026-01-03")
+	fmt.Fprint(w, "USAGE:\n  fantastical validate [--json] [--strict] parse [flags] <sentence...>\n  fantastical validate [--json] show [flags] <view> [date]\n  fantastical validate [--json] events [eventkit events flags]\n")
+	printFlagHelp(w, "validate")
+	fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical validate --json parse \"Dinner at 7\"\n  fantastical validate show month 2026-01-03\n  fantastical validate --json events --next-week --week-start sunday")
 }
 
-func cmdValidate(args []string, in io.Reader, out, errOut io.Writer) error {
+func cmdValidate(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
 	jsonOut := false
-	fs.BoolVar(&jsonOut, "json", false, "Print machine-readable JSON validation result")
+	strict := false
+	bindFlags(fs, "validate", map[string]any{"json": &jsonOut, "strict": &strict})
 
 	fs.Usage = func() {
 		validateUsage(errOut)
@@ -801,33 +1075,67 @@ func cmdValidate(args []string, in io.Reader, out, errOut io.Writer) error {
 
 	if fs.NArg() < 1 {
 		validateUsage(errOut)
-		return fmt.Errorf("%w: missing validate target (parse|show)", errUsage)
+		return fmt.Errorf("%w: missing validate target (parse|show|events)", errUsage)
 	}
 
 	sub := strings.ToLower(fs.Arg(0))
+	if sub == "events" {
+		r, err := eventKitEventsRange(fs.Args()[1:], errOut)
+		if !jsonOut {
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(out, "%s %s\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+			return err
+		}
+		payload := validateResult{OK: err == nil, Target: sub}
+		if err != nil {
+			payload.Error = err.Error()
+		} else {
+			payload.Range = &r
+		}
+		return writeJSON(out, payload)
+	}
 	rest := fs.Args()[1:]
 	rest = append([]string{"--dry-run", "--print"}, rest...)
 
 	runValidate := func(fn func([]string, io.Reader, io.Writer, io.Writer) error) error {
-		if !jsonOut {
-			return fn(rest, in, out, errOut)
-		}
-
 		var buf bytes.Buffer
 		err := fn(rest, in, &buf, errOut)
-		if err != nil {
-			payload := map[string]any{
-				"ok":     false,
-				"target": sub,
-				"error":  err.Error(),
+		output := strings.TrimSpace(buf.String())
+
+		var predicted *eventPrediction
+		if err == nil && sub == "parse" && strings.HasPrefix(output, fantasticalScheme) {
+			if p, perr := predictFromParseURL(output, time.Now()); perr == nil {
+				predicted = &p
+			}
+		}
+		if err == nil && strict && predicted != nil && len(predicted.Warnings) > 0 {
+			err = fmt.Errorf("sentence looks wrong: %s", strings.Join(predicted.Warnings, "; "))
+		}
+
+		if !jsonOut {
+			if err != nil {
+				return err
 			}
-			return writeJSON(out, payload)
+			if predicted != nil {
+				for _, warning := range predicted.Warnings {
+					fmt.Fprintf(errOut, "[fantastical] warning: %s\n", warning)
+				}
+			}
+			_, werr := io.WriteString(out, buf.String())
+			return werr
 		}
 
-		payload := map[string]any{
-			"ok":     true,
-			"target": sub,
-			"output": strings.TrimSpace(buf.String()),
+		payload := validateResult{
+			OK:        err == nil,
+			Target:    sub,
+			Predicted: predicted,
+		}
+		if err != nil {
+			payload.Error = err.Error()
+		} else {
+			payload.Output = output
 		}
 		return writeJSON(out, payload)
 	}
@@ -835,110 +1143,16 @@ func cmdValidate(args []string, in io.Reader, out, errOut io.Writer) error {
 	switch sub {
 	case "parse":
 		return runValidate(func(args []string, in io.Reader, out io.Writer, errOut io.Writer) error {
-			return cmdParse(args, in, out, errOut)
+			return cmdParse(ctx, args, in, out, errOut)
 		})
 	case "show":
 		return runValidate(func(args []string, in io.Reader, out io.Writer, errOut io.Writer) error {
-			return cmdShow(args, out, errOut)
+			return cmdShow(ctx, args, out, errOut)
 		})
 	default:
 		validateUsage(errOut)
-		return fmt.Errorf("%w: unknown validate target %q (want: parse, show)", errUsage, sub)
-	}
-}
-
-type doctorOptions struct {
-	json    bool
-	verbose bool
-	skipApp bool
-}
-
-func doctorUsage(w io.Writer) {
-	fmt.Fprint(w, "USAGE:\n  fantastical doctor [--json] [--skip-app]\n")
-	fmt.Fprintln(w, "\nEXAMPLE:\n  fantastical doctor --json")
-}
-
-func cmdDoctor(args []string, out, errOut io.Writer) error {
-	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
-	fs.SetOutput(io.Discard)
-
-	opts := doctorOptions{}
-	fs.BoolVar(&opts.json, "json", false, "Print machine-readable JSON output")
-	fs.BoolVar(&opts.verbose, "verbose", false, "Verbose output to stderr")
-	fs.BoolVar(&opts.skipApp, "skip-app", false, "Skip Fantastical app lookup")
-
-	fs.Usage = func() {
-		doctorUsage(errOut)
+		return fmt.Errorf("%w: unknown validate target %q (want: parse, show, events)", errUsage, sub)
 	}
-
-	if err := fs.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			fs.Usage()
-			return nil
-		}
-		fs.Usage()
-		return fmt.Errorf("%w: %v", errUsage, err)
-	}
-
-	osascriptPath, osascriptErr := exec.LookPath("osascript")
-	pbcopyPath, pbcopyErr := exec.LookPath("pbcopy")
-	appErr := error(nil)
-	if !opts.skipApp {
-		appErr = exec.Command("open", "-Ra", "Fantastical").Run()
-	}
-
-	if opts.json {
-		payload := map[string]any{
-			"osascript": map[string]any{
-				"ok":   osascriptErr == nil,
-				"path": osascriptPath,
-			},
-			"pbcopy": map[string]any{
-				"ok":   pbcopyErr == nil,
-				"path": pbcopyPath,
-			},
-			"fantastical_app": map[string]any{
-				"ok":    appErr == nil,
-				"check": !opts.skipApp,
-			},
-			"permissions": "Grant Terminal Automation permission if AppleScript prompts or fails.",
-		}
-		if err := writeJSON(out, payload); err != nil {
-			return err
-		}
-	} else {
-		if appErr == nil {
-			fmt.Fprintln(out, "Fantastical app: ok")
-		} else if opts.skipApp {
-			fmt.Fprintln(out, "Fantastical app: skipped")
-		} else {
-			fmt.Fprintln(out, "Fantastical app: not found (install from the App Store)")
-		}
-
-		if osascriptErr == nil {
-			fmt.Fprintln(out, "osascript: ok")
-		} else {
-			fmt.Fprintln(out, "osascript: missing (macOS scripting not available)")
-		}
-
-		if pbcopyErr == nil {
-			fmt.Fprintln(out, "pbcopy: ok")
-		} else {
-			fmt.Fprintln(out, "pbcopy: missing (install Xcode command line tools)")
-		}
-
-		fmt.Fprintln(out, "Automation permissions: grant Terminal access if AppleScript fails.")
-	}
-
-	if appErr != nil && !opts.skipApp {
-		return fmt.Errorf("Fantastical app not found")
-	}
-	if osascriptErr != nil {
-		return fmt.Errorf("osascript not available")
-	}
-
-	logVerbose(errOut, opts.verbose, "doctor completed")
-	return nil
 }
 
 type gretaOptions struct {
@@ -946,22 +1160,27 @@ type gretaOptions struct {
 	schema       string
 	examples     bool
 	capabilities bool
+	schemas      bool
 }
 
 func gretaUsage(w io.Writer) {
-	fmt.Fprint(w, "USAGE:\n  fantastical greta [--format json|markdown] [--schema v1] [--examples] [--capabilities]\n")
-	fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical greta --format json\n  fantastical greta --examples\n  fantastical greta --capabilities --format json")
+	fmt.Fprint(w, "USAGE:\n  fantastical greta [--format json|markdown] [--schema v1] [--examples] [--capabilities] [--schemas]\n")
+	printFlagHelp(w, "greta")
+	fmt.Fprintln(w, "\nEXAMPLES:\n  fantastical greta --format json\n  fantastical greta --examples\n  fantastical greta --capabilities --format json\n  fantastical greta --schemas")
 }
 
-func cmdGreta(args []string, out, errOut io.Writer) error {
+func cmdGreta(ctx context.Context, args []string, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("greta", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
 	opts := gretaOptions{format: "json", schema: "v1"}
-	fs.StringVar(&opts.format, "format", opts.format, "Output format: json or markdown")
-	fs.StringVar(&opts.schema, "schema", opts.schema, "Schema version (v1)")
-	fs.BoolVar(&opts.examples, "examples", false, "Output curated examples only")
-	fs.BoolVar(&opts.capabilities, "capabilities", false, "Output capability summary only")
+	bindFlags(fs, "greta", map[string]any{
+		"format":       &opts.format,
+		"schema":       &opts.schema,
+		"examples":     &opts.examples,
+		"capabilities": &opts.capabilities,
+		"schemas":      &opts.schemas,
+	})
 
 	fs.Usage = func() {
 		gretaUsage(errOut)
@@ -985,9 +1204,15 @@ func cmdGreta(args []string, out, errOut io.Writer) error {
 		return fmt.Errorf("%w: unknown schema %q (want: v1)", errUsage, opts.schema)
 	}
 
-	if opts.examples && opts.capabilities {
+	modes := 0
+	for _, set := range []bool{opts.examples, opts.capabilities, opts.schemas} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
 		fs.Usage()
-		return fmt.Errorf("%w: --examples and --capabilities are mutually exclusive", errUsage)
+		return fmt.Errorf("%w: --examples, --capabilities and --schemas are mutually exclusive", errUsage)
 	}
 
 	if opts.examples {
@@ -999,17 +1224,34 @@ func cmdGreta(args []string, out, errOut io.Writer) error {
 	}
 	if opts.capabilities {
 		if format == "markdown" {
-			fmt.Fprintln(out, gretaCapabilitiesMarkdown())
+			fmt.Fprintln(out, gretaCapabilitiesMarkdown(gretaBackend(), inspectEventKitHelper(ctx)))
+			return nil
+		}
+		return writeJSON(out, gretaCapabilities(opts.schema, gretaBackend(), inspectEventKitHelper(ctx)))
+	}
+	if opts.schemas {
+		if format == "markdown" {
+			fmt.Fprint(out, schemasMarkdown())
 			return nil
 		}
-		return writeJSON(out, gretaCapabilities(opts.schema))
+		return writeJSON(out, schemaSpec(opts.schema))
 	}
 
+	aliases := loadAliases(errOut)
+
 	switch format {
 	case "json":
-		return writeJSON(out, gretaSpec(opts.schema))
+		spec := gretaSpec(opts.schema)
+		spec["aliases"] = sortedAliases(aliases)
+		return writeJSON(out, spec)
 	case "markdown":
 		fmt.Fprintln(out, gretaMarkdown())
+		if len(aliases) > 0 {
+			fmt.Fprintln(out, "## Aliases")
+			for _, alias := range sortedAliases(aliases) {
+				fmt.Fprintf(out, "- %s: %s\n", alias.Name, alias.Expansion)
+			}
+		}
 		return nil
 	default:
 		fs.Usage()
@@ -1022,154 +1264,18 @@ func gretaSpec(schema string) map[string]any {
 		"schemaVersion": schema,
 		"name":          appName,
 		"description":   "CLI for Fantastical URL handler and AppleScript integration (macOS only)",
-		"usage":         "fantastical [--version] <command> [flags] [args]",
+		"usage":         "fantastical [--version] [--timeout duration] <command> [flags] [args]",
 		"notes": []string{
 			"For parse/applescript, put flags before the sentence or use -- to separate.",
 			"EventKit commands require Calendar access and compile a helper with swiftc on first use.",
 		},
-		"commands": []map[string]any{
-			{
-				"name":        "parse",
-				"description": "Build x-fantastical3://parse URL",
-				"args":        "<sentence...>",
-				"flags": []string{
-					"--note, -n",
-					"--calendar, --calendarName",
-					"--add",
-					"--open",
-					"--print",
-					"--copy",
-					"--json",
-					"--plain",
-					"--dry-run",
-					"--verbose",
-					"--stdin",
-					"--param key=value",
-					"--timezone IANA",
-					"--config path",
-				},
-			},
-			{
-				"name":        "show",
-				"description": "Build x-fantastical3://show URL",
-				"args":        "<view> [date] | set <calendar-set-name>",
-				"flags": []string{
-					"--view",
-					"--calendar-set",
-					"--open",
-					"--print",
-					"--copy",
-					"--json",
-					"--plain",
-					"--dry-run",
-					"--verbose",
-					"--param key=value",
-					"--timezone IANA",
-					"--config path",
-				},
-			},
-			{
-				"name":        "applescript",
-				"description": "Run Fantastical AppleScript parse sentence",
-				"args":        "<sentence...>",
-				"flags": []string{
-					"--add",
-					"--run",
-					"--print",
-					"--dry-run",
-					"--verbose",
-					"--stdin",
-					"--config path",
-				},
-			},
-			{
-				"name":        "validate",
-				"description": "Validate parse/show input and print URL",
-				"args":        "parse|show ...",
-			},
-			{
-				"name":        "doctor",
-				"description": "Check Fantastical + macOS integration status",
-				"flags": []string{
-					"--json",
-					"--skip-app",
-					"--verbose",
-				},
-			},
-			{
-				"name":        "eventkit",
-				"description": "List calendars or events via EventKit",
-				"args":        "status|calendars|events [flags]",
-				"flags": []string{
-					"--format plain|json|table",
-					"--json",
-					"--plain",
-					"--no-input",
-					"--calendar",
-					"--calendar-id",
-					"--from",
-					"--to",
-					"--days",
-					"--today",
-					"--tomorrow",
-					"--this-week",
-					"--next-week",
-					"--limit",
-					"--include-all-day",
-					"--include-declined",
-					"--sort start|end|title|calendar",
-					"--tz IANA",
-					"--query text",
-					"--refresh",
-					"--wait seconds",
-					"--interval seconds",
-				},
-			},
-			{
-				"name":        "greta",
-				"description": "Machine-readable CLI spec for agents",
-				"flags": []string{
-					"--format json|markdown",
-					"--schema v1",
-					"--examples",
-					"--capabilities",
-				},
-			},
-			{
-				"name":        "explain",
-				"description": "Human-readable command walkthrough",
-				"args":        "<command>",
-			},
-			{
-				"name":        "man",
-				"description": "Manual page output (markdown or json)",
-				"flags": []string{
-					"--format markdown|json",
-				},
-			},
-			{
-				"name":        "help",
-				"description": "Show help for a command",
-				"flags": []string{
-					"--json",
-				},
-			},
-			{
-				"name":        "completion",
-				"description": "Print or install/uninstall shell completions",
-				"args":        "[bash|zsh|fish]",
-			},
-		},
+		"globalFlags": flagDisplays(globalFlags),
+		"commands":    gretaCommands(),
 		"output": map[string]any{
 			"stdout": "URLs, JSON output, scripts, or diagnostics",
 			"stderr": "Errors and verbose logs",
 		},
-		"config": map[string]any{
-			"user":    "~/.config/fantastical/config.json",
-			"project": ".fantastical.json",
-			"env":     "FANTASTICAL_CONFIG overrides user config path",
-			"order":   "flags > env > project config > user config",
-		},
+		"config": configSpec(),
 		"env": []string{
 			"FANTASTICAL_DEFAULT_OPEN",
 			"FANTASTICAL_DEFAULT_PRINT",
@@ -1185,11 +1291,19 @@ func gretaSpec(schema string) map[string]any {
 			"FANTASTICAL_APPLESCRIPT_RUN",
 			"FANTASTICAL_APPLESCRIPT_PRINT",
 			"FANTASTICAL_EVENTKIT_HELPER",
+			"FANTASTICAL_CALENDAR_CACHE_TTL",
+			"FANTASTICAL_SERVE_TOKEN",
+			"FANTASTICAL_BACKEND",
+			"FANTASTICAL_CALDAV_URL",
+			"FANTASTICAL_CALDAV_USERNAME",
+			"FANTASTICAL_CALDAV_PASSWORD",
 		},
 		"exit_codes": map[string]int{
-			"success": 0,
-			"usage":   2,
-			"error":   1,
+			"success":     0,
+			"usage":       2,
+			"error":       1,
+			"timeout":     exitTimeout,
+			"interrupted": 130,
 		},
 	}
 }
@@ -1198,27 +1312,20 @@ func gretaMarkdown() string {
 	return `# fantastical CLI spec
 
 - Name: fantastical
-- Usage: fantastical [--version] <command> [flags] [args]
+- Usage: fantastical [--version] [--timeout duration] <command> [flags] [args]
 - macOS only
 - Note: for parse/applescript, put flags before the sentence or use -- to separate.
 - Note: eventkit commands request Calendar access on first use.
 - Note: eventkit builds a small Swift helper (swiftc) on first use.
+- Note: exit codes are 0 success, 1 error, 2 usage, 124 --timeout, 128+n signal n.
 
+## Global flags
+` + globalFlagsMarkdown() + `
 ## Commands
-- parse: build x-fantastical3://parse URL
-- show: build x-fantastical3://show URL
-- applescript: run Fantastical AppleScript parse sentence
-- validate: validate parse/show input and print URL
-- doctor: check Fantastical + macOS integration status
-- eventkit: list calendars or events via EventKit
-- greta: machine-readable CLI spec for agents
-- explain: human-readable command walkthrough
-- man: manual page output
-- completion: print/install/uninstall shell completions
-
+` + commandsMarkdown() + `
 ## Config
-- User: ~/.config/fantastical/config.json
-- Project: .fantastical.json
+- User: ~/.config/fantastical/config.json (or config.toml/config.yaml)
+- Project: .fantastical.json, .fantastical.toml or .fantastical.yaml, searched from the current directory up to the repo root or home
 - Env override: FANTASTICAL_CONFIG
 - Precedence: flags > env > project config > user config
 `
@@ -1277,6 +1384,7 @@ func gretaExamplesMarkdown() string {
   fantastical parse --json "Dinner tomorrow 7pm"
 - Show month view on a specific date:
   fantastical show --view month 2026-01-03
+  fantastical show week "next monday"
 - Show a calendar set:
   fantastical show --calendar-set "My Calendar Set"
 - Validate a parse command:
@@ -1292,10 +1400,38 @@ func gretaExamplesMarkdown() string {
 `
 }
 
-func gretaCapabilities(schema string) map[string]any {
+// gretaBackendInfo is the calendar backend selected through config or
+// FANTASTICAL_BACKEND, as reported by greta --capabilities.
+type gretaBackendInfo struct {
+	Name      string   `json:"name,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Available []string `json:"available"`
+}
+
+// gretaBackend resolves the configured backend without opening it, so
+// reporting capabilities never touches the network or the helper.
+func gretaBackend() gretaBackendInfo {
+	info := gretaBackendInfo{Available: backendNames}
+	cfg, err := loadConfigWithPath("")
+	if err == nil {
+		var sel backendSelection
+		if sel, err = selectBackend("", cfg); err == nil {
+			info.Name, info.Source = sel.Name, sel.Source
+		}
+	}
+	if err != nil {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+func gretaCapabilities(schema string, backend gretaBackendInfo, helper eventKitHelperInfo) map[string]any {
 	return map[string]any{
-		"schemaVersion": schema,
-		"platform":      "macOS",
+		"schemaVersion":  schema,
+		"backend":        backend,
+		"eventkitHelper": helper,
+		"platform":       "macOS",
 		"views": []string{
 			"mini", "calendar", "day", "week", "month", "agenda", "set",
 		},
@@ -1309,20 +1445,34 @@ func gretaCapabilities(schema string) map[string]any {
 			"validate",
 			"doctor",
 			"eventkit",
+			"mcp",
+			"serve",
+			"await",
 			"greta",
+			"schemas",
 			"explain",
 			"man",
 		},
 	}
 }
 
-func gretaCapabilitiesMarkdown() string {
+func gretaCapabilitiesMarkdown(backend gretaBackendInfo, helper eventKitHelperInfo) string {
+	active := backend.Name
+	if backend.Source != "" {
+		active += " (" + backend.Source + ")"
+	}
+	if backend.Error != "" {
+		active = "invalid: " + backend.Error
+	}
 	return `# fantastical capabilities
 
 - Platform: macOS
 - Views: mini, calendar, day, week, month, agenda, set
 - Output modes: plain, json
-- Features: stdin, config, completion, validate, doctor, eventkit, greta, explain, man
+- Features: stdin, config, completion, validate, doctor, eventkit, mcp, serve, greta, schemas, explain, man
+- Backend: ` + active + `
+- Backends: ` + strings.Join(backend.Available, ", ") + `
+- EventKit helper: ` + helper.summary() + `
 `
 }
 
@@ -1347,7 +1497,22 @@ func cmdExplain(args []string, out, errOut io.Writer) error {
 	return nil
 }
 
+// explainText returns the walkthrough for command followed by the flag
+// reference generated from the command registry.
 func explainText(command string) (string, error) {
+	text, err := explainProse(command)
+	if err != nil {
+		return "", err
+	}
+	if spec, ok := lookupCommand(command); ok {
+		if ref := flagReference(spec); ref != "" {
+			text += "\n" + ref
+		}
+	}
+	return text, nil
+}
+
+func explainProse(command string) (string, error) {
 	switch strings.ToLower(strings.TrimSpace(command)) {
 	case "parse":
 		return `parse builds an x-fantastical3://parse URL from a natural language sentence.
@@ -1357,11 +1522,31 @@ Common usage:
 
 Flags:
   --note, --calendar, --add control Fantastical's parse behavior.
+  --calendar accepts config aliases and case-insensitive or partial names;
+  they are resolved against your EventKit calendars when access is available.
   Put flags before the sentence, or use -- to separate flags from the sentence.
   --param key=value lets you pass extra Fantastical query params.
   --timezone sets tz=... for the URL.
   --json outputs machine-readable JSON with the URL.
-  --dry-run disables open/copy side effects.`, nil
+  --dry-run disables open/copy side effects.
+  --await opens the x-callback-url form of the URL and waits (up to --await-timeout)
+  for Fantastical to call back on a one-shot 127.0.0.1 listener; the outcome
+  (success, error, cancel or timeout) is in the JSON "callback" field and the
+  exit status is non-zero unless it was success.
+  --verify snapshots EventKit events around the predicted date (or the next
+  week) before opening the URL, then polls (up to --verify-timeout) and
+  reports the events that newly appeared, diffed by id, in the JSON "verify"
+  field.
+  --backend fixture:/path/events.json (or FANTASTICAL_BACKEND) records the
+  predicted event in the JSON fixture instead of opening Fantastical; the
+  JSON "recorded" field holds the stored event.
+
+Structured flags compose the sentence instead of taking one:
+  fantastical parse --title "Design review" --start 2026-10-20T10:00 --duration 45m \
+    --location "Room 4" --invitees ana@example.com --repeat weekly --alert 10m
+  Titles and locations that would be read as dates, times or places are
+  quoted; --calendar adds a /Calendar token. --explain prints the composed
+  sentence phrase by phrase with the URL and never opens anything.`, nil
 	case "show":
 		return `show builds x-fantastical3://show URLs for views or calendar sets.
 
@@ -1370,6 +1555,8 @@ Examples:
   fantastical show --view month 2026-01-03
   fantastical show --calendar-set "My Calendar Set"
 
+The date accepts the same expressions as eventkit --from/--to (yyyy-mm-dd,
+today, +3d, friday, end of month, 2026-W42, ...).
 Use --timezone to set tz=... and --param to pass extra query params.`, nil
 	case "applescript":
 		return `applescript sends a sentence to Fantastical via osascript.
@@ -1384,14 +1571,53 @@ Use --print to see the script and --dry-run to avoid execution.`, nil
 Example:
   fantastical validate --json parse "Dinner at 7"
 
+For parse, an offline best-effort parser also predicts the title, start,
+end, recurrence and calendar ("predicted" in --json output) and warns about
+sentences without a date or time, or with a date in the past. Fantastical's
+own parser is authoritative. --strict turns those warnings into failures.
+
+validate events takes eventkit events flags and prints the absolute range
+they select ("range" in --json output) without calling EventKit.
+
 Useful for scripting and CI checks.`, nil
 	case "doctor":
-		return `doctor checks Fantastical app availability and macOS tooling.
+		return `doctor checks Fantastical, macOS tooling, config, the EventKit helper and completion.
 
-Example:
+Examples:
   fantastical doctor --json
-
+  fantastical doctor --check eventkit-helper,eventkit-access
+  fantastical doctor --fix
+
+Each check has an id, a status (ok, warn, fail, skip) and a hint; doctor
+exits 1 if any check fails. --fix builds the EventKit helper and installs
+completion for $SHELL, then checks again. --skip-app skips the app, version
+and x-fantastical3 URL handler checks. A missing or stale cached helper is
+only a warning (the next eventkit command rebuilds it), while an
+incompatible FANTASTICAL_EVENTKIT_HELPER fails.
 If AppleScript fails, grant Terminal Automation permission.`, nil
+	case "config":
+		return `config show prints the effective config and the files it was merged from.
+
+Examples:
+  fantastical config show
+  fantastical config show --json
+
+Project config is searched from the current directory up to the repo root (or home).
+.fantastical.json, .fantastical.toml and .fantastical.yaml are accepted.
+
+Aliases defined under "aliases" expand before dispatch:
+  {"aliases": {"wk": "eventkit events --this-week --format table"}}
+  fantastical wk --json`, nil
+	case "cache":
+		return `cache shows or clears the cached calendar list used for name resolution.
+
+Examples:
+  fantastical cache show --json
+  fantastical cache clear
+
+The cache lives next to the compiled EventKit helper and is refreshed by
+eventkit calendars --json or when it is older than calendars.cache_ttl (default 1h).
+Use --no-cache on parse or eventkit to bypass it.`, nil
 	case "eventkit":
 		return `eventkit lists calendars or events via EventKit (system Calendar access).
 
@@ -1399,18 +1625,84 @@ Examples:
   fantastical eventkit status --json
   fantastical eventkit calendars --format table
   fantastical eventkit events --next-week --calendar "Work"
+  fantastical eventkit events --from "next monday 9am" --to +2w
+  fantastical eventkit events --where 'calendar = Work and duration >= 30m' --sort -start
   fantastical eventkit events --refresh --wait 20 --interval 2 --query "test"
+  fantastical eventkit search "dentist" --years 5
+  fantastical eventkit create --backend ics:~/cal --calendar Personal --title Trip --start 2026-10-20
 
 Note:
   macOS will prompt for Calendar access on first use. Use --no-input to fail instead of prompting.
-  Use --format to select plain/json/table output and --query to filter events.`, nil
+  Use --format to select plain/json/table output, --query for a substring match
+  and --where for field conditions (title ~ /standup/i, calendar in (Work, Team),
+  duration >= 30m, start = tomorrow, not allDay). --sort takes several keys
+  (calendar,-start) and --offset/--limit page the result; all of this runs in
+  the CLI on the helper's JSON.
+  --from/--to take date expressions (2026-10-20, +3d, next monday, end of month,
+  2026-W42, tomorrow 9am PST) and are sent to the helper as RFC 3339.
+  Shortcuts and weeks are resolved by the CLI too; --week-start (or config
+  dates.week_start, then the locale) picks Monday or Sunday. Check the range with
+  fantastical validate events --this-week.
+  Ranges longer than a year are fetched in one-year chunks and merged, since
+  EventKit truncates long queries. eventkit search uses this to look through
+  the last --years years (default 5) through one year ahead, newest first.
+  --backend ics:/path (or config backend.name) reads .ics files or a vdir
+  directory instead of EventKit, and --backend caldav:https://server/dav/ a
+  CalDAV server (FANTASTICAL_CALDAV_USERNAME/_PASSWORD); recurring events are
+  expanded in the CLI. --backend fixture:/path/events.json serves events from
+  a JSON file and --backend fixture alone a read-only demo week.
+  eventkit create and eventkit delete write through the ics (vdir), caldav
+  and fixture backends; EventKit stays read-only, so use parse there.
+  The Swift helper is compiled on first use; eventkit helper build
+  [--universal] [--output path] prebuilds it, eventkit helper info --json
+  shows its hashes, compiler and build time, and eventkit helper clean
+  removes it.`, nil
 	case "greta":
 		return `greta outputs a full CLI spec for AI agents.
 
 Examples:
   fantastical greta --format json
   fantastical greta --examples
-  fantastical greta --capabilities`, nil
+  fantastical greta --capabilities
+  fantastical greta --schemas
+
+--schemas prints JSON Schema (draft 2020-12) documents for every --json
+output, the config file and the EventKit helper output, generated from the
+Go types the CLI encodes.`, nil
+	case "mcp":
+		return `mcp runs a Model Context Protocol server over stdio for AI agents.
+
+Tools:
+  create_event_from_sentence  parse a sentence into a Fantastical event
+  list_events                 list events via EventKit
+  list_calendars              list calendars via EventKit
+  find_free_time              free slots between events, optionally within working hours
+  show_view                   open a Fantastical view or calendar set
+
+Tools run the same code as parse, show and eventkit, so config defaults and
+calendar aliases apply. Start with --dry-run to build URLs without opening
+Fantastical.
+
+Example client config:
+  {"command": "fantastical", "args": ["mcp"]}`, nil
+	case "serve":
+		return `serve runs a localhost HTTP/JSON API backed by the same tools as mcp.
+
+Endpoints:
+  POST /parse      {"sentence": "...", "calendar": "...", "dry_run": true}
+  POST /show       {"view": "month", "date": "2026-01-03"}
+  GET  /events     ?from=2026-01-05&to=2026-01-09&calendars=Work
+  GET  /calendars
+  GET  /free       ?from=2026-01-05&duration_minutes=60&day_start=09:00&day_end=17:00
+  GET  /status
+
+Request bodies and query parameters use the MCP tool argument names. Set
+serve.token in config (or FANTASTICAL_SERVE_TOKEN) and send it as
+"Authorization: Bearer <token>"; without one, a random token is generated
+and printed at startup. POST bodies must be application/json, and requests
+from non-loopback origins are rejected. Only loopback addresses are accepted.
+Each request is logged to stderr; SIGINT/SIGTERM drain in-flight requests
+before exiting.`, nil
 	case "explain":
 		return "explain prints a human-readable walkthrough for a command.", nil
 	case "man":
@@ -1421,7 +1713,11 @@ Examples:
 Examples:
   fantastical completion zsh
   fantastical completion install zsh
-  fantastical completion uninstall zsh`, nil
+  fantastical completion uninstall zsh
+
+The scripts call the hidden fantastical __complete command, so calendar names
+(from the calendar cache), calendar sets, views, timezones and config aliases
+are completed without reinstalling.`, nil
 	case "help":
 		return "help shows command usage. Use --json for machine-readable help.", nil
 	case "version":
@@ -1437,6 +1733,7 @@ type manOptions struct {
 
 func manUsage(w io.Writer) {
 	fmt.Fprint(w, "USAGE:\n  fantastical man [--format markdown|json]\n")
+	printFlagHelp(w, "man")
 	fmt.Fprintln(w, "\nEXAMPLE:\n  fantastical man --format json")
 }
 
@@ -1445,7 +1742,7 @@ func cmdMan(args []string, out, errOut io.Writer) error {
 	fs.SetOutput(io.Discard)
 
 	opts := manOptions{format: "markdown"}
-	fs.StringVar(&opts.format, "format", opts.format, "Output format: markdown or json")
+	bindFlags(fs, "man", map[string]any{"format": &opts.format})
 
 	fs.Usage = func() {
 		manUsage(errOut)
@@ -1476,19 +1773,16 @@ func cmdMan(args []string, out, errOut io.Writer) error {
 func manSpec() map[string]any {
 	return map[string]any{
 		"name":        appName,
-		"synopsis":    "fantastical [--version] <command> [flags] [args]",
+		"synopsis":    "fantastical [--version] [--timeout duration] <command> [flags] [args]",
 		"description": "CLI for Fantastical URL handler and AppleScript integration (macOS only).",
 		"commands":    gretaSpec("v1")["commands"],
-		"config": map[string]any{
-			"user":    "~/.config/fantastical/config.json",
-			"project": ".fantastical.json",
-			"env":     "FANTASTICAL_CONFIG overrides user config path",
-			"order":   "flags > env > project config > user config",
-		},
+		"config":      configSpec(),
 		"exit_codes": map[string]int{
-			"success": 0,
-			"usage":   2,
-			"error":   1,
+			"success":     0,
+			"usage":       2,
+			"error":       1,
+			"timeout":     exitTimeout,
+			"interrupted": 130,
 		},
 	}
 }
@@ -1500,7 +1794,7 @@ func manMarkdown() string {
 fantastical — CLI for Fantastical URL handler and AppleScript integration (macOS only)
 
 ## SYNOPSIS
-fantastical [--version] <command> [flags] [args]
+fantastical [--version] [--timeout duration] <command> [flags] [args]
 
 ## DESCRIPTION
 Use Fantastical's URL handler and AppleScript integration from the command line.
@@ -1511,15 +1805,18 @@ EventKit commands require Calendar access; macOS will prompt on first use.
 EventKit commands compile a small Swift helper (swiftc) on first use.
 
 ## COMMANDS
-See fantastical help or fantastical greta --format json.
+` + manCommandsMarkdown() + `
 
 ## CONFIG
-User: ~/.config/fantastical/config.json
-Project: .fantastical.json
+User: ~/.config/fantastical/config.json (or config.toml/config.yaml)
+Project: .fantastical.json, .fantastical.toml or .fantastical.yaml, searched from the current directory up to the repo root or home
+Use fantastical config show to see which files were loaded.
 Precedence: flags > env > project config > user config
 
+## GLOBAL FLAGS
+` + globalFlagsMarkdown() + `
 ## EXIT CODES
-0 success, 1 error, 2 usage
+0 success, 1 error, 2 usage, 124 --timeout elapsed, 128+n stopped by signal n (130 for Ctrl-C)
 `
 }
 
@@ -1584,7 +1881,7 @@ func cmdCompletionInstall(args []string, out, errOut io.Writer) error {
 	fs.SetOutput(io.Discard)
 
 	opts := completionInstallOptions{}
-	fs.StringVar(&opts.path, "path", "", "Install path (defaults to a user-local location)")
+	bindFlags(fs, "completion install", map[string]any{"path": &opts.path})
 
 	fs.Usage = func() {
 		fmt.Fprint(errOut, "USAGE:\n  fantastical completion install [--path <path>] [bash|zsh|fish]\n")
@@ -1656,7 +1953,7 @@ func cmdCompletionUninstall(args []string, out, errOut io.Writer) error {
 	fs.SetOutput(io.Discard)
 
 	opts := completionInstallOptions{}
-	fs.StringVar(&opts.path, "path", "", "Completion path (defaults to a user-local location)")
+	bindFlags(fs, "completion uninstall", map[string]any{"path": &opts.path})
 
 	fs.Usage = func() {
 		fmt.Fprint(errOut, "USAGE:\n  fantastical completion uninstall [--path <path>] [bash|zsh|fish]\n")
@@ -1698,362 +1995,6 @@ func cmdCompletionUninstall(args []string, out, errOut io.Writer) error {
 	return nil
 }
 
-func completionScript(shell string) (string, error) {
-	switch shell {
-	case "bash":
-		return bashCompletion(), nil
-	case "zsh":
-		return zshCompletion(), nil
-	case "fish":
-		return fishCompletion(), nil
-	default:
-		return "", fmt.Errorf("%w: unknown shell %q (want: bash, zsh, fish)", errUsage, shell)
-	}
-}
-
-func bashCompletion() string {
-	return `_fantastical_completions() {
-  local cur prev
-  cur="${COMP_WORDS[COMP_CWORD]}"
-  prev="${COMP_WORDS[COMP_CWORD-1]}"
-
-  local cmds="parse show applescript validate doctor eventkit greta explain man as completion help version"
-  if [[ $COMP_CWORD -eq 1 ]]; then
-    COMPREPLY=( $(compgen -W "$cmds" -- "$cur") )
-    return 0
-  fi
-
-  case "${COMP_WORDS[1]}" in
-    parse)
-      local flags="--note -n --calendar --calendarName --add --open --print --copy --json --plain --dry-run --verbose --stdin --param --timezone --config --help"
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    show)
-      local flags="--open --print --copy --json --plain --dry-run --verbose --param --view --calendar-set --timezone --config --help"
-      local subs="mini calendar day week month agenda set"
-      if [[ $COMP_CWORD -eq 2 ]]; then
-        COMPREPLY=( $(compgen -W "$subs" -- "$cur") )
-        return 0
-      fi
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    applescript|as)
-      local flags="--add --run --print --dry-run --verbose --stdin --config --help"
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    validate)
-      local flags="--json --help"
-      local subs="parse show"
-      if [[ $COMP_CWORD -eq 2 ]]; then
-        COMPREPLY=( $(compgen -W "$subs" -- "$cur") )
-        return 0
-      fi
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    doctor)
-      local flags="--json --skip-app --verbose --help"
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    eventkit)
-      local subs="status calendars events"
-      if [[ $COMP_CWORD -eq 2 ]]; then
-        COMPREPLY=( $(compgen -W "$subs" -- "$cur") )
-        return 0
-      fi
-      local sub="${COMP_WORDS[2]}"
-      if [[ "$sub" == "status" ]]; then
-        local flags="--format --json --plain --verbose --help"
-        COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-        return 0
-      fi
-      if [[ "$sub" == "calendars" ]]; then
-        local flags="--format --json --plain --no-input --verbose --help"
-        COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-        return 0
-      fi
-      if [[ "$sub" == "events" ]]; then
-        local flags="--format --json --plain --no-input --verbose --calendar --calendar-id --from --to --days --today --tomorrow --this-week --next-week --limit --include-all-day --include-declined --sort --tz --query --help"
-        COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-        return 0
-      fi
-      COMPREPLY=( $(compgen -W "$subs" -- "$cur") )
-      ;;
-    greta)
-      local flags="--format --schema --examples --capabilities --help"
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    explain)
-      COMPREPLY=( $(compgen -W "$cmds" -- "$cur") )
-      ;;
-    man)
-      local flags="--format --help"
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-    completion)
-      local subs="install uninstall bash zsh fish"
-      COMPREPLY=( $(compgen -W "$subs" -- "$cur") )
-      ;;
-    help)
-      local flags="--json --help"
-      if [[ $COMP_CWORD -eq 2 ]]; then
-        COMPREPLY=( $(compgen -W "$cmds" -- "$cur") )
-        return 0
-      fi
-      COMPREPLY=( $(compgen -W "$flags" -- "$cur") )
-      ;;
-  esac
-}
-
-complete -F _fantastical_completions fantastical`
-}
-
-func zshCompletion() string {
-	return `#compdef fantastical
-
-_fantastical() {
-  local -a commands
-  commands=(
-    'parse:Build x-fantastical3://parse URL'
-    'show:Build x-fantastical3://show URL'
-    'applescript:Run Fantastical AppleScript'
-    'validate:Validate input and print URL'
-    'doctor:Check Fantastical integration'
-    'eventkit:List calendars or events via EventKit'
-    'greta:CLI spec for agents'
-    'explain:Human-readable command walkthrough'
-    'man:Manual page output'
-    'completion:Generate shell completion'
-    'help:Show help for a command'
-    'version:Print version information'
-  )
-
-  _arguments -C \
-    '1:command:->cmds' \
-    '*::arg:->args'
-
-  case $state in
-    cmds)
-      _describe 'command' commands
-      ;;
-    args)
-      case $words[2] in
-        parse)
-          _arguments '*:sentence:' \
-            '--note[Optional note]' \
-            '-n[Optional note]' \
-            '--calendar[Calendar name]' \
-            '--calendarName[Calendar name]' \
-            '--add[Add immediately]' \
-            '--open[Open URL]' \
-            '--print[Print URL]' \
-            '--copy[Copy URL]' \
-            '--json[JSON output]' \
-            '--plain[Plain output]' \
-            '--dry-run[Preview only]' \
-            '--verbose[Verbose output]' \
-            '--stdin[Read from stdin]' \
-            '--param[Extra query param]' \
-            '--timezone[Timezone (tz)]' \
-            '--config[Config file path]'
-          ;;
-        show)
-          _arguments '1:target:(mini calendar day week month agenda set)' '*:date or name:' \
-            '--open[Open URL]' \
-            '--print[Print URL]' \
-            '--copy[Copy URL]' \
-            '--json[JSON output]' \
-            '--plain[Plain output]' \
-            '--dry-run[Preview only]' \
-            '--verbose[Verbose output]' \
-            '--param[Extra query param]' \
-            '--view[View name]' \
-            '--calendar-set[Calendar set]' \
-            '--timezone[Timezone (tz)]' \
-            '--config[Config file path]'
-          ;;
-        applescript|as)
-          _arguments '*:sentence:' \
-            '--add[Add immediately]' \
-            '--run[Run osascript]' \
-            '--print[Print script]' \
-            '--dry-run[Preview only]' \
-            '--verbose[Verbose output]' \
-            '--stdin[Read from stdin]' \
-            '--config[Config file path]'
-          ;;
-        validate)
-          _arguments \
-            '--json[JSON output]' \
-            '1:target:(parse show)' '*:args:'
-          ;;
-        doctor)
-          _arguments \
-            '--json[JSON output]' \
-            '--skip-app[Skip app check]' \
-            '--verbose[Verbose output]'
-          ;;
-        eventkit)
-          if (( CURRENT == 3 )); then
-            _arguments '1:sub:(status calendars events)'
-            return
-          fi
-          case $words[3] in
-            status)
-              _arguments \
-                '--format[Output format (plain|json)]' \
-                '--json[JSON output]' \
-                '--plain[Plain output]' \
-                '--verbose[Verbose output]'
-              ;;
-            calendars)
-              _arguments \
-                '--format[Output format (plain|json|table)]' \
-                '--json[JSON output]' \
-                '--plain[Plain output]' \
-                '--no-input[Do not prompt for access]' \
-                '--verbose[Verbose output]'
-              ;;
-            events)
-              _arguments \
-                '--format[Output format (plain|json|table)]' \
-                '--json[JSON output]' \
-                '--plain[Plain output]' \
-                '--no-input[Do not prompt for access]' \
-                '--verbose[Verbose output]' \
-                '--calendar[Calendar name]' \
-                '--calendar-id[Calendar identifier]' \
-                '--from[Start date/time]' \
-                '--to[End date/time]' \
-                '--days[Days from now]' \
-                '--today[Today]' \
-                '--tomorrow[Tomorrow]' \
-                '--this-week[This week]' \
-                '--next-week[Next week]' \
-                '--limit[Limit events]' \
-                '--include-all-day[Include all-day events]' \
-                '--include-declined[Include declined events]' \
-                '--sort[Sort order]' \
-                '--tz[Timezone]' \
-                '--query[Query text]' \
-                '--refresh[Refresh sources]' \
-                '--wait[Wait seconds]' \
-                '--interval[Polling interval seconds]'
-              ;;
-            *)
-              _arguments '1:sub:(status calendars events)'
-              ;;
-          esac
-          ;;
-        greta)
-          _arguments \
-            '--format[Output format (json|markdown)]' \
-            '--schema[Schema version (v1)]' \
-            '--examples[Examples only]' \
-            '--capabilities[Capabilities only]'
-          ;;
-        explain)
-          _arguments '1:command:(parse show applescript validate doctor eventkit greta completion help version)'
-          ;;
-        man)
-          _arguments '--format[Output format (markdown|json)]'
-          ;;
-        completion)
-          _arguments '1:sub:(install uninstall bash zsh fish)'
-          ;;
-        help)
-          _arguments '--json[JSON output]' '1:command:(parse show applescript validate doctor eventkit greta explain man completion help version)'
-          ;;
-      esac
-      ;;
-  esac
-}
-
-_fantastical "$@"`
-}
-
-func fishCompletion() string {
-	return `complete -c fantastical -f
-complete -c fantastical -n '__fish_use_subcommand' -a 'parse show applescript validate doctor eventkit greta explain man completion help version' -d 'fantastical command'
-
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l note -d 'Optional note'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -s n -d 'Optional note'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l calendar -d 'Calendar name'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l calendarName -d 'Calendar name'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l add -d 'Add immediately'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l open -d 'Open URL'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l print -d 'Print URL'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l copy -d 'Copy URL'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l json -d 'JSON output'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l plain -d 'Plain output'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l dry-run -d 'Preview only'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l verbose -d 'Verbose output'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l stdin -d 'Read from stdin'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l param -d 'Extra query param'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l timezone -d 'Timezone (tz)'
-complete -c fantastical -n '__fish_seen_subcommand_from parse' -l config -d 'Config file path'
-
-complete -c fantastical -n '__fish_seen_subcommand_from show' -a 'mini calendar day week month agenda set' -d 'Show target'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l open -d 'Open URL'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l print -d 'Print URL'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l copy -d 'Copy URL'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l json -d 'JSON output'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l plain -d 'Plain output'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l dry-run -d 'Preview only'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l verbose -d 'Verbose output'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l param -d 'Extra query param'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l view -d 'View name'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l calendar-set -d 'Calendar set'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l timezone -d 'Timezone (tz)'
-complete -c fantastical -n '__fish_seen_subcommand_from show' -l config -d 'Config file path'
-
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l add -d 'Add immediately'
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l run -d 'Run osascript'
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l print -d 'Print script'
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l dry-run -d 'Preview only'
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l verbose -d 'Verbose output'
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l stdin -d 'Read from stdin'
-complete -c fantastical -n '__fish_seen_subcommand_from applescript' -l config -d 'Config file path'
-
-complete -c fantastical -n '__fish_seen_subcommand_from validate' -l json -d 'JSON output'
-complete -c fantastical -n '__fish_seen_subcommand_from validate' -a 'parse show' -d 'Validate target'
-complete -c fantastical -n '__fish_seen_subcommand_from doctor' -l json -d 'JSON output'
-complete -c fantastical -n '__fish_seen_subcommand_from doctor' -l skip-app -d 'Skip app check'
-complete -c fantastical -n '__fish_seen_subcommand_from doctor' -l verbose -d 'Verbose output'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -a 'status calendars events' -d 'EventKit target'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l json -d 'JSON output'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l plain -d 'Plain output'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l format -d 'Output format'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l no-input -d 'Do not prompt for access'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l verbose -d 'Verbose output'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l calendar -d 'Calendar name'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l calendar-id -d 'Calendar identifier'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l from -d 'Start date/time'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l to -d 'End date/time'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l days -d 'Days from now'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l today -d 'Today'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l tomorrow -d 'Tomorrow'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l this-week -d 'This week'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l next-week -d 'Next week'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l limit -d 'Limit events'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l include-all-day -d 'Include all-day events'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l include-declined -d 'Include declined events'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l sort -d 'Sort order'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l tz -d 'Timezone'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l query -d 'Query text'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l refresh -d 'Refresh sources'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l wait -d 'Wait seconds'
-complete -c fantastical -n '__fish_seen_subcommand_from eventkit' -l interval -d 'Polling interval seconds'
-complete -c fantastical -n '__fish_seen_subcommand_from greta' -l format -d 'Format'
-complete -c fantastical -n '__fish_seen_subcommand_from greta' -l schema -d 'Schema'
-complete -c fantastical -n '__fish_seen_subcommand_from greta' -l examples -d 'Examples only'
-complete -c fantastical -n '__fish_seen_subcommand_from greta' -l capabilities -d 'Capabilities only'
-complete -c fantastical -n '__fish_seen_subcommand_from explain' -a 'parse show applescript validate doctor eventkit greta completion help version' -d 'Command'
-complete -c fantastical -n '__fish_seen_subcommand_from man' -l format -d 'Format'
-complete -c fantastical -n '__fish_seen_subcommand_from completion' -a 'install uninstall bash zsh fish' -d 'Shell'
-complete -c fantastical -n '__fish_seen_subcommand_from help' -l json -d 'JSON output'
-complete -c fantastical -n '__fish_seen_subcommand_from help' -a 'parse show applescript validate doctor eventkit greta explain man completion help version' -d 'Command'`
-}
-
 func buildParseURL(sentence, note, calendar string, add bool, extra url.Values) string {
 	q := url.Values{}
 	q.Set("s", sentence)
@@ -2081,16 +2022,16 @@ func encodeQuery(v url.Values) string {
 	return strings.ReplaceAll(enc, "+", "%20")
 }
 
-func openURL(u string, out, errOut io.Writer) error {
+func openURL(ctx context.Context, u string, out, errOut io.Writer) error {
 	cmdName, cmdArgs, err := openCommand(u)
 	if err != nil {
 		return err
 	}
 
-	cmd := exec.Command(cmdName, cmdArgs...)
+	cmd := subprocess(ctx, cmdName, cmdArgs...)
 	cmd.Stdout = out
 	cmd.Stderr = errOut
-	return cmd.Run()
+	return runSubprocess(ctx, cmd)
 }
 
 func openCommand(u string) (string, []string, error) {
@@ -2115,53 +2056,37 @@ func osascriptCommand() string {
 	return "osascript"
 }
 
-func copyToClipboard(text string) error {
+func copyToClipboard(ctx context.Context, text string) error {
 	path, err := exec.LookPath("pbcopy")
 	if err != nil {
 		return errors.New("pbcopy not found (install Xcode command line tools or use --print)")
 	}
-	cmd := exec.Command(path)
+	cmd := subprocess(ctx, path)
 	cmd.Stdin = strings.NewReader(text)
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runSubprocess(ctx, cmd)
 }
 
-func parseDateArg(s string) (time.Time, error) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return time.Time{}, fmt.Errorf("%w: empty date", errUsage)
-	}
-
-	now := time.Now()
-	// normalize to local midnight for relative dates
-	midnight := func(t time.Time) time.Time {
-		loc := t.Location()
-		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
-	}
-
-	switch strings.ToLower(s) {
-	case "today":
-		return midnight(now), nil
-	case "tomorrow":
-		return midnight(now.AddDate(0, 0, 1)), nil
-	case "yesterday":
-		return midnight(now.AddDate(0, 0, -1)), nil
-	default:
-		// Expect yyyy-mm-dd
-		t, err := time.ParseInLocation("2006-01-02", s, time.Local)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("%w: invalid date %q; want yyyy-mm-dd (e.g. 2026-01-03) or today/tomorrow/yesterday", errUsage, s)
-		}
-		return t, nil
+func parseDateArg(s string, weekStart time.Weekday) (time.Time, error) {
+	c := newDateContext(nowFunc(), time.Local)
+	c.weekStart = weekStart
+	t, _, err := c.parse(s)
+	if err != nil {
+		return time.Time{}, err
 	}
+	// show takes a day; drop any time of day.
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
 }
 
-func readSentence(args []string, fromStdin bool, in io.Reader) (string, error) {
+func readSentence(ctx context.Context, args []string, fromStdin bool, in io.Reader) (string, error) {
 	if fromStdin {
 		if len(args) > 0 {
 			return "", fmt.Errorf("%w: cannot use args with --stdin", errUsage)
 		}
-		data, err := io.ReadAll(in)
+		data, err := readCommand(ctx, func() ([]byte, error) { return io.ReadAll(in) })
+		if err != nil && ctx.Err() != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
 		if err != nil {
 			return "", fmt.Errorf("%w: read stdin: %v", errUsage, err)
 		}
@@ -5,6 +5,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"net/url"
 	"os"
@@ -18,6 +19,23 @@ func setupTestEnv(t *testing.T) {
 	t.Helper()
 	configPath := filepath.Join(t.TempDir(), "config.json")
 	t.Setenv("FANTASTICAL_CONFIG", configPath)
+	isolateHelperEnv(t)
+}
+
+// isolateHelperEnv keeps tests away from the real EventKit helper and from the
+// user's cache directory (calendar cache, compiled helper).
+func isolateHelperEnv(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("FANTASTICAL_CALENDAR_CACHE_TTL", "")
+	// Calendar names are resolved via EventKit; never build the real helper in tests.
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "false")
+	t.Setenv("FANTASTICAL_BACKEND", "")
+	t.Setenv("FANTASTICAL_CALDAV_URL", "")
+	t.Setenv("FANTASTICAL_CALDAV_USERNAME", "")
+	t.Setenv("FANTASTICAL_CALDAV_PASSWORD", "")
 }
 
 func TestEncodeQuerySpaces(t *testing.T) {
@@ -66,7 +84,7 @@ func TestBuildParseURL(t *testing.T) {
 }
 
 func TestParseDateArgAbsolute(t *testing.T) {
-	d, err := parseDateArg("2026-01-03")
+	d, err := parseDateArg("2026-01-03", time.Monday)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -79,7 +97,7 @@ func TestParseDateArgRelative(t *testing.T) {
 	now := time.Now()
 	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
-	d, err := parseDateArg("today")
+	d, err := parseDateArg("today", time.Monday)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -89,7 +107,7 @@ func TestParseDateArgRelative(t *testing.T) {
 }
 
 func TestParseDateArgInvalid(t *testing.T) {
-	_, err := parseDateArg("not-a-date")
+	_, err := parseDateArg("not-a-date", time.Monday)
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -102,7 +120,7 @@ func TestCmdParsePrintOnly(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"--open=false", "--print", "Wake", "up"}, strings.NewReader(""), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"--open=false", "--print", "Wake", "up"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -120,7 +138,7 @@ func TestCmdParseStdin(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"--open=false", "--print", "--stdin"}, strings.NewReader("From stdin"), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"--open=false", "--print", "--stdin"}, strings.NewReader("From stdin"), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -133,7 +151,7 @@ func TestCmdParseJSON(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"--open=false", "--json", "Hello"}, strings.NewReader(""), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"--open=false", "--json", "Hello"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -146,7 +164,7 @@ func TestCmdParseTimezone(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"--open=false", "--print", "--timezone", "America/Los_Angeles", "Hello"}, strings.NewReader(""), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"--open=false", "--print", "--timezone", "America/Los_Angeles", "Hello"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -159,7 +177,7 @@ func TestCmdParseMissingSentence(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdParse([]string{"--open=false", "--print"}, strings.NewReader(""), &out, &errOut); err == nil {
+	if err := cmdParse(context.Background(), []string{"--open=false", "--print"}, strings.NewReader(""), &out, &errOut); err == nil {
 		t.Fatalf("expected error")
 	} else if !errors.Is(err, errUsage) {
 		t.Fatalf("expected usage error, got: %v", err)
@@ -170,7 +188,7 @@ func TestCmdShowMiniDate(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdShow([]string{"--open=false", "--print", "mini", "2026-01-03"}, &out, &errOut); err != nil {
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "mini", "2026-01-03"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	expected := fantasticalScheme + "show/mini/2026-01-03\n"
@@ -183,7 +201,7 @@ func TestCmdShowViewFlag(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdShow([]string{"--open=false", "--print", "--view", "month", "2026-01-03"}, &out, &errOut); err != nil {
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "--view", "month", "2026-01-03"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	expected := fantasticalScheme + "show/month/2026-01-03\n"
@@ -196,7 +214,7 @@ func TestCmdShowCalendarSetFlag(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdShow([]string{"--open=false", "--print", "--calendar-set", "My Set"}, &out, &errOut); err != nil {
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "--calendar-set", "My Set"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	q := url.Values{}
@@ -211,7 +229,7 @@ func TestCmdShowTimezone(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdShow([]string{"--open=false", "--print", "--timezone", "UTC", "month", "2026-01-03"}, &out, &errOut); err != nil {
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "--timezone", "UTC", "month", "2026-01-03"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "tz=UTC") {
@@ -223,7 +241,7 @@ func TestCmdShowTooManyArgs(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdShow([]string{"--open=false", "--print", "mini", "2026-01-03", "extra"}, &out, &errOut); err == nil {
+	if err := cmdShow(context.Background(), []string{"--open=false", "--print", "mini", "2026-01-03", "extra"}, &out, &errOut); err == nil {
 		t.Fatalf("expected error")
 	} else if !errors.Is(err, errUsage) {
 		t.Fatalf("expected usage error, got: %v", err)
@@ -234,7 +252,7 @@ func TestCmdAppleScriptPrintOnly(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdAppleScript([]string{"--run=false", "--print", "Wake", "up"}, strings.NewReader(""), &out, &errOut); err != nil {
+	if err := cmdAppleScript(context.Background(), []string{"--run=false", "--print", "Wake", "up"}, strings.NewReader(""), &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "tell application \"Fantastical\"") {
@@ -249,7 +267,7 @@ func TestCmdAppleScriptStdin(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdAppleScript([]string{"--run=false", "--print", "--stdin"}, strings.NewReader("Wake up"), &out, &errOut); err != nil {
+	if err := cmdAppleScript(context.Background(), []string{"--run=false", "--print", "--stdin"}, strings.NewReader("Wake up"), &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "parse sentence theSentence") {
@@ -261,7 +279,7 @@ func TestCmdValidateParse(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdValidate([]string{"parse", "Wake"}, strings.NewReader(""), &out, &errOut); err != nil {
+	if err := cmdValidate(context.Background(), []string{"parse", "Wake"}, strings.NewReader(""), &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "x-fantastical3://parse") {
@@ -273,7 +291,7 @@ func TestCmdValidateJSON(t *testing.T) {
 	setupTestEnv(t)
 
 	var out, errOut bytes.Buffer
-	if err := cmdValidate([]string{"--json", "parse", "Wake"}, strings.NewReader(""), &out, &errOut); err != nil {
+	if err := cmdValidate(context.Background(), []string{"--json", "parse", "Wake"}, strings.NewReader(""), &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "\"ok\":true") {
@@ -283,7 +301,7 @@ func TestCmdValidateJSON(t *testing.T) {
 
 func TestCmdDoctorJSON(t *testing.T) {
 	var out, errOut bytes.Buffer
-	if err := cmdDoctor([]string{"--json", "--skip-app"}, &out, &errOut); err != nil {
+	if err := cmdDoctor(context.Background(), []string{"--json", "--skip-app"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "\"osascript\"") {
@@ -293,7 +311,7 @@ func TestCmdDoctorJSON(t *testing.T) {
 
 func TestCmdGretaJSON(t *testing.T) {
 	var out, errOut bytes.Buffer
-	if err := cmdGreta([]string{"--format", "json"}, &out, &errOut); err != nil {
+	if err := cmdGreta(context.Background(), []string{"--format", "json"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "\"commands\"") {
@@ -303,7 +321,7 @@ func TestCmdGretaJSON(t *testing.T) {
 
 func TestCmdGretaExamples(t *testing.T) {
 	var out, errOut bytes.Buffer
-	if err := cmdGreta([]string{"--examples"}, &out, &errOut); err != nil {
+	if err := cmdGreta(context.Background(), []string{"--examples"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "\"examples\"") {
@@ -313,7 +331,7 @@ func TestCmdGretaExamples(t *testing.T) {
 
 func TestCmdGretaCapabilities(t *testing.T) {
 	var out, errOut bytes.Buffer
-	if err := cmdGreta([]string{"--capabilities"}, &out, &errOut); err != nil {
+	if err := cmdGreta(context.Background(), []string{"--capabilities"}, &out, &errOut); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(out.String(), "\"views\"") {
@@ -509,9 +527,10 @@ func TestConfigDefaults(t *testing.T) {
 		t.Fatalf("write config: %v", err)
 	}
 	t.Setenv("FANTASTICAL_CONFIG", configPath)
+	isolateHelperEnv(t)
 
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"Meeting"}, strings.NewReader(""), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"Meeting"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -529,8 +548,9 @@ func TestConfigOverrideFlag(t *testing.T) {
 	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
 		t.Fatalf("write config: %v", err)
 	}
+	isolateHelperEnv(t)
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"--config", configPath, "Meeting"}, strings.NewReader(""), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"--config", configPath, "Meeting"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -544,7 +564,7 @@ func TestOpenCommandOverride(t *testing.T) {
 	t.Setenv("FANTASTICAL_OPEN_COMMAND", "true")
 
 	var out, errOut bytes.Buffer
-	err := cmdParse([]string{"--open", "Wake"}, strings.NewReader(""), &out, &errOut)
+	err := cmdParse(context.Background(), []string{"--open", "Wake"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -555,7 +575,7 @@ func TestOsascriptCommandOverride(t *testing.T) {
 	t.Setenv("FANTASTICAL_OSASCRIPT_COMMAND", "true")
 
 	var out, errOut bytes.Buffer
-	err := cmdAppleScript([]string{"--run", "Wake"}, strings.NewReader(""), &out, &errOut)
+	err := cmdAppleScript(context.Background(), []string{"--run", "Wake"}, strings.NewReader(""), &out, &errOut)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -0,0 +1,327 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// fixtureData is a fixture file: calendars and events in the EventKit
+// helper's CalendarOutput/EventOutput shape. A bare array of events (the
+// output of eventkit events --json) is accepted too; its calendars are
+// derived from the events.
+type fixtureData struct {
+	Calendars []calendarInfo `json:"calendars"`
+	Events    []eventInfo    `json:"events"`
+}
+
+// fixtureBackend serves calendars and events from a JSON file, for demos,
+// sandboxes and agent dry runs. New events are written back to the file.
+// Without a path it serves read-only demo data around today.
+type fixtureBackend struct {
+	path string
+	data fixtureData
+	// eventsOnly keeps a bare events array in that shape when saving.
+	eventsOnly bool
+}
+
+func newFixtureBackend(path string) (*fixtureBackend, error) {
+	if path == "" {
+		return &fixtureBackend{data: demoFixture(nowFunc().In(time.Local))}, nil
+	}
+	b := &fixtureBackend{path: path}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load (re)reads the fixture file into b.
+func (b *fixtureBackend) load() error {
+	raw, err := os.ReadFile(b.path)
+	if err != nil {
+		return fmt.Errorf("fixture backend: %w", err)
+	}
+	b.data, b.eventsOnly = fixtureData{}, false
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		b.eventsOnly = true
+		err = json.Unmarshal(trimmed, &b.data.Events)
+	} else {
+		err = json.Unmarshal(raw, &b.data)
+	}
+	if err != nil {
+		return fmt.Errorf("fixture backend: %s: %w", b.path, err)
+	}
+	for i, ev := range b.data.Events {
+		if ev.Start.IsZero() || ev.End.Before(ev.Start) {
+			return fmt.Errorf("fixture backend: %s: event %d (%q) needs a start before its end", b.path, i, ev.Title)
+		}
+		if _, ok := b.calendarFor(ev); !ok {
+			b.data.Calendars = append(b.data.Calendars, calendarInfo{
+				ID:                  ev.CalendarID,
+				Title:               ev.Calendar,
+				Source:              "fixture",
+				Type:                "local",
+				AllowsModifications: true,
+			})
+		}
+	}
+	return nil
+}
+
+// update runs apply on a fresh read of the file while holding an exclusive
+// lock, so concurrent writers (parallel parse runs, agents) do not drop each
+// other's events. The lock is a sidecar file because save replaces the
+// fixture itself.
+func (b *fixtureBackend) update(apply func() error) error {
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(b.path), "."+filepath.Base(b.path)+".lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("fixture backend lock: %w", err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("fixture backend lock: %w", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	if err := b.load(); err != nil {
+		return err
+	}
+	return apply()
+}
+
+func (b *fixtureBackend) Name() string { return "fixture" }
+
+// calendarFor finds the calendar an event belongs to, by ID when the event
+// has one and by title otherwise.
+func (b *fixtureBackend) calendarFor(ev eventInfo) (calendarInfo, bool) {
+	for _, cal := range b.data.Calendars {
+		if ev.CalendarID != "" && cal.ID == ev.CalendarID || ev.CalendarID == "" && cal.Title == ev.Calendar {
+			return cal, true
+		}
+	}
+	return calendarInfo{}, false
+}
+
+func (b *fixtureBackend) ListCalendars(ctx context.Context) ([]calendarInfo, error) {
+	return slices.Clone(b.data.Calendars), nil
+}
+
+// ListEvents returns the events overlapping req.Range in its location.
+// Fixture events carry no attendee status, so IncludeDeclined has no
+// effect.
+func (b *fixtureBackend) ListEvents(ctx context.Context, req eventFetch) ([]eventInfo, error) {
+	loc := req.Range.From.Location()
+	var events []eventInfo
+	for _, ev := range b.data.Events {
+		cal, _ := b.calendarFor(ev)
+		if !calendarSelected(cal, req.Calendars) || ev.AllDay && !req.IncludeAllDay {
+			continue
+		}
+		overlaps := ev.End.After(req.Range.From) && !ev.Start.After(req.Range.To)
+		instant := ev.End.Equal(ev.Start) && !ev.Start.Before(req.Range.From) && !ev.Start.After(req.Range.To)
+		if !overlaps && !instant {
+			continue
+		}
+		ev.Start, ev.End = ev.Start.In(loc), ev.End.In(loc)
+		events = append(events, ev)
+	}
+	return mergeEventPages([][]eventInfo{events}), nil
+}
+
+// CreateEvent appends the event and saves the file.
+func (b *fixtureBackend) CreateEvent(ctx context.Context, ev newEvent) (eventInfo, error) {
+	if b.path == "" {
+		return eventInfo{}, fmt.Errorf("fixture backend: the built-in demo data is read-only (use fixture:/path/events.json): %w", errBackendUnsupported)
+	}
+	var created eventInfo
+	err := b.update(func() error {
+		i, err := findCalendar(b.data.Calendars, ev.Calendar)
+		if err != nil {
+			return err
+		}
+		if ev, err = ev.normalize(); err != nil {
+			return err
+		}
+		var raw [8]byte
+		if _, err := rand.Read(raw[:]); err != nil {
+			return err
+		}
+		created = ev.info("fixture-"+hex.EncodeToString(raw[:]), b.data.Calendars[i])
+		b.data.Events = append(b.data.Events, created)
+		if err := b.save(); err != nil {
+			b.data.Events = b.data.Events[:len(b.data.Events)-1]
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return eventInfo{}, err
+	}
+	return created, nil
+}
+
+// DeleteEvent removes the event with the given ID and saves the file.
+func (b *fixtureBackend) DeleteEvent(ctx context.Context, calendar, id string) error {
+	if b.path == "" {
+		return fmt.Errorf("fixture backend: the built-in demo data is read-only (use fixture:/path/events.json): %w", errBackendUnsupported)
+	}
+	return b.update(func() error {
+		i, err := findCalendar(b.data.Calendars, calendar)
+		if err != nil {
+			return err
+		}
+		cal := b.data.Calendars[i]
+		for j, ev := range b.data.Events {
+			if ev.ID != id {
+				continue
+			}
+			if owner, _ := b.calendarFor(ev); owner.ID != cal.ID {
+				continue
+			}
+			events := b.data.Events
+			b.data.Events = slices.Delete(slices.Clone(events), j, j+1)
+			if err := b.save(); err != nil {
+				b.data.Events = events
+				return err
+			}
+			return nil
+		}
+		return fmt.Errorf("%w: %q in %s", errEventNotFound, id, cal.Title)
+	})
+}
+
+// save rewrites the fixture file atomically, in the shape it was read.
+func (b *fixtureBackend) save() error {
+	var v any = b.data
+	if b.eventsOnly {
+		v = b.data.Events
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".fantastical-*.tmp")
+	if err != nil {
+		return fmt.Errorf("fixture backend: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if st, err := os.Stat(b.path); err == nil {
+		if err := tmp.Chmod(st.Mode().Perm()); err != nil {
+			tmp.Close()
+			return fmt.Errorf("fixture backend: %w", err)
+		}
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fixture backend: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("fixture backend: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("fixture backend: %w", err)
+	}
+	return nil
+}
+
+// demoFixture builds a small work/personal week around now: standups on
+// weekdays of this and next week plus a few one-off events.
+func demoFixture(now time.Time) fixtureData {
+	work := calendarInfo{ID: "demo-work", Title: "Work", Source: "fixture", Type: "local", Color: "#1BADF8", AllowsModifications: true}
+	personal := calendarInfo{ID: "demo-personal", Title: "Personal", Source: "fixture", Type: "local", Color: "#63DA38", AllowsModifications: true}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	at := func(day time.Time, hour, minute int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+	}
+	event := func(cal calendarInfo, title string, start, end time.Time) eventInfo {
+		return eventInfo{Title: title, Calendar: cal.Title, CalendarID: cal.ID, Start: start, End: end}
+	}
+
+	var events []eventInfo
+	for i := 0; i < 14; i++ {
+		day := monday.AddDate(0, 0, i)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		events = append(events, event(work, "Daily standup", at(day, 9, 30), at(day, 9, 45)))
+	}
+	lunch := event(personal, "Lunch with Sam", at(today, 12, 30), at(today, 13, 30))
+	lunch.Location = "Café Central"
+	review := event(work, "Design review", at(today.AddDate(0, 0, 1), 14, 0), at(today.AddDate(0, 0, 1), 15, 0))
+	review.Notes = "Walk through the new onboarding flow."
+	climbing := event(personal, "Climbing", at(today.AddDate(0, 0, 2), 18, 0), at(today.AddDate(0, 0, 2), 19, 30))
+	offsite := event(work, "Team offsite", monday.AddDate(0, 0, 11), monday.AddDate(0, 0, 12))
+	offsite.AllDay = true
+	planning := event(work, "Sprint planning", at(monday.AddDate(0, 0, 7), 10, 0), at(monday.AddDate(0, 0, 7), 11, 30))
+	events = append(events, lunch, review, climbing, offsite, planning)
+	for i := range events {
+		events[i].ID = fmt.Sprintf("demo-%d", i+1)
+	}
+	return fixtureData{Calendars: []calendarInfo{work, personal}, Events: events}
+}
+
+// parseFixtureBackend returns the fixture parse records events in, or nil
+// when parse should open Fantastical. A configured ics or caldav backend
+// only applies to reads; naming one with parse --backend is an error.
+func parseFixtureBackend(spec string, cfg *Config) (*fixtureBackend, error) {
+	explicit := strings.TrimSpace(spec) != ""
+	if !explicit && cfg != nil {
+		spec = cfg.Backend.Name
+	}
+	name, _, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "fixture":
+		sel, err := selectBackend(spec, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newFixtureBackend(sel.Source)
+	case "", "eventkit":
+		return nil, nil
+	}
+	if explicit {
+		return nil, fmt.Errorf("%w: parse creates events in Fantastical or a fixture, not the %s backend", errUsage, name)
+	}
+	return nil, nil
+}
+
+// recordParsedEvent stores the event predicted for the parse URL u in the
+// fixture, standing in for Fantastical. Without a calendar the first one
+// is used, like Fantastical's default calendar.
+func recordParsedEvent(ctx context.Context, b *fixtureBackend, u, calendar, note string, errOut io.Writer) (eventInfo, error) {
+	p, err := predictFromParseURL(u, nowFunc())
+	if err != nil {
+		return eventInfo{}, err
+	}
+	if p.Start == nil {
+		return eventInfo{}, fmt.Errorf("%w: no date or time found; the fixture backend cannot record %q", errUsage, p.Title)
+	}
+	ev := newEvent{Calendar: calendar, Title: p.Title, Start: *p.Start, AllDay: p.AllDay, Location: p.Location, Notes: note}
+	if p.End != nil {
+		ev.End = *p.End
+	}
+	if ev.Calendar == "" {
+		ev.Calendar = p.Calendar
+	}
+	if ev.Calendar == "" && len(b.data.Calendars) > 0 {
+		ev.Calendar = b.data.Calendars[0].Title
+	}
+	if p.Recurrence != "" {
+		fmt.Fprintf(errOut, "[fantastical] warning: the fixture records the first occurrence of %s only\n", p.Recurrence)
+	}
+	return b.CreateEvent(ctx, ev)
+}
@@ -0,0 +1,312 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureTestJSON = `{
+  "calendars": [
+    {"id": "work", "title": "Work", "source": "fixture", "type": "local", "color": "#1BADF8", "allowsModifications": true},
+    {"id": "home", "title": "Home", "source": "fixture", "type": "local", "allowsModifications": true}
+  ],
+  "events": [
+    {"id": "e1", "title": "Standup", "calendar": "Work", "calendarId": "work", "start": "2026-10-19T07:00:00Z", "end": "2026-10-19T07:15:00Z", "allDay": false},
+    {"id": "e2", "title": "Dentist", "calendar": "Home", "calendarId": "home", "start": "2026-10-20T15:00:00Z", "end": "2026-10-20T16:00:00Z", "allDay": false},
+    {"id": "e3", "title": "Offsite", "calendar": "Work", "calendarId": "work", "start": "2026-10-22T00:00:00+02:00", "end": "2026-10-23T00:00:00+02:00", "allDay": true},
+    {"id": "e4", "title": "Late deploy", "calendar": "Work", "calendarId": "work", "start": "2026-10-19T22:30:00Z", "end": "2026-10-19T23:00:00Z", "allDay": false}
+  ]
+}
+`
+
+func writeFixture(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFixtureBackendListEvents(t *testing.T) {
+	r := icsTestRange(t)
+	b, err := newFixtureBackend(writeFixture(t, fixtureTestJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendars, _ := b.ListCalendars(context.Background())
+	if len(calendars) != 2 || calendars[0].Color != "#1BADF8" {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+
+	events, err := b.ListEvents(context.Background(), eventFetch{Range: r, IncludeAllDay: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"2026-10-19T09:00:00+02:00 09:15 work Standup",
+		"2026-10-20T00:30:00+02:00 01:00 work Late deploy",
+		"2026-10-20T17:00:00+02:00 18:00 home Dentist",
+		"2026-10-22T00:00:00+02:00 00:00 work Offsite",
+	}, "\n")
+	if got := formatICSEvents(events); got != want {
+		t.Fatalf("events:\n%s\nwant:\n%s", got, want)
+	}
+
+	events, _ = b.ListEvents(context.Background(), eventFetch{Range: r, Calendars: []calendarRef{{Title: "Work"}}})
+	if got := formatICSEvents(events); got != "2026-10-19T09:00:00+02:00 09:15 work Standup\n2026-10-20T00:30:00+02:00 01:00 work Late deploy" {
+		t.Fatalf("calendar filter without all-day events:\n%s", got)
+	}
+}
+
+func TestFixtureBackendEventsArray(t *testing.T) {
+	path := writeFixture(t, `[{"id": "x", "title": "Focus", "calendar": "Deep work", "start": "2026-10-19T08:00:00Z", "end": "2026-10-19T10:00:00Z"}]`)
+	b, err := newFixtureBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendars, _ := b.ListCalendars(context.Background())
+	if len(calendars) != 1 || calendars[0].Title != "Deep work" || calendars[0].Source != "fixture" {
+		t.Fatalf("expected a derived calendar: %+v", calendars)
+	}
+	if _, err := b.CreateEvent(context.Background(), newEvent{Calendar: "Deep work", Title: "Reading", Start: time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	var events []eventInfo
+	if err := json.Unmarshal(raw, &events); err != nil || len(events) != 2 || !events[1].End.Equal(time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the file to stay an events array: %v\n%s", err, raw)
+	}
+
+	for _, data := range []string{`{"events": [{"title": "No start"}]}`, `[{"title": "Backwards", "start": "2026-10-19T10:00:00Z", "end": "2026-10-19T09:00:00Z"}]`, `{`} {
+		if _, err := newFixtureBackend(writeFixture(t, data)); err == nil {
+			t.Fatalf("expected an error for %s", data)
+		}
+	}
+}
+
+func TestFixtureBackendWrites(t *testing.T) {
+	path := writeFixture(t, fixtureTestJSON)
+	b, err := newFixtureBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := b.CreateEvent(context.Background(), newEvent{Calendar: "home", Title: "Groceries", Start: time.Date(2026, 10, 21, 17, 0, 0, 0, time.UTC), Location: "Market"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.ID, "fixture-") || created.Calendar != "Home" {
+		t.Fatalf("unexpected event: %+v", created)
+	}
+	if st, _ := os.Stat(path); st.Mode().Perm() != 0o600 {
+		t.Fatalf("expected the file mode to be kept, got %v", st.Mode())
+	}
+
+	reopened, err := newFixtureBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reopened.data.Events); n != 5 || reopened.data.Events[4].Location != "Market" {
+		t.Fatalf("expected the event in the file, got %d events", n)
+	}
+	if _, err := reopened.CreateEvent(context.Background(), newEvent{Calendar: "Gym", Title: "x", Start: created.Start}); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for an unknown calendar, got %v", err)
+	}
+
+	if err := reopened.DeleteEvent(context.Background(), "Work", created.ID); !errors.Is(err, errEventNotFound) {
+		t.Fatalf("expected not found in another calendar, got %v", err)
+	}
+	if err := reopened.DeleteEvent(context.Background(), "Home", created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := newFixtureBackend(path); len(again.data.Events) != 4 {
+		t.Fatalf("expected the delete to persist, got %d events", len(again.data.Events))
+	}
+
+	demo, _ := newFixtureBackend("")
+	if _, err := demo.CreateEvent(context.Background(), newEvent{Calendar: "Work", Title: "x", Start: created.Start}); !errors.Is(err, errBackendUnsupported) {
+		t.Fatalf("expected the demo data to be read-only, got %v", err)
+	}
+}
+
+func TestFixtureBackendConcurrentWrites(t *testing.T) {
+	path := writeFixture(t, fixtureTestJSON)
+	const writers = 8
+	backends := make([]*fixtureBackend, writers)
+	for i := range backends {
+		b, err := newFixtureBackend(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backends[i] = b
+	}
+	errs := make(chan error, writers)
+	for i, b := range backends {
+		go func(i int, b *fixtureBackend) {
+			_, err := b.CreateEvent(context.Background(), newEvent{Calendar: "Home", Title: fmt.Sprintf("Writer %d", i), Start: time.Date(2026, 10, 21, 9+i, 0, 0, 0, time.UTC)})
+			errs <- err
+		}(i, b)
+	}
+	for i := 0; i < writers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	final, err := newFixtureBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(final.data.Events); n != 4+writers {
+		t.Fatalf("expected every concurrent write to be kept, got %d events", n)
+	}
+}
+
+func TestDemoFixture(t *testing.T) {
+	// Sunday afternoon: this week's standups are in the past, next week's
+	// are ahead.
+	data := demoFixture(time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC))
+	standups := 0
+	for _, ev := range data.Events {
+		if ev.Title == "Daily standup" {
+			standups++
+			if wd := ev.Start.Weekday(); wd == time.Saturday || wd == time.Sunday {
+				t.Fatalf("standup on a weekend: %v", ev.Start)
+			}
+		}
+		if ev.ID == "" || ev.End.Before(ev.Start) {
+			t.Fatalf("invalid demo event: %+v", ev)
+		}
+	}
+	if standups != 10 || data.Events[0].Start.Day() != 12 {
+		t.Fatalf("expected two weeks of standups from Monday 10-12, got %d from %v", standups, data.Events[0].Start)
+	}
+}
+
+func TestCmdEventKitFixtureBackend(t *testing.T) {
+	setupTestEnv(t)
+	icsTestRange(t)
+	restore := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = restore })
+	path := writeFixture(t, fixtureTestJSON)
+
+	var out, errOut bytes.Buffer
+	args := []string{"events", "--backend", "fixture:" + path, "--from", "2026-10-19", "--to", "2026-10-19", "--tz", "Europe/Zagreb", "--json"}
+	if err := cmdEventKit(context.Background(), args, &out, &errOut); err != nil {
+		t.Fatalf("events: %v (%s)", err, errOut.String())
+	}
+	var events []eventInfo
+	if err := json.Unmarshal(out.Bytes(), &events); err != nil || len(events) != 1 || events[0].Title != "Standup" {
+		t.Fatalf("unexpected events: %s (%v)", out.String(), err)
+	}
+	if got := events[0].Start.Format(time.RFC3339); got != "2026-10-19T09:00:00+02:00" {
+		t.Fatalf("expected the event in --tz, got %s", got)
+	}
+
+	// The demo data needs no file.
+	out.Reset()
+	if err := cmdEventKit(context.Background(), []string{"events", "--backend", "fixture", "--from", "2026-10-19", "--to", "2026-10-19", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("demo events: %v", err)
+	}
+	if !strings.Contains(out.String(), "Daily standup") || !strings.Contains(out.String(), "Design review") {
+		t.Fatalf("unexpected demo events: %s", out.String())
+	}
+}
+
+func TestCmdParseFixtureBackend(t *testing.T) {
+	setupTestEnv(t)
+	icsTestRange(t)
+	restore := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = restore })
+	path := writeFixture(t, fixtureTestJSON)
+	t.Setenv("FANTASTICAL_BACKEND", "fixture:"+path)
+
+	var out, errOut bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--timezone", "Europe/Zagreb", "--calendar", "Home", "--json", "Dinner tomorrow 7pm"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("parse: %v (%s)", err, errOut.String())
+	}
+	var result parseResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out.String())
+	}
+	if result.Recorded == nil || result.Recorded.Title != "Dinner" || result.Recorded.Calendar != "Home" || result.Open {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+	if got := result.Recorded.Start.Format(time.RFC3339); got != "2026-10-19T19:00:00+02:00" {
+		t.Fatalf("unexpected start %s", got)
+	}
+	if !strings.Contains(errOut.String(), "recorded") {
+		t.Fatalf("expected a note on stderr, got %q", errOut.String())
+	}
+
+	b, _ := newFixtureBackend(path)
+	if len(b.data.Events) != 5 {
+		t.Fatalf("expected the event in the file, got %d events", len(b.data.Events))
+	}
+
+	// A composed sentence quotes the title; the quotes are not recorded.
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--json", "--title", "Meeting at 5", "--start", "2026-10-20T15:00"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("parse: %v (%s)", err, errOut.String())
+	}
+	result = parseResult{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || result.Recorded == nil || result.Recorded.Title != "Meeting at 5" {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+
+	// Dry runs record nothing.
+	out.Reset()
+	if err := cmdParse(context.Background(), []string{"--dry-run", "Lunch tomorrow at noon"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ = newFixtureBackend(path); len(b.data.Events) != 6 {
+		t.Fatalf("dry run wrote to the fixture")
+	}
+
+	for _, args := range [][]string{
+		{"--await", "Lunch tomorrow at noon"},
+		{"--backend", "ics:/tmp", "Lunch tomorrow at noon"},
+		{"Buy apples"},
+	} {
+		if err := cmdParse(context.Background(), args, strings.NewReader(""), &out, &errOut); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected usage error, got %v", args, err)
+		}
+	}
+}
+
+func TestCmdGretaCapabilitiesBackend(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("FANTASTICAL_BACKEND", "fixture")
+
+	var out, errOut bytes.Buffer
+	if err := cmdGreta(context.Background(), []string{"--capabilities"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	var caps struct {
+		Backend gretaBackendInfo `json:"backend"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &caps); err != nil || caps.Backend.Name != "fixture" || len(caps.Backend.Available) != len(backendNames) {
+		t.Fatalf("unexpected capabilities: %s (%v)", out.String(), err)
+	}
+
+	t.Setenv("FANTASTICAL_BACKEND", "ics")
+	out.Reset()
+	if err := cmdGreta(context.Background(), []string{"--capabilities", "--format", "markdown"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "- Backend: invalid:") {
+		t.Fatalf("expected the invalid backend reported, got:\n%s", out.String())
+	}
+}
@@ -3,7 +3,10 @@
 
 package main
 
-import "strings"
+import (
+	"flag"
+	"strings"
+)
 
 type stringSlice []string
 
@@ -15,3 +18,24 @@ func (s *stringSlice) Set(value string) error {
 	*s = append(*s, value)
 	return nil
 }
+
+// parseInterspersed parses args allowing flags after positional arguments
+// (search "term" --years 5) and returns the positionals. Everything after
+// "--" is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
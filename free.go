@@ -0,0 +1,183 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timeSlot is a free interval reported by find_free_time.
+type timeSlot struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Minutes int       `json:"minutes"`
+}
+
+// workingHours limits free time to a daily window, in minutes after midnight.
+// The zero value means the whole day.
+type workingHours struct {
+	start int
+	end   int
+}
+
+func (h workingHours) set() bool {
+	return h.start != 0 || h.end != 0
+}
+
+// parseWorkingHours parses "HH:MM" bounds; both empty means the whole day.
+func parseWorkingHours(start, end string) (workingHours, error) {
+	start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+	if start == "" && end == "" {
+		return workingHours{}, nil
+	}
+	if start == "" {
+		start = "00:00"
+	}
+	if end == "" {
+		end = "24:00"
+	}
+	s, err := parseClock(start)
+	if err != nil {
+		return workingHours{}, err
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return workingHours{}, err
+	}
+	if e <= s {
+		return workingHours{}, fmt.Errorf("%w: day end %s must be after day start %s", errUsage, end, start)
+	}
+	return workingHours{start: s, end: e}, nil
+}
+
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid time %q (want HH:MM)", errUsage, value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// freeSlots returns the gaps of at least minDuration between from and to that
+// no event covers, restricted to hours. All-day events only block time when
+// includeAllDay is set.
+func freeSlots(events []eventInfo, from, to time.Time, minDuration time.Duration, hours workingHours, includeAllDay bool) []timeSlot {
+	type span struct{ start, end time.Time }
+
+	var busy []span
+	for _, ev := range events {
+		if ev.AllDay && !includeAllDay {
+			continue
+		}
+		if !ev.End.After(from) || !ev.Start.Before(to) {
+			continue
+		}
+		busy = append(busy, span{ev.Start, ev.End})
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].start.Before(busy[j].start) })
+
+	windows := []span{{from, to}}
+	if hours.set() {
+		windows = nil
+		loc := from.Location()
+		day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+		for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+			// Build bounds from the wall clock so DST days keep their local hours.
+			start := time.Date(day.Year(), day.Month(), day.Day(), hours.start/60, hours.start%60, 0, 0, loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), hours.end/60, hours.end%60, 0, 0, loc)
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				windows = append(windows, span{start, end})
+			}
+		}
+	}
+
+	slots := []timeSlot{}
+	add := func(start, end time.Time) {
+		if d := end.Sub(start); d > 0 && d >= minDuration {
+			slots = append(slots, timeSlot{Start: start, End: end, Minutes: int(d / time.Minute)})
+		}
+	}
+	for _, w := range windows {
+		cursor := w.start
+		for _, b := range busy {
+			if !b.end.After(cursor) {
+				continue
+			}
+			if !b.start.Before(w.end) {
+				break
+			}
+			if b.start.After(cursor) {
+				add(cursor, b.start)
+			}
+			cursor = b.end
+		}
+		if cursor.Before(w.end) {
+			add(cursor, w.end)
+		}
+	}
+	return slots
+}
+
+// freeTimeResult is the output of the find_free_time MCP tool.
+type freeTimeResult struct {
+	From            time.Time  `json:"from"`
+	To              time.Time  `json:"to"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Slots           []timeSlot `json:"slots"`
+}
+
+// rangeLayouts are the plain date/time forms accepted for --start and --end.
+var rangeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// resolveRange turns optional --from/--to style bounds into an absolute range
+// in loc. Bounds are date expressions (see parseDateExpr); a date-only bound
+// covers the whole day, a missing end defaults to the end of the start day,
+// and a missing start to now.
+func resolveRange(fromValue, toValue string, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	from := now.In(loc)
+	if strings.TrimSpace(fromValue) != "" {
+		t, _, err := parseDateExpr(fromValue, now, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+	to := time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, loc)
+	if strings.TrimSpace(toValue) != "" {
+		t, dateOnly, err := parseDateExpr(toValue, now, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+		if dateOnly {
+			to = t.AddDate(0, 0, 1)
+		}
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be after from", errUsage)
+	}
+	return from, to, nil
+}
+
+func parseRangeBound(value string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range rangeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, layout == "2006-01-02", nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%w: invalid date %q (want YYYY-MM-DD or YYYY-MM-DDTHH:MM)", errUsage, value)
+}
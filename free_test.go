@@ -0,0 +1,85 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFreeSlots(t *testing.T) {
+	loc := time.FixedZone("CEST", 2*60*60)
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, time.October, day, hour, min, 0, 0, loc)
+	}
+	events := []eventInfo{
+		{Title: "Late", Start: at(19, 14, 0), End: at(19, 15, 0)},
+		{Title: "Standup", Start: at(19, 9, 15), End: at(19, 9, 30)},
+		{Title: "Overlap", Start: at(19, 9, 20), End: at(19, 10, 0)},
+		{Title: "Holiday", Start: at(20, 0, 0), End: at(21, 0, 0), AllDay: true},
+		{Title: "Evening", Start: at(20, 18, 0), End: at(20, 19, 0)},
+	}
+
+	slots := freeSlots(events, at(19, 9, 0), at(19, 16, 0), 30*time.Minute, workingHours{}, false)
+	want := []timeSlot{
+		{Start: at(19, 10, 0), End: at(19, 14, 0), Minutes: 240},
+		{Start: at(19, 15, 0), End: at(19, 16, 0), Minutes: 60},
+	}
+	assertSlots(t, slots, want)
+
+	hours, err := parseWorkingHours("09:00", "17:00")
+	if err != nil {
+		t.Fatalf("parseWorkingHours: %v", err)
+	}
+	slots = freeSlots(events, at(19, 0, 0), at(21, 0, 0), time.Hour, hours, false)
+	want = []timeSlot{
+		{Start: at(19, 10, 0), End: at(19, 14, 0), Minutes: 240},
+		{Start: at(19, 15, 0), End: at(19, 17, 0), Minutes: 120},
+		{Start: at(20, 9, 0), End: at(20, 17, 0), Minutes: 480},
+	}
+	assertSlots(t, slots, want)
+
+	slots = freeSlots(events, at(20, 0, 0), at(21, 0, 0), time.Minute, hours, true)
+	assertSlots(t, slots, []timeSlot{})
+}
+
+func assertSlots(t *testing.T, got, want []timeSlot) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d slots %+v, want %+v", len(got), got, want)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) || got[i].Minutes != want[i].Minutes {
+			t.Fatalf("slot %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResolveRange(t *testing.T) {
+	loc := time.FixedZone("X", -5*60*60)
+	now := time.Date(2026, time.March, 7, 13, 45, 0, 0, loc)
+
+	from, to, err := resolveRange("", "", now, loc)
+	if err != nil || !from.Equal(now) || !to.Equal(time.Date(2026, time.March, 8, 0, 0, 0, 0, loc)) {
+		t.Fatalf("default range = %v..%v (%v)", from, to, err)
+	}
+	from, to, err = resolveRange("2026-03-10", "2026-03-11", now, loc)
+	if err != nil || !from.Equal(time.Date(2026, time.March, 10, 0, 0, 0, 0, loc)) || !to.Equal(time.Date(2026, time.March, 12, 0, 0, 0, 0, loc)) {
+		t.Fatalf("date range = %v..%v (%v)", from, to, err)
+	}
+	from, to, err = resolveRange("2026-03-10T09:30", "2026-03-10T11:00", now, loc)
+	if err != nil || from.Hour() != 9 || from.Minute() != 30 || to.Hour() != 11 {
+		t.Fatalf("time range = %v..%v (%v)", from, to, err)
+	}
+	if _, _, err := resolveRange("2026-03-10T11:00", "2026-03-10T09:00", now, loc); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for reversed range, got %v", err)
+	}
+	if _, _, err := resolveRange("soon", "", now, loc); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for bad date, got %v", err)
+	}
+	if _, err := parseWorkingHours("17:00", "09:00"); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for reversed hours, got %v", err)
+	}
+}
@@ -0,0 +1,516 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Files of the helper build cache, inside fantasticalCacheDir.
+const (
+	helperBinaryName   = "eventkit-helper"
+	helperSourceName   = "eventkit-helper.swift"
+	helperManifestName = "eventkit-helper.json"
+	helperLockName     = "eventkit-helper.lock"
+	// helperLegacyHashName recorded only the source hash; it is removed on
+	// the next build.
+	helperLegacyHashName = "eventkit-helper.hash"
+)
+
+// helperManifest records what the cached helper was built from and the
+// digest of the binary, which is verified before every use. Version is the
+// helper's handshake, taken once after the build so that commands do not
+// have to run version --json while the digest still matches.
+type helperManifest struct {
+	SourceHash string         `json:"sourceHash"`
+	SHA256     string         `json:"sha256"`
+	Compiler   string         `json:"compiler"`
+	Universal  bool           `json:"universal,omitempty"`
+	BuiltAt    time.Time      `json:"builtAt"`
+	Version    *helperVersion `json:"version,omitempty"`
+}
+
+// helperBuildOptions controls ensureEventKitHelper.
+type helperBuildOptions struct {
+	// force rebuilds even a valid cached helper.
+	force bool
+	// universal builds an arm64 + x86_64 binary; a cached single-arch
+	// helper is rebuilt.
+	universal bool
+}
+
+// compileHelper builds the Swift source at sourcePath into outputPath and
+// names the compiler it used. Tests replace it with a stub compiler.
+var compileHelper = compileSwiftHelper
+
+// helperTargets are the slices of a universal helper. The source checks
+// for the macOS 14 full-access APIs with #available, so macOS 13 is enough.
+var helperTargets = []string{"arm64-apple-macos13", "x86_64-apple-macos13"}
+
+// eventKitHelperPath is where the compiled helper is cached.
+func eventKitHelperPath(cacheRoot string) string {
+	return filepath.Join(cacheRoot, helperBinaryName)
+}
+
+// ensureCacheDir creates the cache directory readable by the user alone.
+// Older versions created it 0755, so existing directories are tightened.
+func ensureCacheDir() (string, error) {
+	root, err := fantasticalCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+	st, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+	if st.Mode().Perm() != 0o700 {
+		if err := os.Chmod(root, 0o700); err != nil {
+			return "", fmt.Errorf("cache dir: %w", err)
+		}
+	}
+	return root, nil
+}
+
+// ensureEventKitHelper returns the cached helper, compiling it when it is
+// missing, was built from other source, no longer matches its recorded
+// digest, or opts asks for a rebuild. Builds are serialized across
+// processes with a file lock and land with a rename, so no process runs a
+// half-written binary.
+func ensureEventKitHelper(ctx context.Context, errOut io.Writer, verbose bool, opts helperBuildOptions) (string, error) {
+	cacheRoot, err := ensureCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("eventkit %w", err)
+	}
+	helperPath := eventKitHelperPath(cacheRoot)
+	manifestPath := filepath.Join(cacheRoot, helperManifestName)
+	usable := func() error {
+		m, err := verifyCachedHelper(cacheRoot)
+		if err == nil && opts.universal && !m.Universal {
+			err = errors.New("is not a universal binary")
+		}
+		return err
+	}
+
+	if !opts.force {
+		err := usable()
+		if err == nil {
+			return helperPath, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			logVerbose(errOut, verbose, "eventkit helper %v; recompiling", err)
+		}
+	}
+
+	seen, _ := os.ReadFile(manifestPath)
+	unlock, err := lockHelperCache(cacheRoot)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	// Another process may have built the helper while this one waited.
+	if current, _ := os.ReadFile(manifestPath); !opts.force || !bytes.Equal(current, seen) {
+		if usable() == nil {
+			logVerbose(errOut, verbose, "eventkit helper built by another process")
+			return helperPath, nil
+		}
+	}
+
+	sourcePath := filepath.Join(cacheRoot, helperSourceName)
+	if err := os.WriteFile(sourcePath, []byte(eventKitHelperProgram()), 0o600); err != nil {
+		return "", fmt.Errorf("eventkit helper source: %w", err)
+	}
+	m, err := buildHelperAt(ctx, helperPath, sourcePath, opts.universal, 0o700, errOut, verbose)
+	if err != nil {
+		return "", err
+	}
+	if v, err := queryHelperVersion(ctx, helperPath); err == nil {
+		m.Version = &v
+	} else {
+		logVerbose(errOut, verbose, "eventkit helper %v", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(manifestPath, append(data, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("eventkit helper manifest: %w", err)
+	}
+	_ = os.Remove(filepath.Join(cacheRoot, helperLegacyHashName))
+	logVerbose(errOut, verbose, "eventkit helper built (sha256 %s)", m.SHA256)
+	return helperPath, nil
+}
+
+// buildHelperAt compiles sourcePath into a temporary file next to path and
+// renames it over path, so path is never a partial binary.
+func buildHelperAt(ctx context.Context, path, sourcePath string, universal bool, perm os.FileMode, errOut io.Writer, verbose bool) (helperManifest, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return helperManifest{}, fmt.Errorf("eventkit helper: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	compiler, err := compileHelper(ctx, sourcePath, tmpPath, universal, errOut, verbose)
+	if err != nil {
+		return helperManifest{}, err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return helperManifest{}, fmt.Errorf("eventkit helper: %w", err)
+	}
+	sum, err := fileSHA256(tmpPath)
+	if err != nil {
+		return helperManifest{}, fmt.Errorf("eventkit helper: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return helperManifest{}, fmt.Errorf("eventkit helper: %w", err)
+	}
+	return helperManifest{
+		SourceHash: eventKitHelperHash(),
+		SHA256:     sum,
+		Compiler:   compiler,
+		Universal:  universal,
+		BuiltAt:    time.Now().UTC(),
+	}, nil
+}
+
+// verifyCachedHelper checks the cached helper against its manifest, which
+// it returns when readable. It wraps fs.ErrNotExist when the helper has not
+// been built yet.
+func verifyCachedHelper(cacheRoot string) (helperManifest, error) {
+	var m helperManifest
+	data, err := os.ReadFile(filepath.Join(cacheRoot, helperManifestName))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("manifest is unreadable (%v)", err)
+	}
+	if m.SourceHash != eventKitHelperHash() {
+		return m, fmt.Errorf("was built from source %s, want %s", m.SourceHash, eventKitHelperHash())
+	}
+	sum, err := fileSHA256(eventKitHelperPath(cacheRoot))
+	if err != nil {
+		return m, err
+	}
+	if sum != m.SHA256 {
+		return m, fmt.Errorf("binary has sha256 %s, recorded %s", sum, m.SHA256)
+	}
+	return m, nil
+}
+
+// lockHelperCache takes an exclusive lock on the cache's lock file so that
+// concurrent CLI processes compile the helper one at a time.
+func lockHelperCache(cacheRoot string) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(cacheRoot, helperLockName), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("eventkit helper lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("eventkit helper lock: %w", err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// compileSwiftHelper runs swiftc through xcrun when available, falling
+// back to swiftc on PATH. A universal build compiles one slice per
+// helperTargets entry and joins them with lipo.
+func compileSwiftHelper(ctx context.Context, sourcePath, outputPath string, universal bool, errOut io.Writer, verbose bool) (string, error) {
+	var toolchains []string
+	if xcrunPath, err := exec.LookPath("xcrun"); err == nil {
+		toolchains = append(toolchains, xcrunPath)
+	}
+	if _, err := exec.LookPath("swiftc"); err == nil {
+		toolchains = append(toolchains, "")
+	}
+
+	for _, xcrun := range toolchains {
+		compiler := "swiftc"
+		if xcrun != "" {
+			compiler = "xcrun swiftc"
+		}
+		run := func(tool string, args ...string) error {
+			name := tool
+			if xcrun != "" {
+				name, args = xcrun, append([]string{tool}, args...)
+			}
+			cmd := subprocess(ctx, name, args...)
+			cmd.Stdout = errOut
+			cmd.Stderr = errOut
+			return runSubprocess(ctx, cmd)
+		}
+		logVerbose(errOut, verbose, "compiling eventkit helper with %s", compiler)
+		if err := runSwiftBuild(run, sourcePath, outputPath, universal); err == nil {
+			return compiler, nil
+		}
+	}
+
+	return "", errors.New("eventkit helper build failed; install Xcode Command Line Tools (xcode-select --install)")
+}
+
+func runSwiftBuild(run func(tool string, args ...string) error, sourcePath, outputPath string, universal bool) error {
+	args := []string{"-O", "-framework", "EventKit"}
+	if !universal {
+		return run("swiftc", append(args, "-o", outputPath, sourcePath)...)
+	}
+	var slices []string
+	defer func() {
+		for _, slice := range slices {
+			os.Remove(slice)
+		}
+	}()
+	for _, target := range helperTargets {
+		arch, _, _ := strings.Cut(target, "-")
+		slice := outputPath + "." + arch
+		slices = append(slices, slice)
+		if err := run("swiftc", append(args, "-target", target, "-o", slice, sourcePath)...); err != nil {
+			return err
+		}
+	}
+	return run("lipo", append([]string{"-create", "-output", outputPath}, slices...)...)
+}
+
+type eventKitHelperOptions struct {
+	force     bool
+	output    string
+	universal bool
+	json      bool
+	verbose   bool
+}
+
+// helperCleanResult is the output of eventkit helper clean --json.
+type helperCleanResult struct {
+	Dir     string   `json:"dir"`
+	Removed []string `json:"removed"`
+}
+
+func eventKitHelperUsage(w io.Writer) {
+	fmt.Fprint(w, "USAGE:\n  fantastical eventkit helper build [--force] [--output path] [--universal] [--json]\n  fantastical eventkit helper path\n  fantastical eventkit helper info [--json]\n  fantastical eventkit helper clean [--json]\n")
+	fmt.Fprint(w, "\nEXAMPLES:\n  fantastical eventkit helper build --universal\n  fantastical eventkit helper build --output ./eventkit-helper\n  fantastical eventkit helper info --json\n  fantastical eventkit helper clean\n")
+}
+
+func cmdEventKitHelper(ctx context.Context, args []string, out, errOut io.Writer) error {
+	if len(args) < 1 {
+		eventKitHelperUsage(errOut)
+		return fmt.Errorf("%w: missing eventkit helper subcommand", errUsage)
+	}
+
+	sub := strings.ToLower(strings.TrimSpace(args[0]))
+	opts := eventKitHelperOptions{}
+	var targets map[string]any
+	switch sub {
+	case "build":
+		targets = map[string]any{"force": &opts.force, "output": &opts.output, "universal": &opts.universal, "json": &opts.json, "verbose": &opts.verbose}
+	case "path":
+		targets = map[string]any{}
+	case "info", "clean":
+		targets = map[string]any{"json": &opts.json}
+	default:
+		eventKitHelperUsage(errOut)
+		return fmt.Errorf("%w: unknown eventkit helper subcommand %q", errUsage, sub)
+	}
+
+	fs := flag.NewFlagSet("eventkit helper "+sub, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	bindFlags(fs, "eventkit helper "+sub, targets)
+	fs.Usage = func() {
+		eventKitHelperUsage(errOut)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+
+	switch sub {
+	case "build":
+		return helperBuild(ctx, opts, out, errOut)
+	case "path":
+		info := inspectEventKitHelper(ctx)
+		if !info.OK {
+			fmt.Fprintf(errOut, "[fantastical] warning: eventkit helper %s\n", info.Error)
+		}
+		fmt.Fprintln(out, info.Path)
+		return nil
+	case "info":
+		info := inspectEventKitHelper(ctx)
+		if opts.json {
+			return writeJSON(out, info)
+		}
+		writeHelperInfo(out, info)
+		return nil
+	}
+	return helperClean(opts, out)
+}
+
+func helperBuild(ctx context.Context, opts eventKitHelperOptions, out, errOut io.Writer) error {
+	var info eventKitHelperInfo
+	if opts.output != "" {
+		// A standalone build leaves the cache alone; the source is
+		// written to a scratch directory for the compiler.
+		output, err := filepath.Abs(expandHome(opts.output))
+		if err != nil {
+			return err
+		}
+		dir, err := os.MkdirTemp("", "fantastical-helper-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		sourcePath := filepath.Join(dir, helperSourceName)
+		if err := os.WriteFile(sourcePath, []byte(eventKitHelperProgram()), 0o600); err != nil {
+			return fmt.Errorf("eventkit helper source: %w", err)
+		}
+		m, err := buildHelperAt(ctx, output, sourcePath, opts.universal, 0o755, errOut, opts.verbose)
+		if err != nil {
+			return err
+		}
+		info = eventKitHelperInfo{Path: output, Protocol: eventKitHelperProtocol, SourceHash: eventKitHelperHash(), BinarySHA256: m.SHA256, Compiler: m.Compiler, Universal: m.Universal, BuiltAt: &m.BuiltAt}
+		info.handshake(ctx, true)
+	} else {
+		if _, err := ensureEventKitHelper(ctx, errOut, opts.verbose, helperBuildOptions{force: opts.force, universal: opts.universal}); err != nil {
+			return err
+		}
+		cacheRoot, err := fantasticalCacheDir()
+		if err != nil {
+			return err
+		}
+		info = inspectCachedHelper(ctx, cacheRoot)
+		if override := strings.TrimSpace(os.Getenv("FANTASTICAL_EVENTKIT_HELPER")); override != "" {
+			fmt.Fprintf(errOut, "[fantastical] warning: FANTASTICAL_EVENTKIT_HELPER is set; eventkit commands run %s instead\n", override)
+		}
+	}
+	if !info.OK {
+		return fmt.Errorf("eventkit helper %s: %s", info.Path, info.Error)
+	}
+	if opts.json {
+		return writeJSON(out, info)
+	}
+	fmt.Fprintln(out, info.Path)
+	return nil
+}
+
+func writeHelperInfo(w io.Writer, info eventKitHelperInfo) {
+	fmt.Fprintf(w, "path: %s\n", info.Path)
+	if info.Override {
+		fmt.Fprintln(w, "override: FANTASTICAL_EVENTKIT_HELPER")
+	}
+	fmt.Fprintf(w, "source hash: %s (protocol %d)\n", info.SourceHash, info.Protocol)
+	if info.BinarySHA256 != "" {
+		fmt.Fprintf(w, "binary sha256: %s\n", info.BinarySHA256)
+	}
+	if info.BuiltAt != nil {
+		arch := "native"
+		if info.Universal {
+			arch = "universal"
+		}
+		fmt.Fprintf(w, "built: %s with %s (%s)\n", info.BuiltAt.Local().Format(time.RFC3339), info.Compiler, arch)
+	}
+	fmt.Fprintf(w, "status: %s\n", info.summary())
+}
+
+// helperClean removes the cached helper, its manifest and source, and any
+// leftovers from interrupted builds. The lock file stays: removing it could
+// let a waiting build and a new one run at once.
+func helperClean(opts eventKitHelperOptions, out io.Writer) error {
+	cacheRoot, err := fantasticalCacheDir()
+	if err != nil {
+		return fmt.Errorf("eventkit cache dir: %w", err)
+	}
+	result := helperCleanResult{Dir: cacheRoot, Removed: []string{}}
+	if _, err := os.Stat(cacheRoot); err == nil {
+		unlock, err := lockHelperCache(cacheRoot)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		leftovers, _ := filepath.Glob(filepath.Join(cacheRoot, "."+helperBinaryName+"*"))
+		names := []string{helperBinaryName, helperManifestName, helperSourceName, helperLegacyHashName}
+		for _, path := range leftovers {
+			names = append(names, filepath.Base(path))
+		}
+		for _, name := range names {
+			path := filepath.Join(cacheRoot, name)
+			if err := os.Remove(path); err == nil {
+				result.Removed = append(result.Removed, path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("remove %s: %w", path, err)
+			}
+		}
+	}
+	if opts.json {
+		return writeJSON(out, result)
+	}
+	if len(result.Removed) == 0 {
+		fmt.Fprintf(out, "no eventkit helper in %s\n", cacheRoot)
+	}
+	for _, path := range result.Removed {
+		fmt.Fprintf(out, "removed %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubCompiler replaces swiftc with a compiler that writes a helper script
+// answering the handshake, pausing for delay halfway through the write so
+// a racing reader would see a partial binary. It returns the run count.
+func stubCompiler(t *testing.T, delay time.Duration) *atomic.Int32 {
+	t.Helper()
+	var runs atomic.Int32
+	script := withHandshake(t, "#!/bin/sh\necho ok\n")
+	restore := compileHelper
+	compileHelper = func(ctx context.Context, sourcePath, outputPath string, universal bool, errOut io.Writer, verbose bool) (string, error) {
+		runs.Add(1)
+		source, err := os.ReadFile(sourcePath)
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Contains(source, []byte(`let helperSourceHash = "`+eventKitHelperHash()+`"`)) {
+			return "", errors.New("stub compiler: source hash not filled in")
+		}
+		f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_TRUNC, 0o755)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if _, err := f.Write(script[:len(script)/2]); err != nil {
+			return "", err
+		}
+		time.Sleep(delay)
+		_, err = f.Write(script[len(script)/2:])
+		return "stub", err
+	}
+	t.Cleanup(func() { compileHelper = restore })
+	return &runs
+}
+
+// leftoverTempFiles lists build temp files left in the cache.
+func leftoverTempFiles(t *testing.T, cacheRoot string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(cacheRoot, ".eventkit-helper*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestEnsureEventKitHelperCache(t *testing.T) {
+	isolateHelperEnv(t)
+	runs := stubCompiler(t, 0)
+	cacheRoot, err := fantasticalCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A cache directory and hash file from an older version.
+	if err := os.MkdirAll(cacheRoot, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(cacheRoot, helperLegacyHashName)
+	if err := os.WriteFile(legacy, []byte(eventKitHelperHash()+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := ensureEventKitHelper(context.Background(), io.Discard, false, helperBuildOptions{})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if runs.Load() != 1 || path != eventKitHelperPath(cacheRoot) {
+		t.Fatalf("expected one build into the cache, got %d runs, %s", runs.Load(), path)
+	}
+	for file, want := range map[string]os.FileMode{cacheRoot: 0o700, path: 0o700, filepath.Join(cacheRoot, helperManifestName): 0o600} {
+		if st, err := os.Stat(file); err != nil || st.Mode().Perm() != want {
+			t.Fatalf("%s: want mode %v, got %v (%v)", file, want, st.Mode().Perm(), err)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("expected the legacy hash file removed, got %v", err)
+	}
+	if left := leftoverTempFiles(t, cacheRoot); len(left) != 0 {
+		t.Fatalf("temp files left behind: %v", left)
+	}
+	var manifest helperManifest
+	data, _ := os.ReadFile(filepath.Join(cacheRoot, helperManifestName))
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.SourceHash != eventKitHelperHash() || manifest.BuiltAt.IsZero() {
+		t.Fatalf("unexpected manifest: %s (%v)", data, err)
+	}
+	if sum, _ := fileSHA256(path); sum != manifest.SHA256 {
+		t.Fatalf("manifest digest %s does not match the binary %s", manifest.SHA256, sum)
+	}
+
+	if _, err := ensureEventKitHelper(context.Background(), io.Discard, false, helperBuildOptions{}); err != nil || runs.Load() != 1 {
+		t.Fatalf("expected the cached helper reused, got %d runs (%v)", runs.Load(), err)
+	}
+
+	// A modified binary no longer matches its digest and is rebuilt.
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho tampered\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	var errOut bytes.Buffer
+	if _, err := ensureEventKitHelper(context.Background(), &errOut, true, helperBuildOptions{}); err != nil || runs.Load() != 2 {
+		t.Fatalf("expected a rebuild after tampering, got %d runs (%v)", runs.Load(), err)
+	}
+	if !strings.Contains(errOut.String(), "recorded "+manifest.SHA256) {
+		t.Fatalf("expected the digest mismatch logged, got %q", errOut.String())
+	}
+	if _, err := verifyCachedHelper(cacheRoot); err != nil {
+		t.Fatalf("rebuilt helper does not verify: %v", err)
+	}
+}
+
+func TestEnsureEventKitHelperConcurrent(t *testing.T) {
+	isolateHelperEnv(t)
+	runs := stubCompiler(t, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path, err := ensureEventKitHelper(context.Background(), io.Discard, false, helperBuildOptions{})
+			if err == nil {
+				_, err = checkEventKitHelper(context.Background(), path, []string{"status"}, true)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("caller %d: %v", i, err)
+		}
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("expected one compile for concurrent callers, got %d", runs.Load())
+	}
+}
+
+func TestEnsureEventKitHelperBuildFailure(t *testing.T) {
+	isolateHelperEnv(t)
+	stubCompiler(t, 0)
+	path, err := ensureEventKitHelper(context.Background(), io.Discard, false, helperBuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	compileHelper = func(ctx context.Context, sourcePath, outputPath string, universal bool, errOut io.Writer, verbose bool) (string, error) {
+		_ = os.WriteFile(outputPath, []byte("partial"), 0o755)
+		return "", errors.New("swiftc: error")
+	}
+	if _, err := ensureEventKitHelper(context.Background(), io.Discard, false, helperBuildOptions{force: true}); err == nil || err.Error() != "swiftc: error" {
+		t.Fatalf("expected the compiler error, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Fatalf("a failed build replaced the helper: %q", after)
+	}
+	cacheRoot := filepath.Dir(path)
+	if _, err := verifyCachedHelper(cacheRoot); err != nil {
+		t.Fatalf("the previous helper should still verify: %v", err)
+	}
+	if left := leftoverTempFiles(t, cacheRoot); len(left) != 0 {
+		t.Fatalf("temp files left behind: %v", left)
+	}
+}
+
+// fakeToolchain puts an xcrun on PATH that logs its arguments and, for
+// both swiftc and lipo, writes a helper answering the handshake to the
+// -o or -output path. It returns the log path.
+func fakeToolchain(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "helper"), withHandshake(t, "#!/bin/sh\necho ok\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "xcrun.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\nout=\nwhile [ $# -gt 0 ]; do\n  case $1 in -o|-output) out=$2; shift;; esac\n  shift\ndone\ncp " + filepath.Join(dir, "helper") + " \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "xcrun"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+	return logPath
+}
+
+func runHelperCommand(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), append([]string{"helper"}, args...), &out, &errOut); err != nil {
+		t.Fatalf("eventkit helper %q: %v (%s)", args, err, errOut.String())
+	}
+	return out.String(), errOut.String()
+}
+
+func TestCachedHelperSkipsHandshake(t *testing.T) {
+	isolateHelperEnv(t)
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "")
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := withHandshake(t, "#!/bin/sh\necho ok\n")
+	script = bytes.Replace(script, []byte("\n"), []byte("\necho \"$1\" >> "+calls+"\n"), 1)
+	restore := compileHelper
+	compileHelper = func(ctx context.Context, sourcePath, outputPath string, universal bool, errOut io.Writer, verbose bool) (string, error) {
+		return "stub", os.WriteFile(outputPath, script, 0o755)
+	}
+	t.Cleanup(func() { compileHelper = restore })
+
+	path, err := ensureEventKitHelper(context.Background(), io.Discard, false, helperBuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := verifyCachedHelper(filepath.Dir(path))
+	if err != nil || m.Version == nil || m.Version.SourceHash != eventKitHelperHash() {
+		t.Fatalf("expected the handshake in the manifest, got %+v (%v)", m.Version, err)
+	}
+
+	// A new process has no remembered handshakes.
+	helperVersions.Lock()
+	clear(helperVersions.byKey)
+	helperVersions.Unlock()
+	if err := os.Remove(calls); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eventKitHelperCommand(context.Background(), []string{"status"}, io.Discard, false); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(calls); !os.IsNotExist(err) {
+		t.Fatalf("expected no helper run for a verified cache, got %q (%v)", data, err)
+	}
+	if _, err := eventKitHelperCommand(context.Background(), []string{"status", "--bogus"}, io.Discard, false); err == nil {
+		t.Fatal("expected the recorded handshake to reject an unknown flag")
+	}
+}
+
+func TestCompileSwiftHelperUniversal(t *testing.T) {
+	isolateHelperEnv(t)
+	logPath := fakeToolchain(t)
+	dir := t.TempDir()
+	output := filepath.Join(dir, "helper")
+
+	compiler, err := compileSwiftHelper(context.Background(), filepath.Join(dir, "main.swift"), output, true, io.Discard, false)
+	if err != nil || compiler != "xcrun swiftc" {
+		t.Fatalf("compile: %q, %v", compiler, err)
+	}
+	log, _ := os.ReadFile(logPath)
+	want := "swiftc -O -framework EventKit -target arm64-apple-macos13 -o " + output + ".arm64 " + filepath.Join(dir, "main.swift") + "\n" +
+		"swiftc -O -framework EventKit -target x86_64-apple-macos13 -o " + output + ".x86_64 " + filepath.Join(dir, "main.swift") + "\n" +
+		"lipo -create -output " + output + " " + output + ".arm64 " + output + ".x86_64\n"
+	if string(log) != want {
+		t.Fatalf("unexpected toolchain calls:\n%s", log)
+	}
+	if _, err := os.Stat(output + ".arm64"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the per-arch slices removed, got %v", err)
+	}
+}
+
+func TestEventKitHelperCommands(t *testing.T) {
+	isolateHelperEnv(t)
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "")
+	logPath := fakeToolchain(t)
+	cacheRoot, err := fantasticalCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	helperPath := eventKitHelperPath(cacheRoot)
+
+	out, errOut := runHelperCommand(t, "path")
+	if out != helperPath+"\n" || !strings.Contains(errOut, "not built yet") {
+		t.Fatalf("unexpected path output: %q (%q)", out, errOut)
+	}
+
+	out, _ = runHelperCommand(t, "build", "--universal", "--json")
+	var info eventKitHelperInfo
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	sum, _ := fileSHA256(helperPath)
+	if !info.OK || info.Path != helperPath || info.Compiler != "xcrun swiftc" || !info.Universal || info.BinarySHA256 != sum || info.BuiltAt == nil {
+		t.Fatalf("unexpected build info: %+v", info)
+	}
+
+	// An up-to-date universal helper is not rebuilt; --force rebuilds it.
+	runHelperCommand(t, "build")
+	log, _ := os.ReadFile(logPath)
+	if n := strings.Count(string(log), "lipo"); n != 1 {
+		t.Fatalf("expected one build, got %d:\n%s", n, log)
+	}
+	runHelperCommand(t, "build", "--force")
+	log, _ = os.ReadFile(logPath)
+	if n := strings.Count(string(log), "swiftc"); n != 3 {
+		t.Fatalf("expected a native rebuild, got:\n%s", log)
+	}
+
+	out, _ = runHelperCommand(t, "info")
+	for _, want := range []string{"path: " + helperPath, "binary sha256: ", "with xcrun swiftc (native)", "status: ok, protocol 1"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	out, _ = runHelperCommand(t, "clean", "--json")
+	var cleaned helperCleanResult
+	if err := json.Unmarshal([]byte(out), &cleaned); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	want := []string{helperPath, filepath.Join(cacheRoot, helperManifestName), filepath.Join(cacheRoot, helperSourceName)}
+	if strings.Join(cleaned.Removed, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected removals: %v", cleaned.Removed)
+	}
+	if _, err := os.Stat(filepath.Join(cacheRoot, helperLockName)); err != nil {
+		t.Fatalf("expected the lock file kept: %v", err)
+	}
+	if out, _ = runHelperCommand(t, "clean"); !strings.HasPrefix(out, "no eventkit helper in ") {
+		t.Fatalf("unexpected clean output: %q", out)
+	}
+
+	// --output builds elsewhere and leaves the cache alone.
+	output := filepath.Join(t.TempDir(), "eventkit-helper")
+	if out, _ = runHelperCommand(t, "build", "--output", output); out != output+"\n" {
+		t.Fatalf("unexpected build output: %q", out)
+	}
+	if st, err := os.Stat(output); err != nil || st.Mode().Perm() != 0o755 {
+		t.Fatalf("expected an executable helper at %s: %v", output, err)
+	}
+	if _, err := os.Stat(helperPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no cached helper, got %v", err)
+	}
+}
+
+func TestEventKitHelperCommandUsage(t *testing.T) {
+	isolateHelperEnv(t)
+	for _, args := range [][]string{nil, {"rebuild"}, {"path", "extra"}, {"info", "--force"}} {
+		var out, errOut bytes.Buffer
+		if err := cmdEventKit(context.Background(), append([]string{"helper"}, args...), &out, &errOut); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected a usage error, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventKitHelperProtocol is the helper protocol this CLI speaks. Bump it
+// together with helperProtocol in eventKitHelperSource whenever the
+// helper's arguments or output change incompatibly.
+const eventKitHelperProtocol = 1
+
+// helperSourceHashPlaceholder stands for the source hash in
+// eventKitHelperSource until the file is written out for swiftc.
+const helperSourceHashPlaceholder = "@SOURCE_HASH@"
+
+// eventKitHelperHash identifies eventKitHelperSource. The compiled helper
+// reports it from version --json, so a stale binary is detected even when
+// the protocol did not change.
+func eventKitHelperHash() string {
+	sum := sha256.Sum256([]byte(eventKitHelperSource))
+	return hex.EncodeToString(sum[:8])
+}
+
+// eventKitHelperProgram is the Swift source handed to swiftc.
+func eventKitHelperProgram() string {
+	return strings.Replace(eventKitHelperSource, helperSourceHashPlaceholder, eventKitHelperHash(), 1)
+}
+
+// helperVersion is the helper's answer to version --json.
+type helperVersion struct {
+	Protocol   int      `json:"protocol"`
+	SourceHash string   `json:"sourceHash"`
+	Commands   []string `json:"commands"`
+	// Flags maps every flag to the number of values it takes.
+	Flags map[string]int `json:"flags"`
+}
+
+// supports reports why the helper cannot run args, or nil if it can.
+func (v helperVersion) supports(args []string) error {
+	if v.Protocol != eventKitHelperProtocol {
+		return fmt.Errorf("speaks protocol %d, want %d", v.Protocol, eventKitHelperProtocol)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if !slices.Contains(v.Commands, args[0]) {
+		return fmt.Errorf("has no %s subcommand", args[0])
+	}
+	for i := 1; i < len(args); i++ {
+		n, ok := v.Flags[args[i]]
+		if !ok {
+			return fmt.Errorf("does not support %s %s", args[0], args[i])
+		}
+		i += n
+	}
+	return nil
+}
+
+// helperVersions remembers handshakes per binary for the life of the
+// process; the key includes size and mtime so a rebuilt helper is asked
+// again.
+var helperVersions = struct {
+	sync.Mutex
+	byKey map[string]helperVersion
+}{byKey: map[string]helperVersion{}}
+
+// queryHelperVersion runs "<helper> version --json". Helpers that predate
+// the handshake exit with a usage error and are reported as such.
+func queryHelperVersion(ctx context.Context, helper string) (helperVersion, error) {
+	path, err := exec.LookPath(helper)
+	if err != nil {
+		return helperVersion{}, err
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return helperVersion{}, err
+	}
+	key := fmt.Sprintf("%s\x00%d\x00%d", path, st.Size(), st.ModTime().UnixNano())
+	helperVersions.Lock()
+	v, ok := helperVersions.byKey[key]
+	helperVersions.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := subprocess(ctx, path, "version", "--json")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := runSubprocess(ctx, cmd); err != nil {
+		return helperVersion{}, fmt.Errorf("does not answer version --json (%v)", err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &v); err != nil || v.Protocol == 0 {
+		return helperVersion{}, fmt.Errorf("returned no protocol version from version --json")
+	}
+	helperVersions.Lock()
+	helperVersions.byKey[key] = v
+	helperVersions.Unlock()
+	return v, nil
+}
+
+// checkEventKitHelper runs the handshake and checks that the helper can
+// run args. sameSource also requires it to be built from this CLI's source.
+func checkEventKitHelper(ctx context.Context, helper string, args []string, sameSource bool) (helperVersion, error) {
+	v, err := queryHelperVersion(ctx, helper)
+	if err != nil {
+		return v, err
+	}
+	if sameSource && v.SourceHash != eventKitHelperHash() {
+		return v, fmt.Errorf("was built from source %s, want %s", v.SourceHash, eventKitHelperHash())
+	}
+	return v, v.supports(args)
+}
+
+// checkCachedHelper checks the cached helper at path like
+// checkEventKitHelper with sameSource, using the handshake recorded in its
+// manifest when the binary still matches it and running version --json
+// otherwise.
+func checkCachedHelper(ctx context.Context, path string, args []string) error {
+	if m, err := verifyCachedHelper(filepath.Dir(path)); err == nil && m.Version != nil && m.Version.SourceHash == eventKitHelperHash() {
+		return m.Version.supports(args)
+	}
+	_, err := checkEventKitHelper(ctx, path, args, true)
+	return err
+}
+
+// eventKitHelperInfo describes a helper binary for doctor, greta
+// --capabilities and eventkit helper info.
+type eventKitHelperInfo struct {
+	OK       bool   `json:"ok"`
+	Path     string `json:"path"`
+	Override bool   `json:"override"`
+	// Protocol and SourceHash are what this CLI expects.
+	Protocol   int    `json:"protocol"`
+	SourceHash string `json:"sourceHash"`
+	// BinarySHA256 is the digest of the file at Path; the build details
+	// come from the cache manifest and are empty for an override.
+	BinarySHA256 string         `json:"binarySha256,omitempty"`
+	Compiler     string         `json:"compiler,omitempty"`
+	Universal    bool           `json:"universal,omitempty"`
+	BuiltAt      *time.Time     `json:"builtAt,omitempty"`
+	Version      *helperVersion `json:"version,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// inspectEventKitHelper describes the helper eventkit commands would run,
+// without compiling it: a missing or stale cached helper is reported, and
+// rebuilt by the next command that needs it.
+func inspectEventKitHelper(ctx context.Context) eventKitHelperInfo {
+	if override := strings.TrimSpace(os.Getenv("FANTASTICAL_EVENTKIT_HELPER")); override != "" {
+		info := eventKitHelperInfo{Path: override, Override: true, Protocol: eventKitHelperProtocol, SourceHash: eventKitHelperHash()}
+		if path, err := exec.LookPath(override); err == nil {
+			info.BinarySHA256, _ = fileSHA256(path)
+		}
+		info.handshake(ctx, false)
+		return info
+	}
+	cacheRoot, err := fantasticalCacheDir()
+	if err != nil {
+		return eventKitHelperInfo{Protocol: eventKitHelperProtocol, SourceHash: eventKitHelperHash(), Error: err.Error()}
+	}
+	return inspectCachedHelper(ctx, cacheRoot)
+}
+
+// inspectCachedHelper describes the helper in the build cache.
+func inspectCachedHelper(ctx context.Context, cacheRoot string) eventKitHelperInfo {
+	info := eventKitHelperInfo{Path: eventKitHelperPath(cacheRoot), Protocol: eventKitHelperProtocol, SourceHash: eventKitHelperHash()}
+	m, err := verifyCachedHelper(cacheRoot)
+	info.BinarySHA256, _ = fileSHA256(info.Path)
+	if m.SHA256 != "" {
+		info.Compiler, info.Universal = m.Compiler, m.Universal
+		builtAt := m.BuiltAt
+		info.BuiltAt = &builtAt
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		info.Error = "not built yet; it is compiled on first use"
+	case err != nil:
+		info.Error = err.Error() + "; it is recompiled on next use"
+	default:
+		if info.handshake(ctx, true); !info.OK {
+			info.Error += "; it is recompiled on next use"
+		}
+	}
+	return info
+}
+
+// handshake runs version --json against info.Path and records the result.
+func (info *eventKitHelperInfo) handshake(ctx context.Context, sameSource bool) {
+	v, err := checkEventKitHelper(ctx, info.Path, nil, sameSource)
+	if v.Protocol != 0 {
+		info.Version = &v
+	}
+	if err != nil {
+		info.Error = err.Error()
+		return
+	}
+	info.OK = true
+}
+
+// summary is the one-line form doctor and greta print.
+func (info eventKitHelperInfo) summary() string {
+	where := info.Path
+	if info.Override {
+		where += ", FANTASTICAL_EVENTKIT_HELPER"
+	}
+	if !info.OK {
+		return fmt.Sprintf("%s (%s)", info.Error, where)
+	}
+	return fmt.Sprintf("ok, protocol %d, source %s (%s)", info.Version.Protocol, info.Version.SourceHash, where)
+}
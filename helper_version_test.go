@@ -0,0 +1,156 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEventKitHelperProgram(t *testing.T) {
+	program := eventKitHelperProgram()
+	if strings.Contains(program, helperSourceHashPlaceholder) || !strings.Contains(program, `let helperSourceHash = "`+eventKitHelperHash()+`"`) {
+		t.Fatalf("expected the source hash filled in")
+	}
+	v := sourceHelperVersion(t)
+	if len(v.Commands) != 4 || v.Flags["--calendar"] != 1 || v.Flags["--refresh"] != 0 {
+		t.Fatalf("unexpected helper declarations: %+v", v)
+	}
+}
+
+func TestHelperVersionSupports(t *testing.T) {
+	v := sourceHelperVersion(t)
+	for _, args := range [][]string{
+		nil,
+		{"status", "--format", "json"},
+		{"events", "--format", "json", "--calendar", "--refresh", "--from", "2026-10-19T00:00:00Z", "--refresh"},
+	} {
+		if err := v.supports(args); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+	}
+	cases := map[string][]string{
+		"has no reminders subcommand":     {"reminders"},
+		"does not support events --color": {"events", "--format", "json", "--color", "red"},
+	}
+	for want, args := range cases {
+		if err := v.supports(args); err == nil || err.Error() != want {
+			t.Fatalf("%q: expected %q, got %v", args, want, err)
+		}
+	}
+	v.Protocol = 2
+	if err := v.supports(nil); err == nil || !strings.Contains(err.Error(), "protocol 2, want 1") {
+		t.Fatalf("expected a protocol mismatch, got %v", err)
+	}
+}
+
+func writeHelper(t *testing.T, script []byte) string {
+	t.Helper()
+	helper := filepath.Join(t.TempDir(), "helper.sh")
+	if err := os.WriteFile(helper, script, 0o755); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	return helper
+}
+
+func TestEventKitHelperOverrideHandshake(t *testing.T) {
+	isolateHelperEnv(t)
+	v := sourceHelperVersion(t)
+	delete(v.Flags, "--refresh")
+	noRefresh, _ := json.Marshal(v)
+	v = sourceHelperVersion(t)
+	v.Protocol = 2
+	newer, _ := json.Marshal(v)
+
+	cases := map[string][]byte{
+		// Helpers from before the handshake reject the version subcommand.
+		"does not answer version --json":    []byte("#!/bin/sh\necho \"unknown subcommand: $1\" >&2\nexit 2\n"),
+		"speaks protocol 2, want 1":         []byte("#!/bin/sh\necho '" + string(newer) + "'\n"),
+		"does not support events --refresh": []byte("#!/bin/sh\necho '" + string(noRefresh) + "'\n"),
+	}
+	for want, script := range cases {
+		t.Setenv("FANTASTICAL_EVENTKIT_HELPER", writeHelper(t, script))
+		var out, errOut bytes.Buffer
+		err := cmdEventKit(context.Background(), []string{"events", "--today", "--refresh", "--json"}, &out, &errOut)
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "unset FANTASTICAL_EVENTKIT_HELPER") {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+		if out.Len() != 0 {
+			t.Fatalf("the helper must not run after a failed handshake: %q", out.String())
+		}
+	}
+}
+
+func TestEventKitHelperStaleCache(t *testing.T) {
+	isolateHelperEnv(t)
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", "")
+	runs := stubCompiler(t, 0)
+	cacheRoot, err := ensureCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := inspectEventKitHelper(context.Background())
+	if info.OK || !strings.Contains(info.Error, "not built yet") {
+		t.Fatalf("expected an unbuilt helper, got %+v", info)
+	}
+
+	// The manifest matches the binary, but the binary reports other
+	// source: the handshake catches it and the helper is rebuilt.
+	v := sourceHelperVersion(t)
+	v.SourceHash = "0123456789abcdef"
+	stale, _ := json.Marshal(v)
+	helperPath := eventKitHelperPath(cacheRoot)
+	if err := os.WriteFile(helperPath, []byte("#!/bin/sh\necho '"+string(stale)+"'\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := fileSHA256(helperPath)
+	manifest, _ := json.Marshal(helperManifest{SourceHash: eventKitHelperHash(), SHA256: sum})
+	if err := os.WriteFile(filepath.Join(cacheRoot, helperManifestName), manifest, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info = inspectEventKitHelper(context.Background())
+	if info.OK || info.Version == nil || !strings.Contains(info.Error, "built from source 0123456789abcdef") {
+		t.Fatalf("expected a stale helper, got %+v", info)
+	}
+	var errOut bytes.Buffer
+	if _, err := eventKitHelperCommand(context.Background(), []string{"status"}, &errOut, true); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if runs.Load() != 1 || !strings.Contains(errOut.String(), "recompiling") {
+		t.Fatalf("expected one rebuild, got %d (%q)", runs.Load(), errOut.String())
+	}
+	if info = inspectEventKitHelper(context.Background()); !info.OK {
+		t.Fatalf("expected a usable helper after the rebuild, got %+v", info)
+	}
+}
+
+func TestDoctorAndGretaReportHelper(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", writeHelper(t, withHandshake(t, "#!/bin/sh\nexit 1\n")))
+
+	var out, errOut bytes.Buffer
+	_ = cmdDoctor(context.Background(), []string{"--json", "--skip-app"}, &out, &errOut)
+	var doctor doctorResult
+	if err := json.Unmarshal(out.Bytes(), &doctor); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out.String())
+	}
+	helper := doctor.EventKitHelper
+	if !helper.OK || !helper.Override || helper.Version == nil || helper.Version.SourceHash != eventKitHelperHash() {
+		t.Fatalf("unexpected helper report: %+v", helper)
+	}
+
+	out.Reset()
+	if err := cmdGreta(context.Background(), []string{"--capabilities", "--format", "markdown"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "- EventKit helper: ok, protocol 1, source "+eventKitHelperHash()) {
+		t.Fatalf("unexpected capabilities:\n%s", out.String())
+	}
+}
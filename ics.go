@@ -0,0 +1,415 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsProperty is one content line, e.g. DTSTART;TZID=Europe/Zagreb:20261020T090000.
+// Names and parameter keys are upper-cased; values are kept raw.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icsComponent is a BEGIN/END block such as VCALENDAR or VEVENT.
+type icsComponent struct {
+	name     string
+	props    []icsProperty
+	children []*icsComponent
+}
+
+func (c *icsComponent) prop(name string) (icsProperty, bool) {
+	for _, p := range c.props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return icsProperty{}, false
+}
+
+func (c *icsComponent) propValue(name string) string {
+	p, _ := c.prop(name)
+	return p.value
+}
+
+func (c *icsComponent) allProps(name string) []icsProperty {
+	var props []icsProperty
+	for _, p := range c.props {
+		if p.name == name {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+func (c *icsComponent) components(name string) []*icsComponent {
+	var found []*icsComponent
+	for _, child := range c.children {
+		if child.name == name {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+// parseICS parses iCalendar text (RFC 5545) into its top-level components,
+// normally one VCALENDAR.
+func parseICS(data string) ([]*icsComponent, error) {
+	root := &icsComponent{}
+	stack := []*icsComponent{root}
+	for i, line := range unfoldICSLines(data) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := parseICSLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		top := stack[len(stack)-1]
+		switch p.name {
+		case "BEGIN":
+			c := &icsComponent{name: strings.ToUpper(p.value)}
+			top.children = append(top.children, c)
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 1 || top.name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, p.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("line %d: property %s outside a component", i+1, p.name)
+			}
+			top.props = append(top.props, p)
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1].name)
+	}
+	return root.children, nil
+}
+
+// unfoldICSLines splits on CRLF or LF and joins continuation lines, which
+// start with a space or tab.
+func unfoldICSLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseICSLine splits NAME;PARAM=VALUE;...:VALUE, honouring quoted
+// parameter values that may contain ':' or ';'.
+func parseICSLine(line string) (icsProperty, error) {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				parts = append(parts, line[start:i])
+				start = i + 1
+			}
+		case ':':
+			if !quoted {
+				parts = append(parts, line[start:i])
+				p := icsProperty{name: strings.ToUpper(parts[0]), value: line[i+1:]}
+				if p.name == "" {
+					return p, fmt.Errorf("missing property name")
+				}
+				for _, param := range parts[1:] {
+					key, value, _ := strings.Cut(param, "=")
+					if p.params == nil {
+						p.params = map[string]string{}
+					}
+					p.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+				}
+				return p, nil
+			}
+		}
+	}
+	return icsProperty{}, fmt.Errorf("missing ':' in %q", line)
+}
+
+// unescapeICSText decodes TEXT escapes: \n, \,, \; and \\.
+func unescapeICSText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeICSText is the inverse of unescapeICSText.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine wraps a content line at 75 octets without splitting UTF-8
+// sequences.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// windowsZones maps the Windows zone names Outlook and Exchange write as
+// TZID to IANA names.
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"Atlantic Standard Time":         "America/Halifax",
+	"E. South America Standard Time": "America/Sao_Paulo",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+}
+
+// icsZones resolves TZID parameters for one calendar. Floating date-times
+// use floating (X-WR-TIMEZONE when the calendar sets it) and dates use
+// local, so all-day events start at local midnight.
+type icsZones struct {
+	floating  *time.Location
+	local     *time.Location
+	vtimezone map[string]*icsComponent
+}
+
+func newICSZones(cal *icsComponent, local *time.Location) icsZones {
+	z := icsZones{floating: local, local: local, vtimezone: map[string]*icsComponent{}}
+	for _, tz := range cal.components("VTIMEZONE") {
+		z.vtimezone[tz.propValue("TZID")] = tz
+	}
+	if name := cal.propValue("X-WR-TIMEZONE"); name != "" {
+		if loc, err := z.location(name); err == nil {
+			z.floating = loc
+		}
+	}
+	return z
+}
+
+// location resolves a TZID: an IANA name, a vendor-prefixed path such as
+// /mozilla.org/20050126_1/Europe/Berlin, a Windows zone name, the
+// VTIMEZONE's X-LIC-LOCATION, and finally the VTIMEZONE's standard offset
+// as a fixed zone.
+func (z icsZones) location(tzid string) (*time.Location, error) {
+	tzid = strings.Trim(strings.TrimSpace(tzid), `"`)
+	if loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil && tzid != "" && !strings.EqualFold(tzid, "local") {
+		return loc, nil
+	}
+	segments := strings.Split(strings.Trim(tzid, "/"), "/")
+	for n := 3; n >= 2; n-- {
+		if len(segments) > n {
+			if loc, err := time.LoadLocation(strings.Join(segments[len(segments)-n:], "/")); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, nil
+		}
+	}
+	if tz, ok := z.vtimezone[tzid]; ok {
+		if name := tz.propValue("X-LIC-LOCATION"); name != "" {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc, nil
+			}
+		}
+		for _, kind := range []string{"STANDARD", "DAYLIGHT"} {
+			for _, c := range tz.components(kind) {
+				if offset, err := parseICSOffset(c.propValue("TZOFFSETTO")); err == nil {
+					return time.FixedZone(tzid, offset), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown TZID %q", tzid)
+}
+
+// parseICSOffset parses UTC offsets such as +0100, -0530 or +013000.
+func parseICSOffset(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 5 && len(s) != 7 || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i+2 > len(s) {
+			break
+		}
+		n, err := strconv.Atoi(s[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", s)
+		}
+		seconds += n * unit
+	}
+	if s[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+// parseTime parses a DATE or DATE-TIME property value. UTC values end in Z,
+// TZID selects a zone, and anything else is floating.
+func (z icsZones) parseTime(p icsProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+	if p.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, z.local)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s date %q", p.name, value)
+		}
+		return t, true, nil
+	}
+	loc := z.floating
+	if strings.HasSuffix(value, "Z") {
+		loc = time.UTC
+		value = strings.TrimSuffix(value, "Z")
+	} else if tzid := p.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = z.location(tzid); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s %q", p.name, p.value)
+	}
+	return t, false, nil
+}
+
+// parseTimeList parses comma-separated EXDATE/RDATE values.
+func (z icsZones) parseTimeList(p icsProperty) ([]time.Time, error) {
+	var times []time.Time
+	for _, value := range strings.Split(p.value, ",") {
+		single := p
+		single.value = value
+		t, _, err := z.parseTime(single)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// icsDuration is a DURATION value: nominal days (which follow the wall
+// clock across DST) plus an exact duration.
+type icsDuration struct {
+	days int
+	d    time.Duration
+}
+
+func (d icsDuration) addTo(t time.Time) time.Time {
+	return t.AddDate(0, 0, d.days).Add(d.d)
+}
+
+// parseICSDuration parses values such as P1D, PT1H30M, P1W or -PT15M.
+func parseICSDuration(s string) (icsDuration, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	sign := 1
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign, value = -1, value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return icsDuration{}, fmt.Errorf("invalid DURATION %q", s)
+	}
+	var out icsDuration
+	inTime, timeParts, digits := false, 0, ""
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits += string(r)
+		case r == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(digits)
+			if err != nil {
+				return icsDuration{}, fmt.Errorf("invalid DURATION %q", s)
+			}
+			digits = ""
+			switch {
+			case r == 'W' && !inTime:
+				out.days += 7 * n
+			case r == 'D' && !inTime:
+				out.days += n
+			case r == 'H' && inTime:
+				out.d += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				out.d += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				out.d += time.Duration(n) * time.Second
+			default:
+				return icsDuration{}, fmt.Errorf("invalid DURATION %q", s)
+			}
+			if inTime {
+				timeParts++
+			}
+		}
+	}
+	if digits != "" || inTime && timeParts == 0 {
+		return icsDuration{}, fmt.Errorf("invalid DURATION %q", s)
+	}
+	out.days *= sign
+	out.d *= time.Duration(sign)
+	return out, nil
+}
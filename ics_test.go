@@ -0,0 +1,163 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICS(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:a\r\n" +
+		"SUMMARY:Lunch\\, then a walk\\; maybe\r\n" +
+		"DESCRIPTION:line one\\nline two \\\\ done\r\n" +
+		"LOCATION;ALTREP=\"https://example.com/a:b;c\":Cafe\r\n" +
+		"ATTENDEE;CN=Ana;PARTSTAT=DECLINED:mailto:ana@example.com\r\n" +
+		"X-LONG:abc\r\n def\r\n\tghi\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	components, err := parseICS(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(components) != 1 || components[0].name != "VCALENDAR" {
+		t.Fatalf("unexpected components: %+v", components)
+	}
+	events := components[0].components("VEVENT")
+	if len(events) != 1 {
+		t.Fatalf("expected one VEVENT, got %d", len(events))
+	}
+	ev := events[0]
+	if got := unescapeICSText(ev.propValue("SUMMARY")); got != "Lunch, then a walk; maybe" {
+		t.Fatalf("summary = %q", got)
+	}
+	if got := unescapeICSText(ev.propValue("DESCRIPTION")); got != "line one\nline two \\ done" {
+		t.Fatalf("description = %q", got)
+	}
+	if p, _ := ev.prop("LOCATION"); p.value != "Cafe" || p.params["ALTREP"] != "https://example.com/a:b;c" {
+		t.Fatalf("location = %+v", p)
+	}
+	if p, _ := ev.prop("ATTENDEE"); p.value != "mailto:ana@example.com" || p.params["PARTSTAT"] != "DECLINED" {
+		t.Fatalf("attendee = %+v", p)
+	}
+	if got := ev.propValue("X-LONG"); got != "abcdefghi" {
+		t.Fatalf("unfolded = %q", got)
+	}
+
+	for _, bad := range []string{
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nSUMMARY\nEND:VCALENDAR\n",
+		"SUMMARY:outside\n",
+		"BEGIN:VCALENDAR\n",
+	} {
+		if _, err := parseICS(bad); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestICSEscapeAndFold(t *testing.T) {
+	text := "Plan; review, ship\nthen \\ rest"
+	if got := unescapeICSText(escapeICSText(text)); got != text {
+		t.Fatalf("round trip = %q", got)
+	}
+	line := "SUMMARY:" + strings.Repeat("ž", 60)
+	folded := foldICSLine(line)
+	for _, physical := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(physical) > 75 {
+			t.Fatalf("line longer than 75 octets: %d", len(physical))
+		}
+	}
+	if got := unfoldICSLines(folded)[0]; got != line {
+		t.Fatalf("unfold(fold) = %q", got)
+	}
+}
+
+func TestICSZones(t *testing.T) {
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	components, err := parseICS(`BEGIN:VCALENDAR
+X-WR-TIMEZONE:America/New_York
+BEGIN:VTIMEZONE
+TZID:Office
+X-LIC-LOCATION:Asia/Tokyo
+END:VTIMEZONE
+BEGIN:VTIMEZONE
+TZID:Custom Standard Time
+BEGIN:STANDARD
+DTSTART:16010101T000000
+TZOFFSETFROM:+0530
+TZOFFSETTO:+0530
+END:STANDARD
+END:VTIMEZONE
+END:VCALENDAR
+`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	z := newICSZones(components[0], zagreb)
+
+	tests := []struct {
+		prop     icsProperty
+		want     string
+		dateOnly bool
+	}{
+		{icsProperty{name: "DTSTART", value: "20261020T090000Z"}, "2026-10-20T09:00:00Z", false},
+		{icsProperty{name: "DTSTART", value: "20261020T090000"}, "2026-10-20T09:00:00-04:00", false},
+		{icsProperty{name: "DTSTART", value: "20261020"}, "2026-10-20T00:00:00+02:00", true},
+		{icsProperty{name: "DTSTART", params: map[string]string{"VALUE": "DATE"}, value: "20261026"}, "2026-10-26T00:00:00+01:00", true},
+		{icsProperty{name: "DTSTART", params: map[string]string{"TZID": "Europe/Zagreb"}, value: "20261026T090000"}, "2026-10-26T09:00:00+01:00", false},
+		{icsProperty{name: "DTSTART", params: map[string]string{"TZID": "/mozilla.org/20050126_1/America/Argentina/Buenos_Aires"}, value: "20261020T090000"}, "2026-10-20T09:00:00-03:00", false},
+		{icsProperty{name: "DTSTART", params: map[string]string{"TZID": "Pacific Standard Time"}, value: "20261020T090000"}, "2026-10-20T09:00:00-07:00", false},
+		{icsProperty{name: "DTSTART", params: map[string]string{"TZID": "Office"}, value: "20261020T090000"}, "2026-10-20T09:00:00+09:00", false},
+		{icsProperty{name: "DTSTART", params: map[string]string{"TZID": "Custom Standard Time"}, value: "20261020T090000"}, "2026-10-20T09:00:00+05:30", false},
+	}
+	for _, tc := range tests {
+		got, dateOnly, err := z.parseTime(tc.prop)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.prop, err)
+		}
+		if got.Format(time.RFC3339) != tc.want || dateOnly != tc.dateOnly {
+			t.Fatalf("%+v: got %s (dateOnly=%v), want %s", tc.prop, got.Format(time.RFC3339), dateOnly, tc.want)
+		}
+	}
+
+	for _, bad := range []icsProperty{
+		{name: "DTSTART", params: map[string]string{"TZID": "Mars/Olympus_Mons"}, value: "20261020T090000"},
+		{name: "DTSTART", value: "2026-10-20"},
+		{name: "DTSTART", value: "20261020T0900"},
+	} {
+		if _, _, err := z.parseTime(bad); err == nil {
+			t.Fatalf("%+v: expected error", bad)
+		}
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		days  int
+		d     time.Duration
+	}{
+		{"PT1H30M", 0, 90 * time.Minute},
+		{"P1D", 1, 0},
+		{"P2W", 14, 0},
+		{"P1DT12H", 1, 12 * time.Hour},
+		{"-PT15M", 0, -15 * time.Minute},
+		{"PT45S", 0, 45 * time.Second},
+	}
+	for _, tc := range tests {
+		got, err := parseICSDuration(tc.value)
+		if err != nil || got.days != tc.days || got.d != tc.d {
+			t.Fatalf("%s: got %+v, %v", tc.value, got, err)
+		}
+	}
+	for _, bad := range []string{"", "P", "1H", "PT", "P1H", "PT1D", "P1DT", "PT5"} {
+		if _, err := parseICSDuration(bad); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,627 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// icsBackend reads calendars from iCalendar files. The path is either one
+// .ics file, a vdir collection (a directory with a displayname or color
+// file), or a directory whose .ics files and vdir collections (one per
+// subdirectory) are each a calendar. Files are re-read on every call.
+type icsBackend struct {
+	calendars []icsCalendar
+	errOut    io.Writer
+	verbose   bool
+}
+
+// icsCalendar is one .ics file, or a vdir collection whose .ics files are
+// merged. Only collections accept new events.
+type icsCalendar struct {
+	info  calendarInfo
+	files []string
+	dir   string
+}
+
+// vdirMetadataFiles mark a directory as a vdir collection.
+var vdirMetadataFiles = []string{"displayname", "color"}
+
+func newICSBackend(path string, errOut io.Writer, verbose bool) (*icsBackend, error) {
+	b := &icsBackend{errOut: errOut, verbose: verbose}
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("ics backend: %w", err)
+	}
+	switch {
+	case !st.IsDir():
+		cal, err := icsFileCalendar(path)
+		if err != nil {
+			return nil, err
+		}
+		b.calendars = []icsCalendar{cal}
+	case isVdirCollection(path):
+		cal, err := icsCollection(path)
+		if err != nil {
+			return nil, err
+		}
+		b.calendars = []icsCalendar{cal}
+	default:
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("ics backend: %w", err)
+		}
+		for _, entry := range entries {
+			full := filepath.Join(path, entry.Name())
+			switch {
+			case strings.HasPrefix(entry.Name(), "."):
+			case entry.IsDir():
+				cal, err := icsCollection(full)
+				if err != nil {
+					return nil, err
+				}
+				if len(cal.files) > 0 || isVdirCollection(full) {
+					b.calendars = append(b.calendars, cal)
+				}
+			case strings.EqualFold(filepath.Ext(entry.Name()), ".ics"):
+				cal, err := icsFileCalendar(full)
+				if err != nil {
+					return nil, err
+				}
+				b.calendars = append(b.calendars, cal)
+			}
+		}
+		if len(b.calendars) == 0 {
+			return nil, fmt.Errorf("ics backend: no .ics files or vdir collections in %s", path)
+		}
+	}
+	return b, nil
+}
+
+func isVdirCollection(dir string) bool {
+	for _, name := range vdirMetadataFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// icsFileCalendar describes a single exported calendar file, titled by its
+// X-WR-CALNAME or file name.
+func icsFileCalendar(path string) (icsCalendar, error) {
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	title, err := icsCalendarName(path)
+	if err != nil {
+		return icsCalendar{}, err
+	}
+	if title == "" {
+		title = id
+	}
+	return icsCalendar{
+		info:  calendarInfo{ID: id, Title: title, Source: "ics", Type: "local"},
+		files: []string{path},
+	}, nil
+}
+
+// icsCollection describes a vdir collection, titled by its displayname
+// file, then the first X-WR-CALNAME, then the directory name.
+func icsCollection(dir string) (icsCalendar, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return icsCalendar{}, fmt.Errorf("ics backend: %w", err)
+	}
+	cal := icsCalendar{
+		info: calendarInfo{ID: filepath.Base(dir), Source: "vdir", Type: "local", AllowsModifications: true},
+		dir:  dir,
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && strings.EqualFold(filepath.Ext(entry.Name()), ".ics") {
+			cal.files = append(cal.files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "displayname")); err == nil {
+		cal.info.Title = strings.TrimSpace(string(data))
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "color")); err == nil {
+		cal.info.Color = strings.TrimSpace(string(data))
+	}
+	for _, file := range cal.files {
+		if cal.info.Title != "" {
+			break
+		}
+		if cal.info.Title, err = icsCalendarName(file); err != nil {
+			return icsCalendar{}, err
+		}
+	}
+	if cal.info.Title == "" {
+		cal.info.Title = cal.info.ID
+	}
+	return cal, nil
+}
+
+// icsCalendarName returns a file's X-WR-CALNAME without parsing events.
+func icsCalendarName(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("ics backend: %w", err)
+	}
+	for _, line := range unfoldICSLines(string(data)) {
+		if strings.HasPrefix(strings.ToUpper(line), "X-WR-CALNAME:") {
+			return unescapeICSText(line[len("X-WR-CALNAME:"):]), nil
+		}
+		if strings.HasPrefix(strings.ToUpper(line), "BEGIN:VEVENT") {
+			break
+		}
+	}
+	return "", nil
+}
+
+// warn reports data the backend skipped, since a calendar that silently
+// loses events is worse than a noisy one.
+func (b *icsBackend) warn(format string, args ...any) {
+	fmt.Fprintf(b.errOut, "[fantastical] warning: "+format+"\n", args...)
+}
+
+func (b *icsBackend) Name() string { return "ics" }
+
+func (b *icsBackend) ListCalendars(ctx context.Context) ([]calendarInfo, error) {
+	calendars := make([]calendarInfo, len(b.calendars))
+	for i, cal := range b.calendars {
+		calendars[i] = cal.info
+	}
+	return calendars, nil
+}
+
+// ListEvents expands each calendar's events (RRULE, RDATE, EXDATE and
+// RECURRENCE-ID overrides) over req.Range. Cancelled events count as
+// declined.
+func (b *icsBackend) ListEvents(ctx context.Context, req eventFetch) ([]eventInfo, error) {
+	loc := req.Range.From.Location()
+	var pages [][]eventInfo
+	for _, cal := range b.calendars {
+		if !calendarSelected(cal.info, req.Calendars) {
+			continue
+		}
+		set := newICSSeriesSet()
+		for _, file := range cal.files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("ics backend: %w", err)
+			}
+			set.add(file, string(data), loc, b.warn)
+		}
+		pages = append(pages, set.occurrences(req, cal.info, b.warn))
+	}
+	return mergeEventPages(pages), nil
+}
+
+// calendarSelected reports whether cal matches one of refs; no refs selects
+// every calendar.
+func calendarSelected(cal calendarInfo, refs []calendarRef) bool {
+	if len(refs) == 0 {
+		return true
+	}
+	for _, ref := range refs {
+		if ref.ID != "" && ref.ID == cal.ID || !ref.byID && ref.Title == cal.Title {
+			return true
+		}
+	}
+	return false
+}
+
+// findCalendar looks up the calendar an event is written to by ID or
+// case-insensitive title.
+func findCalendar(calendars []calendarInfo, name string) (int, error) {
+	for i, cal := range calendars {
+		if cal.ID == name || strings.EqualFold(cal.Title, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: unknown calendar %q", errUsage, name)
+}
+
+// icsEvent is a VEVENT: a single event, a recurring master or, when
+// recurrenceID is set, an override of one occurrence.
+type icsEvent struct {
+	uid          string
+	summary      string
+	location     string
+	description  string
+	cancelled    bool
+	start, end   time.Time
+	allDay       bool
+	rrule        string
+	rdates       []time.Time
+	exdates      []time.Time
+	recurrenceID time.Time
+}
+
+// icsSeries groups a UID's master event with its overrides.
+type icsSeries struct {
+	master    *icsEvent
+	overrides []icsEvent
+}
+
+// warnFunc reports skipped data; see icsBackend.warn.
+type warnFunc func(format string, args ...any)
+
+// icsSeriesSet groups a calendar's VEVENTs by UID.
+type icsSeriesSet struct {
+	byUID map[string]*icsSeries
+	order []*icsSeries
+}
+
+func newICSSeriesSet() *icsSeriesSet {
+	return &icsSeriesSet{byUID: map[string]*icsSeries{}}
+}
+
+// add parses one iCalendar object, a file or a CalDAV resource named by
+// source. Objects or events that cannot be parsed are skipped with a
+// warning. Floating times are read in loc.
+func (set *icsSeriesSet) add(source, data string, loc *time.Location, warn warnFunc) {
+	components, err := parseICS(data)
+	if err != nil {
+		warn("skipping %s: %v", source, err)
+		return
+	}
+	for _, vcal := range components {
+		if vcal.name != "VCALENDAR" {
+			continue
+		}
+		zones := newICSZones(vcal, loc)
+		for _, vevent := range vcal.components("VEVENT") {
+			ev, err := parseICSEvent(vevent, zones)
+			if err != nil {
+				warn("skipping event in %s: %v", source, err)
+				continue
+			}
+			s := set.byUID[ev.uid]
+			if s == nil || ev.uid == "" {
+				s = &icsSeries{}
+				set.order = append(set.order, s)
+				if ev.uid != "" {
+					set.byUID[ev.uid] = s
+				}
+			}
+			if ev.recurrenceID.IsZero() {
+				s.master = &ev
+			} else {
+				s.overrides = append(s.overrides, ev)
+			}
+		}
+	}
+}
+
+// occurrences lists the events of cal that req selects, in req.Range's
+// location.
+func (set *icsSeriesSet) occurrences(req eventFetch, cal calendarInfo, warn warnFunc) []eventInfo {
+	loc := req.Range.From.Location()
+	var events []eventInfo
+	for _, s := range set.order {
+		for _, occ := range s.expand(req.Range, warn) {
+			if occ.allDay && !req.IncludeAllDay || occ.cancelled && !req.IncludeDeclined {
+				continue
+			}
+			events = append(events, eventInfo{
+				ID:         occ.uid,
+				Title:      occ.summary,
+				Calendar:   cal.Title,
+				CalendarID: cal.ID,
+				Start:      occ.start.In(loc),
+				End:        occ.end.In(loc),
+				AllDay:     occ.allDay,
+				Location:   occ.location,
+				Notes:      occ.description,
+			})
+		}
+	}
+	return events
+}
+
+func parseICSEvent(c *icsComponent, z icsZones) (icsEvent, error) {
+	ev := icsEvent{
+		uid:         c.propValue("UID"),
+		summary:     unescapeICSText(c.propValue("SUMMARY")),
+		location:    unescapeICSText(c.propValue("LOCATION")),
+		description: unescapeICSText(c.propValue("DESCRIPTION")),
+		cancelled:   strings.EqualFold(c.propValue("STATUS"), "CANCELLED"),
+		rrule:       c.propValue("RRULE"),
+	}
+	dtstart, ok := c.prop("DTSTART")
+	if !ok {
+		return ev, fmt.Errorf("%s: missing DTSTART", ev.uid)
+	}
+	var err error
+	if ev.start, ev.allDay, err = z.parseTime(dtstart); err != nil {
+		return ev, fmt.Errorf("%s: %w", ev.uid, err)
+	}
+	if dtend, ok := c.prop("DTEND"); ok {
+		if ev.end, _, err = z.parseTime(dtend); err != nil {
+			return ev, fmt.Errorf("%s: %w", ev.uid, err)
+		}
+	} else if value := c.propValue("DURATION"); value != "" {
+		d, err := parseICSDuration(value)
+		if err != nil {
+			return ev, fmt.Errorf("%s: %w", ev.uid, err)
+		}
+		ev.end = d.addTo(ev.start)
+	} else if ev.allDay {
+		ev.end = ev.start.AddDate(0, 0, 1)
+	} else {
+		ev.end = ev.start
+	}
+	if ev.end.Before(ev.start) {
+		ev.end = ev.start
+	}
+	for _, p := range c.allProps("EXDATE") {
+		times, err := z.parseTimeList(p)
+		if err != nil {
+			return ev, fmt.Errorf("%s: %w", ev.uid, err)
+		}
+		ev.exdates = append(ev.exdates, times...)
+	}
+	for _, p := range c.allProps("RDATE") {
+		if p.params["VALUE"] == "PERIOD" {
+			continue
+		}
+		times, err := z.parseTimeList(p)
+		if err != nil {
+			return ev, fmt.Errorf("%s: %w", ev.uid, err)
+		}
+		ev.rdates = append(ev.rdates, times...)
+	}
+	if p, ok := c.prop("RECURRENCE-ID"); ok {
+		if ev.recurrenceID, _, err = z.parseTime(p); err != nil {
+			return ev, fmt.Errorf("%s: %w", ev.uid, err)
+		}
+	}
+	return ev, nil
+}
+
+// expand returns the series' occurrences that overlap r, in start order.
+func (s *icsSeries) expand(r eventRange, warn warnFunc) []icsEvent {
+	var out []icsEvent
+	add := func(ev icsEvent) {
+		if ev.end.After(r.From) && !ev.start.After(r.To) || ev.end.Equal(ev.start) && !ev.start.Before(r.From) && !ev.start.After(r.To) {
+			out = append(out, ev)
+		}
+	}
+	for _, o := range s.overrides {
+		add(o)
+	}
+	m := s.master
+	if m == nil {
+		return out
+	}
+
+	starts := []time.Time{m.start}
+	if m.rrule != "" {
+		rule, err := parseRRule(m.rrule, m.start.Location())
+		if err != nil {
+			warn("%s: %v; listing the first occurrence only", m.uid, err)
+		} else {
+			starts = rule.starts(m.start, r.To)
+		}
+	}
+	starts = append(starts, m.rdates...)
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	length := m.end.Sub(m.start)
+	days := int(civilDate(m.end).Sub(civilDate(m.start)).Hours() / 24)
+	seen := map[int64]bool{}
+	for _, start := range starts {
+		if seen[start.UnixNano()] || m.excluded(start) || s.overridden(start, m.allDay) {
+			continue
+		}
+		seen[start.UnixNano()] = true
+		ev := *m
+		ev.start = start
+		if m.allDay {
+			ev.end = start.AddDate(0, 0, days)
+		} else {
+			ev.end = start.Add(length)
+		}
+		add(ev)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].start.Before(out[j].start) })
+	return out
+}
+
+// excluded reports whether EXDATE removes the occurrence at start; a
+// date-only EXDATE removes any occurrence on that day.
+func (e *icsEvent) excluded(start time.Time) bool {
+	for _, ex := range e.exdates {
+		if ex.Equal(start) || e.allDay && civilDate(ex) == civilDate(start) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *icsSeries) overridden(start time.Time, allDay bool) bool {
+	for _, o := range s.overrides {
+		if o.recurrenceID.Equal(start) || allDay && civilDate(o.recurrenceID) == civilDate(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateEvent writes a new single-event file into a vdir collection, the
+// way vdirsyncer and khal store events.
+func (b *icsBackend) CreateEvent(ctx context.Context, ev newEvent) (eventInfo, error) {
+	cal, err := b.collection(ev.Calendar)
+	if err != nil {
+		return eventInfo{}, err
+	}
+	ev, err = ev.normalize()
+	if err != nil {
+		return eventInfo{}, err
+	}
+	name, uid, text, err := ev.resource()
+	if err != nil {
+		return eventInfo{}, err
+	}
+
+	tmp, err := os.CreateTemp(cal.dir, ".fantastical-*.tmp")
+	if err != nil {
+		return eventInfo{}, fmt.Errorf("ics backend: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return eventInfo{}, fmt.Errorf("ics backend: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return eventInfo{}, fmt.Errorf("ics backend: %w", err)
+	}
+	path := filepath.Join(cal.dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return eventInfo{}, fmt.Errorf("ics backend: %w", err)
+	}
+	cal.files = append(cal.files, path)
+	return ev.info(uid, cal.info), nil
+}
+
+// DeleteEvent removes the file holding the event with UID id from a vdir
+// collection. Files that also hold other events are left alone.
+func (b *icsBackend) DeleteEvent(ctx context.Context, calendar, id string) error {
+	cal, err := b.collection(calendar)
+	if err != nil {
+		return err
+	}
+	for i, file := range cal.files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("ics backend: %w", err)
+		}
+		components, err := parseICS(string(data))
+		if err != nil {
+			continue
+		}
+		found, others := false, false
+		for _, vcal := range components {
+			for _, vevent := range vcal.components("VEVENT") {
+				if vevent.propValue("UID") == id {
+					found = true
+				} else {
+					others = true
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		if others {
+			return fmt.Errorf("ics backend: %s also holds other events: %w", file, errBackendUnsupported)
+		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("ics backend: %w", err)
+		}
+		cal.files = append(cal.files[:i], cal.files[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("%w: %q in %s", errEventNotFound, id, cal.info.Title)
+}
+
+// collection returns the writable vdir collection named by calendar.
+func (b *icsBackend) collection(calendar string) (*icsCalendar, error) {
+	infos := make([]calendarInfo, len(b.calendars))
+	for i, cal := range b.calendars {
+		infos[i] = cal.info
+	}
+	i, err := findCalendar(infos, calendar)
+	if err != nil {
+		return nil, err
+	}
+	cal := &b.calendars[i]
+	if cal.dir == "" {
+		return nil, fmt.Errorf("ics backend: calendar %q is a single .ics file: %w", cal.info.Title, errBackendUnsupported)
+	}
+	return cal, nil
+}
+
+// normalize checks a new event and fills in a default end: one hour, or
+// one day for all-day events.
+func (ev newEvent) normalize() (newEvent, error) {
+	if strings.TrimSpace(ev.Title) == "" {
+		return ev, fmt.Errorf("%w: missing event title", errUsage)
+	}
+	if ev.End.IsZero() {
+		if ev.AllDay {
+			ev.End = ev.Start.AddDate(0, 0, 1)
+		} else {
+			ev.End = ev.Start.Add(time.Hour)
+		}
+	}
+	if ev.End.Before(ev.Start) {
+		return ev, fmt.Errorf("%w: event end is before its start", errUsage)
+	}
+	return ev, nil
+}
+
+// resource renders ev as a single-event iCalendar object with a random
+// UID; name is the matching <hex>.ics file or resource name.
+func (ev newEvent) resource() (name, uid, text string, err error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", "", "", err
+	}
+	name = hex.EncodeToString(raw[:]) + ".ics"
+	uid = hex.EncodeToString(raw[:]) + "@fantastical-cli"
+	dtstart := "DTSTART:" + ev.Start.UTC().Format("20060102T150405Z")
+	dtend := "DTEND:" + ev.End.UTC().Format("20060102T150405Z")
+	if ev.AllDay {
+		dtstart = "DTSTART;VALUE=DATE:" + ev.Start.Format("20060102")
+		dtend = "DTEND;VALUE=DATE:" + ev.End.Format("20060102")
+	}
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//fantastical-cli//EN",
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTAMP:" + nowFunc().UTC().Format("20060102T150405Z"),
+		dtstart,
+		dtend,
+		"SUMMARY:" + escapeICSText(ev.Title),
+	}
+	if ev.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeICSText(ev.Location))
+	}
+	if ev.Notes != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeICSText(ev.Notes))
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+	}
+	return name, uid, b.String(), nil
+}
+
+// info describes ev once it is stored in cal under uid.
+func (ev newEvent) info(uid string, cal calendarInfo) eventInfo {
+	return eventInfo{
+		ID:         uid,
+		Title:      ev.Title,
+		Calendar:   cal.Title,
+		CalendarID: cal.ID,
+		Start:      ev.Start,
+		End:        ev.End,
+		AllDay:     ev.AllDay,
+		Location:   ev.Location,
+		Notes:      ev.Notes,
+	}
+}
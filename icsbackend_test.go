@@ -0,0 +1,265 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// icsTestRange is the week of 2026-10-19 in Zagreb, which spans the switch
+// from CEST to CET on 10-25.
+func icsTestRange(t *testing.T) eventRange {
+	t.Helper()
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	return eventRange{
+		From: time.Date(2026, 10, 19, 0, 0, 0, 0, zagreb),
+		To:   time.Date(2026, 10, 26, 23, 59, 59, 0, zagreb),
+	}
+}
+
+func formatICSEvents(events []eventInfo) string {
+	lines := make([]string, len(events))
+	for i, ev := range events {
+		lines[i] = ev.Start.Format(time.RFC3339) + " " + ev.End.Format("15:04") + " " + ev.CalendarID + " " + ev.Title
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestICSBackendListEvents(t *testing.T) {
+	r := icsTestRange(t)
+	var errOut bytes.Buffer
+	b, err := newICSBackend("testdata/ics", &errOut, false)
+	if err != nil {
+		t.Fatalf("newICSBackend: %v", err)
+	}
+
+	calendars, err := b.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("ListCalendars: %v", err)
+	}
+	if len(calendars) != 2 ||
+		calendars[0] != (calendarInfo{ID: "holidays", Title: "Holidays", Source: "ics", Type: "local"}) ||
+		calendars[1] != (calendarInfo{ID: "work", Title: "Work", Source: "vdir", Type: "local", Color: "#FF2968", AllowsModifications: true}) {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+
+	events, err := b.ListEvents(context.Background(), eventFetch{Range: r, IncludeAllDay: true})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	want := strings.Join([]string{
+		"2026-10-19T09:00:00+02:00 09:15 work Daily standup",
+		"2026-10-20T10:00:00+02:00 11:00 holidays Tokyo sync",
+		"2026-10-22T14:00:00+02:00 15:30 work Quarterly review with a very long title that has to be folded across two lines",
+		"2026-10-23T00:00:00+02:00 00:00 holidays Team day",
+		"2026-10-23T10:00:00+02:00 10:15 work Daily standup (moved)",
+		"2026-10-25T00:00:00+02:00 00:30 holidays Call home",
+	}, "\n")
+	if got := formatICSEvents(events); got != want {
+		t.Fatalf("events:\n%s\nwant:\n%s", got, want)
+	}
+	if events[0].Location != "Room 4, 2nd floor" || events[2].Notes != "Agenda:\n1. Numbers\n2. Plans" {
+		t.Fatalf("unexpected text fields: %+v / %+v", events[0], events[2])
+	}
+	if !events[3].AllDay || events[3].ID != "team-day" {
+		t.Fatalf("expected the yearly all-day event: %+v", events[3])
+	}
+	if !strings.Contains(errOut.String(), "[fantastical] warning: skipping event in testdata/ics/holidays.ics: broken: missing DTSTART") {
+		t.Fatalf("expected a warning for the broken event, got %q", errOut.String())
+	}
+
+	events, err = b.ListEvents(context.Background(), eventFetch{
+		Range:           r,
+		Calendars:       []calendarRef{{ID: "work", byID: true}},
+		IncludeDeclined: true,
+	})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	want = strings.Join([]string{
+		"2026-10-19T09:00:00+02:00 09:15 work Daily standup",
+		"2026-10-22T14:00:00+02:00 15:30 work Quarterly review with a very long title that has to be folded across two lines",
+		"2026-10-23T10:00:00+02:00 10:15 work Daily standup (moved)",
+		"2026-10-26T09:00:00+01:00 09:15 work Daily standup",
+	}, "\n")
+	if got := formatICSEvents(events); got != want {
+		t.Fatalf("work events with declined:\n%s\nwant:\n%s", got, want)
+	}
+
+	events, err = b.ListEvents(context.Background(), eventFetch{Range: r, Calendars: []calendarRef{{Title: "Holidays"}}})
+	if err != nil || len(events) != 2 || events[0].Title != "Tokyo sync" || events[1].Title != "Call home" {
+		t.Fatalf("holidays without all-day events: %+v (%v)", events, err)
+	}
+}
+
+func TestICSBackendCreateEvent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "personal")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "displayname"), []byte("Personal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var errOut bytes.Buffer
+	b, err := newICSBackend(dir, &errOut, false)
+	if err != nil {
+		t.Fatalf("newICSBackend: %v", err)
+	}
+
+	start := time.Date(2026, 10, 21, 18, 0, 0, 0, time.UTC)
+	created, err := b.CreateEvent(context.Background(), newEvent{
+		Calendar: "personal",
+		Title:    "Dinner, with friends",
+		Start:    start,
+		Location: "Trg bana Jelačića 1",
+		Notes:    "Bring wine\nand cake",
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if !strings.HasSuffix(created.ID, "@fantastical-cli") || created.Calendar != "Personal" || !created.End.Equal(start.Add(time.Hour)) {
+		t.Fatalf("unexpected created event: %+v", created)
+	}
+	if _, err := b.CreateEvent(context.Background(), newEvent{Calendar: "Personal", Title: "Holiday", Start: time.Date(2026, 10, 22, 0, 0, 0, 0, time.UTC), AllDay: true}); err != nil {
+		t.Fatalf("CreateEvent all-day: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(matches) != 3 {
+		t.Fatalf("expected displayname plus two event files, got %v", matches)
+	}
+
+	reread, err := newICSBackend(dir, &errOut, false)
+	if err != nil {
+		t.Fatalf("newICSBackend: %v", err)
+	}
+	events, err := reread.ListEvents(context.Background(), eventFetch{
+		Range:         eventRange{From: time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 10, 22, 23, 59, 59, 0, time.UTC)},
+		IncludeAllDay: true,
+	})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != created.ID || events[0].Title != "Dinner, with friends" ||
+		events[0].Location != "Trg bana Jelačića 1" || events[0].Notes != "Bring wine\nand cake" || !events[1].AllDay {
+		t.Fatalf("created events did not round-trip: %+v", events)
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("unexpected warnings: %q", errOut.String())
+	}
+
+	if err := reread.DeleteEvent(context.Background(), "Personal", created.ID); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.ics")); len(matches) != 1 {
+		t.Fatalf("expected one event file left, got %v", matches)
+	}
+	if err := reread.DeleteEvent(context.Background(), "Personal", created.ID); !errors.Is(err, errEventNotFound) {
+		t.Fatalf("expected errEventNotFound, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		path string
+		ev   newEvent
+		want error
+	}{
+		{"testdata/ics/holidays.ics", newEvent{Calendar: "Holidays", Title: "x", Start: start}, errBackendUnsupported},
+		{dir, newEvent{Calendar: "Nope", Title: "x", Start: start}, errUsage},
+		{dir, newEvent{Calendar: "Personal", Start: start}, errUsage},
+		{dir, newEvent{Calendar: "Personal", Title: "x", Start: start, End: start.Add(-time.Hour)}, errUsage},
+	} {
+		b, err := newICSBackend(tc.path, &errOut, false)
+		if err != nil {
+			t.Fatalf("newICSBackend(%s): %v", tc.path, err)
+		}
+		if _, err := b.CreateEvent(context.Background(), tc.ev); !errors.Is(err, tc.want) {
+			t.Fatalf("%+v: expected %v, got %v", tc.ev, tc.want, err)
+		}
+	}
+}
+
+func TestResolveBackend(t *testing.T) {
+	var errOut bytes.Buffer
+	b, err := resolveBackend("", &Config{}, &errOut, false)
+	if err != nil || b.Name() != "eventkit" {
+		t.Fatalf("default backend: %v, %v", b, err)
+	}
+	b, err = resolveBackend("", &Config{Backend: BackendConfig{Name: "ics", ICS: ICSBackendConfig{Path: "testdata/ics/work"}}}, &errOut, false)
+	if err != nil || b.Name() != "ics" {
+		t.Fatalf("configured backend: %v, %v", b, err)
+	}
+	if calendars, _ := b.ListCalendars(context.Background()); len(calendars) != 1 || calendars[0].Title != "Work" {
+		t.Fatalf("expected the work collection alone: %+v", calendars)
+	}
+	b, err = resolveBackend("ics:testdata/ics/holidays.ics", &Config{Backend: BackendConfig{Name: "eventkit"}}, &errOut, false)
+	if err != nil || b.Name() != "ics" {
+		t.Fatalf("--backend overrides config: %v, %v", b, err)
+	}
+	for _, spec := range []string{"ics", "eventkit:/tmp", "outlook", "caldav", "caldav:dav.example.com"} {
+		if _, err := resolveBackend(spec, &Config{}, &errOut, false); !errors.Is(err, errUsage) {
+			t.Fatalf("%q: expected usage error, got %v", spec, err)
+		}
+	}
+	if _, err := resolveBackend("ics:testdata/ics/missing", &Config{}, &errOut, false); err == nil || errors.Is(err, errUsage) {
+		t.Fatalf("expected a runtime error for a missing path, got %v", err)
+	}
+}
+
+func TestCmdEventKitICSBackend(t *testing.T) {
+	setupTestEnv(t)
+	icsTestRange(t)
+	restore := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = restore })
+	dir, err := filepath.Abs("testdata/ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := cmdEventKit(context.Background(), []string{"calendars", "--backend", "ics:" + dir, "--format", "table"}, &out, &errOut); err != nil {
+		t.Fatalf("calendars: %v", err)
+	}
+	if !strings.Contains(out.String(), "Holidays") || !strings.Contains(out.String(), "vdir") {
+		t.Fatalf("unexpected calendars table:\n%s", out.String())
+	}
+
+	// The helper is never run: FANTASTICAL_EVENTKIT_HELPER is "false".
+	t.Setenv("FANTASTICAL_BACKEND", "ics:"+dir)
+	out.Reset()
+	errOut.Reset()
+	args := []string{"events", "--from", "2026-10-19", "--to", "2026-10-26", "--tz", "Europe/Zagreb", "--calendar", "Work", "--json"}
+	if err := cmdEventKit(context.Background(), args, &out, &errOut); err != nil {
+		t.Fatalf("events: %v (%s)", err, errOut.String())
+	}
+	var events []eventInfo
+	if err := json.Unmarshal(out.Bytes(), &events); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out.String())
+	}
+	if len(events) != 3 || events[0].Title != "Daily standup" || events[2].Title != "Daily standup (moved)" {
+		t.Fatalf("unexpected events: %s", out.String())
+	}
+
+	out.Reset()
+	if err := cmdEventKit(context.Background(), []string{"search", "tokyo", "--years", "1", "--json"}, &out, &errOut); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &events); err != nil || len(events) != 1 || events[0].ID != "tokyo-sync" {
+		t.Fatalf("unexpected search result: %s (%v)", out.String(), err)
+	}
+
+	if err := cmdEventKit(context.Background(), []string{"events", "--backend", "nope"}, &out, &errOut); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for an unknown backend, got %v", err)
+	}
+}
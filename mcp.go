@@ -0,0 +1,261 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// mcpProtocolVersions lists the MCP revisions the server speaks, newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes used by the MCP server.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type mcpRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+// mcpTool exposes one CLI code path as an MCP tool. Args is the argument
+// struct; its JSON Schema is the tool's inputSchema.
+type mcpTool struct {
+	Name        string
+	Description string
+	Args        reflect.Type
+	Call        func(r *toolRunner, ctx context.Context, raw json.RawMessage) (string, error)
+}
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "create_event_from_sentence",
+		Description: "Create an event in Fantastical from a natural-language sentence (fantastical parse). Returns the parse JSON output.",
+		Args:        reflect.TypeOf(createEventArgs{}),
+		Call:        (*toolRunner).createEvent,
+	},
+	{
+		Name:        "list_events",
+		Description: "List calendar events in a date range via EventKit (fantastical eventkit events). Returns a JSON array of events.",
+		Args:        reflect.TypeOf(listEventsArgs{}),
+		Call:        (*toolRunner).listEvents,
+	},
+	{
+		Name:        "list_calendars",
+		Description: "List system calendars via EventKit (fantastical eventkit calendars). Returns a JSON array of calendars.",
+		Args:        reflect.TypeOf(listCalendarsArgs{}),
+		Call:        (*toolRunner).listCalendars,
+	},
+	{
+		Name:        "find_free_time",
+		Description: "Find free slots between events in a date range, optionally within daily working hours.",
+		Args:        reflect.TypeOf(findFreeTimeArgs{}),
+		Call:        (*toolRunner).findFreeTime,
+	},
+	{
+		Name:        "show_view",
+		Description: "Open a Fantastical view (mini, calendar, day, week, month, agenda) or calendar set (fantastical show). Returns the show JSON output.",
+		Args:        reflect.TypeOf(showViewArgs{}),
+		Call:        (*toolRunner).showView,
+	},
+}
+
+// mcpServer answers MCP requests with the shared tool implementations.
+type mcpServer struct {
+	tools toolRunner
+}
+
+func mcpUsage(w io.Writer) {
+	fmt.Fprint(w, "USAGE:\n  fantastical mcp [--dry-run] [--config <path>] [--verbose]\n")
+	printFlagHelp(w, "mcp")
+	fmt.Fprintln(w, "\nEXAMPLE:\n  fantastical mcp --dry-run")
+	fmt.Fprintln(w, "NOTE:\n  Speaks MCP (JSON-RPC 2.0, one message per line) on stdin/stdout; logs go to stderr.")
+}
+
+func cmdMCP(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	tools := toolRunner{errOut: errOut, now: time.Now}
+	bindFlags(fs, "mcp", map[string]any{
+		"config":  &tools.config,
+		"dry-run": &tools.dryRun,
+		"verbose": &tools.verbose,
+	})
+
+	fs.Usage = func() {
+		mcpUsage(errOut)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	s := &mcpServer{tools: tools}
+	return s.serve(ctx, in, out)
+}
+
+// serve reads newline-delimited JSON-RPC messages until in is exhausted or
+// the command is cancelled.
+func (s *mcpServer) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	for {
+		line, readErr := readCommand(ctx, func() ([]byte, error) { return reader.ReadBytes('\n') })
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := s.handle(ctx, line); resp != nil {
+				if err := writeJSON(out, resp); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// handle processes one message and returns the response, or nil for
+// notifications.
+func (s *mcpServer) handle(ctx context.Context, line []byte) *mcpResponse {
+	if !json.Valid(line) {
+		return rpcErrorResponse(nil, rpcParseError, "parse error")
+	}
+	var req mcpRequest
+	if err := json.Unmarshal(line, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcInvalidRequest, "invalid request")
+	}
+	logVerbose(s.tools.errOut, s.tools.verbose, "mcp: %s", req.Method)
+
+	result, rpcErr := s.dispatch(ctx, req.Method, req.Params)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if rpcErr != nil {
+		return &mcpResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &mcpResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) *mcpResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &mcpResponse{JSONRPC: "2.0", ID: id, Error: &mcpError{Code: code, Message: message}}
+}
+
+func (s *mcpServer) dispatch(ctx context.Context, method string, params json.RawMessage) (any, *mcpError) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &mcpError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+		version := mcpProtocolVersions[0]
+		for _, v := range mcpProtocolVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": appName, "version": versionString()},
+			"instructions":    "Tools drive the Fantastical app and read calendars via EventKit. Use dry_run to build URLs without opening Fantastical.",
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		tools := make([]map[string]any, 0, len(mcpTools))
+		for _, tool := range mcpTools {
+			tools = append(tools, map[string]any{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"inputSchema": closeObjects(schemaForType(tool.Args)),
+			})
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &mcpError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		for _, tool := range mcpTools {
+			if tool.Name != p.Name {
+				continue
+			}
+			text, err := tool.Call(&s.tools, ctx, p.Arguments)
+			if err != nil {
+				logVerbose(s.tools.errOut, s.tools.verbose, "mcp: %s failed: %v", tool.Name, err)
+				return mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+			}
+			return mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+		}
+		return nil, &mcpError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	default:
+		if strings.HasPrefix(method, "notifications/") {
+			return nil, nil
+		}
+		return nil, &mcpError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+}
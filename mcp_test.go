@@ -0,0 +1,212 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubOpener installs an opener that records each URL it is asked to open
+// and returns the path of the log.
+func stubOpener(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "opened.log")
+	opener := filepath.Join(dir, "open.sh")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$1\" >> '" + log + "'\n"
+	if err := os.WriteFile(opener, []byte(script), 0o755); err != nil {
+		t.Fatalf("write opener: %v", err)
+	}
+	t.Setenv("FANTASTICAL_OPEN_COMMAND", opener)
+	return log
+}
+
+func openedURLs(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("read opener log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+type mcpTestResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *mcpError       `json:"error"`
+}
+
+// runMCPSession feeds messages to the server and returns responses by id.
+func runMCPSession(t *testing.T, args []string, messages ...string) map[string]mcpTestResponse {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	in := strings.NewReader(strings.Join(messages, "\n") + "\n")
+	if err := cmdMCP(context.Background(), args, in, &out, &errOut); err != nil {
+		t.Fatalf("mcp: %v (%s)", err, errOut.String())
+	}
+	responses := map[string]mcpTestResponse{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp mcpTestResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		responses[string(resp.ID)] = resp
+	}
+	return responses
+}
+
+func toolCall(id int, name, arguments string) string {
+	data, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": json.RawMessage(arguments)},
+	})
+	return string(data)
+}
+
+func toolText(t *testing.T, resp mcpTestResponse) (string, bool) {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("unexpected rpc error: %+v", resp.Error)
+	}
+	var result mcpToolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil || len(result.Content) != 1 {
+		t.Fatalf("invalid tool result %s: %v", resp.Result, err)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestMCPSession(t *testing.T) {
+	writeAliasConfig(t, `{"parse": {"calendar": "home"}}`)
+	stubProtocolHelper(t)
+	log := stubOpener(t)
+
+	responses := runMCPSession(t, nil,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		toolCall(3, "create_event_from_sentence", `{"sentence":"Dinner at 7","note":"Bring wine"}`),
+		toolCall(4, "show_view", `{"view":"month","date":"2026-01-03","dry_run":true}`),
+		toolCall(5, "list_calendars", `{}`),
+		toolCall(6, "list_events", `{"from":"2026-10-19","to":"2026-10-20","calendars":["work (ex"]}`),
+		toolCall(7, "find_free_time", `{"from":"2026-10-19T09:00","to":"2026-10-19T12:00","timezone":"Europe/Zagreb"}`),
+		toolCall(8, "create_event_from_sentence", `{"sentence":" "}`),
+		toolCall(9, "create_event_from_sentence", `{"sentense":"typo"}`),
+		toolCall(10, "nope", `{}`),
+		`{"jsonrpc":"2.0","id":11,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":12,"method":"ping"}`,
+		`{not json`,
+	)
+	if len(responses) != 13 {
+		t.Fatalf("expected 13 responses (no reply to notifications), got %d", len(responses))
+	}
+
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(responses["1"].Result, &init); err != nil || init.ProtocolVersion != "2025-03-26" || init.ServerInfo.Name != appName {
+		t.Fatalf("unexpected initialize result: %s", responses["1"].Result)
+	}
+
+	var list struct {
+		Tools []struct {
+			Name        string         `json:"name"`
+			InputSchema map[string]any `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(responses["2"].Result, &list); err != nil {
+		t.Fatalf("invalid tools/list: %v", err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" || tool.InputSchema["additionalProperties"] != false {
+			t.Fatalf("%s: expected a closed object input schema, got %v", tool.Name, tool.InputSchema)
+		}
+	}
+	if strings.Join(names, ",") != "create_event_from_sentence,list_events,list_calendars,find_free_time,show_view" {
+		t.Fatalf("unexpected tools: %v", names)
+	}
+
+	text, isErr := toolText(t, responses["3"])
+	var parsed parseResult
+	if err := json.Unmarshal([]byte(text), &parsed); isErr || err != nil || !parsed.Open {
+		t.Fatalf("unexpected create result %q (%v)", text, err)
+	}
+	if !strings.Contains(parsed.URL, "calendarName=Home") || !strings.Contains(parsed.URL, "n=Bring%20wine") {
+		t.Fatalf("expected config calendar and note in URL: %s", parsed.URL)
+	}
+	if opened := openedURLs(t, log); len(opened) != 1 || opened[0] != parsed.URL {
+		t.Fatalf("expected only the create URL to be opened, got %v", opened)
+	}
+
+	text, _ = toolText(t, responses["4"])
+	var shown showResult
+	if err := json.Unmarshal([]byte(text), &shown); err != nil || shown.URL != "x-fantastical3://show/month/2026-01-03" || !shown.DryRun {
+		t.Fatalf("unexpected show result %q", text)
+	}
+
+	if text, _ := toolText(t, responses["5"]); text != testCalendarsJSON {
+		t.Fatalf("unexpected calendars: %q", text)
+	}
+	if text, _ := toolText(t, responses["6"]); text != testEventsJSON {
+		t.Fatalf("unexpected events: %q", text)
+	}
+
+	text, _ = toolText(t, responses["7"])
+	var free freeTimeResult
+	if err := json.Unmarshal([]byte(text), &free); err != nil {
+		t.Fatalf("invalid free time %q: %v", text, err)
+	}
+	if len(free.Slots) != 1 || free.Slots[0].Start.Format("15:04") != "09:30" || free.Slots[0].Minutes != 150 {
+		t.Fatalf("unexpected free slots: %s", text)
+	}
+
+	for _, id := range []string{"8", "9"} {
+		if text, isErr := toolText(t, responses[id]); !isErr || !strings.Contains(text, "usage") {
+			t.Fatalf("expected tool error for %s, got %q", id, text)
+		}
+	}
+	wantCodes := map[string]int{"10": rpcInvalidParams, "11": rpcMethodNotFound, "null": rpcParseError}
+	for id, code := range wantCodes {
+		if resp := responses[id]; resp.Error == nil || resp.Error.Code != code {
+			t.Fatalf("expected error %d for %s, got %+v", code, id, resp)
+		}
+	}
+	if string(responses["12"].Result) != "{}" {
+		t.Fatalf("unexpected ping result: %s", responses["12"].Result)
+	}
+}
+
+func TestMCPDryRunNeverOpens(t *testing.T) {
+	setupTestEnv(t)
+	log := stubOpener(t)
+
+	responses := runMCPSession(t, []string{"--dry-run"},
+		toolCall(1, "create_event_from_sentence", `{"sentence":"Lunch tomorrow","add":true}`),
+		toolCall(2, "show_view", `{"calendar_set":"Work Week"}`),
+	)
+	for _, id := range []string{"1", "2"} {
+		text, isErr := toolText(t, responses[id])
+		if isErr || !strings.Contains(text, `"dry_run":true`) {
+			t.Fatalf("expected dry-run result for %s, got %q", id, text)
+		}
+	}
+	if opened := openedURLs(t, log); len(opened) != 0 {
+		t.Fatalf("expected nothing opened, got %v", opened)
+	}
+}
@@ -0,0 +1,693 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventPrediction is a best-effort guess at how Fantastical will read a
+// parse sentence. Fantastical's own parser is authoritative; the prediction
+// exists to catch obviously wrong input (no time, a past date) early.
+type eventPrediction struct {
+	BestEffort bool       `json:"best_effort" desc:"Always true: Fantastical's parser is authoritative"`
+	Title      string     `json:"title"`
+	Start      *time.Time `json:"start,omitempty"`
+	End        *time.Time `json:"end,omitempty"`
+	AllDay     bool       `json:"all_day"`
+	Recurrence string     `json:"recurrence,omitempty" desc:"RFC 5545 RRULE, e.g. FREQ=WEEKLY;BYDAY=TU"`
+	Calendar   string     `json:"calendar,omitempty"`
+	Location   string     `json:"location,omitempty"`
+	Warnings   []string   `json:"warnings,omitempty"`
+}
+
+// predictFromParseURL predicts the event for a parse URL, using its s,
+// calendarName and tz parameters.
+func predictFromParseURL(u string, now time.Time) (eventPrediction, error) {
+	_, query, _ := strings.Cut(u, "?")
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return eventPrediction{}, err
+	}
+	if tz := q.Get("tz"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			now = now.In(loc)
+		}
+	}
+	p := predictEvent(q.Get("s"), now)
+	if cal := q.Get("calendarName"); cal != "" {
+		p.Calendar = cal
+	}
+	return p, nil
+}
+
+type nlToken struct {
+	text   string // as written, for the title
+	lower  string // lower-cased, surrounding punctuation removed
+	used   bool
+	quoted bool // inside "...", which Fantastical takes literally
+}
+
+// nlParser scans a sentence once, left to right. Each matcher recognizes a
+// phrase at a token index and returns how many tokens it covers plus a
+// function that records it; nothing is recorded while peeking.
+type nlParser struct {
+	now    time.Time
+	tokens []nlToken
+
+	date       time.Time // midnight in now's location
+	hasDate    bool
+	start, end int // minutes after midnight
+	hasStart   bool
+	hasEnd     bool
+	relStart   time.Duration
+	hasRel     bool
+	duration   time.Duration
+	zone       *time.Location
+	recurrence string
+	byDay      []time.Weekday
+	calendar   string
+	location   string
+}
+
+type nlMatcher func(p *nlParser, i int) (int, func())
+
+func predictEvent(sentence string, now time.Time) eventPrediction {
+	p := &nlParser{now: now}
+	inQuote := false
+	for _, field := range strings.Fields(sentence) {
+		tok := nlToken{text: field, lower: strings.ToLower(strings.Trim(field, ",.;!?()\"'"))}
+		if inQuote || strings.HasPrefix(field, `"`) {
+			body := field
+			if !inQuote {
+				body = field[1:]
+			}
+			tok.quoted = true
+			tok.text = strings.Trim(field, `"`)
+			inQuote = !strings.HasSuffix(body, `"`)
+		}
+		p.tokens = append(p.tokens, tok)
+	}
+	for i := 0; i < len(p.tokens); {
+		n, apply := p.match(i, nlMatchers)
+		if n == 0 {
+			i++
+			continue
+		}
+		apply()
+		for k := i; k < i+n; k++ {
+			p.tokens[k].used = true
+		}
+		i += n
+	}
+	return p.result()
+}
+
+var nlMatchers []nlMatcher
+
+func init() {
+	// Assigned here because matchLocation consults the list itself.
+	nlMatchers = []nlMatcher{
+		(*nlParser).matchRecurrence,
+		(*nlParser).matchRelative,
+		(*nlParser).matchDuration,
+		(*nlParser).matchDate,
+		(*nlParser).matchTime,
+		(*nlParser).matchZone,
+		(*nlParser).matchCalendar,
+		(*nlParser).matchLocation,
+	}
+}
+
+func (p *nlParser) match(i int, matchers []nlMatcher) (int, func()) {
+	if p.tokens[i].quoted {
+		return 0, nil
+	}
+	for _, m := range matchers {
+		if n, apply := m(p, i); n > 0 {
+			return n, apply
+		}
+	}
+	return 0, nil
+}
+
+func (p *nlParser) word(i int) string {
+	if i < 0 || i >= len(p.tokens) || p.tokens[i].quoted {
+		return ""
+	}
+	return p.tokens[i].lower
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "weds": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var rruleDays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var monthNames = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+// weekdayWord accepts weekday names and their plurals ("tuesdays").
+func weekdayWord(s string) (time.Weekday, bool) {
+	if d, ok := weekdayNames[s]; ok {
+		return d, true
+	}
+	d, ok := weekdayNames[strings.TrimSuffix(s, "s")]
+	return d, ok
+}
+
+func (p *nlParser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+}
+
+// nextWeekday returns the next date falling on d, today included unless
+// strict is set.
+func (p *nlParser) nextWeekday(d time.Weekday, strict bool) time.Time {
+	day := p.today()
+	offset := (int(d) - int(day.Weekday()) + 7) % 7
+	if offset == 0 && strict {
+		offset = 7
+	}
+	return day.AddDate(0, 0, offset)
+}
+
+func (p *nlParser) setDate(d time.Time) func() {
+	return func() {
+		p.date, p.hasDate = d, true
+	}
+}
+
+func (p *nlParser) matchRecurrence(i int) (int, func()) {
+	w := p.word(i)
+	simple := map[string]string{"daily": "FREQ=DAILY", "weekly": "FREQ=WEEKLY", "monthly": "FREQ=MONTHLY", "yearly": "FREQ=YEARLY", "annually": "FREQ=YEARLY"}
+	if rule, ok := simple[w]; ok {
+		return 1, p.setRecurrence(rule, nil)
+	}
+	if w != "every" && w != "each" {
+		return 0, nil
+	}
+	next := p.word(i + 1)
+	workweek := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	weekend := []time.Weekday{time.Saturday, time.Sunday}
+	switch next {
+	case "weekday", "weekdays":
+		return 2, p.setRecurrence("FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", workweek)
+	case "weekend", "weekends":
+		return 2, p.setRecurrence("FREQ=WEEKLY;BYDAY=SA,SU", weekend)
+	}
+	units := map[string]string{"day": "FREQ=DAILY", "week": "FREQ=WEEKLY", "month": "FREQ=MONTHLY", "year": "FREQ=YEARLY"}
+	if rule, ok := units[next]; ok {
+		return 2, p.setRecurrence(rule, nil)
+	}
+	if next == "other" {
+		if rule, ok := units[p.word(i+2)]; ok {
+			return 3, p.setRecurrence(rule+";INTERVAL=2", nil)
+		}
+	}
+	if d, ok := weekdayWord(next); ok {
+		return 2, p.setRecurrence("FREQ=WEEKLY;BYDAY="+rruleDays[d], []time.Weekday{d})
+	}
+	return 0, nil
+}
+
+// setRecurrence records an RRULE; days limits the first occurrence when the
+// sentence has no explicit date.
+func (p *nlParser) setRecurrence(rule string, days []time.Weekday) func() {
+	return func() {
+		p.recurrence, p.byDay = rule, days
+	}
+}
+
+var durationUnits = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+}
+
+var compactDurationPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|hr|hrs|m|min|mins)$|^\d+h\d+m?$`)
+
+// parseAmount reads "30 min", "2 hours", "1h30m", "an hour", "half an hour"
+// starting at i and returns the duration and the tokens used.
+func (p *nlParser) parseAmount(i int, units map[string]time.Duration) (time.Duration, int) {
+	w := p.word(i)
+	if w == "half" && (p.word(i+1) == "an" || p.word(i+1) == "a") && units[p.word(i+2)] == time.Hour {
+		return 30 * time.Minute, 3
+	}
+	if w == "an" || w == "a" {
+		if u, ok := units[p.word(i+1)]; ok {
+			return u, 2
+		}
+		return 0, 0
+	}
+	// Compact forms ("90min", "1h30") only exist for clock units.
+	if _, clock := units["m"]; clock && compactDurationPattern.MatchString(w) {
+		normalized := strings.NewReplacer("hrs", "h", "hr", "h", "mins", "m", "min", "m").Replace(w)
+		if !strings.HasSuffix(normalized, "h") && !strings.HasSuffix(normalized, "m") {
+			normalized += "m"
+		}
+		if d, err := time.ParseDuration(normalized); err == nil {
+			return d, 1
+		}
+	}
+	n, err := strconv.ParseFloat(w, 64)
+	if err != nil || n <= 0 {
+		return 0, 0
+	}
+	if u, ok := units[p.word(i+1)]; ok {
+		return time.Duration(n * float64(u)), 2
+	}
+	return 0, 0
+}
+
+// matchRelative handles "in 2 hours" (a start time) and "in 3 days" (a date).
+func (p *nlParser) matchRelative(i int) (int, func()) {
+	if p.word(i) != "in" {
+		return 0, nil
+	}
+	if d, n := p.parseAmount(i+1, durationUnits); n > 0 {
+		return n + 1, func() { p.relStart, p.hasRel = d, true }
+	}
+	dateUnits := map[string]time.Duration{"day": 24 * time.Hour, "days": 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour}
+	if d, n := p.parseAmount(i+1, dateUnits); n > 0 {
+		return n + 1, p.setDate(p.today().AddDate(0, 0, int(d/(24*time.Hour))))
+	}
+	return 0, nil
+}
+
+func (p *nlParser) matchDuration(i int) (int, func()) {
+	if p.word(i) != "for" {
+		return 0, nil
+	}
+	if d, n := p.parseAmount(i+1, durationUnits); n > 0 {
+		return n + 1, func() { p.duration = d }
+	}
+	return 0, nil
+}
+
+var (
+	isoDatePattern   = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	slashDatePattern = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+	dayNumberPattern = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearPattern      = regexp.MustCompile(`^\d{4}$`)
+)
+
+func (p *nlParser) matchDate(i int) (int, func()) {
+	lead := 0
+	if p.word(i) == "on" {
+		lead = 1
+	}
+	n, apply := p.matchDateAt(i + lead)
+	if n == 0 {
+		return 0, nil
+	}
+	return n + lead, apply
+}
+
+func (p *nlParser) matchDateAt(i int) (int, func()) {
+	today := p.today()
+	w := p.word(i)
+	switch w {
+	case "today", "tonight":
+		return 1, p.setDate(today)
+	case "tomorrow", "tmrw", "tmr":
+		return 1, p.setDate(today.AddDate(0, 0, 1))
+	case "yesterday":
+		return 1, p.setDate(today.AddDate(0, 0, -1))
+	case "next", "this":
+		next := p.word(i + 1)
+		if d, ok := weekdayNames[next]; ok {
+			return 2, p.setDate(p.nextWeekday(d, w == "next"))
+		}
+		if w == "next" && next == "week" {
+			return 2, p.setDate(p.nextWeekday(time.Monday, true))
+		}
+		if w == "next" && next == "month" {
+			return 2, p.setDate(time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()))
+		}
+		return 0, nil
+	}
+	if d, ok := weekdayNames[w]; ok {
+		return 1, p.setDate(p.nextWeekday(d, false))
+	}
+	if m := isoDatePattern.FindStringSubmatch(w); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		return 1, p.setDate(time.Date(y, time.Month(mo), d, 0, 0, 0, 0, today.Location()))
+	}
+	if m := slashDatePattern.FindStringSubmatch(w); m != nil {
+		mo, _ := strconv.Atoi(m[1])
+		d, _ := strconv.Atoi(m[2])
+		if mo < 1 || mo > 12 || d < 1 || d > 31 {
+			return 0, nil
+		}
+		if m[3] == "" {
+			return 1, p.setDate(p.upcoming(time.Month(mo), d))
+		}
+		y, _ := strconv.Atoi(m[3])
+		if y < 100 {
+			y += 2000
+		}
+		return 1, p.setDate(time.Date(y, time.Month(mo), d, 0, 0, 0, 0, today.Location()))
+	}
+	// "oct 20", "october 20th 2027", "20 oct", "20th of october"
+	month, day, n := time.Month(0), 0, 0
+	if mo, ok := monthNames[w]; ok {
+		if m := dayNumberPattern.FindStringSubmatch(p.word(i + 1)); m != nil {
+			day, _ = strconv.Atoi(m[1])
+			month, n = mo, 2
+		}
+	} else if m := dayNumberPattern.FindStringSubmatch(w); m != nil {
+		of := 0
+		if p.word(i+1) == "of" {
+			of = 1
+		}
+		if mo, ok := monthNames[p.word(i+1+of)]; ok {
+			day, _ = strconv.Atoi(m[1])
+			month, n = mo, 2+of
+		}
+	}
+	if n == 0 || day < 1 || day > 31 {
+		return 0, nil
+	}
+	if yearPattern.MatchString(p.word(i + n)) {
+		y, _ := strconv.Atoi(p.word(i + n))
+		return n + 1, p.setDate(time.Date(y, month, day, 0, 0, 0, 0, today.Location()))
+	}
+	return n, p.setDate(p.upcoming(month, day))
+}
+
+// upcoming returns the next month/day on or after today.
+func (p *nlParser) upcoming(month time.Month, day int) time.Time {
+	today := p.today()
+	d := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+	if d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d
+}
+
+// nlClock is a parsed time of day.
+type nlClock struct {
+	minutes  int
+	meridiem string // "am", "pm" or "" when not written
+	bare     bool   // a plain number like "7": only a time in context
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a|p)?$`)
+
+func parseNLClock(s string) (nlClock, bool) {
+	switch s {
+	case "noon", "midday":
+		return nlClock{minutes: 12 * 60, meridiem: "pm"}, true
+	case "midnight":
+		return nlClock{minutes: 0, meridiem: "am"}, true
+	}
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nlClock{}, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if hour > 23 || minute > 59 {
+		return nlClock{}, false
+	}
+	c := nlClock{minutes: hour*60 + minute, bare: m[2] == "" && m[3] == ""}
+	if m[3] != "" {
+		if hour == 0 || hour > 12 {
+			return nlClock{}, false
+		}
+		c.meridiem = strings.TrimSuffix(m[3], "m") + "m"
+		c.minutes = (hour%12)*60 + minute
+		if c.meridiem == "pm" {
+			c.minutes += 12 * 60
+		}
+	} else if hour >= 1 && hour <= 7 && !strings.HasPrefix(m[1], "0") {
+		// "at 3" means the afternoon, as in Fantastical.
+		c.minutes += 12 * 60
+		c.meridiem = "inferred"
+	}
+	return c, true
+}
+
+// clockAt reads a clock at i, joining a separate "am"/"pm" token.
+func (p *nlParser) clockAt(i int) (nlClock, int, bool) {
+	w := p.word(i)
+	next := p.word(i + 1)
+	if (next == "am" || next == "pm") && clockPattern.MatchString(w) && !strings.ContainsAny(w, "ap") {
+		c, ok := parseNLClock(w + next)
+		return c, 2, ok
+	}
+	c, ok := parseNLClock(w)
+	return c, 1, ok
+}
+
+var timeLeads = map[string]bool{"at": true, "@": true, "from": true, "by": true}
+var rangeConnectors = map[string]bool{"-": true, "–": true, "to": true, "until": true, "till": true, "til": true, "through": true}
+
+func (p *nlParser) matchTime(i int) (int, func()) {
+	lead := 0
+	if timeLeads[p.word(i)] {
+		lead = 1
+	}
+	j := i + lead
+	// A range written as one token: "10-11", "9:30am-10:15am".
+	if parts := strings.FieldsFunc(p.word(j), func(r rune) bool { return r == '-' || r == '–' }); len(parts) == 2 {
+		start, ok1 := parseNLClock(parts[0])
+		end, ok2 := parseNLClock(parts[1])
+		if ok1 && ok2 {
+			return lead + 1, p.setRange(start, end)
+		}
+	}
+	start, n, ok := p.clockAt(j)
+	if !ok {
+		return 0, nil
+	}
+	if rangeConnectors[p.word(j+n)] {
+		if end, m, ok := p.clockAt(j + n + 1); ok {
+			return lead + n + 1 + m, p.setRange(start, end)
+		}
+	}
+	if start.bare && lead == 0 {
+		return 0, nil
+	}
+	return lead + n, func() {
+		p.start, p.hasStart = start.minutes, true
+	}
+}
+
+// setRange records start and end, borrowing the end's am/pm for a start
+// written without one ("10-11am", "11-1pm").
+func (p *nlParser) setRange(start, end nlClock) func() {
+	return func() {
+		s, e := start.minutes, end.minutes
+		if start.meridiem == "" || start.meridiem == "inferred" {
+			hour := s / 60 % 12
+			if end.meridiem == "am" || end.meridiem == "pm" {
+				s = hour*60 + s%60
+				if end.meridiem == "pm" {
+					s += 12 * 60
+				}
+				if s > e {
+					s -= 12 * 60
+				}
+			}
+		}
+		if s >= e && s >= 12*60 && s-12*60 < e {
+			s -= 12 * 60
+		}
+		if s >= e && e < 12*60 && e+12*60 > s {
+			e += 12 * 60
+		}
+		p.start, p.end, p.hasStart, p.hasEnd = s, e, true, true
+	}
+}
+
+// zoneAbbreviations maps common abbreviations to zones. Generic names follow
+// daylight saving time; specific ones are fixed offsets.
+var zoneAbbreviations = map[string]string{
+	"utc": "UTC", "gmt": "UTC", "z": "UTC",
+	"pt": "America/Los_Angeles", "mt": "America/Denver", "ct": "America/Chicago", "et": "America/New_York",
+	"pst": "-08", "pdt": "-07", "mst": "-07", "mdt": "-06", "cst": "-06", "cdt": "-05", "est": "-05", "edt": "-04",
+	"bst": "+01", "cet": "+01", "cest": "+02", "eet": "+02", "eest": "+03", "jst": "+09", "aest": "+10", "aedt": "+11",
+}
+
+func zoneForAbbreviation(abbr string) *time.Location {
+	name, ok := zoneAbbreviations[abbr]
+	if !ok {
+		return nil
+	}
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		hours, _ := strconv.Atoi(name)
+		return time.FixedZone(strings.ToUpper(abbr), hours*60*60)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// matchZone only follows a time, so words like "ET" elsewhere stay in the
+// title.
+func (p *nlParser) matchZone(i int) (int, func()) {
+	if i == 0 || !p.tokens[i-1].used || (!p.hasStart && !p.hasRel) {
+		return 0, nil
+	}
+	loc := zoneForAbbreviation(p.word(i))
+	if loc == nil {
+		return 0, nil
+	}
+	return 1, func() { p.zone = loc }
+}
+
+func (p *nlParser) matchCalendar(i int) (int, func()) {
+	w := p.tokens[i].text
+	if len(w) < 2 || w[0] != '/' {
+		return 0, nil
+	}
+	return 1, func() { p.calendar = w[1:] }
+}
+
+// matchLocation takes "at <place>" up to the next recognized phrase.
+func (p *nlParser) matchLocation(i int) (int, func()) {
+	if w := p.word(i); w != "at" && w != "@" {
+		return 0, nil
+	}
+	stops := nlMatchers[:len(nlMatchers)-1]
+	n := 1
+	for i+n < len(p.tokens) {
+		if k, _ := p.match(i+n, stops); k > 0 {
+			break
+		}
+		if w := p.word(i + n); w == "on" || w == "at" || w == "for" || w == "with" {
+			break
+		}
+		n++
+	}
+	if n == 1 {
+		return 0, nil
+	}
+	words := make([]string, 0, n-1)
+	for _, tok := range p.tokens[i+1 : i+n] {
+		words = append(words, strings.Trim(tok.text, ",;"))
+	}
+	return n, func() { p.location = strings.Join(words, " ") }
+}
+
+func (p *nlParser) allowedDay(d time.Weekday) bool {
+	if len(p.byDay) == 0 {
+		return true
+	}
+	for _, day := range p.byDay {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *nlParser) result() eventPrediction {
+	out := eventPrediction{BestEffort: true, Recurrence: p.recurrence, Calendar: p.calendar, Location: p.location}
+
+	var title []string
+	for _, tok := range p.tokens {
+		if !tok.used {
+			title = append(title, tok.text)
+		}
+	}
+	out.Title = strings.Trim(strings.Join(title, " "), " ,;-–")
+	for _, filler := range []string{"on", "at", "from", "for", "in", "with"} {
+		out.Title = strings.TrimSuffix(out.Title, " "+filler)
+	}
+	if out.Title == "" {
+		out.Warnings = append(out.Warnings, "no title left after removing date and time phrases")
+	}
+
+	loc := p.now.Location()
+	if p.zone != nil {
+		loc = p.zone
+	}
+	now := p.now.In(loc)
+
+	var start, end time.Time
+	switch {
+	case p.hasRel:
+		start = p.now.Add(p.relStart).Truncate(time.Minute).In(loc)
+	case p.hasStart:
+		date := p.today()
+		if p.hasDate {
+			date = p.date
+		}
+		start = time.Date(date.Year(), date.Month(), date.Day(), 0, p.start, 0, 0, loc)
+		// Without a date Fantastical picks the next matching occurrence.
+		for !p.hasDate && (start.Before(now) || !p.allowedDay(start.Weekday())) {
+			start = start.AddDate(0, 0, 1)
+		}
+		if p.hasEnd {
+			end = time.Date(start.Year(), start.Month(), start.Day(), 0, p.end, 0, 0, loc)
+			if !end.After(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+		}
+	case p.hasDate || len(p.byDay) > 0:
+		out.AllDay = true
+		start = p.date
+		if !p.hasDate {
+			start = p.today()
+			for !p.allowedDay(start.Weekday()) {
+				start = start.AddDate(0, 0, 1)
+			}
+		}
+		end = start.AddDate(0, 0, 1)
+	default:
+		out.Warnings = append(out.Warnings, "no date or time found; Fantastical will use its defaults")
+		return out
+	}
+	if end.IsZero() {
+		d := p.duration
+		if d == 0 {
+			d = time.Hour
+		}
+		end = start.Add(d)
+	}
+	if !p.hasStart && !p.hasRel && p.duration > 0 {
+		out.Warnings = append(out.Warnings, "duration given without a start time")
+	}
+	out.Start, out.End = &start, &end
+
+	if out.AllDay {
+		if start.Before(p.today()) {
+			out.Warnings = append(out.Warnings, fmt.Sprintf("date %s is in the past", start.Format("2006-01-02")))
+		}
+	} else if start.Before(p.now.Add(-time.Minute)) {
+		out.Warnings = append(out.Warnings, fmt.Sprintf("start %s is in the past", start.Format(time.RFC3339)))
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPredictEvent(t *testing.T) {
+	// Sunday afternoon.
+	now := time.Date(2026, time.October, 18, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		sentence   string
+		title      string
+		start, end string
+		allDay     bool
+		recurrence string
+		calendar   string
+		location   string
+		warning    string
+	}{
+		{sentence: "Dinner tomorrow 7pm", title: "Dinner", start: "2026-10-19T19:00:00+02:00", end: "2026-10-19T20:00:00+02:00"},
+		{sentence: "Standup next tue 10-11", title: "Standup", start: "2026-10-20T10:00:00+02:00", end: "2026-10-20T11:00:00+02:00"},
+		{sentence: "Gym every weekday at 9", title: "Gym", start: "2026-10-19T09:00:00+02:00", end: "2026-10-19T10:00:00+02:00", recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{sentence: "Call mom in 2 hours for 30 min", title: "Call mom", start: "2026-10-18T16:00:00+02:00", end: "2026-10-18T16:30:00+02:00"},
+		{sentence: "Webinar at 3pm PST", title: "Webinar", start: "2026-10-18T15:00:00-08:00", end: "2026-10-18T16:00:00-08:00"},
+		{sentence: "Lunch at Cafe Nero tomorrow at noon /Work", title: "Lunch", start: "2026-10-19T12:00:00+02:00", end: "2026-10-19T13:00:00+02:00", calendar: "Work", location: "Cafe Nero"},
+		{sentence: "Vacation oct 20", title: "Vacation", start: "2026-10-20T00:00:00+02:00", end: "2026-10-21T00:00:00+02:00", allDay: true},
+		{sentence: "Team sync 11-1pm friday", title: "Team sync", start: "2026-10-23T11:00:00+02:00", end: "2026-10-23T13:00:00+02:00"},
+		{sentence: "Yoga every tuesday 6:30pm", title: "Yoga", start: "2026-10-20T18:30:00+02:00", end: "2026-10-20T19:30:00+02:00", recurrence: "FREQ=WEEKLY;BYDAY=TU"},
+		{sentence: "Coffee at 3", title: "Coffee", start: "2026-10-18T15:00:00+02:00", end: "2026-10-18T16:00:00+02:00"},
+		{sentence: "Breakfast at 8am", title: "Breakfast", start: "2026-10-19T08:00:00+02:00", end: "2026-10-19T09:00:00+02:00"},
+		{sentence: "Workshop for 1h30 on 10/22 at 9:30", title: "Workshop", start: "2026-10-22T09:30:00+02:00", end: "2026-10-22T11:00:00+02:00"},
+		{sentence: "Retro every other week on friday 4-5", title: "Retro", start: "2026-10-23T16:00:00+02:00", end: "2026-10-23T17:00:00+02:00", recurrence: "FREQ=WEEKLY;INTERVAL=2"},
+		{sentence: "Review 2025-01-05 at 10am", title: "Review", start: "2025-01-05T10:00:00+02:00", end: "2025-01-05T11:00:00+02:00", warning: "in the past"},
+		{sentence: `"Meeting at 5" on oct 20 at 3pm`, title: "Meeting at 5", start: "2026-10-20T15:00:00+02:00", end: "2026-10-20T16:00:00+02:00"},
+		{sentence: `"Lunch" at "Monday Club" tomorrow at noon`, title: "Lunch", start: "2026-10-19T12:00:00+02:00", end: "2026-10-19T13:00:00+02:00", location: "Monday Club"},
+		{sentence: "Buy 3 apples", title: "Buy 3 apples", warning: "no date or time"},
+		{sentence: "tomorrow at 9am", start: "2026-10-19T09:00:00+02:00", end: "2026-10-19T10:00:00+02:00", warning: "no title"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.sentence, func(t *testing.T) {
+			p := predictEvent(tc.sentence, now)
+			if !p.BestEffort {
+				t.Fatalf("prediction must be labelled best-effort")
+			}
+			if p.Title != tc.title || p.AllDay != tc.allDay || p.Recurrence != tc.recurrence || p.Calendar != tc.calendar || p.Location != tc.location {
+				t.Fatalf("got %+v", p)
+			}
+			if got := formatPredicted(p.Start); got != tc.start {
+				t.Fatalf("start = %s, want %s", got, tc.start)
+			}
+			if got := formatPredicted(p.End); got != tc.end {
+				t.Fatalf("end = %s, want %s", got, tc.end)
+			}
+			warnings := strings.Join(p.Warnings, "; ")
+			if (tc.warning == "") != (warnings == "") || !strings.Contains(warnings, tc.warning) {
+				t.Fatalf("warnings = %q, want %q", warnings, tc.warning)
+			}
+		})
+	}
+}
+
+func formatPredicted(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func TestCmdValidatePredicted(t *testing.T) {
+	setupTestEnv(t)
+
+	var out bytes.Buffer
+	if err := cmdValidate(context.Background(), []string{"--json", "parse", "--timezone", "America/New_York", "Launch 2030-05-06 at 9:30am"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var result validateResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	p := result.Predicted
+	if !result.OK || p == nil || p.Title != "Launch" || formatPredicted(p.Start) != "2030-05-06T09:30:00-04:00" || len(p.Warnings) != 0 {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+
+	out.Reset()
+	if err := cmdValidate(context.Background(), []string{"--json", "--strict", "parse", "Dinner yesterday"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	result = validateResult{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || result.OK || !strings.Contains(result.Error, "in the past") || result.Predicted == nil {
+		t.Fatalf("expected strict failure, got %s", out.String())
+	}
+
+	var errOut bytes.Buffer
+	out.Reset()
+	if err := cmdValidate(context.Background(), []string{"parse", "Buy milk"}, strings.NewReader(""), &out, &errOut); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), fantasticalScheme) || !strings.Contains(errOut.String(), "warning: no date or time") {
+		t.Fatalf("expected URL and warning, got %q / %q", out.String(), errOut.String())
+	}
+	if err := cmdValidate(context.Background(), []string{"--strict", "parse", "Buy milk"}, strings.NewReader(""), io.Discard, io.Discard); err == nil {
+		t.Fatalf("expected --strict to fail")
+	}
+}
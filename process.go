@@ -0,0 +1,183 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// exitTimeout is the exit code for a command stopped by --timeout, as with
+// timeout(1). A command stopped by SIGINT or SIGTERM exits 128+signal.
+const exitTimeout = 124
+
+// subprocessGrace is how long a cancelled subprocess gets to exit after
+// being signalled before it is killed.
+const subprocessGrace = 2 * time.Second
+
+// errTimeout is the cancellation cause once --timeout elapses.
+var errTimeout = errors.New("timed out")
+
+// signalError is the cancellation cause after SIGINT or SIGTERM.
+type signalError struct {
+	sig syscall.Signal
+}
+
+func (e signalError) Error() string {
+	if e.sig == syscall.SIGINT {
+		return "interrupted by SIGINT"
+	}
+	return "stopped by SIGTERM"
+}
+
+// commandContext returns the context for one command, derived from parent:
+// it is cancelled once timeout elapses (when positive) or on SIGINT/SIGTERM,
+// and every subprocess, CalDAV request and poll loop runs under it. stop
+// releases the timer and the signal handler.
+func commandContext(parent context.Context, timeout time.Duration) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stopTimer := func() bool { return false }
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			cancel(fmt.Errorf("%w after %s (--timeout)", errTimeout, timeout))
+		})
+		stopTimer = timer.Stop
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(signalError{sig: sig.(syscall.Signal)})
+			// A second signal gets the default behaviour, so a command stuck
+			// somewhere that does not watch ctx can still be stopped.
+			signal.Stop(signals)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		stopTimer()
+		cancel(nil)
+	}
+}
+
+// exitCodeFor maps a cancelled command to its exit code; ok is false when
+// the command was not cancelled.
+func exitCodeFor(ctx context.Context) (int, bool) {
+	cause := context.Cause(ctx)
+	var sigErr signalError
+	switch {
+	case errors.Is(cause, errTimeout):
+		return exitTimeout, true
+	case errors.As(cause, &sigErr):
+		return 128 + int(sigErr.sig), true
+	}
+	return 0, false
+}
+
+// subprocess is exec.Command bound to ctx. The child runs in its own
+// process group; on cancellation the group gets the signal the CLI received
+// (SIGTERM after a timeout), so anything the child started stops too, and
+// the child is killed if it has not exited within subprocessGrace.
+func subprocess(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		sig := syscall.SIGTERM
+		var sigErr signalError
+		if errors.As(context.Cause(ctx), &sigErr) {
+			sig = sigErr.sig
+		}
+		return syscall.Kill(-cmd.Process.Pid, sig)
+	}
+	cmd.WaitDelay = subprocessGrace
+	return cmd
+}
+
+// runSubprocess runs cmd, made by subprocess with ctx, and reports a
+// cancelled run by its cause rather than the signal that stopped the child.
+func runSubprocess(ctx context.Context, cmd *exec.Cmd) error {
+	err := cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(cmd.Path), context.Cause(ctx))
+	}
+	return err
+}
+
+// sleepCommand waits for d and reports false if the command was cancelled
+// first.
+func sleepCommand(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readCommand runs read, which may block on stdin, and returns the
+// cancellation cause instead if the command is stopped first. A blocked read
+// cannot be interrupted; it is abandoned and ends with the process.
+func readCommand(ctx context.Context, read func() ([]byte, error)) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := read()
+		done <- result{data, err}
+	}()
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+// globalOptions holds the globalFlags given before the command name.
+type globalOptions struct {
+	timeout time.Duration
+}
+
+// splitGlobalFlags parses the globalFlags at the start of args, which begin
+// after the program name, and returns the rest.
+func splitGlobalFlags(args []string) (globalOptions, []string, error) {
+	var opts globalOptions
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		if name != "--timeout" && name != "-timeout" {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return opts, nil, fmt.Errorf("%w: flag needs an argument: %s", errUsage, name)
+			}
+			value, args = args[1], args[1:]
+		}
+		args = args[1:]
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return opts, nil, fmt.Errorf("%w: invalid --timeout %q (want a positive duration like 30s or 2m)", errUsage, value)
+		}
+		opts.timeout = d
+	}
+	return opts, args, nil
+}
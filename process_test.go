@@ -0,0 +1,162 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// sleepStub writes a command that records "started", then sleeps until it
+// is signalled and records which signal arrived in the returned file. The
+// traps stop the background sleep, which sh starts with SIGINT ignored.
+func sleepStub(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	script := "#!/bin/sh\n" +
+		"trap 'echo TERM > " + marker + "; kill $!; exit 143' TERM\n" +
+		"trap 'echo INT > " + marker + "; kill $!; exit 130' INT\n" +
+		"echo started > " + marker + "\n" +
+		"sleep 30 &\nwait\n"
+	stub := filepath.Join(dir, "stub.sh")
+	if err := os.WriteFile(stub, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return stub, marker
+}
+
+func readMarker(marker string) string {
+	data, _ := os.ReadFile(marker)
+	return strings.TrimSpace(string(data))
+}
+
+func runTimed(t *testing.T, args ...string) (int, string, time.Duration) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	start := time.Now()
+	code := run(append([]string{"fantastical"}, args...), strings.NewReader(""), &out, &errOut)
+	return code, errOut.String(), time.Since(start)
+}
+
+func TestGlobalTimeoutStopsSubprocess(t *testing.T) {
+	setupTestEnv(t)
+	stub, marker := sleepStub(t)
+	t.Setenv("FANTASTICAL_OPEN_COMMAND", stub)
+
+	code, errOut, elapsed := runTimed(t, "--timeout", "300ms", "parse", "Dinner at 7")
+	if code != exitTimeout || !strings.Contains(errOut, "stub.sh: timed out after 300ms (--timeout)") {
+		t.Fatalf("expected exit %d with a timeout error, got %d: %q", exitTimeout, code, errOut)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("the command outlived its timeout: %s", elapsed)
+	}
+	if got := readMarker(marker); got != "TERM" {
+		t.Fatalf("expected the stub to receive SIGTERM, got %q", got)
+	}
+}
+
+func TestInterruptIsForwardedToSubprocess(t *testing.T) {
+	setupTestEnv(t)
+	stub, marker := sleepStub(t)
+	t.Setenv("FANTASTICAL_OPEN_COMMAND", stub)
+
+	go func() {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if readMarker(marker) == "started" {
+				syscall.Kill(os.Getpid(), syscall.SIGINT)
+				return
+			}
+		}
+	}()
+	code, errOut, _ := runTimed(t, "parse", "Dinner at 7")
+	if code != 130 || !strings.Contains(errOut, "stub.sh: interrupted by SIGINT") {
+		t.Fatalf("expected exit 130, got %d: %q", code, errOut)
+	}
+	if got := readMarker(marker); got != "INT" {
+		t.Fatalf("expected the stub to receive SIGINT, got %q", got)
+	}
+}
+
+func TestGlobalTimeoutStopsEventKitHelper(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", writeHelper(t, withHandshake(t, "#!/bin/sh\nsleep 30 &\nwait\n")))
+
+	code, errOut, elapsed := runTimed(t, "--timeout=300ms", "eventkit", "status", "--json")
+	if code != exitTimeout || !strings.Contains(errOut, "timed out after 300ms") || elapsed > 5*time.Second {
+		t.Fatalf("expected a timeout, got %d after %s: %q", code, elapsed, errOut)
+	}
+}
+
+func TestGlobalTimeoutStopsPolling(t *testing.T) {
+	setupTestEnv(t)
+
+	code, errOut, elapsed := runTimed(t, "--timeout", "300ms", "eventkit", "events", "--backend", "fixture", "--today", "--query", "no such event", "--wait", "30", "--interval", "1")
+	if code != exitTimeout || elapsed > 5*time.Second {
+		t.Fatalf("expected the --wait loop to stop at the timeout, got %d after %s: %q", code, elapsed, errOut)
+	}
+}
+
+func TestCancelStopsStdinReaders(t *testing.T) {
+	setupTestEnv(t)
+	// The pipe is never written to or closed, like an idle terminal.
+	in, w := io.Pipe()
+	t.Cleanup(func() { w.Close() })
+	runIn := func(args ...string) (int, string, time.Duration) {
+		var out, errOut bytes.Buffer
+		start := time.Now()
+		code := run(append([]string{"fantastical"}, args...), in, &out, &errOut)
+		return code, errOut.String(), time.Since(start)
+	}
+
+	code, errOut, elapsed := runIn("--timeout", "200ms", "parse", "--stdin")
+	if code != exitTimeout || !strings.Contains(errOut, "read stdin: timed out after 200ms") || elapsed > 5*time.Second {
+		t.Fatalf("expected parse --stdin to time out, got %d after %s: %q", code, elapsed, errOut)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+	code, errOut, elapsed = runIn("mcp")
+	if code != 128+int(syscall.SIGTERM) || elapsed > 5*time.Second {
+		t.Fatalf("expected mcp to stop on SIGTERM, got %d after %s: %q", code, elapsed, errOut)
+	}
+}
+
+func TestSplitGlobalFlags(t *testing.T) {
+	opts, rest, err := splitGlobalFlags([]string{"--timeout", "2m", "eventkit", "--timeout", "1s"})
+	if err != nil || opts.timeout != 2*time.Minute || !reflect.DeepEqual(rest, []string{"eventkit", "--timeout", "1s"}) {
+		t.Fatalf("unexpected split: %+v %v %v", opts, rest, err)
+	}
+	for _, args := range [][]string{{"--timeout"}, {"--timeout", "soon", "parse"}, {"--timeout=-1s", "parse"}} {
+		if code, errOut, _ := runTimed(t, args...); code != 2 || !strings.Contains(errOut, "--timeout") {
+			t.Fatalf("%q: expected a usage error, got %d: %q", args, code, errOut)
+		}
+	}
+}
+
+func TestCompleteGlobalFlags(t *testing.T) {
+	setupCompletionEnv(t)
+
+	if got := completionValues("--ti"); !reflect.DeepEqual(got, []string{"--timeout"}) {
+		t.Fatalf("unexpected global flag completion: %v", got)
+	}
+	if got := completionValues("--timeout", ""); got != nil {
+		t.Fatalf("expected no completion for the --timeout value, got %v", got)
+	}
+	if got := completionValues("--timeout", "30s", "pa"); !reflect.DeepEqual(got, []string{"parse"}) {
+		t.Fatalf("unexpected command completion: %v", got)
+	}
+	if got := completionValues("--timeout=30s", "eventkit", "st"); !reflect.DeepEqual(got, []string{"status"}) {
+		t.Fatalf("unexpected subcommand completion: %v", got)
+	}
+}
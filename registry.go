@@ -0,0 +1,728 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// completionSource names a dynamic source of flag values for shell completion.
+type completionSource string
+
+const (
+	completeCalendar    completionSource = "calendar"
+	completeCalendarID  completionSource = "calendar-id"
+	completeCalendarSet completionSource = "calendar-set"
+	completeTimezone    completionSource = "timezone"
+	completeFile        completionSource = "file"
+)
+
+// flagSpec declares one flag. Flag sets are built from these declarations
+// (see bindFlags) and the same list renders help, greta, man, explain and
+// shell completion, so a flag only has to be added here.
+type flagSpec struct {
+	Name     string
+	Short    string // one-letter alias, e.g. n for --note
+	Alias    string // long alias, e.g. calendarName for --calendar
+	Arg      string // value placeholder; empty for boolean flags
+	Values   []string
+	Complete completionSource
+	Usage    string
+}
+
+type commandSpec struct {
+	Name        string
+	Aliases     []string
+	Summary     string
+	Args        string
+	Flags       []flagSpec
+	Subcommands []commandSpec
+	Hidden      bool
+}
+
+func (f flagSpec) takesValue() bool {
+	return f.Arg != "" || len(f.Values) > 0
+}
+
+// names returns the flag name followed by its aliases.
+func (f flagSpec) names() []string {
+	names := []string{f.Name}
+	if f.Short != "" {
+		names = append(names, f.Short)
+	}
+	if f.Alias != "" {
+		names = append(names, f.Alias)
+	}
+	return names
+}
+
+// display renders the flag as "--calendar, --calendarName name".
+func (f flagSpec) display() string {
+	tokens := make([]string, 0, 3)
+	for _, name := range f.names() {
+		tokens = append(tokens, flagToken(name))
+	}
+	out := strings.Join(tokens, ", ")
+	if arg := f.argDisplay(); arg != "" {
+		out += " " + arg
+	}
+	return out
+}
+
+func (f flagSpec) argDisplay() string {
+	if len(f.Values) > 0 {
+		return strings.Join(f.Values, "|")
+	}
+	return f.Arg
+}
+
+func flagToken(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+func outputFlags() []flagSpec {
+	return []flagSpec{
+		{Name: "open", Usage: "Open the generated URL via system opener"},
+		{Name: "print", Usage: "Print the generated URL to stdout"},
+		{Name: "copy", Usage: "Copy the generated URL to clipboard (pbcopy)"},
+		{Name: "json", Usage: "Print machine-readable JSON output"},
+		{Name: "plain", Usage: "Print stable plain-text output"},
+		{Name: "dry-run", Usage: "Preview only; do not open or copy"},
+		{Name: "verbose", Usage: "Verbose output to stderr"},
+	}
+}
+
+var (
+	configFlag   = flagSpec{Name: "config", Arg: "path", Complete: completeFile, Usage: "Config file path (overrides default user config)"}
+	timezoneFlag = flagSpec{Name: "timezone", Arg: "IANA", Complete: completeTimezone, Usage: "Timezone to pass as tz=... (IANA name)"}
+	paramFlag    = flagSpec{Name: "param", Arg: "key=value", Usage: "Extra Fantastical query param (key=value), repeatable"}
+	jsonFlag     = flagSpec{Name: "json", Usage: "Print machine-readable JSON output"}
+	plainFlag    = flagSpec{Name: "plain", Usage: "Print stable plain-text output"}
+	verboseFlag  = flagSpec{Name: "verbose", Usage: "Verbose output to stderr"}
+	noInputFlag  = flagSpec{Name: "no-input", Usage: "Do not prompt for Calendar access"}
+	backendFlag  = flagSpec{Name: "backend", Arg: "name[:source]", Usage: "Calendar backend: eventkit (default), ics:/path, caldav:https://url or fixture[:path] (config backend.name)"}
+)
+
+// globalFlags go before the command name and apply to every command.
+var globalFlags = []flagSpec{
+	{Name: "timeout", Arg: "duration", Usage: "Cancel the command and its subprocesses after this long (exit 124)"},
+}
+
+// commandRegistry lists every command in the order help presents them.
+var commandRegistry = []commandSpec{
+	{
+		Name:    "parse",
+		Summary: "Build (and optionally open) x-fantastical3://parse?... URLs",
+		Args:    "<sentence...>",
+		Flags: append(append([]flagSpec{
+			{Name: "note", Short: "n", Arg: "text", Usage: "Optional note (maps to n=...)"},
+			{Name: "calendar", Alias: "calendarName", Arg: "name", Complete: completeCalendar, Usage: "Optional calendar name (maps to calendarName=...)"},
+			{Name: "add", Usage: "Add immediately without interaction (maps to add=1)"},
+		}, outputFlags()...),
+			flagSpec{Name: "stdin", Usage: "Read sentence from stdin instead of args"},
+			paramFlag,
+			timezoneFlag,
+			configFlag,
+			flagSpec{Name: "no-cache", Usage: "Bypass the cached calendar list when resolving --calendar"},
+			flagSpec{Name: "await", Usage: "Wait for Fantastical's x-callback-url reply and report it"},
+			flagSpec{Name: "await-timeout", Arg: "duration", Usage: "How long --await waits for the callback (default 30s)"},
+			flagSpec{Name: "verify", Usage: "Confirm via EventKit that a new event appeared and report it"},
+			flagSpec{Name: "verify-timeout", Arg: "duration", Usage: "How long --verify polls for the new event (default 20s)"},
+			flagSpec{Name: "title", Arg: "text", Usage: "Event title; composes the sentence from structured flags"},
+			flagSpec{Name: "start", Arg: "datetime", Usage: "Start (YYYY-MM-DD, YYYY-MM-DDTHH:MM or RFC 3339)"},
+			flagSpec{Name: "end", Arg: "datetime", Usage: "End, same formats as --start"},
+			flagSpec{Name: "duration", Arg: "duration", Usage: "Length instead of --end (e.g. 30m, 1h30m)"},
+			flagSpec{Name: "location", Arg: "text", Usage: "Event location"},
+			flagSpec{Name: "invitees", Arg: "emails", Usage: "Comma-separated invitee emails, repeatable"},
+			flagSpec{Name: "repeat", Values: repeatValues, Usage: "Recurrence"},
+			flagSpec{Name: "alert", Arg: "duration", Usage: "Alert before the start (e.g. 10m, 1h)"},
+			flagSpec{Name: "explain", Usage: "Print the composed sentence and URL without opening"},
+			flagSpec{Name: "backend", Arg: "fixture[:path]", Usage: "Record the predicted event in a fixture instead of opening Fantastical (config backend.name)"},
+		),
+	},
+	{
+		Name:    "show",
+		Summary: "Build (and optionally open) x-fantastical3://show/... URLs",
+		Args:    "<view> [date] | set <calendar-set-name>",
+		Flags: append(outputFlags(),
+			paramFlag,
+			flagSpec{Name: "view", Arg: "name", Values: []string{"mini", "calendar", "day", "week", "month", "agenda"}, Usage: "View name (e.g., mini, calendar, day, week, month, agenda)"},
+			flagSpec{Name: "calendar-set", Arg: "name", Complete: completeCalendarSet, Usage: "Calendar set name (equivalent to: show set <name>)"},
+			timezoneFlag,
+			configFlag,
+		),
+	},
+	{
+		Name:    "applescript",
+		Aliases: []string{"as"},
+		Summary: `Send "parse sentence" to Fantastical via osascript (macOS)`,
+		Args:    "<sentence...>",
+		Flags: []flagSpec{
+			{Name: "add", Usage: "Use Fantastical AppleScript 'with add immediately'"},
+			{Name: "run", Usage: "Run osascript (macOS only)"},
+			{Name: "print", Usage: "Print the AppleScript instead of (or in addition to) running it"},
+			{Name: "dry-run", Usage: "Preview only; do not run osascript"},
+			verboseFlag,
+			{Name: "stdin", Usage: "Read sentence from stdin instead of args"},
+			configFlag,
+		},
+	},
+	{
+		Name:    "validate",
+		Summary: "Validate parse/show input and print the URL, or the events range",
+		Args:    "parse|show|events ...",
+		Flags: []flagSpec{
+			{Name: "json", Usage: "Print machine-readable JSON validation result"},
+			{Name: "strict", Usage: "Fail when the predicted event has warnings (no time, past date)"},
+		},
+	},
+	{
+		Name:    "doctor",
+		Summary: "Check Fantastical + macOS integration status",
+		Flags: []flagSpec{
+			jsonFlag,
+			verboseFlag,
+			{Name: "skip-app", Usage: "Skip Fantastical app lookup"},
+			{Name: "fix", Usage: "Apply safe fixes: build the EventKit helper, install shell completion"},
+			{Name: "check", Arg: "id", Values: doctorCheckIDs(), Usage: "Run only these checks (repeatable or comma-separated)"},
+		},
+	},
+	{
+		Name:    "config",
+		Summary: "Show the effective config and which files it came from",
+		Subcommands: []commandSpec{
+			{Name: "show", Summary: "Print the effective config", Flags: []flagSpec{jsonFlag, configFlag}},
+		},
+	},
+	{
+		Name:    "cache",
+		Summary: "Show or clear cached calendar metadata",
+		Subcommands: []commandSpec{
+			{Name: "show", Summary: "Print the cached calendar list", Flags: []flagSpec{jsonFlag}},
+			{Name: "clear", Summary: "Remove the calendar cache", Flags: []flagSpec{jsonFlag}},
+		},
+	},
+	{
+		Name:    "eventkit",
+		Summary: "List calendars or events via EventKit (system Calendar access)",
+		Subcommands: []commandSpec{
+			{
+				Name:    "status",
+				Summary: "Print Calendar authorization status",
+				Flags: []flagSpec{
+					{Name: "format", Values: []string{"plain", "json"}, Usage: "Output format (plain|json)"},
+					jsonFlag,
+					plainFlag,
+					verboseFlag,
+				},
+			},
+			{
+				Name:    "calendars",
+				Summary: "List calendars",
+				Flags: []flagSpec{
+					{Name: "format", Values: []string{"plain", "json", "table"}, Usage: "Output format (plain|json|table)"},
+					jsonFlag,
+					plainFlag,
+					noInputFlag,
+					verboseFlag,
+					{Name: "no-cache", Usage: "Do not update the cached calendar list"},
+					backendFlag,
+					configFlag,
+				},
+			},
+			{
+				Name:    "events",
+				Summary: "List events in a date range",
+				Flags: []flagSpec{
+					{Name: "format", Values: []string{"plain", "json", "table"}, Usage: "Output format (plain|json|table)"},
+					jsonFlag,
+					plainFlag,
+					noInputFlag,
+					verboseFlag,
+					{Name: "calendar", Arg: "name", Complete: completeCalendar, Usage: "Calendar name (repeatable)"},
+					{Name: "calendar-id", Arg: "id", Complete: completeCalendarID, Usage: "Calendar identifier (repeatable)"},
+					{Name: "from", Arg: "date", Usage: "Start date expression (YYYY-MM-DD, RFC 3339, +3d, next monday, 2026-W42, ...)"},
+					{Name: "to", Arg: "date", Usage: "End date expression (YYYY-MM-DD, RFC 3339, +3d, end of month, ...)"},
+					{Name: "days", Arg: "n", Usage: "Days from now (shortcut for --from now --to now+days)"},
+					{Name: "today", Usage: "Use today's date range"},
+					{Name: "tomorrow", Usage: "Use tomorrow's date range"},
+					{Name: "this-week", Usage: "Use this week's date range"},
+					{Name: "next-week", Usage: "Use next week's date range"},
+					{Name: "week-start", Values: []string{"monday", "sunday"}, Usage: "First day of the week (default: config dates.week_start, then locale)"},
+					{Name: "where", Arg: "expr", Usage: "Filter expression, e.g. 'calendar in (Work, Team) and duration >= 30m and not allDay'"},
+					{Name: "offset", Arg: "n", Usage: "Skip the first N matching events (after sorting)"},
+					{Name: "limit", Arg: "n", Usage: "Limit number of events returned"},
+					{Name: "include-all-day", Usage: "Include all-day events"},
+					{Name: "include-declined", Usage: "Include declined events"},
+					{Name: "sort", Arg: "keys", Usage: "Comma-separated sort keys (start, end, title, calendar, location, duration); prefix - for descending"},
+					{Name: "tz", Arg: "IANA", Complete: completeTimezone, Usage: "Timezone for output (IANA name)"},
+					{Name: "query", Arg: "text", Usage: "Filter by title/location/notes (case-insensitive)"},
+					{Name: "refresh", Usage: "Refresh calendar sources before querying"},
+					{Name: "wait", Arg: "seconds", Usage: "Wait up to N seconds for events to appear"},
+					{Name: "interval", Arg: "seconds", Usage: "Polling interval in seconds when using --wait"},
+					configFlag,
+					{Name: "no-cache", Usage: "Bypass the cached calendar list when resolving --calendar"},
+					backendFlag,
+				},
+			},
+			{
+				Name:    "search",
+				Summary: "Search event titles, locations and notes across years",
+				Args:    "<term...>",
+				Flags: []flagSpec{
+					{Name: "format", Values: []string{"plain", "json", "table"}, Usage: "Output format (plain|json|table)"},
+					jsonFlag,
+					plainFlag,
+					noInputFlag,
+					verboseFlag,
+					{Name: "calendar", Arg: "name", Complete: completeCalendar, Usage: "Calendar name (repeatable)"},
+					{Name: "calendar-id", Arg: "id", Complete: completeCalendarID, Usage: "Calendar identifier (repeatable)"},
+					{Name: "years", Arg: "n", Usage: "Search the last N years through one year ahead (default 5)"},
+					{Name: "from", Arg: "date", Usage: "Start date expression, instead of --years"},
+					{Name: "to", Arg: "date", Usage: "End date expression (default one year from today)"},
+					{Name: "week-start", Values: []string{"monday", "sunday"}, Usage: "First day of the week (default: config dates.week_start, then locale)"},
+					{Name: "where", Arg: "expr", Usage: "Additional filter expression, as for eventkit events --where"},
+					{Name: "offset", Arg: "n", Usage: "Skip the first N matching events (after sorting)"},
+					{Name: "limit", Arg: "n", Usage: "Limit number of events returned"},
+					{Name: "include-all-day", Usage: "Include all-day events"},
+					{Name: "include-declined", Usage: "Include declined events"},
+					{Name: "sort", Arg: "keys", Usage: "Comma-separated sort keys (default -start, newest first)"},
+					{Name: "tz", Arg: "IANA", Complete: completeTimezone, Usage: "Timezone for output (IANA name)"},
+					{Name: "refresh", Usage: "Refresh calendar sources before querying"},
+					configFlag,
+					{Name: "no-cache", Usage: "Bypass the cached calendar list when resolving --calendar"},
+					backendFlag,
+				},
+			},
+			{
+				Name:    "create",
+				Summary: "Create an event in an ics, caldav or fixture backend",
+				Flags: []flagSpec{
+					{Name: "format", Values: []string{"plain", "json", "table"}, Usage: "Output format (plain|json|table)"},
+					jsonFlag,
+					plainFlag,
+					verboseFlag,
+					{Name: "calendar", Arg: "name", Usage: "Calendar title or ID (required; calendars.aliases apply)"},
+					{Name: "title", Arg: "text", Usage: "Event title (required)"},
+					{Name: "start", Arg: "datetime", Usage: "Start (YYYY-MM-DD for all-day, YYYY-MM-DDTHH:MM or RFC 3339; required)"},
+					{Name: "end", Arg: "datetime", Usage: "End, or the last day of an all-day event (default: 1 hour or 1 day)"},
+					{Name: "duration", Arg: "duration", Usage: "Length instead of --end, e.g. 45m"},
+					{Name: "location", Arg: "text", Usage: "Event location"},
+					{Name: "notes", Arg: "text", Usage: "Event notes"},
+					{Name: "tz", Arg: "IANA", Complete: completeTimezone, Usage: "Timezone for --start/--end and output (IANA name)"},
+					backendFlag,
+					configFlag,
+				},
+			},
+			{
+				Name:    "delete",
+				Summary: "Delete an event by id from an ics, caldav or fixture backend",
+				Args:    "<event-id>",
+				Flags: []flagSpec{
+					jsonFlag,
+					verboseFlag,
+					{Name: "calendar", Arg: "name", Usage: "Calendar title or ID holding the event (required)"},
+					backendFlag,
+					configFlag,
+				},
+			},
+			{
+				Name:    "helper",
+				Summary: "Build, locate, inspect or remove the compiled Swift helper",
+				Subcommands: []commandSpec{
+					{
+						Name:    "build",
+						Summary: "Compile the helper into the cache (or --output)",
+						Flags: []flagSpec{
+							{Name: "force", Usage: "Rebuild even if the cached helper is up to date"},
+							{Name: "output", Arg: "path", Complete: completeFile, Usage: "Write the helper to this path instead of the cache"},
+							{Name: "universal", Usage: "Build an arm64 + x86_64 binary"},
+							jsonFlag,
+							verboseFlag,
+						},
+					},
+					{Name: "path", Summary: "Print the path of the helper eventkit commands run"},
+					{Name: "info", Summary: "Print source hash, binary hash, compiler and build time", Flags: []flagSpec{jsonFlag}},
+					{Name: "clean", Summary: "Remove the cached helper", Flags: []flagSpec{jsonFlag}},
+				},
+			},
+		},
+	},
+	{
+		Name:    "mcp",
+		Summary: "Run a Model Context Protocol server over stdio",
+		Flags: []flagSpec{
+			{Name: "dry-run", Usage: "Never open Fantastical; tools only build URLs"},
+			configFlag,
+			verboseFlag,
+		},
+	},
+	{
+		Name:    "serve",
+		Summary: "Run a localhost HTTP/JSON API",
+		Flags: []flagSpec{
+			{Name: "addr", Arg: "host:port", Usage: "Loopback address to listen on (default 127.0.0.1:7419, or serve.addr)"},
+			{Name: "token", Arg: "token", Usage: "Require this bearer token (default serve.token)"},
+			{Name: "dry-run", Usage: "Never open Fantastical; /parse and /show only build URLs"},
+			configFlag,
+			verboseFlag,
+		},
+	},
+	{
+		Name:    "greta",
+		Summary: "Machine-readable CLI spec for agents",
+		Flags: []flagSpec{
+			{Name: "format", Values: []string{"json", "markdown"}, Usage: "Output format: json or markdown"},
+			{Name: "schema", Values: []string{"v1"}, Usage: "Schema version (v1)"},
+			{Name: "examples", Usage: "Output curated examples only"},
+			{Name: "capabilities", Usage: "Output capability summary only"},
+			{Name: "schemas", Usage: "Output JSON Schemas for command output, the config file and the EventKit helper"},
+		},
+	},
+	{
+		Name:    "explain",
+		Summary: "Human-readable command walkthrough",
+		Args:    "<command>",
+	},
+	{
+		Name:    "man",
+		Summary: "Manual page output (markdown or json)",
+		Flags: []flagSpec{
+			{Name: "format", Values: []string{"markdown", "json"}, Usage: "Output format: markdown or json"},
+		},
+	},
+	{
+		Name:    "completion",
+		Summary: "Print or install shell completion (bash|zsh|fish)",
+		Args:    "[bash|zsh|fish]",
+		Subcommands: []commandSpec{
+			{
+				Name:    "install",
+				Summary: "Install the completion script",
+				Args:    "[bash|zsh|fish]",
+				Flags:   []flagSpec{{Name: "path", Arg: "path", Complete: completeFile, Usage: "Install path (defaults to a user-local location)"}},
+			},
+			{
+				Name:    "uninstall",
+				Summary: "Remove the completion script",
+				Args:    "[bash|zsh|fish]",
+				Flags:   []flagSpec{{Name: "path", Arg: "path", Complete: completeFile, Usage: "Completion path (defaults to a user-local location)"}},
+			},
+		},
+	},
+	{
+		Name:    "help",
+		Summary: "Show help for a command",
+		Args:    "[command]",
+		Flags: []flagSpec{
+			{Name: "json", Usage: "Print machine-readable JSON help"},
+		},
+	},
+	{
+		Name:    "version",
+		Summary: "Print version information",
+	},
+	{
+		Name:    completeCommand,
+		Summary: "Print shell completion candidates",
+		Hidden:  true,
+	},
+}
+
+// lookupCommand finds a command by space-separated path, e.g. "eventkit events".
+// Aliases such as "as" match too.
+func lookupCommand(path string) (commandSpec, bool) {
+	specs := commandRegistry
+	var found commandSpec
+	for _, word := range strings.Fields(strings.ToLower(path)) {
+		ok := false
+		for _, spec := range specs {
+			if spec.matches(word) {
+				found, ok = spec, true
+				break
+			}
+		}
+		if !ok {
+			return commandSpec{}, false
+		}
+		specs = found.Subcommands
+	}
+	return found, found.Name != ""
+}
+
+func (c commandSpec) matches(name string) bool {
+	if strings.EqualFold(c.Name, name) {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandFlags returns the flags registered for a command path.
+func commandFlags(path string) []flagSpec {
+	spec, _ := lookupCommand(path)
+	return spec.Flags
+}
+
+// allFlags returns the command's flags followed by those of its subcommands.
+// A flag declared by several subcommands appears once with the union of its
+// values.
+func (c commandSpec) allFlags() []flagSpec {
+	index := map[string]int{}
+	var flags []flagSpec
+	var walk func(commandSpec)
+	walk = func(spec commandSpec) {
+		for _, f := range spec.Flags {
+			i, ok := index[f.Name]
+			if !ok {
+				index[f.Name] = len(flags)
+				flags = append(flags, f)
+				continue
+			}
+			for _, v := range f.Values {
+				if !containsFold(flags[i].Values, v) {
+					flags[i].Values = append(append([]string(nil), flags[i].Values...), v)
+				}
+			}
+		}
+		for _, sub := range spec.Subcommands {
+			walk(sub)
+		}
+	}
+	walk(c)
+	return flags
+}
+
+// bindFlags registers the flags of a command path on fs. targets maps each
+// flag name to a *bool, *string, *int or flag.Value whose current value is the
+// default. A flag without a target, a target without a flag, or a target whose
+// kind disagrees with the declaration is a programming error and panics.
+func bindFlags(fs *flag.FlagSet, path string, targets map[string]any) {
+	specs := commandFlags(path)
+	if len(specs) != len(targets) {
+		panic(fmt.Sprintf("%s: %d flags registered but %d bound", path, len(specs), len(targets)))
+	}
+	for _, spec := range specs {
+		target, ok := targets[spec.Name]
+		if !ok {
+			panic(fmt.Sprintf("%s: flag --%s has no target", path, spec.Name))
+		}
+		if _, isBool := target.(*bool); isBool == spec.takesValue() {
+			panic(fmt.Sprintf("%s: flag --%s value kind does not match its declaration", path, spec.Name))
+		}
+		for _, name := range spec.names() {
+			usage := spec.Usage
+			if name != spec.Name {
+				usage = "Alias for --" + spec.Name
+			}
+			switch p := target.(type) {
+			case *bool:
+				fs.BoolVar(p, name, *p, usage)
+			case *string:
+				fs.StringVar(p, name, *p, usage)
+			case *int:
+				fs.IntVar(p, name, *p, usage)
+			case *time.Duration:
+				fs.DurationVar(p, name, *p, usage)
+			case flag.Value:
+				fs.Var(p, name, usage)
+			default:
+				panic(fmt.Sprintf("%s: flag --%s has unsupported target %T", path, spec.Name, target))
+			}
+		}
+	}
+}
+
+// commandListing renders the COMMANDS section of the top-level usage.
+func commandListing() string {
+	var b strings.Builder
+	for _, spec := range commandRegistry {
+		if spec.Hidden {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-12s %s\n", spec.Name, spec.Summary)
+	}
+	return b.String()
+}
+
+func flagDisplays(flags []flagSpec) []string {
+	out := make([]string, len(flags))
+	for i, f := range flags {
+		out[i] = f.display()
+	}
+	return out
+}
+
+// gretaCommands renders the registry for greta, help --json and man --format json.
+func gretaCommands() []map[string]any {
+	var commands []map[string]any
+	for _, spec := range commandRegistry {
+		if spec.Hidden {
+			continue
+		}
+		commands = append(commands, gretaCommand(spec))
+	}
+	return commands
+}
+
+func gretaCommand(spec commandSpec) map[string]any {
+	entry := map[string]any{
+		"name":        spec.Name,
+		"description": spec.Summary,
+	}
+	if len(spec.Aliases) > 0 {
+		entry["aliases"] = spec.Aliases
+	}
+	args := spec.Args
+	if args == "" && len(spec.Subcommands) > 0 {
+		names := make([]string, len(spec.Subcommands))
+		for i, sub := range spec.Subcommands {
+			names[i] = sub.Name
+		}
+		args = strings.Join(names, "|")
+	}
+	if args != "" {
+		entry["args"] = args
+	}
+	if flags := spec.allFlags(); len(flags) > 0 {
+		entry["flags"] = flagDisplays(flags)
+	}
+	if len(spec.Subcommands) > 0 {
+		subs := make([]map[string]any, len(spec.Subcommands))
+		for i, sub := range spec.Subcommands {
+			subs[i] = gretaCommand(sub)
+		}
+		entry["subcommands"] = subs
+	}
+	return entry
+}
+
+// commandsMarkdown renders the registry as a markdown list for greta.
+func commandsMarkdown() string {
+	var b strings.Builder
+	for _, spec := range commandRegistry {
+		if spec.Hidden {
+			continue
+		}
+		writeMarkdownCommand(&b, "", "", spec)
+	}
+	return b.String()
+}
+
+// globalFlagsMarkdown renders globalFlags for greta and man.
+func globalFlagsMarkdown() string {
+	var b strings.Builder
+	displays := flagDisplays(globalFlags)
+	for i, f := range globalFlags {
+		fmt.Fprintf(&b, "- `%s`: %s\n", displays[i], f.Usage)
+	}
+	return b.String()
+}
+
+func writeMarkdownCommand(b *strings.Builder, indent, prefix string, spec commandSpec) {
+	name := strings.TrimSpace(prefix + " " + spec.Name)
+	fmt.Fprintf(b, "%s- %s: %s\n", indent, name, spec.Summary)
+	writeMarkdownFlags(b, indent+"  ", spec.Flags)
+	for _, sub := range spec.Subcommands {
+		writeMarkdownCommand(b, indent+"  ", name, sub)
+	}
+}
+
+func writeMarkdownFlags(b *strings.Builder, indent string, flags []flagSpec) {
+	if len(flags) > 0 {
+		fmt.Fprintf(b, "%s- flags: `%s`\n", indent, strings.Join(flagDisplays(flags), "`, `"))
+	}
+}
+
+// manCommandsMarkdown renders one section per command with its flags.
+func manCommandsMarkdown() string {
+	var b strings.Builder
+	for _, spec := range commandRegistry {
+		if spec.Hidden {
+			continue
+		}
+		writeManCommand(&b, appName, spec)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeManCommand(b *strings.Builder, prefix string, spec commandSpec) {
+	name := prefix + " " + spec.Name
+	synopsis := name
+	if len(spec.Flags) > 0 {
+		synopsis += " [flags]"
+	}
+	if spec.Args != "" {
+		synopsis += " " + spec.Args
+	}
+	fmt.Fprintf(b, "### %s\n%s\n\n%s\n\n", strings.TrimPrefix(name, appName+" "), spec.Summary, "`"+synopsis+"`")
+	for _, f := range spec.Flags {
+		fmt.Fprintf(b, "- `%s`: %s\n", f.display(), f.Usage)
+	}
+	if len(spec.Flags) > 0 {
+		b.WriteString("\n")
+	}
+	for _, sub := range spec.Subcommands {
+		writeManCommand(b, name, sub)
+	}
+}
+
+// flagReference renders the flags of a command (and its subcommands) for
+// explain.
+func flagReference(spec commandSpec) string {
+	var b strings.Builder
+	var walk func(prefix string, spec commandSpec)
+	walk = func(prefix string, spec commandSpec) {
+		name := strings.TrimSpace(prefix + " " + spec.Name)
+		if len(spec.Flags) > 0 {
+			fmt.Fprintf(&b, "\n%s flags:\n", name)
+			writeFlagTable(&b, spec.Flags)
+		}
+		for _, sub := range spec.Subcommands {
+			walk(name, sub)
+		}
+	}
+	walk("", spec)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFlagTable(w io.Writer, flags []flagSpec) {
+	width := 0
+	for _, f := range flags {
+		if n := len(f.display()); n > width {
+			width = n
+		}
+	}
+	for _, f := range flags {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, f.display(), f.Usage)
+	}
+}
+
+// printFlagHelp writes the FLAGS section of a command's usage text.
+func printFlagHelp(w io.Writer, path string) {
+	if flags := commandFlags(path); len(flags) > 0 {
+		fmt.Fprintln(w, "FLAGS:")
+		writeFlagTable(w, flags)
+	}
+}
@@ -0,0 +1,174 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type registryPath struct {
+	path  string
+	words []string
+	spec  commandSpec
+}
+
+func registryPaths() []registryPath {
+	var paths []registryPath
+	var walk func(words []string, spec commandSpec)
+	walk = func(words []string, spec commandSpec) {
+		words = append(append([]string(nil), words...), spec.Name)
+		paths = append(paths, registryPath{path: strings.Join(words, " "), words: words, spec: spec})
+		for _, sub := range spec.Subcommands {
+			walk(words, sub)
+		}
+	}
+	for _, spec := range commandRegistry {
+		if !spec.Hidden {
+			walk(nil, spec)
+		}
+	}
+	return paths
+}
+
+func TestRegistryFlagsAppearEverywhere(t *testing.T) {
+	setupTestEnv(t)
+
+	var gretaJSON, gretaMD, manJSON, manMD, errOut bytes.Buffer
+	if err := cmdGreta(context.Background(), []string{"--format", "json"}, &gretaJSON, &errOut); err != nil {
+		t.Fatalf("greta json: %v", err)
+	}
+	if err := cmdGreta(context.Background(), []string{"--format", "markdown"}, &gretaMD, &errOut); err != nil {
+		t.Fatalf("greta markdown: %v", err)
+	}
+	if err := cmdMan([]string{"--format", "json"}, &manJSON, &errOut); err != nil {
+		t.Fatalf("man json: %v", err)
+	}
+	if err := cmdMan([]string{"--format", "markdown"}, &manMD, &errOut); err != nil {
+		t.Fatalf("man markdown: %v", err)
+	}
+
+	for _, rp := range registryPaths() {
+		top := rp.words[0]
+		var helpJSON, explain bytes.Buffer
+		if err := cmdHelp([]string{"--json", top}, &helpJSON, &errOut); err != nil {
+			t.Fatalf("help --json %s: %v", top, err)
+		}
+		if err := cmdExplain([]string{top}, &explain, &errOut); err != nil {
+			t.Fatalf("explain %s: %v", top, err)
+		}
+		var usage bytes.Buffer
+		printFlagHelp(&usage, rp.path)
+
+		var completions []string
+		for _, c := range completeArgs(append(append([]string(nil), rp.words...), "-")) {
+			completions = append(completions, c.Value)
+		}
+
+		outputs := map[string]string{
+			"greta json":     gretaJSON.String(),
+			"greta markdown": gretaMD.String(),
+			"man json":       manJSON.String(),
+			"man markdown":   manMD.String(),
+			"help json":      helpJSON.String(),
+			"explain":        explain.String(),
+			"usage":          usage.String(),
+		}
+		for _, f := range rp.spec.Flags {
+			for name, output := range outputs {
+				if !strings.Contains(output, f.display()) {
+					t.Errorf("%s: flag %q missing from %s", rp.path, f.display(), name)
+				}
+			}
+			for _, alias := range f.names() {
+				if !containsString(completions, flagToken(alias)) {
+					t.Errorf("%s: flag %s missing from completion %v", rp.path, flagToken(alias), completions)
+				}
+			}
+		}
+	}
+}
+
+func TestRegistryMatchesFlagSets(t *testing.T) {
+	flagSets := map[string]func() *flag.FlagSet{
+		"parse": func() *flag.FlagSet {
+			fs, _ := newParseFlagSet(io.Discard, defaultParseOptions(nil))
+			return fs
+		},
+		"show": func() *flag.FlagSet {
+			fs, _ := newShowFlagSet(io.Discard, defaultShowOptions(nil))
+			return fs
+		},
+		"applescript": func() *flag.FlagSet {
+			fs, _ := newAppleScriptFlagSet(io.Discard, defaultAppleScriptOptions(nil))
+			return fs
+		},
+		"eventkit status": func() *flag.FlagSet {
+			fs, _ := newEventKitStatusFlagSet(io.Discard)
+			return fs
+		},
+		"eventkit calendars": func() *flag.FlagSet {
+			fs, _ := newEventKitCalendarsFlagSet(io.Discard)
+			return fs
+		},
+		"eventkit events": func() *flag.FlagSet {
+			fs, _ := newEventKitEventsFlagSet(io.Discard)
+			return fs
+		},
+		"eventkit search": func() *flag.FlagSet {
+			fs, _ := newEventKitSearchFlagSet(io.Discard)
+			return fs
+		},
+		"eventkit create": func() *flag.FlagSet {
+			fs, _ := newEventKitCreateFlagSet(io.Discard)
+			return fs
+		},
+		"eventkit delete": func() *flag.FlagSet {
+			fs, _ := newEventKitDeleteFlagSet(io.Discard)
+			return fs
+		},
+	}
+	for path, build := range flagSets {
+		var got []string
+		build().VisitAll(func(f *flag.Flag) {
+			got = append(got, f.Name)
+		})
+		var want []string
+		for _, f := range commandFlags(path) {
+			want = append(want, f.names()...)
+		}
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: flag set %v, registry %v", path, got, want)
+		}
+	}
+}
+
+func TestBindFlagsRejectsUnregisteredTarget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for unregistered flag")
+		}
+	}()
+	var jsonOut, extra bool
+	bindFlags(flag.NewFlagSet("doctor", flag.ContinueOnError), "validate", map[string]any{"json": &jsonOut, "extra": &extra})
+}
+
+func TestLookupCommand(t *testing.T) {
+	if spec, ok := lookupCommand("as"); !ok || spec.Name != "applescript" {
+		t.Fatalf("expected alias lookup, got %+v", spec)
+	}
+	if spec, ok := lookupCommand("eventkit events"); !ok || spec.Name != "events" {
+		t.Fatalf("expected subcommand lookup, got %+v", spec)
+	}
+	if _, ok := lookupCommand("eventkit nope"); ok {
+		t.Fatalf("expected unknown subcommand to fail")
+	}
+}
@@ -0,0 +1,344 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrenceRule is a parsed RRULE (RFC 5545 section 3.3.10). DAILY,
+// WEEKLY, MONTHLY and YEARLY rules with INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY, BYMONTH, BYSETPOS and WKST are supported; other parts are
+// rejected so callers can fall back to the first occurrence.
+type recurrenceRule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+	bySetPos   []int
+	weekStart  time.Weekday
+}
+
+// weekdayNum is a BYDAY entry such as MO, 2TU or -1FR; n is 0 without an
+// ordinal.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRRule parses an RRULE value. A floating or date-only UNTIL is read
+// in loc; a date-only UNTIL includes that whole day.
+func parseRRule(value string, loc *time.Location) (recurrenceRule, error) {
+	r := recurrenceRule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(strings.TrimSpace(value), ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key, val = strings.ToUpper(key), strings.ToUpper(val)
+		var err error
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = val
+			default:
+				return r, fmt.Errorf("unsupported RRULE FREQ=%s", val)
+			}
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(val); err != nil || r.interval < 1 {
+				return r, fmt.Errorf("invalid RRULE INTERVAL=%s", val)
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(val); err != nil || r.count < 1 {
+				return r, fmt.Errorf("invalid RRULE COUNT=%s", val)
+			}
+		case "UNTIL":
+			z := icsZones{floating: loc, local: loc}
+			until, dateOnly, err := z.parseTime(icsProperty{name: "UNTIL", value: val})
+			if err != nil {
+				return r, err
+			}
+			if dateOnly {
+				until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			r.until = until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				if len(item) < 2 {
+					return r, fmt.Errorf("invalid RRULE BYDAY=%s", val)
+				}
+				day, ok := icsWeekdays[item[len(item)-2:]]
+				if !ok {
+					return r, fmt.Errorf("invalid RRULE BYDAY=%s", val)
+				}
+				wd := weekdayNum{day: day}
+				if ordinal := item[:len(item)-2]; ordinal != "" {
+					if wd.n, err = strconv.Atoi(ordinal); err != nil || wd.n == 0 || wd.n < -53 || wd.n > 53 {
+						return r, fmt.Errorf("invalid RRULE BYDAY=%s", val)
+					}
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "BYMONTHDAY":
+			if r.byMonthDay, err = parseRRuleInts(key, val, 31); err != nil {
+				return r, err
+			}
+		case "BYMONTH":
+			months, err := parseRRuleInts(key, val, 12)
+			if err != nil {
+				return r, err
+			}
+			for _, m := range months {
+				if m < 0 {
+					return r, fmt.Errorf("invalid RRULE BYMONTH=%s", val)
+				}
+				r.byMonth = append(r.byMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			if r.bySetPos, err = parseRRuleInts(key, val, 366); err != nil {
+				return r, err
+			}
+		case "WKST":
+			day, ok := icsWeekdays[val]
+			if !ok {
+				return r, fmt.Errorf("invalid RRULE WKST=%s", val)
+			}
+			r.weekStart = day
+		default:
+			return r, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+	}
+	if r.freq == "" {
+		return r, fmt.Errorf("RRULE without FREQ")
+	}
+	if r.count > 0 && !r.until.IsZero() {
+		return r, fmt.Errorf("RRULE cannot have both COUNT and UNTIL")
+	}
+	return r, nil
+}
+
+// parseRRuleInts parses a comma-separated list of non-zero integers within
+// ±limit.
+func parseRRuleInts(key, val string, limit int) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < -limit || n > limit {
+			return nil, fmt.Errorf("invalid RRULE %s=%s", key, val)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// starts returns the occurrence starts of a series beginning at dtstart, in
+// order, up to and including limit. DTSTART is always the first occurrence
+// and counts towards COUNT. Occurrences keep dtstart's wall-clock time in
+// its zone, so they follow DST changes.
+func (r recurrenceRule) starts(dtstart, limit time.Time) []time.Time {
+	if dtstart.After(limit) {
+		return nil
+	}
+	out := []time.Time{dtstart}
+	done := func(t time.Time) bool {
+		return t.After(limit) || (!r.until.IsZero() && t.After(r.until)) || (r.count > 0 && len(out) >= r.count)
+	}
+
+	loc := dtstart.Location()
+	hour, minute, sec := dtstart.Clock()
+	day0 := civilDate(dtstart)
+	for period := 0; ; period++ {
+		first, days := r.periodDays(day0, period)
+		if first.After(civilDate(limit.In(loc))) {
+			return out
+		}
+		for _, d := range days {
+			t := time.Date(d.Year(), d.Month(), d.Day(), hour, minute, sec, dtstart.Nanosecond(), loc)
+			if !t.After(dtstart) {
+				continue
+			}
+			if done(t) {
+				return out
+			}
+			out = append(out, t)
+		}
+	}
+}
+
+// civilDate returns t's calendar date at UTC midnight, for date arithmetic
+// that ignores zones and DST.
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// periodDays returns the first day of the period-th interval after day0
+// and the sorted days in it that match the rule, after BYSETPOS.
+func (r recurrenceRule) periodDays(day0 time.Time, period int) (time.Time, []time.Time) {
+	var first time.Time
+	var days []time.Time
+	switch r.freq {
+	case "DAILY":
+		first = day0.AddDate(0, 0, period*r.interval)
+		if r.matchesMonth(first) && r.matchesMonthDay(first) && r.matchesWeekday(first) {
+			days = []time.Time{first}
+		}
+	case "WEEKLY":
+		weekStart := day0.AddDate(0, 0, -((int(day0.Weekday()) - int(r.weekStart) + 7) % 7))
+		first = weekStart.AddDate(0, 0, 7*period*r.interval)
+		for i := 0; i < 7; i++ {
+			d := first.AddDate(0, 0, i)
+			if !r.matchesMonth(d) {
+				continue
+			}
+			if len(r.byDay) == 0 && d.Weekday() != day0.Weekday() || len(r.byDay) > 0 && !r.matchesWeekday(d) {
+				continue
+			}
+			days = append(days, d)
+		}
+	case "MONTHLY":
+		first = time.Date(day0.Year(), day0.Month()+time.Month(period*r.interval), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(first) {
+			days = r.monthDays(first, day0.Day())
+		}
+	case "YEARLY":
+		first = time.Date(day0.Year()+period*r.interval, time.January, 1, 0, 0, 0, 0, time.UTC)
+		days = r.yearDays(first, day0)
+	}
+	return first, r.applySetPos(days)
+}
+
+// monthDays returns the days of month that match BYMONTHDAY and BYDAY
+// (with ordinals counted within the month), or dtstart's day of month when
+// neither is set.
+func (r recurrenceRule) monthDays(month time.Time, defaultDay int) []time.Time {
+	last := month.AddDate(0, 1, -1).Day()
+	if len(r.byMonthDay) == 0 && len(r.byDay) == 0 {
+		if defaultDay > last {
+			return nil
+		}
+		return []time.Time{month.AddDate(0, 0, defaultDay-1)}
+	}
+	var days []time.Time
+	for i := 0; i < last; i++ {
+		d := month.AddDate(0, 0, i)
+		if r.matchesMonthDay(d) && r.matchesOrdinalWeekday(d, month, last) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// yearDays expands a YEARLY period: per BYMONTH month when given, else per
+// month for BYMONTHDAY, else across the year for BYDAY, else dtstart's
+// month and day.
+func (r recurrenceRule) yearDays(year time.Time, day0 time.Time) []time.Time {
+	switch {
+	case len(r.byMonth) > 0 || len(r.byMonthDay) > 0:
+		var days []time.Time
+		for m := time.January; m <= time.December; m++ {
+			month := time.Date(year.Year(), m, 1, 0, 0, 0, 0, time.UTC)
+			if r.matchesMonth(month) {
+				days = append(days, r.monthDays(month, day0.Day())...)
+			}
+		}
+		return days
+	case len(r.byDay) > 0:
+		last := year.AddDate(1, 0, -1).YearDay()
+		var days []time.Time
+		for i := 0; i < last; i++ {
+			d := year.AddDate(0, 0, i)
+			if r.matchesOrdinalWeekday(d, year, last) {
+				days = append(days, d)
+			}
+		}
+		return days
+	default:
+		d := time.Date(year.Year(), day0.Month(), day0.Day(), 0, 0, 0, 0, time.UTC)
+		if d.Month() != day0.Month() {
+			return nil // Feb 29 in a common year
+		}
+		return []time.Time{d}
+	}
+}
+
+func (r recurrenceRule) matchesMonth(d time.Time) bool {
+	return len(r.byMonth) == 0 || slices.Contains(r.byMonth, d.Month())
+}
+
+func (r recurrenceRule) matchesMonthDay(d time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, n := range r.byMonthDay {
+		if n == d.Day() || n < 0 && last+n+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday checks BYDAY ignoring ordinals.
+func (r recurrenceRule) matchesWeekday(d time.Time) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, wd := range r.byDay {
+		if wd.day == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesOrdinalWeekday checks BYDAY with ordinals counted within the
+// period that starts at periodStart and has length days.
+func (r recurrenceRule) matchesOrdinalWeekday(d, periodStart time.Time, length int) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	index := int(d.Sub(periodStart).Hours() / 24)
+	fromStart := index/7 + 1
+	fromEnd := -((length-1-index)/7 + 1)
+	for _, wd := range r.byDay {
+		if wd.day == d.Weekday() && (wd.n == 0 || wd.n == fromStart || wd.n == fromEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r recurrenceRule) applySetPos(days []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	var picked []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(days) + pos
+		}
+		if i >= 0 && i < len(days) && !slices.ContainsFunc(picked, days[i].Equal) {
+			picked = append(picked, days[i])
+		}
+	}
+	slices.SortFunc(picked, time.Time.Compare)
+	return picked
+}
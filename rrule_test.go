@@ -0,0 +1,148 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRuleStarts(t *testing.T) {
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	far := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		limit   time.Time
+		want    string
+	}{
+		{
+			name: "weekly keeps the wall clock across DST", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5",
+			dtstart: time.Date(2026, 10, 19, 9, 0, 0, 0, zagreb), limit: far,
+			want: "2026-10-19T09:00:00+02:00 2026-10-21T09:00:00+02:00 2026-10-23T09:00:00+02:00 2026-10-26T09:00:00+01:00 2026-10-28T09:00:00+01:00",
+		},
+		{
+			name: "every other day", rule: "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			dtstart: time.Date(2026, 10, 30, 8, 0, 0, 0, time.UTC), limit: far,
+			want: "2026-10-30T08:00:00Z 2026-11-01T08:00:00Z 2026-11-03T08:00:00Z",
+		},
+		{
+			name: "last friday of the month up to the limit", rule: "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: time.Date(2026, 1, 30, 16, 0, 0, 0, time.UTC), limit: time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC),
+			want: "2026-01-30T16:00:00Z 2026-02-27T16:00:00Z 2026-03-27T16:00:00Z 2026-04-24T16:00:00Z 2026-05-29T16:00:00Z",
+		},
+		{
+			name: "the 31st skips short months", rule: "FREQ=MONTHLY;COUNT=4",
+			dtstart: time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC), limit: far,
+			want: "2026-01-31T12:00:00Z 2026-03-31T12:00:00Z 2026-05-31T12:00:00Z 2026-07-31T12:00:00Z",
+		},
+		{
+			name: "last weekday of the month", rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3",
+			dtstart: time.Date(2026, 1, 30, 17, 0, 0, 0, time.UTC), limit: far,
+			want: "2026-01-30T17:00:00Z 2026-02-27T17:00:00Z 2026-03-31T17:00:00Z",
+		},
+		{
+			name: "first and last day of the month", rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=4",
+			dtstart: time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC), limit: far,
+			want: "2026-02-01T09:00:00Z 2026-02-28T09:00:00Z 2026-03-01T09:00:00Z 2026-03-31T09:00:00Z",
+		},
+		{
+			name: "february 29th only in leap years", rule: "FREQ=YEARLY;COUNT=3",
+			dtstart: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), limit: far,
+			want: "2024-02-29T00:00:00Z 2028-02-29T00:00:00Z 2032-02-29T00:00:00Z",
+		},
+		{
+			name: "thanksgiving", rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;COUNT=3",
+			dtstart: time.Date(2026, 11, 26, 15, 0, 0, 0, newYork), limit: far,
+			want: "2026-11-26T15:00:00-05:00 2027-11-25T15:00:00-05:00 2028-11-23T15:00:00-05:00",
+		},
+		{
+			name: "20th monday of the year (RFC 5545)", rule: "FREQ=YEARLY;BYDAY=20MO;COUNT=3",
+			dtstart: time.Date(1997, 5, 19, 9, 0, 0, 0, newYork), limit: far,
+			want: "1997-05-19T09:00:00-04:00 1998-05-18T09:00:00-04:00 1999-05-17T09:00:00-04:00",
+		},
+		{
+			name: "WKST=MO (RFC 5545)", rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart: time.Date(1997, 8, 5, 9, 0, 0, 0, newYork), limit: far,
+			want: "1997-08-05T09:00:00-04:00 1997-08-10T09:00:00-04:00 1997-08-19T09:00:00-04:00 1997-08-24T09:00:00-04:00",
+		},
+		{
+			name: "WKST=SU (RFC 5545)", rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart: time.Date(1997, 8, 5, 9, 0, 0, 0, newYork), limit: far,
+			want: "1997-08-05T09:00:00-04:00 1997-08-17T09:00:00-04:00 1997-08-19T09:00:00-04:00 1997-08-31T09:00:00-04:00",
+		},
+		{
+			name: "date-only UNTIL includes the day", rule: "FREQ=DAILY;UNTIL=20261022",
+			dtstart: time.Date(2026, 10, 20, 18, 0, 0, 0, zagreb), limit: far,
+			want: "2026-10-20T18:00:00+02:00 2026-10-21T18:00:00+02:00 2026-10-22T18:00:00+02:00",
+		},
+		{
+			name: "UTC UNTIL", rule: "FREQ=DAILY;UNTIL=20261022T065959Z",
+			dtstart: time.Date(2026, 10, 20, 9, 0, 0, 0, zagreb), limit: far,
+			want: "2026-10-20T09:00:00+02:00 2026-10-21T09:00:00+02:00",
+		},
+		{
+			name: "DTSTART counts even off the rule", rule: "FREQ=WEEKLY;BYDAY=FR;COUNT=2",
+			dtstart: time.Date(2026, 10, 21, 9, 0, 0, 0, time.UTC), limit: far,
+			want: "2026-10-21T09:00:00Z 2026-10-23T09:00:00Z",
+		},
+		{
+			name: "daily restricted to a month", rule: "FREQ=DAILY;BYMONTH=1;BYDAY=SA,SU;COUNT=3",
+			dtstart: time.Date(2026, 12, 30, 10, 0, 0, 0, time.UTC), limit: far,
+			want: "2026-12-30T10:00:00Z 2027-01-02T10:00:00Z 2027-01-03T10:00:00Z",
+		},
+		{
+			name: "starts after the limit", rule: "FREQ=DAILY",
+			dtstart: time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC), limit: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			want: "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := parseRRule(tc.rule, tc.dtstart.Location())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			starts := rule.starts(tc.dtstart, tc.limit)
+			got := make([]string, len(starts))
+			for i, s := range starts {
+				got[i] = s.Format(time.RFC3339)
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Fatalf("got  %s\nwant %s", strings.Join(got, " "), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261022",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=0MO",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;WKST=XY",
+		"FREQ",
+	} {
+		if _, err := parseRRule(rule, time.UTC); err == nil {
+			t.Fatalf("%q: expected error", rule)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is the subset of JSON Schema (draft 2020-12) needed to describe
+// the CLI's JSON documents.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+}
+
+// schemaDocument names a JSON document the CLI reads or writes and the Go
+// type it is encoded from. Input documents are decoded leniently, so none of
+// their properties are required.
+type schemaDocument struct {
+	Name        string
+	Description string
+	Type        reflect.Type
+	Input       bool
+}
+
+// schemaDocuments lists every published schema. Command outputs are keyed by
+// command path; the eventkit entries are also the EventKit helper protocol,
+// since those commands relay the helper's stdout unchanged.
+var schemaDocuments = []schemaDocument{
+	{Name: "parse", Description: "Output of fantastical parse --json", Type: reflect.TypeOf(parseResult{})},
+	{Name: "show", Description: "Output of fantastical show --json", Type: reflect.TypeOf(showResult{})},
+	{Name: "validate", Description: "Output of fantastical validate --json", Type: reflect.TypeOf(validateResult{})},
+	{Name: "doctor", Description: "Output of fantastical doctor --json", Type: reflect.TypeOf(doctorResult{})},
+	{Name: "config show", Description: "Output of fantastical config show --json", Type: reflect.TypeOf(configShowResult{})},
+	{Name: "cache show", Description: "Output of fantastical cache show --json", Type: reflect.TypeOf(cacheShowResult{})},
+	{Name: "cache clear", Description: "Output of fantastical cache clear --json", Type: reflect.TypeOf(cacheClearResult{})},
+	{Name: "eventkit status", Description: "Output of fantastical eventkit status --json (helper: status --format json)", Type: reflect.TypeOf(eventKitStatus{})},
+	{Name: "eventkit calendars", Description: "Output of fantastical eventkit calendars --json (helper: calendars --format json)", Type: reflect.TypeOf([]calendarInfo{})},
+	{Name: "eventkit events", Description: "Output of fantastical eventkit events --json (helper: events --format json)", Type: reflect.TypeOf([]eventInfo{})},
+	{Name: "eventkit search", Description: "Output of fantastical eventkit search --json (the events shape)", Type: reflect.TypeOf([]eventInfo{})},
+	{Name: "eventkit create", Description: "Output of fantastical eventkit create --json (one event in the events shape)", Type: reflect.TypeOf(eventInfo{})},
+	{Name: "eventkit delete", Description: "Output of fantastical eventkit delete --json", Type: reflect.TypeOf(eventDeleteResult{})},
+	{Name: "eventkit helper info", Description: "Output of fantastical eventkit helper info --json and helper build --json", Type: reflect.TypeOf(eventKitHelperInfo{})},
+	{Name: "eventkit helper clean", Description: "Output of fantastical eventkit helper clean --json", Type: reflect.TypeOf(helperCleanResult{})},
+	{Name: "eventkit helper version", Description: "EventKit helper handshake (helper: version --json), checked before the helper is used", Type: reflect.TypeOf(helperVersion{})},
+	{Name: "config", Description: "Config file; config.toml and config.yaml decode to the same document", Type: reflect.TypeOf(Config{}), Input: true},
+}
+
+func (d schemaDocument) schema() *jsonSchema {
+	s := schemaForType(d.Type)
+	if d.Input {
+		dropRequired(s)
+	}
+	s.Schema = jsonSchemaDialect
+	s.ID = "urn:fantastical:schema:v1:" + strings.ReplaceAll(d.Name, " ", "-")
+	s.Title = d.Name
+	s.Description = d.Description
+	return s
+}
+
+// schemaSpec is the payload of greta --schemas.
+func schemaSpec(schema string) map[string]any {
+	schemas := make(map[string]*jsonSchema, len(schemaDocuments))
+	for _, doc := range schemaDocuments {
+		schemas[doc.Name] = doc.schema()
+	}
+	return map[string]any{
+		"schemaVersion": schema,
+		"schemas":       schemas,
+	}
+}
+
+func schemasMarkdown() string {
+	var b strings.Builder
+	b.WriteString("# fantastical JSON schemas\n")
+	for _, doc := range schemaDocuments {
+		data, err := json.MarshalIndent(doc.schema(), "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(&b, "\n## %s\n%s.\n\n```json\n%s\n```\n", doc.Name, doc.Description, data)
+	}
+	return b.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaForType derives a schema from t the way encoding/json encodes it:
+// json tag names are used, omitempty fields are optional, pointers may be
+// null and time.Time is an RFC 3339 string. An enum:"a,b" tag restricts a
+// string field to the listed values and a desc tag becomes its description.
+// Objects stay open to new properties, so outputs and the config file can
+// grow without breaking validators; see closeObjects.
+func schemaForType(t reflect.Type) *jsonSchema {
+	if t == timeType {
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaForType(t.Elem())
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+		return s
+	case reflect.Struct:
+		s := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			prop := schemaForType(field.Type)
+			if enum := field.Tag.Get("enum"); enum != "" {
+				prop.Enum = strings.Split(enum, ",")
+			}
+			if desc := field.Tag.Get("desc"); desc != "" {
+				prop.Description = desc
+			}
+			s.Properties[name] = prop
+			if !containsOption(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: schemaForType(t.Elem())}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	default:
+		return &jsonSchema{}
+	}
+}
+
+func dropRequired(s *jsonSchema) {
+	s.Required = nil
+	for _, prop := range s.Properties {
+		dropRequired(prop)
+	}
+	if s.Items != nil {
+		dropRequired(s.Items)
+	}
+	if extra, ok := s.AdditionalProperties.(*jsonSchema); ok {
+		dropRequired(extra)
+	}
+}
+
+// closeObjects forbids properties a struct does not declare, for schemas of
+// input that is decoded strictly, such as tool arguments.
+func closeObjects(s *jsonSchema) *jsonSchema {
+	if s.Properties != nil {
+		s.AdditionalProperties = false
+	}
+	for _, prop := range s.Properties {
+		closeObjects(prop)
+	}
+	if s.Items != nil {
+		closeObjects(s.Items)
+	}
+	if extra, ok := s.AdditionalProperties.(*jsonSchema); ok {
+		closeObjects(extra)
+	}
+	return s
+}
+
+func containsOption(opts, want string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testEventsJSON = `[{"id":"ev-1","title":"Standup","calendar":"Work (Exchange)","calendarId":"work-ex",` +
+	`"start":"2026-10-19T09:15:00+02:00","end":"2026-10-19T09:30:00+02:00","allDay":false,"location":"Room 4"},` +
+	`{"id":"ev-2","title":"Birthday","calendar":"Birthdays","calendarId":"bday",` +
+	`"start":"2026-10-20T00:00:00+02:00","end":"2026-10-21T00:00:00+02:00","allDay":true,"notes":"Cake"}]`
+
+// stubProtocolHelper installs a helper that answers status, calendars and
+// events with fixture JSON, as the Swift helper does for --format json.
+func stubProtocolHelper(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"status":    `{"status":"full_access","canPrompt":false}`,
+		"calendars": testCalendarsJSON,
+		"events":    testEventsJSON,
+	}
+	for name, data := range fixtures {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(data), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+	helper := filepath.Join(dir, "helper.sh")
+	script := "#!/bin/sh\ncat '" + dir + "'/\"$1\".json\n"
+	if err := os.WriteFile(helper, withHandshake(t, script), 0o755); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
+}
+
+func publishedSchemas(t *testing.T) map[string]map[string]any {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	if err := cmdGreta(context.Background(), []string{"--schemas"}, &out, &errOut); err != nil {
+		t.Fatalf("greta --schemas: %v", err)
+	}
+	var spec struct {
+		SchemaVersion string                    `json:"schemaVersion"`
+		Schemas       map[string]map[string]any `json:"schemas"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if spec.SchemaVersion != "v1" {
+		t.Fatalf("unexpected schemaVersion %q", spec.SchemaVersion)
+	}
+	return spec.Schemas
+}
+
+func TestCommandOutputsMatchSchemas(t *testing.T) {
+	writeAliasConfig(t, `{
+  "output": {"open": false, "dry_run": true},
+  "parse": {"calendar": "Home", "note": "n"},
+  "show": {"calendar_sets": ["Work Week"]},
+  "applescript": {"run": false},
+  "calendars": {"aliases": {"w": "Work (Exchange)"}, "cache_ttl": "30m"},
+  "aliases": {"wk": "eventkit events --this-week"}
+}`)
+	stubProtocolHelper(t)
+	schemas := publishedSchemas(t)
+
+	runs := []struct {
+		schema string
+		args   []string
+	}{
+		{"parse", []string{"parse", "--json", "Dinner at 7"}},
+		{"show", []string{"show", "--json", "month", "2026-01-03"}},
+		{"validate", []string{"validate", "--json", "parse", "Dinner at 7"}},
+		{"validate", []string{"validate", "--json", "show", "nope"}},
+		{"doctor", []string{"doctor", "--json", "--skip-app"}},
+		{"config show", []string{"config", "show", "--json"}},
+		{"cache show", []string{"cache", "show", "--json"}},
+		{"eventkit status", []string{"eventkit", "status", "--json"}},
+		{"eventkit calendars", []string{"eventkit", "calendars", "--json"}},
+		{"cache show", []string{"cache", "show", "--json"}},
+		{"eventkit events", []string{"eventkit", "events", "--json", "--today"}},
+		{"eventkit search", []string{"eventkit", "search", "--json", "standup"}},
+		{"cache clear", []string{"cache", "clear", "--json"}},
+		{"eventkit helper info", []string{"eventkit", "helper", "info", "--json"}},
+		{"eventkit helper clean", []string{"eventkit", "helper", "clean", "--json"}},
+	}
+	for _, r := range runs {
+		var out, errOut bytes.Buffer
+		if code := run(append([]string{"fantastical"}, r.args...), strings.NewReader(""), &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d (%s)", r.args, code, errOut.String())
+		}
+		schema, ok := schemas[r.schema]
+		if !ok {
+			t.Fatalf("no schema published for %q", r.schema)
+		}
+		var doc any
+		if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("%v: invalid json %q: %v", r.args, out.String(), err)
+		}
+		if errs := validateAgainstSchema("$", schema, doc); len(errs) > 0 {
+			t.Fatalf("%v does not match schema %q:\n%s\noutput: %s", r.args, r.schema, strings.Join(errs, "\n"), out.String())
+		}
+	}
+}
+
+func TestConfigSchemaAcceptsConfigFiles(t *testing.T) {
+	schema := publishedSchemas(t)["config"]
+	valid := `{"output":{"open":false,"json":null},"parse":{"calendar":"Work"},"calendars":{"aliases":{"w":"Work"}}}`
+	var doc any
+	if err := json.Unmarshal([]byte(valid), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if errs := validateAgainstSchema("$", schema, doc); len(errs) > 0 {
+		t.Fatalf("expected config to validate: %v", errs)
+	}
+
+	invalid := `{"output":{"open":"yes"},"parse":{"calender":"Work"}}`
+	doc = nil
+	if err := json.Unmarshal([]byte(invalid), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	// Unknown keys are ignored when the config is loaded, so a config
+	// written for a newer version still validates.
+	errs := validateAgainstSchema("$", schema, doc)
+	sort.Strings(errs)
+	want := []string{
+		"$.output.open: expected boolean,null, got string",
+	}
+	if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(errs, "\n"))
+	}
+}
+
+func TestSchemaForType(t *testing.T) {
+	type sample struct {
+		Name    string            `json:"name" enum:"a,b"`
+		Count   int               `json:"count,omitempty"`
+		When    *time.Time        `json:"when"`
+		Labels  map[string]string `json:"labels,omitempty"`
+		Skipped string            `json:"-"`
+		hidden  string
+	}
+
+	data, err := json.Marshal(schemaForType(reflect.TypeOf(sample{})))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"type":"object","properties":{"count":{"type":"integer"},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string","enum":["a","b"]},` +
+		`"when":{"type":["string","null"],"format":"date-time"}},` +
+		`"required":["name","when"]}`
+	if string(data) != want {
+		t.Fatalf("unexpected schema:\n%s", data)
+	}
+
+	closed, _ := json.Marshal(closeObjects(schemaForType(reflect.TypeOf(sample{}))))
+	if !strings.HasSuffix(string(closed), `"required":["name","when"],"additionalProperties":false}`) || !strings.Contains(string(closed), `"additionalProperties":{"type":"string"}`) {
+		t.Fatalf("expected the struct closed and the map left alone:\n%s", closed)
+	}
+}
+
+// validateAgainstSchema checks doc against the keywords schemaForType emits
+// and returns one message per violation.
+func validateAgainstSchema(path string, schema map[string]any, doc any) []string {
+	var errs []string
+	if types, ok := schemaTypes(schema["type"]); ok && !matchesAnyType(types, doc) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, ","), jsonTypeName(doc))}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, v := range enum {
+			if v == doc {
+				found = true
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v not in enum %v", path, doc, enum))
+		}
+	}
+	if schema["format"] == "date-time" {
+		if s, ok := doc.(string); ok {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %q is not a date-time", path, s))
+			}
+		}
+	}
+	switch v := doc.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+				}
+			}
+		}
+		for key, value := range v {
+			child := path + "." + key
+			if prop, ok := props[key].(map[string]any); ok {
+				errs = append(errs, validateAgainstSchema(child, prop, value)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, child+": unexpected property")
+				}
+			case map[string]any:
+				errs = append(errs, validateAgainstSchema(child, extra, value)...)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, validateAgainstSchema(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+			}
+		}
+	}
+	return errs
+}
+
+func schemaTypes(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []any:
+		var types []string
+		for _, t := range v {
+			types = append(types, t.(string))
+		}
+		return types, true
+	}
+	return nil, false
+}
+
+func matchesAnyType(types []string, doc any) bool {
+	for _, t := range types {
+		if t == jsonTypeName(doc) {
+			return true
+		}
+		if t == "number" && jsonTypeName(doc) == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(doc any) string {
+	switch v := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
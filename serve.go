@@ -0,0 +1,418 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultServeAddr = "127.0.0.1:7419"
+	maxRequestBody   = 1 << 20
+	shutdownTimeout  = 10 * time.Second
+	// requestTimeout bounds one API request, including a first-use helper
+	// build.
+	requestTimeout = 2 * time.Minute
+)
+
+// apiEndpoint wraps one tool as an HTTP endpoint. Endpoints that open
+// Fantastical only accept POST; read-only ones also take query parameters.
+type apiEndpoint struct {
+	Path    string
+	Methods []string
+	Args    reflect.Type
+	Call    func(r *toolRunner, ctx context.Context, raw json.RawMessage) (string, error)
+}
+
+var apiEndpoints = []apiEndpoint{
+	{Path: "/parse", Methods: []string{http.MethodPost}, Args: reflect.TypeOf(createEventArgs{}), Call: (*toolRunner).createEvent},
+	{Path: "/show", Methods: []string{http.MethodPost}, Args: reflect.TypeOf(showViewArgs{}), Call: (*toolRunner).showView},
+	{Path: "/events", Methods: []string{http.MethodGet, http.MethodPost}, Args: reflect.TypeOf(listEventsArgs{}), Call: (*toolRunner).listEvents},
+	{Path: "/calendars", Methods: []string{http.MethodGet}, Args: reflect.TypeOf(listCalendarsArgs{}), Call: (*toolRunner).listCalendars},
+	{Path: "/free", Methods: []string{http.MethodGet, http.MethodPost}, Args: reflect.TypeOf(findFreeTimeArgs{}), Call: (*toolRunner).findFreeTime},
+}
+
+// serveStatus is the response of GET /status. EventKit is the helper's
+// status output, or null with EventKitError set when the helper failed.
+type serveStatus struct {
+	OK            bool            `json:"ok"`
+	Version       string          `json:"version"`
+	UptimeSeconds int             `json:"uptime_seconds"`
+	DryRun        bool            `json:"dry_run"`
+	EventKit      json.RawMessage `json:"eventkit"`
+	EventKitError string          `json:"eventkit_error,omitempty"`
+}
+
+type serveOptions struct {
+	addr  string
+	token string
+	tools toolRunner
+}
+
+func serveUsage(w io.Writer) {
+	fmt.Fprint(w, "USAGE:\n  fantastical serve [--addr 127.0.0.1:7419] [--token <token>] [--dry-run] [--config <path>] [--verbose]\n")
+	printFlagHelp(w, "serve")
+	fmt.Fprintln(w, "\nENDPOINTS:\n  POST /parse  POST /show  GET|POST /events  GET /calendars  GET|POST /free  GET /status")
+	fmt.Fprintln(w, "\nEXAMPLE:\n  curl -H \"Authorization: Bearer $TOKEN\" 'http://127.0.0.1:7419/events?days=7'")
+}
+
+func cmdServe(ctx context.Context, args []string, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	opts := serveOptions{tools: toolRunner{errOut: errOut, now: time.Now}}
+	bindFlags(fs, "serve", map[string]any{
+		"addr":    &opts.addr,
+		"token":   &opts.token,
+		"dry-run": &opts.tools.dryRun,
+		"config":  &opts.tools.config,
+		"verbose": &opts.tools.verbose,
+	})
+
+	fs.Usage = func() {
+		serveUsage(errOut)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.Usage()
+			return nil
+		}
+		fs.Usage()
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	cfg, err := loadConfigWithPath(opts.tools.config)
+	if err != nil {
+		return err
+	}
+	if opts.addr == "" {
+		opts.addr = cfg.Serve.Addr
+	}
+	if opts.addr == "" {
+		opts.addr = defaultServeAddr
+	}
+	if opts.token == "" {
+		opts.token = cfg.Serve.Token
+	}
+	if err := checkLoopbackAddr(opts.addr); err != nil {
+		return err
+	}
+	generated := opts.token == ""
+	if generated {
+		if opts.token, err = randomToken(); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(out, "listening on http://%s\n", ln.Addr())
+	if generated {
+		fmt.Fprintf(out, "token: %s\n", opts.token)
+		fmt.Fprintln(errOut, "[fantastical] serve: no token configured (serve.token or FANTASTICAL_SERVE_TOKEN); generated one for this run")
+	}
+	// Requests run concurrently and share stderr for logs.
+	logOut := &lockedWriter{w: errOut}
+	opts.tools.errOut = logOut
+	return runHTTPServer(ctx, ln, newAPIServer(opts.tools, opts.token, logOut), logOut)
+}
+
+// checkLoopbackAddr rejects listen addresses reachable from other machines.
+func checkLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%w: invalid --addr %q: %v", errUsage, addr, err)
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("%w: --addr %q is not a loopback address (use 127.0.0.1, ::1 or localhost)", errUsage, addr)
+	}
+	return nil
+}
+
+// randomToken returns a bearer token for a serve run without a configured
+// one, so the API is never left open to every local process.
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate serve token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// isLoopbackOrigin reports whether a browser Origin header names a page
+// served from this machine.
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return isLoopbackHost(u.Hostname())
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runHTTPServer serves h on ln until ctx is cancelled, then waits for
+// in-flight requests to finish.
+func runHTTPServer(ctx context.Context, ln net.Listener, h http.Handler, errOut io.Writer) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(errOut, "[fantastical] serve: shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// apiServer routes requests to the shared tool implementations. Requests run
+// concurrently: helper builds are serialized by a file lock and cache and
+// fixture writes are atomic, so the server itself holds no lock. Each request
+// runs under its own context, which ends after timeout or when the client
+// goes away, stopping any helper it started.
+type apiServer struct {
+	tools   toolRunner
+	token   string
+	logOut  io.Writer
+	started time.Time
+	timeout time.Duration
+}
+
+func newAPIServer(tools toolRunner, token string, logOut io.Writer) *apiServer {
+	// Concurrent requests all write their access log lines to logOut.
+	if _, ok := logOut.(*lockedWriter); !ok {
+		logOut = &lockedWriter{w: logOut}
+	}
+	return &apiServer{tools: tools, token: token, logOut: logOut, started: time.Now(), timeout: requestTimeout}
+}
+
+// requestContext is the context a request's tool run uses.
+func (s *apiServer) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeoutCause(r.Context(), s.timeout, fmt.Errorf("%w after %s (server request timeout)", errTimeout, s.timeout))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.route(rec, r)
+	fmt.Fprintf(s.logOut, "[fantastical] %s %s %d %s\n", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+}
+
+func (s *apiServer) route(w http.ResponseWriter, r *http.Request) {
+	// A loopback Host header keeps DNS-rebound browser pages out.
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	if !isLoopbackHost(host) {
+		writeAPIError(w, http.StatusForbidden, errors.New("forbidden host"))
+		return
+	}
+	// Browsers send Origin on cross-site requests; only local pages may call.
+	if origin := r.Header.Get("Origin"); origin != "" && !isLoopbackOrigin(origin) {
+		writeAPIError(w, http.StatusForbidden, errors.New("forbidden origin"))
+		return
+	}
+	if s.token != "" {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+	}
+
+	if r.URL.Path == "/status" {
+		if !allowMethod(w, r, []string{http.MethodGet}) {
+			return
+		}
+		s.status(w, r)
+		return
+	}
+	for _, ep := range apiEndpoints {
+		if ep.Path != r.URL.Path {
+			continue
+		}
+		if !allowMethod(w, r, ep.Methods) {
+			return
+		}
+		s.call(w, r, ep)
+		return
+	}
+	writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %s", r.URL.Path))
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods []string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func (s *apiServer) call(w http.ResponseWriter, r *http.Request, ep apiEndpoint) {
+	var raw json.RawMessage
+	if r.Method == http.MethodPost {
+		// A JSON content type cannot be sent by a plain HTML form, and
+		// forces a CORS preflight for scripted cross-site requests.
+		if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+			writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("Content-Type must be application/json"))
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		raw = body
+	} else {
+		args, err := queryArgs(r.URL.Query(), ep.Args)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		raw = args
+	}
+
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+	text, err := ep.Call(&s.tools, ctx, raw)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errUsage):
+			status = http.StatusBadRequest
+		case errors.Is(err, errTimeout):
+			status = http.StatusGatewayTimeout
+		}
+		writeAPIError(w, status, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintln(w, text)
+}
+
+func (s *apiServer) status(w http.ResponseWriter, r *http.Request) {
+	payload := serveStatus{
+		OK:            true,
+		Version:       versionString(),
+		UptimeSeconds: int(time.Since(s.started).Seconds()),
+		DryRun:        s.tools.dryRun,
+		EventKit:      json.RawMessage("null"),
+	}
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+	text, err := s.tools.status(ctx)
+	if err != nil {
+		payload.EventKitError = err.Error()
+	} else if json.Valid([]byte(text)) {
+		payload.EventKit = json.RawMessage(text)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, payload)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, map[string]string{"error": err.Error()})
+}
+
+// queryArgs converts query parameters into a JSON object for a tool's
+// argument struct, typing each value from the struct's schema. Repeated
+// parameters fill array fields.
+func queryArgs(q url.Values, t reflect.Type) (json.RawMessage, error) {
+	schema := schemaForType(t)
+	obj := map[string]any{}
+	for key, values := range q {
+		prop, ok := schema.Properties[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown parameter %q", errUsage, key)
+		}
+		typ, _ := prop.Type.(string)
+		if types, ok := prop.Type.([]string); ok {
+			typ = types[0]
+		}
+		value := values[len(values)-1]
+		switch typ {
+		case "array":
+			obj[key] = values
+		case "integer":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter %s must be an integer", errUsage, key)
+			}
+			obj[key] = n
+		case "boolean":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter %s must be true or false", errUsage, key)
+			}
+			obj[key] = b
+		default:
+			obj[key] = value
+		}
+	}
+	return json.Marshal(obj)
+}
@@ -0,0 +1,326 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newTestAPIServer(t *testing.T, token string) (*apiServer, *bytes.Buffer) {
+	t.Helper()
+	var logs bytes.Buffer
+	return newAPIServer(toolRunner{errOut: io.Discard, now: time.Now}, token, &logs), &logs
+}
+
+// lockedBuffer is a bytes.Buffer safe to read while a server writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func apiRequest(s http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:7419"
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeEndpoints(t *testing.T) {
+	writeAliasConfig(t, `{"parse": {"calendar": "home"}}`)
+	stubProtocolHelper(t)
+	opened := stubOpener(t)
+	s, logs := newTestAPIServer(t, "")
+
+	rec := apiRequest(s, http.MethodPost, "/parse", `{"sentence":"Dinner at 7","add":true}`, nil)
+	var parsed parseResult
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &parsed) != nil {
+		t.Fatalf("/parse: %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(parsed.URL, "calendarName=Home") || !strings.Contains(parsed.URL, "add=1") {
+		t.Fatalf("unexpected parse URL: %s", parsed.URL)
+	}
+	if urls := openedURLs(t, opened); len(urls) != 1 || urls[0] != parsed.URL {
+		t.Fatalf("expected parse URL opened, got %v", urls)
+	}
+
+	rec = apiRequest(s, http.MethodPost, "/show", `{"view":"week","date":"2026-01-05","dry_run":true}`, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"url":"x-fantastical3://show/week/2026-01-05"`) {
+		t.Fatalf("/show: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = apiRequest(s, http.MethodGet, "/calendars", "", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != testCalendarsJSON {
+		t.Fatalf("/calendars: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = apiRequest(s, http.MethodGet, "/events?from=2026-10-19&to=2026-10-20&calendars=home&calendars=bdays&include_all_day=false", "", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != testEventsJSON {
+		t.Fatalf("/events: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = apiRequest(s, http.MethodGet, "/events?from=2026-10-19&to=2026-10-20&where=calendar+in+(Birthdays,+Home)+or+duration+%3C+20m&sort=-start", "", nil)
+	var filtered []eventInfo
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &filtered) != nil || len(filtered) != 2 || filtered[0].ID != "ev-2" {
+		t.Fatalf("/events with where/sort: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = apiRequest(s, http.MethodGet, "/free?from=2026-10-19T09:00&to=2026-10-19T12:00&timezone=Europe/Zagreb&duration_minutes=60", "", nil)
+	var free freeTimeResult
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &free) != nil || len(free.Slots) != 1 || free.Slots[0].Minutes != 150 {
+		t.Fatalf("/free: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = apiRequest(s, http.MethodGet, "/status", "", nil)
+	var status serveStatus
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &status) != nil || !status.OK || string(status.EventKit) != `{"status":"full_access","canPrompt":false}` {
+		t.Fatalf("/status: %d %s", rec.Code, rec.Body.String())
+	}
+
+	errorCases := []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodGet, "/parse", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/parse", `{"sentence":""}`, http.StatusBadRequest},
+		{http.MethodPost, "/parse", `{"sentense":"typo"}`, http.StatusBadRequest},
+		{http.MethodPost, "/show", `{"view":"--dry-run=false"}`, http.StatusBadRequest},
+		{http.MethodPost, "/show", `{"view":"week","date":"--dry-run=false","dry_run":true}`, http.StatusBadRequest},
+		{http.MethodGet, "/events?limit=many", "", http.StatusBadRequest},
+		{http.MethodGet, "/events?color=red", "", http.StatusBadRequest},
+		{http.MethodGet, "/nope", "", http.StatusNotFound},
+	}
+	for _, tc := range errorCases {
+		rec := apiRequest(s, tc.method, tc.target, tc.body, nil)
+		var payload map[string]string
+		if rec.Code != tc.code || json.Unmarshal(rec.Body.Bytes(), &payload) != nil || payload["error"] == "" {
+			t.Fatalf("%s %s: got %d %s, want %d", tc.method, tc.target, rec.Code, rec.Body.String(), tc.code)
+		}
+	}
+
+	for _, want := range []string{"[fantastical] POST /parse 200 ", "[fantastical] GET /events 200 ", "[fantastical] GET /nope 404 "} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("expected %q in request log:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestServeAuthAndHost(t *testing.T) {
+	setupTestEnv(t)
+	stubProtocolHelper(t)
+	s, _ := newTestAPIServer(t, "s3cret")
+
+	if rec := apiRequest(s, http.MethodGet, "/calendars", "", nil); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := apiRequest(s, http.MethodGet, "/calendars", "", map[string]string{"Authorization": "Bearer nope"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", rec.Code)
+	}
+	if rec := apiRequest(s, http.MethodGet, "/calendars", "", map[string]string{"Authorization": "Bearer s3cret"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Host = "evil.example:7419"
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-loopback host, got %d", rec.Code)
+	}
+}
+
+func TestServeRejectsCrossSiteRequests(t *testing.T) {
+	setupTestEnv(t)
+	stubProtocolHelper(t)
+	s, _ := newTestAPIServer(t, "")
+	body := `{"view":"week","dry_run":true}`
+
+	cases := []struct {
+		header map[string]string
+		code   int
+	}{
+		{map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{map[string]string{"Content-Type": ""}, http.StatusUnsupportedMediaType},
+		{map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{map[string]string{"Origin": "http://localhost:3000", "Content-Type": "application/json; charset=utf-8"}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if rec := apiRequest(s, http.MethodPost, "/show", body, tc.header); rec.Code != tc.code {
+			t.Fatalf("%v: got %d %s, want %d", tc.header, rec.Code, rec.Body.String(), tc.code)
+		}
+	}
+	if rec := apiRequest(s, http.MethodGet, "/calendars", "", map[string]string{"Origin": "https://evil.example"}); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a cross-origin GET rejected, got %d", rec.Code)
+	}
+}
+
+func TestServeRequestsRunConcurrently(t *testing.T) {
+	setupTestEnv(t)
+	// calendars hangs until signalled; status answers at once.
+	marker := filepath.Join(t.TempDir(), "marker")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = status ]; then echo '{\"status\":\"full_access\",\"canPrompt\":false}'; exit 0; fi\n" +
+		"trap 'echo TERM > " + marker + "; kill $!; exit 143' TERM\n" +
+		"echo started > " + marker + "\n" +
+		"sleep 30 &\nwait\n"
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", writeHelper(t, withHandshake(t, script)))
+	s, _ := newTestAPIServer(t, "")
+	s.timeout = 500 * time.Millisecond
+	waitStarted := func() {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); readMarker(marker) != "started"; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("helper did not start")
+			}
+		}
+	}
+
+	start := time.Now()
+	slow := make(chan *httptest.ResponseRecorder, 1)
+	go func() { slow <- apiRequest(s, http.MethodGet, "/calendars", "", nil) }()
+	waitStarted()
+	if rec := apiRequest(s, http.MethodGet, "/status", "", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "full_access") {
+		t.Fatalf("/status blocked or failed behind /calendars: %d %s", rec.Code, rec.Body.String())
+	}
+	rec := <-slow
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), "server request timeout") || time.Since(start) > 5*time.Second {
+		t.Fatalf("expected /calendars to time out, got %d %s after %s", rec.Code, rec.Body.String(), time.Since(start))
+	}
+	if got := readMarker(marker); got != "TERM" {
+		t.Fatalf("expected the helper stopped, got %q", got)
+	}
+
+	// A client that goes away stops its helper too.
+	os.Remove(marker)
+	s.timeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/calendars", nil).WithContext(ctx)
+	req.Host = "127.0.0.1:7419"
+	done := make(chan struct{})
+	go func() {
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	waitStarted()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request outlived its client")
+	}
+	if got := readMarker(marker); got != "TERM" {
+		t.Fatalf("expected the helper stopped, got %q", got)
+	}
+}
+
+func TestServeGeneratesToken(t *testing.T) {
+	setupTestEnv(t)
+	out := &lockedBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- cmdServe(context.Background(), []string{"--addr", "127.0.0.1:0"}, out, io.Discard) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "token: ") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// serve stops on SIGINT, which it catches instead of exiting.
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	_, token, ok := strings.Cut(out.String(), "token: ")
+	if token = strings.TrimSpace(token); !ok || len(token) != 48 {
+		t.Fatalf("expected a generated token, got:\n%s", out.String())
+	}
+}
+
+func TestServeRejectsNonLoopbackAddr(t *testing.T) {
+	setupTestEnv(t)
+	for _, addr := range []string{"0.0.0.0:7419", ":7419", "192.168.1.2:80", "nope"} {
+		err := cmdServe(context.Background(), []string{"--addr", addr}, io.Discard, io.Discard)
+		if !errors.Is(err, errUsage) {
+			t.Fatalf("%s: expected usage error, got %v", addr, err)
+		}
+	}
+}
+
+func TestRunHTTPServerGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var logs bytes.Buffer
+	result := make(chan error, 1)
+	go func() {
+		result <- runHTTPServer(ctx, ln, handler, &logs)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	// Give the request time to reach the handler, then shut down while it
+	// is still in flight.
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request not completed: %q", got)
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("runHTTPServer: %v", err)
+	}
+	if !strings.Contains(logs.String(), "shutting down") {
+		t.Fatalf("expected shutdown log, got %q", logs.String())
+	}
+}
@@ -0,0 +1,297 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// toolRunner maps structured tool arguments onto the regular command code
+// paths, so config, calendar resolution and dry-run behave as on the command
+// line. It backs both the MCP server and the HTTP API.
+type toolRunner struct {
+	config  string
+	dryRun  bool
+	verbose bool
+	errOut  io.Writer
+	now     func() time.Time
+}
+
+type createEventArgs struct {
+	Sentence string `json:"sentence" desc:"Natural-language event, e.g. \"Lunch with Ana tomorrow at noon\""`
+	Calendar string `json:"calendar,omitempty" desc:"Calendar name, alias or ID"`
+	Note     string `json:"note,omitempty" desc:"Event note"`
+	Add      bool   `json:"add,omitempty" desc:"Add immediately instead of opening Fantastical's editor"`
+	Timezone string `json:"timezone,omitempty" desc:"IANA timezone passed to Fantastical"`
+	DryRun   bool   `json:"dry_run,omitempty" desc:"Only build the URL; do not open Fantastical"`
+}
+
+type showViewArgs struct {
+	View        string `json:"view,omitempty" enum:"mini,calendar,day,week,month,agenda" desc:"View to open"`
+	Date        string `json:"date,omitempty" desc:"YYYY-MM-DD, today, tomorrow or yesterday"`
+	CalendarSet string `json:"calendar_set,omitempty" desc:"Calendar set to show instead of a view"`
+	Timezone    string `json:"timezone,omitempty" desc:"IANA timezone passed to Fantastical"`
+	DryRun      bool   `json:"dry_run,omitempty" desc:"Only build the URL; do not open Fantastical"`
+}
+
+type listCalendarsArgs struct{}
+
+type listEventsArgs struct {
+	From          string   `json:"from,omitempty" desc:"Start date expression (YYYY-MM-DD, RFC 3339, +3d, next monday, ...); defaults to today"`
+	To            string   `json:"to,omitempty" desc:"End date expression (YYYY-MM-DD, RFC 3339, +1w, end of month, ...)"`
+	Days          int      `json:"days,omitempty" desc:"Days from now, instead of from/to"`
+	Calendars     []string `json:"calendars,omitempty" desc:"Calendar names, aliases or IDs"`
+	Query         string   `json:"query,omitempty" desc:"Case-insensitive filter on title, location and notes"`
+	Where         string   `json:"where,omitempty" desc:"Filter expression, e.g. calendar in (Work, Team) and duration >= 30m and not allDay"`
+	Sort          string   `json:"sort,omitempty" desc:"Comma-separated sort keys (start, end, title, calendar, location, duration); prefix - for descending"`
+	Offset        int      `json:"offset,omitempty" desc:"Skip the first N matching events"`
+	Limit         int      `json:"limit,omitempty" desc:"Maximum number of events"`
+	IncludeAllDay *bool    `json:"include_all_day,omitempty" desc:"Include all-day events (default true)"`
+	Timezone      string   `json:"timezone,omitempty" desc:"IANA timezone for event times"`
+}
+
+type findFreeTimeArgs struct {
+	From            string   `json:"from,omitempty" desc:"Start date expression (YYYY-MM-DD, RFC 3339, tomorrow 9am, ...); defaults to now"`
+	To              string   `json:"to,omitempty" desc:"End date expression; defaults to the end of the start day"`
+	DurationMinutes int      `json:"duration_minutes,omitempty" desc:"Minimum slot length in minutes (default 30)"`
+	DayStart        string   `json:"day_start,omitempty" desc:"Earliest time of day to consider (HH:MM)"`
+	DayEnd          string   `json:"day_end,omitempty" desc:"Latest time of day to consider (HH:MM)"`
+	Calendars       []string `json:"calendars,omitempty" desc:"Calendars whose events count as busy (default all)"`
+	IncludeAllDay   bool     `json:"include_all_day,omitempty" desc:"Treat all-day events as busy"`
+	Timezone        string   `json:"timezone,omitempty" desc:"IANA timezone for from/to, working hours and results"`
+}
+
+// decodeToolArgs decodes tool arguments strictly so typos surface as errors.
+func decodeToolArgs(raw json.RawMessage, v any) error {
+	if len(bytes.TrimSpace(raw)) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid arguments: %v", errUsage, err)
+	}
+	return checkEnumArgs(v)
+}
+
+// checkEnumArgs rejects string fields outside their enum:"a,b" tag, which
+// the schema advertises but JSON decoding does not enforce.
+func checkEnumArgs(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		enum := field.Tag.Get("enum")
+		value := rv.Field(i)
+		if enum == "" || value.Kind() != reflect.String || value.String() == "" {
+			continue
+		}
+		allowed := strings.Split(enum, ",")
+		if !slices.Contains(allowed, value.String()) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			return fmt.Errorf("%w: %s must be one of %s, got %q", errUsage, name, strings.Join(allowed, ", "), value.String())
+		}
+	}
+	return nil
+}
+
+// jsonArgs starts the argument list for commands that accept --config.
+func (r *toolRunner) jsonArgs() []string {
+	args := []string{"--json"}
+	if r.config != "" {
+		args = append(args, "--config", r.config)
+	}
+	return args
+}
+
+// run invokes a command with args and returns its trimmed stdout. The
+// command's stderr is forwarded only in verbose mode.
+func (r *toolRunner) run(ctx context.Context, cmd func(context.Context, []string, io.Writer, io.Writer) error, args []string) (string, error) {
+	logVerbose(r.errOut, r.verbose, "run: %s", strings.Join(args, " "))
+	var out, errOut bytes.Buffer
+	err := cmd(ctx, args, &out, &errOut)
+	if r.verbose {
+		r.errOut.Write(errOut.Bytes())
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func (r *toolRunner) createEvent(ctx context.Context, raw json.RawMessage) (string, error) {
+	var a createEventArgs
+	if err := decodeToolArgs(raw, &a); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(a.Sentence) == "" {
+		return "", fmt.Errorf("%w: sentence is required", errUsage)
+	}
+	args := r.jsonArgs()
+	if a.Calendar != "" {
+		args = append(args, "--calendar", a.Calendar)
+	}
+	if a.Note != "" {
+		args = append(args, "--note", a.Note)
+	}
+	if a.Add {
+		args = append(args, "--add")
+	}
+	if a.Timezone != "" {
+		args = append(args, "--timezone", a.Timezone)
+	}
+	if a.DryRun || r.dryRun {
+		args = append(args, "--dry-run")
+	}
+	args = append(args, "--", a.Sentence)
+	return r.run(ctx, func(ctx context.Context, args []string, out, errOut io.Writer) error {
+		return cmdParse(ctx, args, strings.NewReader(""), out, errOut)
+	}, args)
+}
+
+func (r *toolRunner) showView(ctx context.Context, raw json.RawMessage) (string, error) {
+	var a showViewArgs
+	if err := decodeToolArgs(raw, &a); err != nil {
+		return "", err
+	}
+	args := r.jsonArgs()
+	if a.Timezone != "" {
+		args = append(args, "--timezone", a.Timezone)
+	}
+	if a.DryRun || r.dryRun {
+		args = append(args, "--dry-run")
+	}
+	switch {
+	case a.CalendarSet != "":
+		if a.View != "" || a.Date != "" {
+			return "", fmt.Errorf("%w: calendar_set cannot be combined with view or date", errUsage)
+		}
+		args = append(args, "--calendar-set", a.CalendarSet)
+	case a.View != "":
+		args = append(args, "--", a.View)
+		if a.Date != "" {
+			args = append(args, a.Date)
+		}
+	default:
+		return "", fmt.Errorf("%w: view or calendar_set is required", errUsage)
+	}
+	return r.run(ctx, cmdShow, args)
+}
+
+func (r *toolRunner) listCalendars(ctx context.Context, raw json.RawMessage) (string, error) {
+	var a listCalendarsArgs
+	if err := decodeToolArgs(raw, &a); err != nil {
+		return "", err
+	}
+	return r.run(ctx, cmdEventKitCalendars, r.jsonArgs())
+}
+
+// status reports EventKit authorization without prompting.
+func (r *toolRunner) status(ctx context.Context) (string, error) {
+	return r.run(ctx, cmdEventKitStatus, []string{"--json"})
+}
+
+func (r *toolRunner) listEvents(ctx context.Context, raw json.RawMessage) (string, error) {
+	var a listEventsArgs
+	if err := decodeToolArgs(raw, &a); err != nil {
+		return "", err
+	}
+	args := r.jsonArgs()
+	if a.From != "" {
+		args = append(args, "--from", a.From)
+	}
+	if a.To != "" {
+		args = append(args, "--to", a.To)
+	}
+	if a.Days > 0 {
+		args = append(args, "--days", strconv.Itoa(a.Days))
+	}
+	args = appendCalendarArgs(args, a.Calendars)
+	if a.Query != "" {
+		args = append(args, "--query", a.Query)
+	}
+	if a.Where != "" {
+		args = append(args, "--where", a.Where)
+	}
+	if a.Sort != "" {
+		args = append(args, "--sort", a.Sort)
+	}
+	if a.Offset > 0 {
+		args = append(args, "--offset", strconv.Itoa(a.Offset))
+	}
+	if a.Limit > 0 {
+		args = append(args, "--limit", strconv.Itoa(a.Limit))
+	}
+	if a.IncludeAllDay != nil {
+		args = append(args, "--include-all-day="+strconv.FormatBool(*a.IncludeAllDay))
+	}
+	if a.Timezone != "" {
+		args = append(args, "--tz", a.Timezone)
+	}
+	return r.run(ctx, cmdEventKitEvents, args)
+}
+
+func (r *toolRunner) findFreeTime(ctx context.Context, raw json.RawMessage) (string, error) {
+	var a findFreeTimeArgs
+	if err := decodeToolArgs(raw, &a); err != nil {
+		return "", err
+	}
+	loc := time.Local
+	if a.Timezone != "" {
+		l, err := time.LoadLocation(a.Timezone)
+		if err != nil {
+			return "", fmt.Errorf("%w: unknown timezone %q", errUsage, a.Timezone)
+		}
+		loc = l
+	}
+	from, to, err := resolveRange(a.From, a.To, r.now(), loc)
+	if err != nil {
+		return "", err
+	}
+	hours, err := parseWorkingHours(a.DayStart, a.DayEnd)
+	if err != nil {
+		return "", err
+	}
+	duration := a.DurationMinutes
+	if duration <= 0 {
+		duration = 30
+	}
+
+	args := append(r.jsonArgs(), "--from", from.Format(time.RFC3339), "--to", to.Format(time.RFC3339))
+	args = appendCalendarArgs(args, a.Calendars)
+	text, err := r.run(ctx, cmdEventKitEvents, args)
+	if err != nil {
+		return "", err
+	}
+	var events []eventInfo
+	if err := json.Unmarshal([]byte(text), &events); err != nil {
+		return "", fmt.Errorf("invalid events output: %w", err)
+	}
+
+	slots := freeSlots(events, from, to, time.Duration(duration)*time.Minute, hours, a.IncludeAllDay)
+	for i := range slots {
+		slots[i].Start = slots[i].Start.In(loc)
+		slots[i].End = slots[i].End.In(loc)
+	}
+	data, err := json.Marshal(freeTimeResult{From: from, To: to, DurationMinutes: duration, Slots: slots})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func appendCalendarArgs(args []string, calendars []string) []string {
+	for _, c := range calendars {
+		args = append(args, "--calendar", c)
+	}
+	return args
+}
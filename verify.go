@@ -0,0 +1,138 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	defaultVerifyTimeout = 20 * time.Second
+	verifyPollInterval   = time.Second
+	// verifyFallbackDays bounds the snapshot when the event's date cannot be
+	// predicted.
+	verifyFallbackDays = 7
+)
+
+// verifyOutcome reports the events that appeared after parse opened its URL.
+type verifyOutcome struct {
+	Status    string      `json:"status" enum:"created,timeout"`
+	From      time.Time   `json:"from"`
+	To        time.Time   `json:"to"`
+	Events    []eventInfo `json:"events"`
+	Polls     int         `json:"polls"`
+	ElapsedMs int64       `json:"elapsed_ms"`
+}
+
+func (o verifyOutcome) err() error {
+	if o.Status == "created" {
+		return nil
+	}
+	return fmt.Errorf("no new event appeared in %s between %s and %s", time.Duration(o.ElapsedMs)*time.Millisecond, o.From.Format(time.RFC3339), o.To.Format(time.RFC3339))
+}
+
+// eventSnapshot remembers which event ids existed in a range before parse
+// opened its URL, so events that show up afterwards can be told apart.
+type eventSnapshot struct {
+	from, to time.Time
+	calendar string
+	seen     map[string]bool
+	errOut   io.Writer
+	verbose  bool
+}
+
+// verifyWindow returns the range to snapshot for a parse URL opened at now:
+// the predicted event's days with one day either side (for timezone slack),
+// or from the start of yesterday through verifyFallbackDays ahead when no
+// start can be predicted.
+func verifyWindow(u string, now time.Time) (time.Time, time.Time) {
+	startOfDay := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	p, err := predictFromParseURL(u, now)
+	if err != nil || p.Start == nil {
+		day := startOfDay(now)
+		return day.AddDate(0, 0, -1), day.AddDate(0, 0, verifyFallbackDays)
+	}
+	end := *p.Start
+	if p.End != nil && p.End.After(end) {
+		end = *p.End
+	}
+	return startOfDay(p.Start.In(now.Location())).AddDate(0, 0, -1), startOfDay(end.In(now.Location())).AddDate(0, 0, 2)
+}
+
+func takeEventSnapshot(ctx context.Context, from, to time.Time, calendar string, errOut io.Writer, verbose bool) (*eventSnapshot, error) {
+	s := &eventSnapshot{from: from, to: to, calendar: calendar, seen: map[string]bool{}, errOut: errOut, verbose: verbose}
+	events, err := s.fetch(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("verify snapshot: %w", err)
+	}
+	for _, ev := range events {
+		s.seen[ev.ID] = true
+	}
+	logVerbose(errOut, verbose, "verify: %d existing events between %s and %s", len(events), from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return s, nil
+}
+
+func (s *eventSnapshot) fetch(ctx context.Context, refresh bool) ([]eventInfo, error) {
+	args := []string{"events", "--format", "json",
+		"--from", s.from.Format(time.RFC3339),
+		"--to", s.to.Format(time.RFC3339),
+		"--no-input"}
+	if s.calendar != "" {
+		args = append(args, "--calendar", s.calendar)
+	}
+	if refresh {
+		args = append(args, "--refresh")
+	}
+	var buf bytes.Buffer
+	if err := runEventKitHelper(ctx, args, &buf, s.errOut, s.verbose); err != nil {
+		return nil, err
+	}
+	var events []eventInfo
+	if err := json.Unmarshal(buf.Bytes(), &events); err != nil {
+		return nil, fmt.Errorf("invalid events output: %w", err)
+	}
+	return events, nil
+}
+
+// poll re-reads the range until events missing from the snapshot appear or
+// timeout elapses. Only the first poll asks the helper to refresh calendar
+// sources, which is slow; helper failures while polling are retried.
+func (s *eventSnapshot) poll(ctx context.Context, timeout, interval time.Duration) verifyOutcome {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	outcome := verifyOutcome{Status: "timeout", From: s.from, To: s.to, Events: []eventInfo{}}
+	for {
+		outcome.Polls++
+		events, err := s.fetch(ctx, outcome.Polls == 1)
+		if err != nil {
+			logVerbose(s.errOut, s.verbose, "verify: poll %d failed: %v", outcome.Polls, err)
+		}
+		for _, ev := range events {
+			if !s.seen[ev.ID] {
+				outcome.Events = append(outcome.Events, ev)
+			}
+		}
+		if len(outcome.Events) > 0 {
+			outcome.Status = "created"
+			break
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		if !sleepCommand(ctx, min(interval, remaining)) {
+			break
+		}
+	}
+	outcome.ElapsedMs = time.Since(start).Milliseconds()
+	logVerbose(s.errOut, s.verbose, "verify: %s after %d polls", outcome.Status, outcome.Polls)
+	return outcome
+}
@@ -0,0 +1,143 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNewEventJSON = `{"id":"EV-NEW","title":"Dentist","calendar":"Home","calendarId":"cal-home","start":"2026-10-23T10:00:00+02:00","end":"2026-10-23T11:00:00+02:00","allDay":false}`
+
+// stubVerifyEnv installs a helper that lists testEventsJSON until the opener
+// has run, and testEventsJSON plus testNewEventJSON afterwards (unless
+// create is false). It returns the helper's argument log.
+func stubVerifyEnv(t *testing.T, create bool) string {
+	t.Helper()
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.json")
+	after := filepath.Join(dir, "after.json")
+	marker := filepath.Join(dir, "opened")
+	argLog := filepath.Join(dir, "helper.log")
+	if err := os.WriteFile(before, []byte(testEventsJSON), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	if err := os.WriteFile(after, []byte(strings.TrimSuffix(testEventsJSON, "]")+","+testNewEventJSON+"]"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	helper := filepath.Join(dir, "helper.sh")
+	script := "#!/bin/sh\necho \"$@\" >> '" + argLog + "'\nif [ -f '" + marker + "' ]; then cat '" + after + "'; else cat '" + before + "'; fi\n"
+	opener := filepath.Join(dir, "open.sh")
+	openScript := "#!/bin/sh\n"
+	if create {
+		openScript += "touch '" + marker + "'\n"
+	}
+	for path, data := range map[string][]byte{helper: withHandshake(t, script), opener: []byte(openScript)} {
+		if err := os.WriteFile(path, data, 0o755); err != nil {
+			t.Fatalf("write stub: %v", err)
+		}
+	}
+	t.Setenv("FANTASTICAL_EVENTKIT_HELPER", helper)
+	t.Setenv("FANTASTICAL_OPEN_COMMAND", opener)
+	return argLog
+}
+
+func TestCmdParseVerify(t *testing.T) {
+	setupTestEnv(t)
+	argLog := stubVerifyEnv(t, true)
+
+	var out bytes.Buffer
+	if err := cmdParse(context.Background(), []string{"--verify", "--add", "--json", "Dentist Friday 10am"}, strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	var result parseResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	v := result.Verify
+	if v == nil || v.Status != "created" || len(v.Events) != 1 || v.Events[0].ID != "EV-NEW" || v.Polls != 1 {
+		t.Fatalf("unexpected verify outcome: %s", out.String())
+	}
+	if v.From.Weekday() != time.Thursday || v.To.Sub(v.From) > 4*24*time.Hour {
+		t.Fatalf("expected a window around the predicted Friday, got %s..%s", v.From, v.To)
+	}
+
+	data, err := os.ReadFile(argLog)
+	if err != nil {
+		t.Fatalf("read helper log: %v", err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 2 || strings.Contains(calls[0], "--refresh") || !strings.Contains(calls[1], "--refresh") || !strings.Contains(calls[0], "--no-input") {
+		t.Fatalf("expected a snapshot then one refreshed poll, got %q", calls)
+	}
+}
+
+func TestCmdParseVerifyTimeout(t *testing.T) {
+	setupTestEnv(t)
+	stubVerifyEnv(t, false)
+
+	var out bytes.Buffer
+	err := cmdParse(context.Background(), []string{"--verify", "--verify-timeout", "150ms", "--json", "Nothing happens"}, strings.NewReader(""), &out, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "no new event") {
+		t.Fatalf("expected verify timeout error, got %v", err)
+	}
+	var result parseResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || result.Verify == nil || result.Verify.Status != "timeout" || len(result.Verify.Events) != 0 {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+
+	if err := cmdParse(context.Background(), []string{"--verify", "--dry-run", "Lunch"}, strings.NewReader(""), io.Discard, io.Discard); !errors.Is(err, errUsage) {
+		t.Fatalf("expected usage error for --verify --dry-run, got %v", err)
+	}
+}
+
+func TestVerifyWindow(t *testing.T) {
+	loc := time.FixedZone("X", 2*60*60)
+	now := time.Date(2026, time.October, 18, 23, 30, 0, 0, loc)
+	day := func(d int) time.Time { return time.Date(2026, time.October, d, 0, 0, 0, 0, loc) }
+	cases := []struct {
+		sentence string
+		from, to time.Time
+	}{
+		{"Dentist Friday 10am", day(22), day(25)},
+		{"Lunch tomorrow at noon", day(18), day(21)},
+		{"Call mom sometime", day(17), day(25)},
+	}
+	for _, tc := range cases {
+		u := buildParseURL(tc.sentence, "", "", false, nil)
+		if from, to := verifyWindow(u, now); !from.Equal(tc.from) || !to.Equal(tc.to) {
+			t.Fatalf("%q: unexpected window %s..%s", tc.sentence, from, to)
+		}
+	}
+}
+
+func TestVerifyPollRefreshesOnce(t *testing.T) {
+	setupTestEnv(t)
+	argLog := stubVerifyEnv(t, false)
+
+	from, to := verifyWindow("", time.Now())
+	s, err := takeEventSnapshot(context.Background(), from, to, "", io.Discard, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome := s.poll(context.Background(), 50*time.Millisecond, 10*time.Millisecond); outcome.Status != "timeout" || outcome.Polls < 3 {
+		t.Fatalf("expected several polls, got %+v", outcome)
+	}
+	data, _ := os.ReadFile(argLog)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i, call := range calls {
+		if strings.Contains(call, "--refresh") != (i == 1) {
+			t.Fatalf("expected --refresh on the first poll only, got %q", calls)
+		}
+	}
+}